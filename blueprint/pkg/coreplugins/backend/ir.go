@@ -22,6 +22,11 @@ type (
 		service.ServiceNode
 	}
 
+	PubSub interface {
+		ir.IRNode
+		service.ServiceNode
+	}
+
 	RelDB interface {
 		ir.IRNode
 		service.ServiceNode
@@ -10,23 +10,20 @@ import (
 	"golang.org/x/exp/slog"
 )
 
-func generateClient(builder golang.ModuleBuilder, wrapped *gocode.ServiceInterface, outputPackage string, MinReqs int64, FailureRate float64, Interval string) error {
+func generateClient(builder golang.ModuleBuilder, wrapped *gocode.ServiceInterface, outputPackage string) error {
 	pkg, err := builder.CreatePackage(outputPackage)
 	if err != nil {
 		return err
 	}
 
 	client := clientArgs{
-		Package:     pkg,
-		Service:     wrapped,
-		Name:        wrapped.BaseName + "_CircuitBreakerClient",
-		MinReqs:     MinReqs,
-		FailureRate: FailureRate,
-		Interval:    Interval,
-		Imports:     gogen.NewImports(pkg.Name),
+		Package: pkg,
+		Service: wrapped,
+		Name:    wrapped.BaseName + "_CircuitBreakerClient",
+		Imports: gogen.NewImports(pkg.Name),
 	}
 
-	client.Imports.AddPackages("context", "time", "github.com/mercari/go-circuitbreaker")
+	client.Imports.AddPackages("context", "github.com/blueprint-uservices/blueprint/runtime/core/control", "github.com/blueprint-uservices/blueprint/runtime/plugins/circuitbreaker")
 
 	slog.Info(fmt.Sprintf("Generating %v/%v", client.Package.PackageName, wrapped.BaseName+"CircuitBreakerClient"))
 	outputFile := filepath.Join(client.Package.Path, wrapped.BaseName+"_CircuitBreakerClient.go")
@@ -34,13 +31,10 @@ func generateClient(builder golang.ModuleBuilder, wrapped *gocode.ServiceInterfa
 }
 
 type clientArgs struct {
-	Package     golang.PackageInfo
-	Service     *gocode.ServiceInterface
-	Name        string
-	MinReqs     int64
-	FailureRate float64
-	Interval    string
-	Imports     *gogen.Imports
+	Package golang.PackageInfo
+	Service *gocode.ServiceInterface
+	Name    string
+	Imports *gogen.Imports
 }
 
 var clientTemplate = `// Blueprint: Auto-generated by CircuitBreaker Plugin
@@ -50,30 +44,17 @@ package {{.Package.ShortName}}
 
 type {{.Name}} struct {
 	Client {{.Imports.NameOf .Service.UserType}}
-	MinReqs int64
-	FailureRate float64
-	cb *circuitbreaker.CircuitBreaker
+	Breaker *circuitbreaker.Breaker
 }
 
-func New_{{.Name}} (ctx context.Context, client {{.Imports.NameOf .Service.UserType}}) (*{{.Name}}, error) {
+func New_{{.Name}} (ctx context.Context, client {{.Imports.NameOf .Service.UserType}}, name string, opts ...string) (*{{.Name}}, error) {
 	handler := &{{.Name}}{}
 	handler.Client = client
-	handler.MinReqs = {{.MinReqs}}
-	handler.FailureRate = {{.FailureRate}}
-
-	dur, err := time.ParseDuration("{{.Interval}}")
+	breaker, err := circuitbreaker.NewBreaker(name, opts...)
 	if err != nil {
 		return nil, err
 	}
-
-	cb := circuitbreaker.New(
-		circuitbreaker.WithFailOnContextCancel(true),
-		circuitbreaker.WithFailOnContextDeadline(true),
-		circuitbreaker.WithCounterResetInterval(dur),
-		circuitbreaker.WithTripFunc(circuitbreaker.NewTripFuncFailureRate(handler.MinReqs,handler.FailureRate)),
-	)
-	handler.cb = cb
-
+	handler.Breaker = control.Register(name, "circuitbreaker", breaker).(*circuitbreaker.Breaker)
 	return handler, nil
 }
 
@@ -81,12 +62,12 @@ func New_{{.Name}} (ctx context.Context, client {{.Imports.NameOf .Service.UserT
 {{$receiver := .Name -}}
 {{ range $_, $f := .Service.Methods }}
 func (client *{{$receiver}}) {{$f.Name -}} ({{ArgVarsAndTypes $f "ctx context.Context"}}) ({{RetVarsAndTypes $f "err error"}}) {
-	if !client.cb.Ready() {
-		err = circuitbreaker.ErrOpen
+	err = client.Breaker.Do(ctx, "{{$f.Name}}", func(ctx context.Context) (err error) {
+		{{RetVars $f "err"}} = client.Client.{{$f.Name}}({{ArgVars $f "ctx"}})
 		return
-	}
-	defer func() { err = client.cb.Done(ctx, err)}()
-	return client.Client.{{$f.Name}}({{ArgVars $f "ctx"}})
+	})
+	err = client.Breaker.Fallback(ctx, "{{$f.Name}}", err{{range $i, $_ := $f.Returns}}, &ret{{$i}}{{end}})
+	return
 }
 {{end}}
 `
@@ -1,8 +1,11 @@
 package circuitbreaker
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/service"
@@ -24,6 +27,7 @@ type CircuitBreakerClient struct {
 	Min_Reqs      int64
 	FailureRate   float64
 	Interval      string
+	Options       []ir.IRNode // Additional configuration of the runtime breaker, e.g. fallbacks
 }
 
 func (node *CircuitBreakerClient) ImplementsGolangNode() {}
@@ -33,10 +37,14 @@ func (node *CircuitBreakerClient) Name() string {
 }
 
 func (node *CircuitBreakerClient) String() string {
-	return node.Name() + " = CircuitBreaker(" + node.Wrapped.Name() + ")"
+	args := []string{node.Wrapped.Name()}
+	for _, opt := range node.Options {
+		args = append(args, opt.String())
+	}
+	return node.Name() + " = CircuitBreaker(" + strings.Join(args, ", ") + ")"
 }
 
-func newCircuitBreakerClient(name string, server ir.IRNode, min_reqs int64, failure_rate float64, interval string) (*CircuitBreakerClient, error) {
+func newCircuitBreakerClient(name string, server ir.IRNode, min_reqs int64, failure_rate float64, interval string, opts []Option) (*CircuitBreakerClient, error) {
 	serverNode, is_callable := server.(golang.Service)
 	if !is_callable {
 		return nil, blueprint.Errorf("circuitbreaker client wrapper requires %s to be a golang service but got %s", server.Name(), reflect.TypeOf(server).String())
@@ -49,6 +57,12 @@ func newCircuitBreakerClient(name string, server ir.IRNode, min_reqs int64, fail
 	node.Min_Reqs = min_reqs
 	node.FailureRate = failure_rate
 	node.Interval = interval
+	for _, opt := range opts {
+		if reason, invalid := strings.CutPrefix(string(opt), invalidOption); invalid {
+			return nil, blueprint.Errorf("circuitbreaker %v is %v", name, reason)
+		}
+		node.Options = append(node.Options, &ir.IRValue{Value: string(opt)})
+	}
 
 	return node, nil
 }
@@ -71,7 +85,7 @@ func (node *CircuitBreakerClient) GenerateFuncs(builder golang.ModuleBuilder) er
 		return err
 	}
 
-	return generateClient(builder, iface, node.outputPackage, node.Min_Reqs, node.FailureRate, node.Interval)
+	return generateClient(builder, iface, node.outputPackage)
 }
 
 func (node *CircuitBreakerClient) AddInstantiation(builder golang.NamespaceBuilder) error {
@@ -91,9 +105,49 @@ func (node *CircuitBreakerClient) AddInstantiation(builder golang.NamespaceBuild
 			Arguments: []gocode.Variable{
 				{Name: "ctx", Type: &gocode.UserType{Package: "context", Name: "Context"}},
 				{Name: "client", Type: iface},
+				{Name: "name", Type: &gocode.BasicType{Name: "string"}},
+				{Name: "opts", Type: &gocode.Ellipsis{EllipsisOf: &gocode.BasicType{Name: "string"}}},
 			},
 		},
 	}
 
-	return builder.DeclareConstructor(node.InstanceName, constructor, []ir.IRNode{node.Wrapped})
+	if err := checkFallbacks(iface, node.Options); err != nil {
+		return blueprint.Errorf("%v: %v", node.InstanceName, err)
+	}
+
+	args := []ir.IRNode{
+		node.Wrapped,
+		&ir.IRValue{Value: node.InstanceName},
+		&ir.IRValue{Value: "minreqs=" + strconv.FormatInt(node.Min_Reqs, 10)},
+		&ir.IRValue{Value: "failurerate=" + strconv.FormatFloat(node.FailureRate, 'g', -1, 64)},
+		&ir.IRValue{Value: "interval=" + node.Interval},
+	}
+	return builder.DeclareConstructor(node.InstanceName, constructor, append(args, node.Options...))
+}
+
+// Checks that fallback options name methods of iface and have one value for each of the method's return values
+func checkFallbacks(iface *gocode.ServiceInterface, opts []ir.IRNode) error {
+	for _, opt := range opts {
+		value, isValue := opt.(*ir.IRValue)
+		if !isValue {
+			continue
+		}
+		key, values, _ := strings.Cut(value.Value, "=")
+		methodName, isFallback := strings.CutPrefix(key, "fallback.")
+		if !isFallback {
+			continue
+		}
+		method, exists := iface.Methods[methodName]
+		if !exists {
+			return fmt.Errorf("fallback for unknown method %v of %v", methodName, iface.Name)
+		}
+		var decoded []json.RawMessage
+		if err := json.Unmarshal([]byte(values), &decoded); err != nil {
+			return fmt.Errorf("invalid fallback for %v: %v", methodName, err)
+		}
+		if len(decoded) != len(method.Returns) {
+			return fmt.Errorf("fallback for %v has %v values but %v returns %v values", methodName, len(decoded), methodName, len(method.Returns))
+		}
+	}
+	return nil
 }
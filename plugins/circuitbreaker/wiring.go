@@ -1,9 +1,30 @@
 // Package circuitbreaker provides a Blueprint modifier for the client side of service calls.
 //
-// The plugin wraps clients with a circuitbreaker that blocks any new requests from being sent out over a connection if the failure rate exceeds a provided number in a fixed duration. The block is removed after the completion of the fixed duration interval.
+// The plugin wraps clients with a circuitbreaker that blocks any new requests from being sent out over a connection if the failure rate exceeds a provided number in a fixed duration.
+// Each method of the client has its own circuit.  An open circuit becomes half-open after a cool-down, which defaults to the interval, and lets probe requests through;
+// the circuit closes if the probes succeed and re-opens if any of them fails.
+//
+// Options configure the cool-down and the number of probes, share one circuit between all methods, and provide fallback values that are returned instead of an error
+// while a method's circuit is open, e.g.
+//
+//	circuitbreaker.AddCircuitBreaker(spec, "user_service", 100, 0.5, "10s",
+//		circuitbreaker.Cooldown(30*time.Second),
+//		circuitbreaker.HalfOpenProbes(5),
+//		circuitbreaker.Fallback("GetRecommendations", []string{}),
+//	)
+//
+// State transitions are logged, added as events to the current trace span, and counted in metrics.  The plugin utilizes some code in the [runtime/plugins/circuitbreaker] package.
+// The thresholds can be changed while the application is running using the [controlplane] plugin.
+//
+// [controlplane]: https://github.com/Blueprint-uServices/blueprint/tree/main/plugins/controlplane
+// [runtime/plugins/circuitbreaker]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/plugins/circuitbreaker
 package circuitbreaker
 
 import (
+	"encoding/json"
+	"fmt"
+	"time"
+
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/pointer"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
@@ -16,10 +37,11 @@ import (
 // Uses a [blueprint.WiringSpec].
 // Circuit breaker trips when `failure_rate` percentage of requests fail. Minimum number of requests for the circuit to break is specified using `min_reqs`.
 // The circuit breaker counters are reset after `interval` duration.
+// Additional [Option]s can further configure the circuit breaker.
 // Usage:
 //
 //	AddCircuitBreaker(spec, "serviceA", 1000, 0.1, "1s")
-func AddCircuitBreaker(spec wiring.WiringSpec, serviceName string, min_reqs int64, failure_rate float64, interval string) {
+func AddCircuitBreaker(spec wiring.WiringSpec, serviceName string, min_reqs int64, failure_rate float64, interval string, opts ...Option) {
 	clientWrapper := serviceName + ".client.cb"
 
 	ptr := pointer.GetPointer(spec, serviceName)
@@ -37,6 +59,44 @@ func AddCircuitBreaker(spec wiring.WiringSpec, serviceName string, min_reqs int6
 			return nil, blueprint.Errorf("CircuitBreaker %s expected %s to be a golang.Service, but encountered %s", clientWrapper, clientNext, err)
 		}
 
-		return newCircuitBreakerClient(clientWrapper, wrapped, min_reqs, failure_rate, interval)
+		return newCircuitBreakerClient(clientWrapper, wrapped, min_reqs, failure_rate, interval, opts)
 	})
 }
+
+// An option that configures a circuit breaker; see [runtime/plugins/circuitbreaker] for the full list of options.
+//
+// [runtime/plugins/circuitbreaker]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/plugins/circuitbreaker
+type Option string
+
+// Prefixes an [Option] returned by a function such as [Fallback] that was unable to build the option, followed
+// by the reason.  Building the circuit breaker then fails with the reason as its error.
+const invalidOption = "!invalid "
+
+// Sets how long an open circuit stays open before it becomes half-open; defaults to the interval
+func Cooldown(d time.Duration) Option {
+	return Option(fmt.Sprintf("cooldown=%v", d))
+}
+
+// Sets the number of probe requests that a half-open circuit lets through, all of which must succeed for the circuit to close
+func HalfOpenProbes(n int64) Option {
+	return Option(fmt.Sprintf("halfopenprobes=%v", n))
+}
+
+// Uses one circuit for all methods of the client, rather than a circuit for each method
+func SharedCircuit() Option {
+	return "permethod=false"
+}
+
+// Returns values instead of an error from calls of method that are rejected because its circuit is open.  values are
+// the method's return values, excluding the error, and are encoded as JSON; a method that only returns an error
+// needs no values.  If values cannot be encoded as JSON, then building the circuit breaker fails.
+func Fallback(method string, values ...any) Option {
+	if values == nil {
+		values = []any{}
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return Option(fmt.Sprintf("%vunable to encode fallback values of %v due to %v", invalidOption, method, err.Error()))
+	}
+	return Option("fallback." + method + "=" + string(encoded))
+}
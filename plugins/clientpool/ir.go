@@ -21,6 +21,7 @@ type ClientPool struct {
 
 	PoolName string
 	N        int
+	Options  []string
 	Client   golang.Service
 	Edges    []ir.IRNode
 	Nodes    []ir.IRNode
@@ -34,7 +35,11 @@ func (pool *ClientPool) Name() string {
 // Implements ir.IRNode
 func (pool *ClientPool) String() string {
 	var b strings.Builder
-	b.WriteString(fmt.Sprintf("%v = ClientPool(%v, %v) {\n", pool.PoolName, pool.Client.Name(), pool.N))
+	args := []string{pool.Client.Name(), fmt.Sprint(pool.N)}
+	for _, opt := range pool.Options {
+		args = append(args, fmt.Sprintf("%q", opt))
+	}
+	b.WriteString(fmt.Sprintf("%v = ClientPool(%v) {\n", pool.PoolName, strings.Join(args, ", ")))
 	var children []string
 	for _, child := range pool.Nodes {
 		children = append(children, child.String())
@@ -143,6 +148,7 @@ func (pool *ClientPool) getTemplateArgs(module golang.ModuleBuilder) (*templateA
 	args.WrappedClient = pool.Client.Name()
 	args.InstanceName = pool.PoolName
 	args.MaxClients = pool.N
+	args.Options = append([]string{"name=" + pool.PoolName}, pool.Options...)
 	args.PoolName = args.Service.Name + "_ClientPool"
 	args.PackageShortName = "pool"
 	args.PackageName = module.Info().Name + "/" + args.PackageShortName
@@ -165,6 +171,7 @@ type (
 		InstanceName      string
 		WrappedClient     string
 		MaxClients        int
+		Options           []string
 		PoolName          string
 		PackageShortName  string
 		PackageName       string
@@ -178,7 +185,7 @@ type (
 )
 
 var buildPoolTemplate = `func(n *golang.Namespace) (any, error) {
-		return pool.{{.PoolConstructor}}(n), nil
+		return pool.{{.PoolConstructor}}(n)
 	}`
 
 var poolTemplate = `// This file is auto-generated by the Blueprint clientpool plugin
@@ -190,7 +197,7 @@ type {{.PoolName}} struct {
 	clients *clientpool.ClientPool[{{NameOf .Service.UserType}}]
 }
 
-func {{.PoolConstructor}}(parent *golang.Namespace) *{{.PoolName}} {
+func {{.PoolConstructor}}(parent *golang.Namespace) (*{{.PoolName}}, error) {
 	i := 0
 	createClient := func() ({{NameOf .Service.UserType}}, error) {
 		clientName := fmt.Sprintf("{{.InstanceName}}.%v", i)
@@ -203,8 +210,11 @@ func {{.PoolConstructor}}(parent *golang.Namespace) *{{.PoolName}} {
 		err = n.Get("{{.WrappedClient}}", &client)
 		return client, err
 	}
-	clients := clientpool.NewClientPool({{.MaxClients}}, createClient)
-	return &{{.PoolName}}{clients: clients}
+	clients, err := clientpool.NewClientPool({{.MaxClients}}, createClient{{range .Options}}, {{printf "%q" .}}{{end}})
+	if err != nil {
+		return nil, err
+	}
+	return &{{.PoolName}}{clients: clients}, nil
 }
 
 {{$service := .Service -}}
@@ -215,7 +225,7 @@ func (pool *{{$receiver}}) {{SignatureWithRetVars $f}} {
 	if err != nil {
 		return
 	}
-	defer pool.clients.Push(client)
+	defer func() { pool.clients.Release(client, err) }()
 	return client.{{$f.Name}}({{ArgVars $f "ctx"}})
 }
 {{end}}
@@ -7,6 +7,10 @@
 //
 //	clientpool.Create(spec, "my_service", 10)
 //
+// Options can be provided to evict idle, old, or broken clients from the pool, e.g.
+//
+//	clientpool.Create(spec, "my_service", 10, clientpool.IdleTimeout(time.Minute), clientpool.EvictOnError())
+//
 // # Description
 //
 // When applied, the clientpool plugin instantiates N instances of clients to a service, and callers have exclusive
@@ -15,6 +19,9 @@
 // By contrast, the default Blueprint behavior is for all callers to share a single client that allows an unlimited
 // number of concurrent calls.
 //
+// Clients that implement the runtime HealthChecker interface are health-checked before they are reused.  Pool
+// statistics, such as the number of waiting callers and evicted clients, are reported as OpenTelemetry metrics.
+//
 // After applying the clientpool plugin to a service, you can continue to apply application-level
 // modifiers to the service.
 //
@@ -27,6 +34,8 @@
 package clientpool
 
 import (
+	"time"
+
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/pointer"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/wiring"
@@ -44,8 +53,10 @@ import (
 // serviceName must be an application-level service instance, e.g. clientpool must be applied to the service
 // before deploying the service over RPC or to a process.
 //
+// opts can be provided to configure eviction of clients from the pool.
+//
 // After calling [Create] you can continue to apply application-level modifiers to serviceName.
-func Create(spec wiring.WiringSpec, serviceName string, numClients int) {
+func Create(spec wiring.WiringSpec, serviceName string, numClients int, opts ...Option) {
 	poolName := serviceName + ".clientpool"
 
 	// Get the pointer metadata
@@ -61,6 +72,9 @@ func Create(spec wiring.WiringSpec, serviceName string, numClients int) {
 	// Define the client pool
 	spec.Define(poolName, &ClientPool{}, func(namespace wiring.Namespace) (ir.IRNode, error) {
 		pool := &ClientPool{PoolName: poolName, N: numClients}
+		for _, opt := range opts {
+			pool.Options = append(pool.Options, string(opt))
+		}
 		poolNamespace, err := namespace.DeriveNamespace(poolName, &clientPoolNamespace{pool})
 		if err != nil {
 			return nil, err
@@ -69,6 +83,24 @@ func Create(spec wiring.WiringSpec, serviceName string, numClients int) {
 	})
 }
 
+// An Option configures the pool created by [Create]
+type Option string
+
+// [IdleTimeout] evicts clients that have been idle in the pool for longer than timeout, shrinking the pool.
+func IdleTimeout(timeout time.Duration) Option {
+	return Option("idletimeout=" + timeout.String())
+}
+
+// [MaxLifetime] evicts clients once they are older than lifetime; a new client is built in its place when needed.
+func MaxLifetime(lifetime time.Duration) Option {
+	return Option("maxlifetime=" + lifetime.String())
+}
+
+// [EvictOnError] evicts a client from the pool whenever a call made with the client returns an error.
+func EvictOnError() Option {
+	return Option("evictonerror=true")
+}
+
 // A [wiring.NamespaceHandler] used to build [ClientPool] IRNodes
 type clientPoolNamespace struct {
 	*ClientPool
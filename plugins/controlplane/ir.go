@@ -0,0 +1,59 @@
+package controlplane
+
+import (
+	"fmt"
+
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/address"
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
+	"github.com/blueprint-uservices/blueprint/plugins/golang"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow/workflowspec"
+	"github.com/blueprint-uservices/blueprint/runtime/plugins/controlplane"
+	"golang.org/x/exp/slog"
+)
+
+// Blueprint IR node representing a process's control plane admin server
+type AdminServer struct {
+	golang.Node
+	golang.Instantiable
+
+	InstanceName string
+	Bind         *address.BindConfig
+	Spec         *workflowspec.Service
+}
+
+func newAdminServer(name string) (*AdminServer, error) {
+	spec, err := workflowspec.GetService[controlplane.AdminServer]()
+	node := &AdminServer{
+		InstanceName: name,
+		Spec:         spec,
+	}
+	return node, err
+}
+
+// Implements ir.IRNode
+func (node *AdminServer) Name() string {
+	return node.InstanceName
+}
+
+// Implements ir.IRNode
+func (node *AdminServer) String() string {
+	return node.Name() + " = AdminServer(" + node.Bind.Name() + ")"
+}
+
+// Implements golang.ProvidesModule
+func (node *AdminServer) AddToWorkspace(builder golang.WorkspaceBuilder) error {
+	return node.Spec.AddToWorkspace(builder)
+}
+
+// Implements golang.Instantiable
+func (node *AdminServer) AddInstantiation(builder golang.NamespaceBuilder) error {
+	if builder.Visited(node.InstanceName) {
+		return nil
+	}
+
+	slog.Info(fmt.Sprintf("Instantiating AdminServer %v in %v/%v", node.InstanceName, builder.Info().Package.PackageName, builder.Info().FileName))
+
+	return builder.DeclareConstructor(node.InstanceName, node.Spec.Constructor.AsConstructor(), []ir.IRNode{node.Bind})
+}
+
+func (node *AdminServer) ImplementsGolangNode() {}
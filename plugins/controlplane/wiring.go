@@ -0,0 +1,58 @@
+// Package controlplane provides a Blueprint plugin that adds an admin HTTP endpoint to a golang process,
+// for inspecting and changing the parameters of the process's modifiers while it is running.
+//
+// Modifiers such as retries, timeouts, circuit breakers, latency injection and fault injection register
+// their parameters with the process's control plane when they are instantiated.  The admin endpoint lists
+// every registered modifier and lets their parameters be changed live, so that experiments can sweep
+// parameters without recompiling and redeploying the application.
+//
+// # Wiring Spec Usage
+//
+// Add an admin endpoint to a process that has been defined with the [goproc] plugin:
+//
+//	proc := goproc.Deploy(spec, "leaf_service")
+//	controlplane.AddAdminServer(spec, proc)
+//
+// # Running the admin endpoint
+//
+// The admin server binds the address proc.admin.bind_addr, which is assigned in the same way as the addresses
+// of the process's services.  Modifiers are named after their wiring spec nodes.
+//
+//	curl http://$ADDR/modifiers
+//	curl http://$ADDR/modifiers/leaf_service.client.retrier
+//	curl -X PUT -d '{"maxtries": 5}' http://$ADDR/modifiers/leaf_service.client.retrier
+//
+// See [control.Handler] for the full API.
+//
+// [goproc]: https://github.com/Blueprint-uServices/blueprint/tree/main/plugins/goproc
+// [control.Handler]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/core/control
+package controlplane
+
+import (
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/address"
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/wiring"
+	"github.com/blueprint-uservices/blueprint/plugins/goproc"
+)
+
+// Adds an admin server to the golang process procName that serves the process's control plane.
+//
+// Returns the name of the admin server node.
+func AddAdminServer(spec wiring.WiringSpec, procName string) string {
+	server := procName + ".admin"
+	addr := server + ".addr"
+
+	address.Define[*AdminServer](spec, addr, server)
+
+	spec.Define(server, &AdminServer{}, func(ns wiring.Namespace) (ir.IRNode, error) {
+		node, err := newAdminServer(server)
+		if err != nil {
+			return nil, err
+		}
+		err = address.Bind[*AdminServer](ns, addr, node, &node.Bind)
+		return node, err
+	})
+
+	goproc.AddToProcess(spec, procName, server)
+	return server
+}
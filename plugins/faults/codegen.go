@@ -0,0 +1,75 @@
+package faults
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/blueprint-uservices/blueprint/plugins/golang"
+	"github.com/blueprint-uservices/blueprint/plugins/golang/gocode"
+	"github.com/blueprint-uservices/blueprint/plugins/golang/gogen"
+	"golang.org/x/exp/slog"
+)
+
+// code generation function called from the ir.go file.
+func generateWrapper(builder golang.ModuleBuilder, wrapped *gocode.ServiceInterface, outputPackage string) error {
+	pkg, err := builder.CreatePackage(outputPackage)
+	if err != nil {
+		return err
+	}
+
+	wrapper := wrapperArgs{
+		Package: pkg,
+		Service: wrapped,
+		Name:    wrapped.BaseName + "_FaultInjector",
+		Imports: gogen.NewImports(pkg.Name),
+	}
+
+	wrapper.Imports.AddPackages("context", "github.com/blueprint-uservices/blueprint/runtime/plugins/faults")
+	slog.Info(fmt.Sprintf("Generating %v/%v", wrapper.Package.PackageName, wrapper.Name))
+	outputFile := filepath.Join(wrapper.Package.Path, wrapper.Name+".go")
+
+	return gogen.ExecuteTemplateToFile("FaultInjector", wrapperTemplate, wrapper, outputFile)
+}
+
+type wrapperArgs struct {
+	Package golang.PackageInfo
+	Service *gocode.ServiceInterface
+	Name    string
+	Imports *gogen.Imports
+}
+
+var wrapperTemplate = `// Blueprint: Auto-generated by FaultInjector Plugin
+package {{.Package.ShortName}}
+
+{{.Imports}}
+
+type {{.Name}} struct {
+	Service {{.Imports.NameOf .Service.UserType}}
+	Injector *faults.Injector
+}
+
+func New_{{.Name}} (ctx context.Context, service {{.Imports.NameOf .Service.UserType}}, name string, opts ...string) (*{{.Name}}, error) {
+	handler := &{{.Name}}{}
+	handler.Service = service
+	injector, err := faults.NewInjector(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	handler.Injector = injector
+	return handler, nil
+}
+
+{{$service := .Service.Name -}}
+{{$receiver := .Name -}}
+{{ range $_, $f := .Service.Methods }}
+func (handler *{{$receiver}}) {{$f.Name -}} ({{ArgVarsAndTypes $f "ctx context.Context"}}) ({{RetVarsAndTypes $f "err error"}}) {
+	fault, err := handler.Injector.Before(ctx, "{{$f.Name}}")
+	if err != nil {
+		return
+	}
+	{{RetVars $f "err"}} = handler.Service.{{$f.Name}}({{ArgVars $f "ctx"}})
+	err = handler.Injector.After(ctx, "{{$f.Name}}", fault, err)
+	return
+}
+{{end}}
+`
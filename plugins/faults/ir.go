@@ -0,0 +1,114 @@
+package faults
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/service"
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
+	"github.com/blueprint-uservices/blueprint/plugins/golang"
+	"github.com/blueprint-uservices/blueprint/plugins/golang/gocode"
+)
+
+// Blueprint IR Node representing a fault injector on the server or client side of a service
+type FaultInjectorWrapper struct {
+	golang.Service
+	golang.GeneratesFuncs
+	golang.Instantiable
+
+	InstanceName  string
+	Wrapped       golang.Service
+	outputPackage string
+	Options       []ir.IRNode // Configuration of the runtime fault injector
+}
+
+func newFaultInjectorWrapper(name string, wrapped ir.IRNode, opts []Option) (*FaultInjectorWrapper, error) {
+	wrappedNode, is_callable := wrapped.(golang.Service)
+	if !is_callable {
+		return nil, blueprint.Errorf("fault injector wrapper requires %s to be a golang service but got %s", wrapped.Name(), reflect.TypeOf(wrapped).String())
+	}
+
+	node := &FaultInjectorWrapper{}
+	node.InstanceName = name
+	node.Wrapped = wrappedNode
+	node.outputPackage = "faultinjector"
+	for _, opt := range opts {
+		node.Options = append(node.Options, &ir.IRValue{Value: string(opt)})
+	}
+	return node, nil
+}
+
+// Implements [ir.IRNode]
+func (node *FaultInjectorWrapper) ImplementsGolangNode() {}
+
+// Implements [golang.Service]
+func (node *FaultInjectorWrapper) ImplementsGolangService() {}
+
+// Implements [ir.IRNode]
+func (node *FaultInjectorWrapper) Name() string {
+	return node.InstanceName
+}
+
+// Implements [ir.IRNode]
+func (node *FaultInjectorWrapper) String() string {
+	args := []string{node.Wrapped.Name()}
+	for _, opt := range node.Options {
+		args = append(args, opt.String())
+	}
+	return node.Name() + " = FaultInjector(" + strings.Join(args, ", ") + ")"
+}
+
+// Implements [golang.Service]
+func (node *FaultInjectorWrapper) AddInterfaces(builder golang.ModuleBuilder) error {
+	return node.Wrapped.AddInterfaces(builder)
+}
+
+// Implements [golang.Service]
+func (node *FaultInjectorWrapper) GetInterface(ctx ir.BuildContext) (service.ServiceInterface, error) {
+	return node.Wrapped.GetInterface(ctx)
+}
+
+// Implements [golang.GeneratesFuncs]
+func (node *FaultInjectorWrapper) GenerateFuncs(builder golang.ModuleBuilder) error {
+	iface, err := golang.GetGoInterface(builder, node)
+	if err != nil {
+		return err
+	}
+
+	// The same wrapper is used on the server and client sides, so only generate it once per interface
+	if builder.Visited(iface.Name + "_FaultInjector") {
+		return nil
+	}
+
+	return generateWrapper(builder, iface, node.outputPackage)
+}
+
+// Implements golang.Instantiable
+func (node *FaultInjectorWrapper) AddInstantiation(builder golang.NamespaceBuilder) error {
+	if builder.Visited(node.InstanceName) {
+		return nil
+	}
+
+	iface, err := golang.GetGoInterface(builder, node.Wrapped)
+	if err != nil {
+		return err
+	}
+
+	constructor := &gocode.Constructor{
+		Package: builder.Module().Info().Name + "/" + node.outputPackage,
+		Func: gocode.Func{
+			Name: fmt.Sprintf("New_%v_FaultInjector", iface.BaseName),
+			Arguments: []gocode.Variable{
+				{Name: "ctx", Type: &gocode.UserType{Package: "context", Name: "Context"}},
+				{Name: "service", Type: iface},
+				{Name: "name", Type: &gocode.BasicType{Name: "string"}},
+				{Name: "opts", Type: &gocode.Ellipsis{EllipsisOf: &gocode.BasicType{Name: "string"}}},
+			},
+		},
+	}
+
+	args := append([]ir.IRNode{node.Wrapped, &ir.IRValue{Value: node.InstanceName}}, node.Options...)
+	return builder.DeclareConstructor(node.InstanceName, constructor, args)
+}
@@ -0,0 +1,129 @@
+// Package faults provides a Blueprint modifier for the server or client side of service calls that injects faults,
+// for resilience experiments.
+//
+// The plugin generates a wrapper class that, at configurable per-method rates, makes calls fail with an error,
+// aborts calls by replacing their response with an error, drops the response of calls so that callers wait until
+// their context is done or a timeout expires, or panics.
+// Example usage to make 10% of calls to my_service fail, and 1% of calls to its GetCart method panic:
+//
+//	import "github.com/blueprint-uservices/blueprint/plugins/faults"
+//	faults.AddServerFaults(spec, "my_service", faults.ErrorRate(0.1), faults.PanicRate(0.01, "GetCart"))
+//
+// Fault injectors can be enabled, disabled and reconfigured while the application is running.  The FAULTS_<NAME>
+// environment variable of a process configures the injector with instance name <name>, e.g. the variable
+// FAULTS_MY_SERVICE_SERVER_FAULTS="drop=0.5" configures the injector added to my_service above.  If the
+// FAULTS_ADMIN_ADDR environment variable of a process is set, the process serves an admin HTTP endpoint at that
+// address for viewing and changing the configuration of its injectors.
+//
+// The plugin utilizes some code in the [runtime/plugins/faults] package, which documents the admin endpoint.
+//
+// [runtime/plugins/faults]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/plugins/faults
+package faults
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/pointer"
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/wiring"
+	"github.com/blueprint-uservices/blueprint/plugins/golang"
+	"golang.org/x/exp/slog"
+)
+
+// An Option configures the faults injected by [AddServerFaults] and [AddClientFaults]
+type Option string
+
+func rate(fault string, rate float64, methods []string) Option {
+	if len(methods) == 0 {
+		return Option(fmt.Sprintf("%v=%v", fault, rate))
+	}
+	return Option(fmt.Sprintf("%v.%v=%v", strings.Join(methods, ","), fault, rate))
+}
+
+// [ErrorRate] makes the given fraction of calls fail with an error, without the call being made.  If methods are
+// named, only applies to calls to those methods; otherwise applies to all methods.  Methods that are named by any
+// option don't use the rates of options that apply to all methods.
+func ErrorRate(fraction float64, methods ...string) Option {
+	return rate("error", fraction, methods)
+}
+
+// [AbortRate] aborts the given fraction of calls: the call is made, but its response is replaced by an error.
+// Methods are as for [ErrorRate].
+func AbortRate(fraction float64, methods ...string) Option {
+	return rate("abort", fraction, methods)
+}
+
+// [DropRate] drops the response of the given fraction of calls: the call is made, but the caller waits until its
+// context is done or the drop timeout expires, then receives an error.  Methods are as for [ErrorRate].
+func DropRate(fraction float64, methods ...string) Option {
+	return rate("drop", fraction, methods)
+}
+
+// [DropTimeout] sets the longest that callers of dropped calls wait for their context to be done.  The default
+// is 30 seconds.
+func DropTimeout(timeout time.Duration) Option {
+	return Option("droptimeout=" + timeout.String())
+}
+
+// [PanicRate] makes the given fraction of calls panic, without the call being made.  Methods are as for [ErrorRate].
+func PanicRate(fraction float64, methods ...string) Option {
+	return rate("panic", fraction, methods)
+}
+
+// [Disabled] disables the fault injector when the process starts, so that it can be enabled at runtime.
+func Disabled() Option {
+	return Option("enabled=false")
+}
+
+// Adds fault injection on the server side of the specified service.
+// Uses a [blueprint.WiringSpec]
+// Modifies the given service such that faults are injected into requests before they are handled by the service.
+// The fault injector's instance name is `serviceName.server.faults`.
+// Usage:
+//
+//	AddServerFaults(spec, "my_service", faults.ErrorRate(0.1))
+func AddServerFaults(spec wiring.WiringSpec, serviceName string, opts ...Option) {
+	wrapperName := serviceName + ".server.faults"
+	ptr := pointer.GetPointer(spec, serviceName)
+	if ptr == nil {
+		slog.Error("Unable to add a fault injector to " + serviceName + " as it is not a pointer")
+		return
+	}
+
+	serverNext := ptr.AddDstModifier(spec, wrapperName)
+	defineWrapper(spec, wrapperName, serverNext, opts)
+}
+
+// Adds fault injection on the client side of the specified service.
+// Uses a [blueprint.WiringSpec]
+// Modifies the given service such that faults are injected into the calls made by all clients of the service.
+// The fault injector's instance name is `serviceName.client.faults`.
+// Usage:
+//
+//	AddClientFaults(spec, "my_service", faults.DropRate(0.05))
+func AddClientFaults(spec wiring.WiringSpec, serviceName string, opts ...Option) {
+	wrapperName := serviceName + ".client.faults"
+	ptr := pointer.GetPointer(spec, serviceName)
+	if ptr == nil {
+		slog.Error("Unable to add a fault injector to " + serviceName + " as it is not a pointer")
+		return
+	}
+
+	clientNext := ptr.AddSrcModifier(spec, wrapperName)
+	defineWrapper(spec, wrapperName, clientNext, opts)
+}
+
+func defineWrapper(spec wiring.WiringSpec, wrapperName string, next string, opts []Option) {
+	spec.Define(wrapperName, &FaultInjectorWrapper{}, func(ns wiring.Namespace) (ir.IRNode, error) {
+		var wrapped golang.Service
+
+		if err := ns.Get(next, &wrapped); err != nil {
+			return nil, blueprint.Errorf("FaultInjector %s expected %s to be a golang.Service, but encountered %s", wrapperName, next, err)
+		}
+
+		return newFaultInjectorWrapper(wrapperName, wrapped, opts)
+	})
+}
@@ -21,6 +21,21 @@ type (
 		Name      string
 		Arguments []Variable
 		Returns   []Variable
+
+		// Directives declared in the func's doc comment, e.g. //blueprint:idempotent, keyed by
+		// the directive name without the blueprint: prefix.  A directive's value is the text after
+		// an equals sign, e.g. //blueprint:name=value, or the empty string.
+		Directives map[string]string
+	}
+
+	// A stream of values between the caller and callee of a service method, declared by one of the
+	// method's arguments or return values; see [Func.Streams]
+	Stream struct {
+		Variable            // The argument or return value that declares the stream
+		Index      int      // The position of Variable in the method's arguments, or returns if it is returned
+		IsArgument bool     // Whether Variable is an argument of the method rather than a return value
+		IsCallback bool     // Whether Variable is an iterator callback, e.g. yield func(Order) error, rather than a channel
+		Elem       TypeName // The type of the streamed values
 	}
 
 	Constructor struct {
@@ -144,6 +159,55 @@ func (f Func) String() string {
 	}
 }
 
+// Returns the streams declared by the arguments and return values of f.  Arguments that are
+// channels, e.g. orders <-chan Order, stream values from the caller to the callee, which sends
+// values on the channel and closes it.  Returned channels, and arguments that are iterator
+// callbacks, e.g. yield func(Order) error, stream values from the callee to the caller.
+func (f Func) Streams() (requests []Stream, responses []Stream) {
+	for i, arg := range f.Arguments {
+		if elem := receivedType(arg.Type); elem != nil {
+			requests = append(requests, Stream{Variable: arg, Index: i, IsArgument: true, Elem: elem})
+		} else if elem := callbackType(arg.Type); elem != nil {
+			responses = append(responses, Stream{Variable: arg, Index: i, IsArgument: true, IsCallback: true, Elem: elem})
+		}
+	}
+	for i, ret := range f.Returns {
+		if elem := receivedType(ret.Type); elem != nil {
+			responses = append(responses, Stream{Variable: ret, Index: i, Elem: elem})
+		}
+	}
+	return
+}
+
+// Reports whether any of the arguments or return values of f declare a stream; see [Func.Streams]
+func (f Func) IsStreaming() bool {
+	requests, responses := f.Streams()
+	return len(requests) > 0 || len(responses) > 0
+}
+
+// Returns the element type of t if values can be received from t, i.e. t is chan T or <-chan T
+func receivedType(t TypeName) TypeName {
+	switch c := t.(type) {
+	case *Chan:
+		return c.ChanOf
+	case *ReceiveChan:
+		return c.ReceiveType
+	}
+	return nil
+}
+
+// Returns T if t is an iterator callback func(T) error
+func callbackType(t TypeName) TypeName {
+	f, isFunc := t.(*FuncType)
+	if !isFunc || len(f.Arguments) != 1 || len(f.Returns) != 1 {
+		return nil
+	}
+	if ret, isBasic := f.Returns[0].(*BasicType); !isBasic || ret.Name != "error" {
+		return nil
+	}
+	return f.Arguments[0]
+}
+
 func (i *ServiceInterface) String() string {
 	return i.UserType.String()
 }
@@ -94,7 +94,18 @@ func typeof(t reflect.Type) TypeName {
 			}
 		}
 	case reflect.Func:
-		return &FuncType{}
+		f := &FuncType{}
+		for i := 0; i < t.NumIn(); i++ {
+			if t.IsVariadic() && i == t.NumIn()-1 {
+				f.Arguments = append(f.Arguments, &Ellipsis{EllipsisOf: typeof(t.In(i).Elem())})
+			} else {
+				f.Arguments = append(f.Arguments, typeof(t.In(i)))
+			}
+		}
+		for i := 0; i < t.NumOut(); i++ {
+			f.Returns = append(f.Returns, typeof(t.Out(i)))
+		}
+		return f
 	case reflect.Interface:
 		if t.Name() == "" {
 			return &InterfaceType{}
@@ -224,13 +235,14 @@ type (
 	}
 
 	/*
-		A function signature.  For now Blueprint doesn't support
-		functions in service method declarations, so we don't
-		bother unravelling and representing the function
-		declaration here
+		A function signature, e.g. func(string, int) error.  Service
+		methods can accept functions as iterator callbacks, e.g.
+		yield func(Order) error; see [Func.Streams]
 	*/
 	FuncType struct {
 		TypeName
+		Arguments []TypeName
+		Returns   []TypeName
 	}
 
 	/*
@@ -308,7 +320,21 @@ func (t *AnyType) String() string {
 }
 
 func (t *FuncType) String() string {
-	return "func(.){.}"
+	var args, rets []string
+	for _, arg := range t.Arguments {
+		args = append(args, fmt.Sprint(arg))
+	}
+	for _, ret := range t.Returns {
+		rets = append(rets, fmt.Sprint(ret))
+	}
+	switch len(rets) {
+	case 0:
+		return fmt.Sprintf("func(%s)", strings.Join(args, ", "))
+	case 1:
+		return fmt.Sprintf("func(%s) %s", strings.Join(args, ", "), rets[0])
+	default:
+		return fmt.Sprintf("func(%s) (%s)", strings.Join(args, ", "), strings.Join(rets, ", "))
+	}
 }
 
 func (t *StructType) String() string {
@@ -458,8 +484,21 @@ func (t *FuncType) Equals(other TypeName) bool {
 	if t == nil || other == nil {
 		return false
 	}
-	_, isSameType := other.(*FuncType)
-	return isSameType // TODO: functype matching not implemented yet
+	t2, isSameType := other.(*FuncType)
+	if !isSameType || len(t.Arguments) != len(t2.Arguments) || len(t.Returns) != len(t2.Returns) {
+		return false
+	}
+	for i := range t.Arguments {
+		if !t.Arguments[i].Equals(t2.Arguments[i]) {
+			return false
+		}
+	}
+	for i := range t.Returns {
+		if !t.Returns[i].Equals(t2.Returns[i]) {
+			return false
+		}
+	}
+	return true
 }
 
 func (t *StructType) Equals(other TypeName) bool {
@@ -0,0 +1,65 @@
+package gogen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
+	"github.com/blueprint-uservices/blueprint/plugins/golang"
+	cp "github.com/otiai10/copy"
+	"golang.org/x/exp/slices"
+	"golang.org/x/exp/slog"
+)
+
+// The directory of a generated module to which [PackageFile] copies files.  Processes run with their module's
+// directory as their working directory, and processes deployed to containers have this directory copied alongside
+// the process binary.
+const FilesDir = "files"
+
+// Copies the file or directory at path into the module, so that files that are read by the generated code when
+// it runs, e.g. SQL migrations or fixtures, are packaged with the generated process and its container.
+//
+// name should be the name of the node that uses the file.  path is resolved relative to the working directory of
+// the wiring spec.  Returns the path of the copy, relative to the directory from which the process runs.
+//
+// If nothing exists at path, then path is returned unchanged, to be resolved by the process when it runs, e.g.
+// because it is an absolute path that only exists in the environment where the process is deployed.
+func PackageFile(module golang.ModuleBuilder, name string, path string) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			slog.Info(fmt.Sprintf("Not packaging %v for %v as it does not exist; the process will resolve it when it runs", path, name))
+			return path, nil
+		}
+		return "", err
+	}
+	packaged := filepath.ToSlash(filepath.Join(FilesDir, ir.CleanName(name), filepath.Base(path)))
+	slog.Info(fmt.Sprintf("Packaging %v for %v as %v", path, name, packaged))
+	if err := cp.Copy(path, filepath.Join(module.Info().Path, packaged)); err != nil {
+		return "", blueprint.Errorf("unable to package %v for %v due to %v", path, name, err.Error())
+	}
+	return packaged, nil
+}
+
+// Packages the files named by the "key=value" options opts whose key is one of keys, using [PackageFile].  Returns
+// opts with the values of those options replaced by the paths of the packaged files.
+func PackageFileOptions(module golang.ModuleBuilder, name string, opts []ir.IRNode, keys ...string) ([]ir.IRNode, error) {
+	var packaged []ir.IRNode
+	for _, opt := range opts {
+		value, isValue := opt.(*ir.IRValue)
+		if isValue {
+			key, path, found := strings.Cut(value.Value, "=")
+			if found && slices.Contains(keys, key) {
+				path, err := PackageFile(module, name+"."+key, path)
+				if err != nil {
+					return nil, err
+				}
+				opt = &ir.IRValue{Value: key + "=" + path}
+			}
+		}
+		packaged = append(packaged, opt)
+	}
+	return packaged, nil
+}
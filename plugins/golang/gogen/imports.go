@@ -107,6 +107,19 @@ func (imports *Imports) AddType(typeName gocode.TypeName) {
 		{
 			imports.AddType(t.SendType)
 		}
+	case *gocode.Ellipsis:
+		{
+			imports.AddType(t.EllipsisOf)
+		}
+	case *gocode.FuncType:
+		{
+			for _, arg := range t.Arguments {
+				imports.AddType(arg)
+			}
+			for _, ret := range t.Returns {
+				imports.AddType(ret)
+			}
+		}
 	}
 }
 
@@ -175,6 +188,28 @@ func (imports *Imports) NameOf(typeName gocode.TypeName) string {
 		{
 			return "chan<- " + imports.NameOf(t.SendType)
 		}
+	case *gocode.Ellipsis:
+		{
+			return "..." + imports.NameOf(t.EllipsisOf)
+		}
+	case *gocode.FuncType:
+		{
+			var args, rets []string
+			for _, arg := range t.Arguments {
+				args = append(args, imports.NameOf(arg))
+			}
+			for _, ret := range t.Returns {
+				rets = append(rets, imports.NameOf(ret))
+			}
+			switch len(rets) {
+			case 0:
+				return fmt.Sprintf("func(%s)", strings.Join(args, ", "))
+			case 1:
+				return fmt.Sprintf("func(%s) %s", strings.Join(args, ", "), rets[0])
+			default:
+				return fmt.Sprintf("func(%s) (%s)", strings.Join(args, ", "), strings.Join(rets, ", "))
+			}
+		}
 	default:
 		{
 			slog.Warn(fmt.Sprintf("Importing unknown type %v %v", typeName, reflect.TypeOf(typeName)))
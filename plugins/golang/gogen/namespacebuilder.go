@@ -3,6 +3,7 @@ package gogen
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
@@ -160,7 +161,13 @@ var buildFuncTemplate = `func(n *golang.Namespace) (any, error) {
 
 // Implements [golang.NamespaceBuilder]
 func (namespace *NamespaceBuilderImpl) DeclareConstructor(name string, constructor *gocode.Constructor, args []ir.IRNode) error {
-	if len(constructor.Arguments) != len(args)+1 {
+	// If the constructor is variadic, then any number of args can be provided for the final parameter
+	params := constructor.Arguments[1:]
+	variadic := false
+	if len(params) > 0 {
+		_, variadic = params[len(params)-1].Type.(*gocode.Ellipsis)
+	}
+	if (!variadic && len(params) != len(args)) || (variadic && len(args) < len(params)-1) {
 		argNames := []string{}
 		for _, arg := range args {
 			argNames = append(argNames, arg.Name())
@@ -174,13 +181,23 @@ func (namespace *NamespaceBuilderImpl) DeclareConstructor(name string, construct
 		InstanceName: ir.CleanName(name),
 		Constructor:  &gocode.UserType{Package: constructor.Package, Name: constructor.Name},
 	}
-	for i, Var := range constructor.Arguments[1:] {
+	for i := range args {
+		var Var gocode.Variable
+		if i < len(params)-1 || !variadic {
+			Var = params[i]
+		} else {
+			// Variadic args each need their own variable name
+			Var = params[len(params)-1]
+			Var.Name = fmt.Sprintf("%s%d", Var.Name, i-len(params)+1)
+		}
+
 		if _, isMetadata := args[i].(ir.IRMetadata); isMetadata {
 			return blueprint.Errorf("invalid constructor argument %v; metadata nodes are not instantiable", args[i].Name())
 		}
 
 		if v, isValue := args[i].(*ir.IRValue); isValue {
-			templateArgs.Args = append(templateArgs.Args, v.String())
+			// Quoted as a Go string literal, since values such as JSON can contain quotes
+			templateArgs.Args = append(templateArgs.Args, strconv.Quote(v.Value))
 			continue
 		}
 
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
+	"github.com/blueprint-uservices/blueprint/plugins/golang/gocode"
 )
 
 // Represents a set of code modules that have been parsed.
@@ -196,3 +197,28 @@ func (set *ParsedModuleSet) FindInterface(pkgName string, name string) (*ParsedI
 	}
 	return nil, nil
 }
+
+// Returns the underlying type of t, following the declarations of named types, e.g. string for a
+// type declared as type Name string.
+//
+// Returns t itself if t is not a named type, or if t is a struct, an interface, a type from the
+// standard library, or a named type whose declaration couldn't be resolved.
+//
+// Returns an error if the package of a named type cannot be found or parsed.
+func (set *ParsedModuleSet) Underlying(t gocode.TypeName) (gocode.TypeName, error) {
+	for {
+		user, isUserType := t.(*gocode.UserType)
+		if !isUserType || gocode.IsBuiltinPackage(user.Package) {
+			return t, nil
+		}
+		pkg, err := set.GetPackage(user.Package)
+		if err != nil {
+			return nil, err
+		}
+		named, isNamed := pkg.NamedTypes[user.Name]
+		if !isNamed || named.Underlying == nil {
+			return t, nil
+		}
+		t = named.Underlying
+	}
+}
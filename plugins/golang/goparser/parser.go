@@ -56,6 +56,7 @@ type (
 		DeclaredTypes map[string]gocode.UserType  // Types declared within this package
 		Structs       map[string]*ParsedStruct    // Structs parsed from this package
 		Interfaces    map[string]*ParsedInterface // Interfaces parsed from this package
+		NamedTypes    map[string]*ParsedNamedType // Other named types parsed from this package, e.g. enums
 		Funcs         map[string]*ParsedFunc      // Functions parsed from this package (does not include funcs with receiver types)
 		Vars          map[string]*ParsedVar       // Vars declared in this package; we save their AST but don't process them
 	}
@@ -83,10 +84,20 @@ type (
 	}
 
 	ParsedInterface struct {
-		File    *ParsedFile
-		Ast     *ast.InterfaceType
-		Name    string
-		Methods map[string]*ParsedFunc
+		File     *ParsedFile
+		Ast      *ast.InterfaceType
+		Name     string
+		Methods  map[string]*ParsedFunc // Methods declared directly on this interface, does not include the methods of embedded interfaces (not implemented yet)
+		Embedded []ast.Expr             // Interfaces embedded in this interface, e.g. grpc.ClientStream
+	}
+
+	// A named type that is neither a struct nor an interface, e.g. type Name string
+	ParsedNamedType struct {
+		File       *ParsedFile
+		Ast        ast.Expr
+		Name       string
+		Underlying gocode.TypeName // The type that the named type is declared as, or nil if it couldn't be resolved
+		TypeParams []string        // Names of generic type parameters
 	}
 
 	ParsedFunc struct {
@@ -202,6 +213,7 @@ func (mod *ParsedModule) Load() error {
 			}
 			p.DeclaredTypes = make(map[string]gocode.UserType)
 			p.Interfaces = make(map[string]*ParsedInterface)
+			p.NamedTypes = make(map[string]*ParsedNamedType)
 			p.Structs = make(map[string]*ParsedStruct)
 			p.Funcs = make(map[string]*ParsedFunc)
 			p.Vars = make(map[string]*ParsedVar)
@@ -307,6 +319,10 @@ func (pkg *ParsedPackage) Parse() error {
 			return err
 		}
 	}
+	for _, t := range pkg.NamedTypes {
+		// Not all types can be resolved, e.g. arrays, so named types are parsed on a best-effort basis
+		t.Underlying = t.File.ResolveType(t.Ast, t.TypeParams...)
+	}
 	return nil
 }
 
@@ -441,7 +457,7 @@ func (f *ParsedFile) ResolveType(expr ast.Expr, typeParams ...string) gocode.Typ
 			return &gocode.Chan{ChanOf: f.ResolveType(e.Value, typeParams...)}
 		}
 	case *ast.FuncType:
-		return &gocode.FuncType{}
+		return f.resolveFuncType(e, typeParams...)
 	case *ast.StructType:
 		return &gocode.StructType{}
 	case *ast.IndexExpr:
@@ -454,6 +470,28 @@ func (f *ParsedFile) ResolveType(expr ast.Expr, typeParams ...string) gocode.Typ
 	return nil
 }
 
+// Resolves the types of the params and results of a func type, e.g. func(Order) error
+func (f *ParsedFile) resolveFuncType(e *ast.FuncType, typeParams ...string) *gocode.FuncType {
+	t := &gocode.FuncType{}
+	if e.Params != nil {
+		for _, p := range e.Params.List {
+			argType := f.ResolveType(p.Type, typeParams...)
+			for i := 0; i < max(len(p.Names), 1); i++ {
+				t.Arguments = append(t.Arguments, argType)
+			}
+		}
+	}
+	if e.Results != nil {
+		for _, r := range e.Results.List {
+			retType := f.ResolveType(r.Type, typeParams...)
+			for i := 0; i < max(len(r.Names), 1); i++ {
+				t.Returns = append(t.Returns, retType)
+			}
+		}
+	}
+	return t
+}
+
 func (f *ParsedFile) LoadImports() error {
 	for _, imp := range f.Ast.Imports {
 		i := &ParsedImport{}
@@ -521,8 +559,8 @@ func (f *ParsedFile) LoadStructsAndInterfaces() error {
 			u := gocode.UserType{Package: f.Package.Name, Name: typespec.Name.Name}
 			f.Package.DeclaredTypes[u.Name] = u
 
-			// Also specifically save interface and struct AST info which we later want to parse.
-			// This ignores enums
+			// Also specifically save interface and struct AST info which we later want to parse,
+			// and the AST of other named types, e.g. enums
 			switch t := typespec.Type.(type) {
 			case *ast.InterfaceType:
 				{
@@ -537,13 +575,18 @@ func (f *ParsedFile) LoadStructsAndInterfaces() error {
 					for _, methodDecl := range t.Methods.List {
 						funcType, isFuncType := methodDecl.Type.(*ast.FuncType)
 						if !isFuncType {
-							return blueprint.Errorf("expected a function declaration in interface " + iface.Name)
+							// Embedded interfaces are saved but not parsed, so that users of the interface,
+							// e.g. workflow services, can reject it
+							// TODO (not implemented yet): promote the methods of embedded interfaces
+							iface.Embedded = append(iface.Embedded, methodDecl.Type)
+							continue
 						}
 
 						method := &ParsedFunc{}
 						method.Ast = funcType
 						method.File = f
 						method.Name = methodDecl.Names[0].Name
+						method.Directives = parseDirectives(methodDecl.Doc)
 						iface.Methods[method.Name] = method
 					}
 				}
@@ -580,6 +623,15 @@ func (f *ParsedFile) LoadStructsAndInterfaces() error {
 						}
 					}
 				}
+			default:
+				{
+					named := &ParsedNamedType{}
+					named.Ast = t
+					named.File = f
+					named.Name = typespec.Name.Name
+					named.TypeParams = typeParams
+					f.Package.NamedTypes[named.Name] = named
+				}
 			}
 		}
 	}
@@ -705,9 +757,10 @@ func (iface *ParsedInterface) ServiceInterface(ctx ir.BuildContext) *gocode.Serv
 	methods := make(map[string]gocode.Func)
 	for name, method := range iface.Methods {
 		methods[name] = gocode.Func{
-			Name:      method.Name,
-			Arguments: method.Arguments[1:],
-			Returns:   method.Returns[:len(method.Returns)-1],
+			Name:       method.Name,
+			Arguments:  method.Arguments[1:],
+			Returns:    method.Returns[:len(method.Returns)-1],
+			Directives: method.Directives,
 		}
 	}
 	return &gocode.ServiceInterface{
@@ -829,3 +882,29 @@ func (f *ParsedField) String() string {
 		return f.Name + " " + f.Type.String()
 	}
 }
+
+// The prefix of comment directives on workflow interface methods, e.g. //blueprint:idempotent
+const directivePrefix = "//blueprint:"
+
+// Parses the comment directives from a method's doc comment.  Directives have the form
+// //blueprint:name or //blueprint:name=value.  Returns nil if there are no directives.
+func parseDirectives(doc *ast.CommentGroup) map[string]string {
+	if doc == nil {
+		return nil
+	}
+	var directives map[string]string
+	for _, comment := range doc.List {
+		if !strings.HasPrefix(comment.Text, directivePrefix) {
+			continue
+		}
+		name, value, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(comment.Text, directivePrefix)), "=")
+		if name == "" {
+			continue
+		}
+		if directives == nil {
+			directives = make(map[string]string)
+		}
+		directives[name] = value
+	}
+	return directives
+}
@@ -104,7 +104,7 @@ func main() {
 	slog.Info("Running {{.Name}}")
 	n, err := {{.NamespaceConstructor}}("{{.Name}}").Build(context.Background())
 	if err != nil {
-		slog.Error(err.Error())
+		slog.Error("{{.Name}} failed to start: " + err.Error())
 		os.Exit(1)
 	}
 	n.Await()
@@ -16,6 +16,7 @@ func GenerateDockerfileBuildCommands(goProcName string) (string, error) {
 	args := dockerfileBuildTemplateArgs{
 		ProcName:  goProcName,
 		GoVersion: goVersion,
+		FilesDir:  gogen.FilesDir,
 	}
 	return gogen.ExecuteTemplate("dockerfile_buildgoproc", dockerfileBuildTemplate, args)
 }
@@ -23,6 +24,7 @@ func GenerateDockerfileBuildCommands(goProcName string) (string, error) {
 type dockerfileBuildTemplateArgs struct {
 	ProcName  string
 	GoVersion string
+	FilesDir  string
 }
 
 var dockerfileBuildTemplate = `
@@ -40,6 +42,9 @@ RUN go mod download
 RUN mkdir /{{.ProcName}}
 RUN go build -o /{{.ProcName}} ./{{.ProcName}}
 
+# Files packaged with the process are read relative to the directory from which the process runs
+RUN if [ -d ./{{.ProcName}}/{{.FilesDir}} ]; then cp -r ./{{.ProcName}}/{{.FilesDir}} /{{.ProcName}}/{{.FilesDir}}; fi
+
 #
 # custom docker build commands provided by goproc.Process {{.ProcName}}
 ######## END
@@ -17,18 +17,31 @@ func GenerateClient(builder golang.ModuleBuilder, service *gocode.ServiceInterfa
 		return err
 	}
 
+	streaming, err := getStreamingMethods(service)
+	if err != nil {
+		return err
+	}
+
 	client := &clientArgs{
-		Package: pkg,
-		Service: service,
-		Name:    service.BaseName + "_GRPCClient",
-		Imports: gogen.NewImports(pkg.Name),
+		Package:   pkg,
+		Service:   service,
+		Streaming: streaming,
+		Name:      service.BaseName + "_GRPCClient",
+		Imports:   gogen.NewImports(pkg.Name),
 	}
 
 	client.Imports.AddPackages(
 		"context", "time",
 		"google.golang.org/grpc",
 		"google.golang.org/grpc/credentials/insecure",
+		"google.golang.org/grpc/metadata",
+		"google.golang.org/grpc/status",
+		"github.com/blueprint-uservices/blueprint/runtime/core/idempotency",
+		"github.com/blueprint-uservices/blueprint/runtime/core/rpcerror",
 	)
+	if len(streaming) > 0 {
+		client.Imports.AddPackage("io")
+	}
 
 	slog.Info(fmt.Sprintf("Generating %v/%v.go", client.Package.PackageName, client.Name))
 	outputFile := filepath.Join(client.Package.Path, client.Name+".go")
@@ -39,10 +52,11 @@ func GenerateClient(builder golang.ModuleBuilder, service *gocode.ServiceInterfa
 Arguments to the template code
 */
 type clientArgs struct {
-	Package golang.PackageInfo
-	Service *gocode.ServiceInterface
-	Name    string         // Name of the generated client class
-	Imports *gogen.Imports // Manages imports for us
+	Package   golang.PackageInfo
+	Service   *gocode.ServiceInterface
+	Streaming map[string]*streamingMethod // The methods of Service that stream values
+	Name      string                      // Name of the generated client class
+	Imports   *gogen.Imports              // Manages imports for us
 }
 
 var clientTemplate = `// Blueprint: Auto-generated by GRPC Plugin
@@ -75,9 +89,159 @@ func New_{{.Name}}(ctx context.Context, serverAddress string) (*{{.Name}}, error
 	return c, nil
 }
 
+// Reconstructs the error returned by the service from the error of a call and the call's trailer
+func (client *{{.Name}}) decodeError(err error, trailer metadata.MD) error {
+	if encoded := trailer.Get(rpcerror.GRPCTrailer); len(encoded) > 0 {
+		return rpcerror.Decode([]byte(encoded[0]))
+	} else if s, isStatus := status.FromError(err); isStatus {
+		return rpcerror.New(rpcerror.FromGRPCCode(uint32(s.Code())), "%s", s.Message())
+	}
+	return err
+}
+
 {{$service := .Service.Name -}}
 {{$receiver := .Name -}}
 {{- range $_, $f := .Service.Methods }}
+{{- with $s := index $.Streaming $f.Name}}
+func (client *{{$receiver}}) {{SignatureWithRetVars $f}} {
+	// Streams are not subject to the client's timeout; instead the stream is cancelled once the call completes
+	ctx, cancel := context.WithCancel(ctx)
+	{{- if or (not $s.ResponseStream) $s.ResponseStream.IsCallback}}
+	defer cancel()
+	{{- end}}
+
+	// Propagate the idempotency key of the call, if any
+	if key := idempotency.OutgoingKey(ctx, "{{$f.Name}}"); key != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, idempotency.Header, key)
+	}
+
+	// Make the remote call
+	{{- if $s.RequestStream}}
+	stream, err := client.Client.{{$f.Name}}(ctx)
+	if err != nil {
+		{{- if and $s.ResponseStream (not $s.ResponseStream.IsCallback)}}
+		cancel()
+		{{- end}}
+		err = client.decodeError(err, nil)
+		return
+	}
+
+	// The first message of the stream carries the arguments that are not streamed, and subsequent messages the streamed values
+	header := &{{$service}}_{{$f.Name}}_RequestStream{Header: new({{$service}}_{{$f.Name}}_Request).marshall({{ArgVars $s.Header}})}
+	{{- if $s.ResponseStream}}
+	go func() {
+		if err := client.send{{$f.Name}}(ctx, stream, header, {{$s.RequestStream.Name}}); err == nil {
+			stream.CloseSend()
+		} else if err != io.EOF {
+			cancel()
+		}
+	}()
+	{{- else}}
+	if err = client.send{{$f.Name}}(ctx, stream, header, {{$s.RequestStream.Name}}); err != nil && err != io.EOF {
+		return
+	}
+
+	// If the server ended the stream while values were being sent, the stream's status is returned here
+	rsp, err := stream.CloseAndRecv()
+	if err != nil {
+		err = client.decodeError(err, stream.Trailer())
+		return
+	}
+
+	{{RetVarsEquals $f}} rsp.unmarshall()
+	return
+	{{- end}}
+	{{- else}}
+	stream, err := client.Client.{{$f.Name}}(ctx, new({{$service}}_{{$f.Name}}_Request).marshall({{ArgVars $s.Header}}))
+	if err != nil {
+		{{- if not $s.ResponseStream.IsCallback}}
+		cancel()
+		{{- end}}
+		err = client.decodeError(err, nil)
+		return
+	}
+	{{- end}}
+	{{- if $s.ResponseStream}}
+	{{- if $s.ResponseStream.IsCallback}}
+
+	// Pass each value streamed by the server to the callback
+	for {
+		msg, recvErr := stream.Recv()
+		if recvErr == io.EOF {
+			return
+		} else if recvErr != nil {
+			err = client.decodeError(recvErr, stream.Trailer())
+			return
+		}
+		if err = {{$s.ResponseStream.Name}}(msg.unmarshall()); err != nil {
+			return
+		}
+	}
+	{{- else}}
+
+	// The server sends headers once the call succeeds; otherwise the stream ends with the call's error
+	md, err := stream.Header()
+	if err == nil && md == nil {
+		_, err = stream.Recv()
+	}
+	if err != nil {
+		cancel()
+		err = client.decodeError(err, stream.Trailer())
+		return
+	}
+
+	// Values streamed by the server are received on the returned channel, which is closed when the
+	// stream ends or ctx is done.  If the stream fails part-way, the error is saved for rpcerror.StreamError
+	items := make(chan {{NameOf $s.ResponseStream.Elem}})
+	go func() {
+		defer cancel()
+		defer close(items)
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				return
+			} else if err != nil {
+				rpcerror.SetStreamError(items, client.decodeError(err, stream.Trailer()))
+				return
+			}
+			select {
+			case items <- msg.unmarshall():
+			case <-ctx.Done():
+				rpcerror.SetStreamError(items, ctx.Err())
+				return
+			}
+		}
+	}()
+	ret{{$s.ResponseStream.Index}} = items
+	return
+	{{- end}}
+	{{- end}}
+}
+{{- if $s.RequestStream}}
+
+// Sends the header, then the values streamed to {{$f.Name}} until the channel is closed.  Returns io.EOF if the
+// server ended the stream, or ctx's error if ctx is done first, in which case the stream must be cancelled
+// rather than closed, so that the server doesn't mistake the values sent so far for the complete stream.
+func (client *{{$receiver}}) send{{$f.Name}}(ctx context.Context, stream {{$service}}_{{$f.Name}}Client, header *{{$service}}_{{$f.Name}}_RequestStream, items <-chan {{NameOf $s.RequestStream.Elem}}) error {
+	if err := stream.Send(header); err != nil {
+		return err
+	}
+	for {
+		select {
+		case item, ok := <-items:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(&{{$service}}_{{$f.Name}}_RequestStream{Item: new({{$service}}_{{$f.Name}}_RequestItem).marshall(item)}); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+{{- end}}
+{{- else}}
 func (client *{{$receiver}}) {{SignatureWithRetVars $f}} {
 	// Create and marshall the GRPC Request object
 	req := &{{$service}}_{{$f.Name}}_Request{}
@@ -87,17 +251,26 @@ func (client *{{$receiver}}) {{SignatureWithRetVars $f}} {
 	ctx, cancel := context.WithTimeout(ctx, client.Timeout)
 	defer cancel()
 
+	// Propagate the idempotency key of the call, if any
+	if key := idempotency.OutgoingKey(ctx, "{{$f.Name}}"); key != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, idempotency.Header, key)
+	}
+
 	// Make the remote call
-	rsp, err := client.Client.{{$f.Name}}(ctx, req)
+	var trailer metadata.MD
+	rsp, err := client.Client.{{$f.Name}}(ctx, req, grpc.Trailer(&trailer))
 	if err == nil {
 		err = ctx.Err()
 	}
 	if err != nil {
+		// Reconstruct the error returned by the service
+		err = client.decodeError(err, trailer)
 		return
 	}
 
 	{{RetVarsEquals $f}} rsp.unmarshall()
 	return
 }
+{{- end}}
 {{end}}
 `
@@ -58,6 +58,50 @@ func (msg *{{$method.Response.GRPCType.Name}}) unmarshall() (
 	{{- end}}
 	return
 }
+{{- with $method.RequestItem}}
+
+// Client-side function to pack a value streamed to {{$service.Name}}.{{$method.Name}} into a GRPC {{.GRPCType.Name}} struct
+func (msg *{{.GRPCType.Name}}) marshall(
+	{{- range $j, $arg := .FieldList}}{{$arg.Name}} {{$imports.NameOf $arg.SrcType}}{{end -}}
+) *{{.GRPCType.Name}} {
+	{{- range $j, $arg := .FieldList}}
+	{{$arg.Marshall $imports ""}}
+	{{- end}}
+	return msg
+}
+
+// Server-side function to unpack a value streamed to {{$service.Name}}.{{$method.Name}} from a GRPC {{.GRPCType.Name}} struct
+func (msg *{{.GRPCType.Name}}) unmarshall() (
+	{{- range $j, $arg := .FieldList}}{{$arg.Name}} {{$imports.NameOf $arg.SrcType}}{{end -}}
+) {
+	{{- range $j, $arg := .FieldList}}
+	{{$arg.Unmarshall $imports ""}}
+	{{- end}}
+	return
+}
+{{- end}}
+{{- with $method.ResponseItem}}
+
+// Server-side function to pack a value streamed by {{$service.Name}}.{{$method.Name}} into a GRPC {{.GRPCType.Name}} struct
+func (msg *{{.GRPCType.Name}}) marshall(
+	{{- range $j, $ret := .FieldList}}{{$ret.Name}} {{$imports.NameOf $ret.SrcType}}{{end -}}
+) *{{.GRPCType.Name}} {
+	{{- range $j, $ret := .FieldList}}
+	{{$ret.Marshall $imports ""}}
+	{{- end}}
+	return msg
+}
+
+// Client-side function to unpack a value streamed by {{$service.Name}}.{{$method.Name}} from a GRPC {{.GRPCType.Name}} struct
+func (msg *{{.GRPCType.Name}}) unmarshall() (
+	{{- range $j, $ret := .FieldList}}{{$ret.Name}} {{$imports.NameOf $ret.SrcType}}{{end -}}
+) {
+	{{- range $j, $ret := .FieldList}}
+	{{$ret.Unmarshall $imports ""}}
+	{{- end}}
+	return
+}
+{{- end}}
 
 {{end -}}
 {{end -}}
@@ -129,7 +173,7 @@ func (f *gRPCField) Marshall(imports *gogen.Imports, obj string) (string, error)
 		{
 			switch pt := t.PointerTo.(type) {
 			case *gocode.UserType:
-				return fmt.Sprintf("msg.%s = new(%s).marshall(%s%s)", strings.Title(f.Name), pt.Name, obj, f.Name), nil
+				return fmt.Sprintf("if %s%s != nil { msg.%s = new(%s).marshall(%s%s) }", obj, f.Name, strings.Title(f.Name), pt.Name, obj, f.Name), nil
 			case *gocode.BasicType:
 				return fmt.Sprintf("msg.%s = %s(*%s%s)", strings.Title(f.Name), pt.Name, obj, f.Name), nil
 			default:
@@ -184,7 +228,8 @@ func (f *gRPCField) Unmarshall(imports *gogen.Imports, obj string) (string, erro
 		{
 			switch pt := t.PointerTo.(type) {
 			case *gocode.UserType:
-				return fmt.Sprintf("msg.%s.unmarshall(%s%s)", strings.Title(f.Name), obj, f.Name), nil
+				return fmt.Sprintf("if msg.%s != nil { %s%s = new(%s); msg.%s.unmarshall(%s%s) }",
+					strings.Title(f.Name), obj, f.Name, imports.NameOf(f.SrcType.(*gocode.Pointer).PointerTo), strings.Title(f.Name), obj, f.Name), nil
 			case *gocode.BasicType:
 				return fmt.Sprintf("%s%s = &%v(msg.%s)", obj, f.Name, f.SrcType, strings.Title(f.Name)), nil
 			default:
@@ -124,6 +124,14 @@ type (
 		Name     string
 		Request  *gRPCMessageDecl
 		Response *gRPCMessageDecl
+
+		// For client-streaming methods, the message that carries each value streamed to the server, and
+		// the message of the stream, which carries the Request in its first message and values thereafter
+		RequestItem   *gRPCMessageDecl
+		RequestStream *gRPCMessageDecl
+
+		// For server-streaming methods, the message that carries each value streamed to the client
+		ResponseItem *gRPCMessageDecl
 	}
 
 	gRPCServiceDecl struct {
@@ -169,7 +177,9 @@ message {{$msg.Name}} {
 {{ range $k, $service := .Services }}
 service {{$service.Name}} {
     {{- range $k, $method := $service.Methods}}
-    rpc {{$method.Name}} ({{$method.Request.Name}}) returns ({{$method.Response.Name}}) {}
+    rpc {{$method.Name}} (
+        {{- if $method.RequestStream}}stream {{$method.RequestStream.Name}}{{else}}{{$method.Request.Name}}{{end}}) returns (
+        {{- if $method.ResponseItem}}stream {{$method.ResponseItem.Name}}{{else}}{{$method.Response.Name}}{{end}}) {}
     {{- end}}
 }
 {{ end }}
@@ -219,6 +229,33 @@ func (b *gRPCServiceDecl) newMethod(name string) *gRPCMethodDecl {
 	return m
 }
 
+// Adds the messages of a streaming method
+func (m *gRPCMethodDecl) addStreams(method *streamingMethod) error {
+	b := m.Service.Builder
+	if s := method.RequestStream; s != nil {
+		itemList, err := b.makeFieldList([]gocode.Variable{{Name: "item", Type: s.Elem}})
+		if err != nil {
+			return err
+		}
+		m.RequestItem = b.newMessage(fmt.Sprintf("%s_%s_RequestItem", m.Service.Name, m.Name))
+		m.RequestItem.FieldList = itemList
+		m.RequestStream = b.newMessage(fmt.Sprintf("%s_%s_RequestStream", m.Service.Name, m.Name))
+		m.RequestStream.FieldList = []*gRPCField{
+			{ProtoType: m.Request.Name, GRPCType: m.Request.GRPCType, Name: "header", Position: 1},
+			{ProtoType: m.RequestItem.Name, GRPCType: m.RequestItem.GRPCType, Name: "item", Position: 2},
+		}
+	}
+	if s := method.ResponseStream; s != nil {
+		itemList, err := b.makeFieldList([]gocode.Variable{{Name: "item", Type: s.Elem}})
+		if err != nil {
+			return err
+		}
+		m.ResponseItem = b.newMessage(fmt.Sprintf("%s_%s_ResponseItem", m.Service.Name, m.Name))
+		m.ResponseItem.FieldList = itemList
+	}
+	return nil
+}
+
 func (b *gRPCProtoBuilder) makeFieldList(vars []gocode.Variable) ([]*gRPCField, error) {
 	var fieldList []*gRPCField
 	for i, arg := range vars {
@@ -250,10 +287,24 @@ Adds a service declaration for the provided golang service interface.
 For arguments and return values on methods in the interface, corresponding GRPC message objects
 are needed.  The ProtoBuilder will consult the parsed code to find the definitions of arguments
 and return values.
+
+Methods that stream values are declared as streaming GRPC methods, with additional messages for
+the streamed values.  Arguments and return values that are streamed are omitted from the method's
+request and response messages.
 */
 func (b *gRPCProtoBuilder) AddService(iface *gocode.ServiceInterface) error {
+	streaming, err := getStreamingMethods(iface)
+	if err != nil {
+		return err
+	}
+
 	serviceDecl := b.newService(iface.Name) // TODO: (not implemented yet) possibility of name collisions
 	for _, method := range iface.Methods {
+		stream, isStreaming := streaming[method.Name]
+		if isStreaming {
+			method = stream.Header
+		}
+
 		argList, err := b.makeFieldList(method.Arguments)
 		if err != nil {
 			return err
@@ -267,6 +318,12 @@ func (b *gRPCProtoBuilder) AddService(iface *gocode.ServiceInterface) error {
 		methodDecl := serviceDecl.newMethod(method.Name)
 		methodDecl.Request.FieldList = argList
 		methodDecl.Response.FieldList = retList
+
+		if isStreaming {
+			if err := methodDecl.addStreams(stream); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
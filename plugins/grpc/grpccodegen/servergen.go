@@ -21,18 +21,35 @@ func GenerateServerHandler(builder golang.ModuleBuilder, service *gocode.Service
 		return err
 	}
 
+	streaming, err := getStreamingMethods(service)
+	if err != nil {
+		return err
+	}
+
 	server := &serverArgs{
-		Package: pkg,
-		Service: service,
-		Name:    service.BaseName + "_GRPCServerHandler",
-		Imports: gogen.NewImports(pkg.Name),
+		Package:   pkg,
+		Service:   service,
+		Streaming: streaming,
+		Name:      service.BaseName + "_GRPCServerHandler",
+		Imports:   gogen.NewImports(pkg.Name),
 	}
 
 	server.Imports.AddPackages(
 		"context", "net",
 		"google.golang.org/grpc",
+		"google.golang.org/grpc/codes",
+		"google.golang.org/grpc/metadata",
+		"google.golang.org/grpc/status",
+		"github.com/blueprint-uservices/blueprint/runtime/core/idempotency",
+		"github.com/blueprint-uservices/blueprint/runtime/core/rpcerror",
 	)
 
+	for _, s := range streaming {
+		if s.RequestStream != nil {
+			server.Imports.AddPackage("io")
+		}
+	}
+
 	slog.Info(fmt.Sprintf("Generating %v/%v_GRPCServer.go", server.Package.PackageName, service.Name))
 	outputFile := filepath.Join(server.Package.Path, service.Name+"_GRPCServer.go")
 	return gogen.ExecuteTemplateToFile("GRPCServer", serverTemplate, server, outputFile)
@@ -42,10 +59,11 @@ func GenerateServerHandler(builder golang.ModuleBuilder, service *gocode.Service
 Arguments to the template code
 */
 type serverArgs struct {
-	Package golang.PackageInfo
-	Service *gocode.ServiceInterface
-	Name    string         // Name of the generated wrapper class
-	Imports *gogen.Imports // Manages imports for us
+	Package   golang.PackageInfo
+	Service   *gocode.ServiceInterface
+	Streaming map[string]*streamingMethod // The methods of Service that stream values
+	Name      string                      // Name of the generated wrapper class
+	Imports   *gogen.Imports              // Manages imports for us
 }
 
 var serverTemplate = `// Blueprint: Auto-generated by GRPC Plugin
@@ -89,17 +107,117 @@ func (handler *{{.Name}}) Run(ctx context.Context) error {
 {{$service := .Service.Name -}}
 {{$receiver := .Name -}}
 {{ range $_, $f := .Service.Methods }}
+{{- with $s := index $.Streaming $f.Name}}
+func (handler *{{$receiver}}) {{$f.Name -}}
+		({{if not $s.RequestStream}}req *{{$service}}_{{$f.Name}}_Request, {{end}}stream {{$service}}_{{$f.Name}}Server) error {
+	// The stream is cancelled when the handler returns
+	ctx, cancel := context.WithCancelCause(stream.Context())
+	defer cancel(nil)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if keys := md.Get(idempotency.Header); len(keys) > 0 {
+			ctx = idempotency.NewIncomingContext(ctx, "{{$f.Name}}", keys[0])
+		}
+	}
+
+	var err error
+	{{- if $s.RequestStream}}
+
+	// The first message of the stream carries the arguments that are not streamed
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if first.Header == nil {
+		return status.Error(codes.InvalidArgument, "expected the arguments of {{$f.Name}} in the first message of the stream")
+	}
+	{{ArgVarsEquals $s.Header}} first.Header.unmarshall()
+
+	// Subsequent messages carry the streamed values.  The channel is closed once the client has sent all of its
+	// values.  If the stream fails first, the channel is left open and ctx is cancelled instead, so that the
+	// service doesn't mistake the values received so far for the complete stream.
+	{{$s.RequestStream.Name}} := make(chan {{NameOf $s.RequestStream.Elem}})
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				close({{$s.RequestStream.Name}})
+				return
+			} else if err != nil {
+				cancel(err)
+				return
+			} else if msg.Item == nil {
+				cancel(status.Error(codes.InvalidArgument, "expected a value streamed to {{$f.Name}}"))
+				return
+			}
+			select {
+			case {{$s.RequestStream.Name}} <- msg.Item.unmarshall():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	{{- else}}
+	{{ArgVarsEquals $s.Header}} req.unmarshall()
+	{{- end}}
+	{{- if and $s.ResponseStream $s.ResponseStream.IsCallback}}
+
+	// Each value passed to the callback is sent to the client
+	{{$s.ResponseStream.Name}} := func(item {{NameOf $s.ResponseStream.Elem}}) error {
+		return stream.Send(new({{$service}}_{{$f.Name}}_ResponseItem).marshall(item))
+	}
+	{{- end}}
+
+	{{RetVars $f "err"}} {{HasNewReturnVars $f}} handler.Service.{{$f.Name}}({{ArgVars $f "ctx"}})
+	if err != nil {
+		// gRPC statuses only have a code and message, so the details of the error are sent in a trailer
+		stream.SetTrailer(metadata.Pairs(rpcerror.GRPCTrailer, string(rpcerror.Encode(err))))
+		return status.Error(codes.Code(rpcerror.GRPCCode(rpcerror.CodeOf(err))), err.Error())
+	}
+	{{- if not $s.ResponseStream}}
+
+	return stream.SendAndClose(new({{$service}}_{{$f.Name}}_Response).marshall({{RetVars $f}}))
+	{{- else if $s.ResponseStream.IsCallback}}
+
+	return nil
+	{{- else}}
+
+	// Headers tell the client that the call succeeded; the values on the returned channel are then sent to the client.
+	// The service should stop sending values once ctx is done.
+	if err = stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	if ret{{$s.ResponseStream.Index}} == nil {
+		// A nil channel is an empty stream
+		return nil
+	}
+	for item := range ret{{$s.ResponseStream.Index}} {
+		if err = stream.Send(new({{$service}}_{{$f.Name}}_ResponseItem).marshall(item)); err != nil {
+			return err
+		}
+	}
+	return nil
+	{{- end}}
+}
+{{- else}}
 func (handler *{{$receiver}}) {{$f.Name -}}
 		(ctx context.Context, req *{{$service}}_{{$f.Name}}_Request) (*{{$service}}_{{$f.Name}}_Response, error) {
 	{{ArgVarsEquals $f}} req.unmarshall()
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if keys := md.Get(idempotency.Header); len(keys) > 0 {
+			ctx = idempotency.NewIncomingContext(ctx, "{{$f.Name}}", keys[0])
+		}
+	}
 	{{RetVars $f "err"}} := handler.Service.{{$f.Name}}({{ArgVars $f "ctx"}})
 	if err != nil {
-		return nil, err
+		// gRPC statuses only have a code and message, so the details of the error are sent in a trailer
+		grpc.SetTrailer(ctx, metadata.Pairs(rpcerror.GRPCTrailer, string(rpcerror.Encode(err))))
+		return nil, status.Error(codes.Code(rpcerror.GRPCCode(rpcerror.CodeOf(err))), err.Error())
 	}
 
 	rsp := &{{$service}}_{{$f.Name}}_Response{}
 	rsp.marshall({{RetVars $f}})
 	return rsp, nil
 }
+{{- end}}
 {{end}}
 `
@@ -0,0 +1,67 @@
+package grpccodegen
+
+import (
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
+	"github.com/blueprint-uservices/blueprint/plugins/golang/gocode"
+)
+
+/*
+A service method that streams values, which is implemented by a streaming GRPC method.
+
+Methods that accept a channel, e.g. orders <-chan Order, are client-streaming.  Methods that
+return a channel, e.g. <-chan Order, or that accept an iterator callback, e.g. yield func(Order) error,
+are server-streaming.  Methods that do both are bidirectional.
+*/
+type streamingMethod struct {
+	RequestStream  *gocode.Stream // The argument that streams values to the server, or nil
+	ResponseStream *gocode.Stream // The argument or return value that streams values to the client, or nil
+
+	// The method with only the arguments and return values that are not streamed.  These are sent in
+	// the method's Request and Response messages
+	Header gocode.Func
+}
+
+// Returns the streaming methods of the service, keyed by method name.  Returns an error if a method
+// streams values in a way that cannot be implemented by a GRPC method.
+func getStreamingMethods(service *gocode.ServiceInterface) (map[string]*streamingMethod, error) {
+	methods := make(map[string]*streamingMethod)
+	for name, f := range service.Methods {
+		requests, responses := f.Streams()
+		if len(requests) == 0 && len(responses) == 0 {
+			continue
+		}
+		if len(requests) > 1 {
+			return nil, blueprint.Errorf("GRPC method %v.%v can only stream one argument but streams %v", service.Name, name, len(requests))
+		}
+		if len(responses) > 1 {
+			return nil, blueprint.Errorf("GRPC method %v.%v can only stream one result but streams %v", service.Name, name, len(responses))
+		}
+
+		m := &streamingMethod{Header: gocode.Func{Name: f.Name}}
+		if len(requests) == 1 {
+			m.RequestStream = &requests[0]
+		}
+		if len(responses) == 1 {
+			m.ResponseStream = &responses[0]
+			if (m.ResponseStream.IsCallback && len(f.Returns) > 0) || (!m.ResponseStream.IsCallback && len(f.Returns) > 1) {
+				return nil, blueprint.Errorf("GRPC method %v.%v streams its results so cannot also return %v", service.Name, name, f.Returns)
+			}
+		}
+		for i, arg := range f.Arguments {
+			if !isStream(m.RequestStream, i, true) && !isStream(m.ResponseStream, i, true) {
+				m.Header.Arguments = append(m.Header.Arguments, arg)
+			}
+		}
+		for i, ret := range f.Returns {
+			if !isStream(m.ResponseStream, i, false) {
+				m.Header.Returns = append(m.Header.Returns, ret)
+			}
+		}
+		methods[name] = m
+	}
+	return methods, nil
+}
+
+func isStream(s *gocode.Stream, index int, isArgument bool) bool {
+	return s != nil && s.Index == index && s.IsArgument == isArgument
+}
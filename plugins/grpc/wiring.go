@@ -17,6 +17,34 @@
 //
 // After deploying a service to gRPC, you will probably want to deploy the service in a process.
 //
+// Errors returned by the service are returned to callers with a gRPC status code, and errors from the [rpcerror]
+// package are reconstructed by the client with their codes and details.
+//
+// # Streaming
+//
+// Service methods that accept or return channels are deployed as streaming gRPC methods, and the
+// generated client has the same method signatures as the service:
+//
+//	// Server-streaming: the service sends results on the returned channel, then closes it
+//	WatchOrders(ctx context.Context, userID string) (<-chan Order, error)
+//	// Server-streaming: the service calls yield for each result
+//	ListOrders(ctx context.Context, userID string, yield func(Order) error) error
+//	// Client-streaming: the caller sends values on the channel, then closes it
+//	ImportOrders(ctx context.Context, orders <-chan Order) (int, error)
+//	// Bidirectional
+//	PriceOrders(ctx context.Context, currency string, orders <-chan Order) (<-chan Price, error)
+//
+// A method can stream at most one argument and one result, and a method that streams its results
+// cannot return other values.  Streaming calls are not subject to the client's timeout.  Channels
+// returned by the client are closed when the stream ends or the call's context is done, so callers
+// that stop receiving early should cancel the context.  If the stream fails part-way, the error is
+// saved, and callers retrieve it with StreamError of the [rpcerror] package once the channel is closed.
+//
+// Channels passed to services are closed once the caller has sent all of its values.  If the stream
+// fails first, the channel is left open and the call's context is cancelled instead, so services
+// should stop receiving once the call's context is done.  Likewise, services should stop sending on
+// returned channels once the call's context is done.  A nil returned channel is an empty stream.
+//
 // # Example
 //
 // The SockShop [grpc wiring spec] uses the grpc plugin.
@@ -45,6 +73,7 @@
 // on the machine that is compiling the Blueprint wiring spec.  Installation instructions
 // can be found on the [gRPC Quick Start].
 //
+// [rpcerror]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/core/rpcerror
 // [grpccodegen]: https://github.com/Blueprint-uServices/blueprint/tree/main/plugins/grpc/grpccodegen
 // [grpc wiring spec]: https://github.com/Blueprint-uServices/blueprint/tree/main/examples/sockshop/wiring/specs/grpc.go
 // [gRPC Quick Start]: https://grpc.io/docs/languages/go/quickstart/
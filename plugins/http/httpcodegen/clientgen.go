@@ -7,11 +7,13 @@ import (
 	"github.com/blueprint-uservices/blueprint/plugins/golang"
 	"github.com/blueprint-uservices/blueprint/plugins/golang/gocode"
 	"github.com/blueprint-uservices/blueprint/plugins/golang/gogen"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow/workflowspec"
 	"golang.org/x/exp/slog"
 )
 
-// This function is used by the HTTP plugin to generate the client-side HTTP service
-func GenerateClient(builder golang.ModuleBuilder, service *gocode.ServiceInterface, outputPackage string) error {
+// This function is used by the HTTP plugin to generate the client-side HTTP service.
+// routes overrides the routes of the service's methods; see [Routes].
+func GenerateClient(builder golang.ModuleBuilder, service *gocode.ServiceInterface, outputPackage string, routes map[string]string) error {
 	pkg, err := builder.CreatePackage(outputPackage)
 	if err != nil {
 		return err
@@ -25,8 +27,26 @@ func GenerateClient(builder golang.ModuleBuilder, service *gocode.ServiceInterfa
 	}
 
 	client.Imports.AddPackages(
-		"net/http", "encoding/json", "context", "net/url", "fmt", "io", "errors",
+		"net/http", "encoding/json", "context", "net/url", "io", "errors",
+		"github.com/blueprint-uservices/blueprint/runtime/core/deadline",
+		"github.com/blueprint-uservices/blueprint/runtime/core/idempotency",
+		"github.com/blueprint-uservices/blueprint/runtime/core/rpcerror",
 	)
+	if client.Routes, err = Routes(service, routes); err != nil {
+		return err
+	}
+
+	// Parse the current output code to resolve the types of arguments
+	modules := workflowspec.Get().Derive().Modules
+	if err := modules.AddWorkspace(builder.Workspace().Info().Path); err != nil {
+		return err
+	}
+	if err := BindRawParams(modules, client.Routes); err != nil {
+		return err
+	}
+	if hasBody(client.Routes) {
+		client.Imports.AddPackages("bytes")
+	}
 
 	slog.Info(fmt.Sprintf("Generating %v/%v.go", client.Package.PackageName, client.Name))
 	outputFile := filepath.Join(client.Package.Path, client.Name+".go")
@@ -39,6 +59,7 @@ type clientArgs struct {
 	Service *gocode.ServiceInterface
 	Name    string
 	Imports *gogen.Imports
+	Routes  map[string]*Route
 }
 
 var clientTemplate = `// Blueprint: Auto-generated by the HTTP Plugin
@@ -73,34 +94,77 @@ func New_{{.Name}}(ctx context.Context, serverAddress string) (*{{.Name}}, error
 {{$service := .Service.Name -}}
 {{$receiver := .Name -}}
 {{- range $_, $f := .Service.Methods }}
+{{- $route := index $.Routes $f.Name}}
 func (client *{{$receiver}}) {{SignatureWithRetVars $f}} {
 	vals := url.Values{}
-	{{range $_, $arg := $f.Arguments}}
-	{{if eq (NameOf $arg.Type) "string" -}}
-	vals.Add("{{$arg.Name}}", {{$arg.Name}})
+	{{- if $route.BodyParams}}
+	request_body := struct {
+		{{- range $_, $param := $route.BodyParams}}
+		{{$param.Field}} {{NameOf $param.Type}} ` + "`" + `json:"{{$param.Name}}"` + "`" + `
+		{{- end}}
+	}{}
+	{{- end}}
+	{{range $_, $param := $route.Params}}
+	{{if eq $param.In "body" -}}
+	request_body.{{$param.Field}} = {{$param.Name}}
 	{{- else -}}
-	bytes_{{$arg.Name}}, err := json.Marshal({{$arg.Name}})
+	{{if $param.Raw -}}
+	param_{{$param.Name}} := string({{$param.Name}})
+	{{- else -}}
+	bytes_{{$param.Name}}, err := json.Marshal({{$param.Name}})
 	if err != nil {
 		return
 	}
-	vals.Add("{{$arg.Name}}", string(bytes_{{$arg.Name}}))
+	param_{{$param.Name}} := string(bytes_{{$param.Name}})
+	{{- end}}
+	{{if eq $param.In "query" -}}
+	vals.Add("{{$param.Name}}", param_{{$param.Name}})
+	{{- end}}
 	{{- end}}
 	{{end}}
 
-	encoded_url, err := url.Parse(client.ServerAddress + "/{{$f.Name}}")
+	encoded_url, err := url.Parse(client.ServerAddress + {{$route.PathExpr}})
 	if err != nil {
 		return
 	}
 	encoded_url.RawQuery = vals.Encode()
 
-	resp, err := client.Client.Get(encoded_url.String())
+	var request_reader io.Reader
+	{{- if $route.BodyParams}}
+	request_bytes, err := json.Marshal(request_body)
+	if err != nil {
+		return
+	}
+	request_reader = bytes.NewReader(request_bytes)
+	{{- end}}
+	// The request is aborted if ctx is cancelled, which also cancels the server-side handler
+	req, err := http.NewRequestWithContext(ctx, "{{$route.ClientVerb}}", encoded_url.String(), request_reader)
+	if err != nil {
+		return
+	}
+	{{- if $route.BodyParams}}
+	req.Header.Set("Content-Type", "application/json")
+	{{- end}}
+	if key := idempotency.OutgoingKey(ctx, "{{$f.Name}}"); key != "" {
+		req.Header.Set(idempotency.Header, key)
+	}
+	if timeout := deadline.FormatHeader(ctx); timeout != "" {
+		req.Header.Set(deadline.Header, timeout)
+	}
+
+	resp, err := client.Client.Do(req)
 	if err != nil {
 		return
 	}
 	defer resp.Body.Close()
+	resp_bytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
 	statusOk := resp.StatusCode >= 200 && resp.StatusCode < 300
 	if !statusOk {
-		err = fmt.Errorf("StatusCode was %d", resp.StatusCode)
+		// Reconstruct the error returned by the service
+		err = rpcerror.DecodeHTTP(resp.StatusCode, resp_bytes)
 		return
 	}
 	response := struct {
@@ -108,10 +172,6 @@ func (client *{{$receiver}}) {{SignatureWithRetVars $f}} {
 		Ret{{$i}} {{NameOf $arg.Type}}
 		{{end}}
 	}{}
-	resp_bytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return
-	}
 	err = json.Unmarshal(resp_bytes, &response)
 	if err != nil {
 		return
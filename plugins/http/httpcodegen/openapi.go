@@ -0,0 +1,461 @@
+package httpcodegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
+	"github.com/blueprint-uservices/blueprint/plugins/golang"
+	"github.com/blueprint-uservices/blueprint/plugins/golang/gocode"
+	"github.com/blueprint-uservices/blueprint/plugins/golang/goparser"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow/workflowspec"
+	"golang.org/x/exp/slog"
+)
+
+/*
+Generates an OpenAPI 3 document that describes the routes of the HTTP server generated by
+[GenerateServerHandler], and writes it to <BaseName>_openapi.json alongside the server.
+
+The schemas of arguments and return values are derived from their Go types.  Types declared in
+the workflow spec, and the types nested within them, are described by schemas in the document's
+components.  The schemas of structs follow the JSON encoding of the structs, including their json
+field tags, and other named types, e.g. enums, are described by their underlying types.
+
+routes overrides the routes of the service's methods; see [Routes].
+*/
+func GenerateOpenAPI(builder golang.ModuleBuilder, service *gocode.ServiceInterface, outputPackage string, routes map[string]string) error {
+	pkg, err := builder.CreatePackage(outputPackage)
+	if err != nil {
+		return err
+	}
+
+	parsedRoutes, err := Routes(service, routes)
+	if err != nil {
+		return err
+	}
+
+	// Parse the current output code to get definitions that may have been generated by other plugins
+	modules := workflowspec.Get().Derive().Modules
+	if err := modules.AddWorkspace(builder.Workspace().Info().Path); err != nil {
+		return err
+	}
+
+	if err := BindRawParams(modules, parsedRoutes); err != nil {
+		return err
+	}
+
+	doc, err := newOpenAPIBuilder(modules).build(service, parsedRoutes)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return blueprint.Errorf("unable to encode OpenAPI document of %v due to %v", service.Name, err.Error())
+	}
+
+	slog.Info(fmt.Sprintf("Generating %v/%v_openapi.json", pkg.PackageName, service.BaseName))
+	outputFile := filepath.Join(pkg.Path, service.BaseName+"_openapi.json")
+	if err := os.WriteFile(outputFile, append(b, '\n'), 0644); err != nil {
+		return blueprint.Errorf("unable to write %v due to %v", outputFile, err.Error())
+	}
+	return nil
+}
+
+/* A subset of the OpenAPI 3.0 document structure; see https://spec.openapis.org/oas/v3.0.3 */
+type (
+	openAPIDocument struct {
+		OpenAPI    string                     `json:"openapi"`
+		Info       openAPIInfo                `json:"info"`
+		Paths      map[string]openAPIPathItem `json:"paths"`
+		Components openAPIComponents          `json:"components,omitempty"`
+	}
+
+	openAPIInfo struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	}
+
+	openAPIComponents struct {
+		Schemas map[string]*openAPISchema `json:"schemas,omitempty"`
+	}
+
+	// Operations keyed by lowercase verb
+	openAPIPathItem map[string]*openAPIOperation
+
+	openAPIOperation struct {
+		OperationID string                      `json:"operationId"`
+		Tags        []string                    `json:"tags,omitempty"`
+		Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+		RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+		Responses   map[string]*openAPIResponse `json:"responses"`
+	}
+
+	openAPIParameter struct {
+		Name     string                       `json:"name"`
+		In       string                       `json:"in"`
+		Required bool                         `json:"required,omitempty"`
+		Schema   *openAPISchema               `json:"schema,omitempty"`
+		Content  map[string]*openAPIMediaType `json:"content,omitempty"`
+	}
+
+	openAPIRequestBody struct {
+		Content map[string]*openAPIMediaType `json:"content"`
+	}
+
+	openAPIResponse struct {
+		Description string                       `json:"description"`
+		Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+	}
+
+	openAPIMediaType struct {
+		Schema *openAPISchema `json:"schema"`
+	}
+
+	openAPISchema struct {
+		Ref                  string                    `json:"$ref,omitempty"`
+		Type                 string                    `json:"type,omitempty"`
+		Format               string                    `json:"format,omitempty"`
+		Description          string                    `json:"description,omitempty"`
+		Nullable             bool                      `json:"nullable,omitempty"`
+		Minimum              *int                      `json:"minimum,omitempty"`
+		Items                *openAPISchema            `json:"items,omitempty"`
+		Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+		AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+		Required             []string                  `json:"required,omitempty"`
+		AllOf                []*openAPISchema          `json:"allOf,omitempty"`
+	}
+)
+
+type openAPIBuilder struct {
+	code    *goparser.ParsedModuleSet
+	schemas map[string]*openAPISchema
+	names   map[gocode.UserType]string // Names of the component schemas of user types
+}
+
+func newOpenAPIBuilder(code *goparser.ParsedModuleSet) *openAPIBuilder {
+	return &openAPIBuilder{
+		code:    code,
+		schemas: make(map[string]*openAPISchema),
+		names:   make(map[gocode.UserType]string),
+	}
+}
+
+func (b *openAPIBuilder) build(service *gocode.ServiceInterface, routes map[string]*Route) (*openAPIDocument, error) {
+	doc := &openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: service.Name, Version: "1.0.0"},
+		Paths:   make(map[string]openAPIPathItem),
+	}
+	for _, name := range sortedMethods(service) {
+		op, err := b.operation(service, service.Methods[name], routes[name])
+		if err != nil {
+			return nil, err
+		}
+		route := routes[name]
+		if doc.Paths[route.Path] == nil {
+			doc.Paths[route.Path] = make(openAPIPathItem)
+		}
+		doc.Paths[route.Path][strings.ToLower(route.ClientVerb())] = op
+	}
+	doc.Components.Schemas = b.schemas
+	return doc, nil
+}
+
+// Describes the operation that serves f at route
+func (b *openAPIBuilder) operation(service *gocode.ServiceInterface, f gocode.Func, route *Route) (*openAPIOperation, error) {
+	op := &openAPIOperation{
+		OperationID: f.Name,
+		Tags:        []string{service.BaseName},
+		Responses:   make(map[string]*openAPIResponse),
+	}
+
+	// Arguments other than raw params are JSON-encoded by the client; the server rejects those it cannot decode
+	decoded := false
+	body := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+	for _, param := range route.Params {
+		schema, err := b.schema(param.Type)
+		if err != nil {
+			return nil, blueprint.Errorf("cannot describe argument %v of %v in OpenAPI due to %v", param.Name, f.Name, err.Error())
+		}
+		if param.In == "body" {
+			body.Properties[param.Name] = schema
+			continue
+		}
+		p := &openAPIParameter{Name: param.Name, In: param.In, Required: param.In == "path"}
+		if param.Raw {
+			p.Schema = schema
+		} else {
+			p.Content = map[string]*openAPIMediaType{"application/json": {Schema: schema}}
+			decoded = true
+		}
+		op.Parameters = append(op.Parameters, p)
+	}
+	if len(body.Properties) > 0 {
+		op.RequestBody = &openAPIRequestBody{Content: map[string]*openAPIMediaType{"application/json": {Schema: body}}}
+		decoded = true
+	}
+
+	// The response is an object with a field Ret<i> for each return value
+	response := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+	for i, ret := range f.Returns {
+		schema, err := b.schema(ret.Type)
+		if err != nil {
+			return nil, blueprint.Errorf("cannot describe return value %v of %v in OpenAPI due to %v", i, f.Name, err.Error())
+		}
+		name := fmt.Sprintf("Ret%v", i)
+		response.Properties[name] = schema
+		response.Required = append(response.Required, name)
+	}
+	op.Responses["200"] = &openAPIResponse{
+		Description: "The return values of " + f.Name,
+		Content:     map[string]*openAPIMediaType{"application/json": {Schema: response}},
+	}
+	errorContent := map[string]*openAPIMediaType{"application/json": {Schema: b.errorSchema()}}
+	if decoded {
+		op.Responses["400"] = &openAPIResponse{Description: "The arguments could not be decoded", Content: errorContent}
+	}
+	op.Responses["default"] = &openAPIResponse{
+		Description: f.Name + " returned an error; the status code is determined by the error's code",
+		Content:     errorContent,
+	}
+	return op, nil
+}
+
+// Returns a reference to the schema of errors encoded by the rpcerror package, adding it to the
+// components if it hasn't already been added.  The name of the schema cannot collide with the
+// names of structs, since it contains a dot.
+func (b *openAPIBuilder) errorSchema() *openAPISchema {
+	name := "rpcerror.Error"
+	if _, exists := b.schemas[name]; !exists {
+		b.schemas[name] = &openAPISchema{
+			Type: "object",
+			Properties: map[string]*openAPISchema{
+				"code":    {Type: "string", Description: "e.g. not_found or invalid_argument"},
+				"message": {Type: "string"},
+				"details": {Type: "object", AdditionalProperties: &openAPISchema{Type: "string"}},
+			},
+			Required: []string{"code", "message"},
+		}
+	}
+	return &openAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+var basicToOpenAPI = map[string]openAPISchema{
+	"bool":   {Type: "boolean"},
+	"string": {Type: "string"},
+	"int":    {Type: "integer", Format: "int64"}, "int8": {Type: "integer", Format: "int32"}, "int16": {Type: "integer", Format: "int32"}, "int32": {Type: "integer", Format: "int32"}, "int64": {Type: "integer", Format: "int64"},
+	"uint": {Type: "integer", Format: "int64"}, "uint8": {Type: "integer", Format: "int32"}, "uint16": {Type: "integer", Format: "int32"}, "uint32": {Type: "integer", Format: "int64"}, "uint64": {Type: "integer", Format: "int64"},
+	"byte":    {Type: "integer", Format: "int32"},
+	"rune":    {Type: "integer", Format: "int32"},
+	"float32": {Type: "number", Format: "float"}, "float64": {Type: "number", Format: "double"},
+}
+
+// Returns the schema of the JSON encoding of values of type t, adding component schemas for any
+// structs that t refers to
+func (b *openAPIBuilder) schema(t gocode.TypeName) (*openAPISchema, error) {
+	switch arg := t.(type) {
+	case *gocode.BasicType:
+		{
+			basic, hasSchema := basicToOpenAPI[arg.Name]
+			if !hasSchema {
+				return nil, blueprint.Errorf("%v cannot be encoded as JSON", arg.Name)
+			}
+			if strings.HasPrefix(arg.Name, "uint") || arg.Name == "byte" {
+				basic.Minimum = new(int)
+			}
+			return &basic, nil
+		}
+	case *gocode.UserType:
+		return b.userTypeSchema(arg)
+	case *gocode.Pointer:
+		{
+			schema, err := b.schema(arg.PointerTo)
+			if err != nil {
+				return nil, err
+			}
+			// A $ref cannot have siblings, so a nullable reference is wrapped
+			if schema.Ref != "" {
+				schema = &openAPISchema{AllOf: []*openAPISchema{schema}}
+			}
+			schema.Nullable = true
+			return schema, nil
+		}
+	case *gocode.Slice:
+		{
+			// []byte is encoded as a base64 string
+			if basic, isBasic := arg.SliceOf.(*gocode.BasicType); isBasic && (basic.Name == "byte" || basic.Name == "uint8") {
+				return &openAPISchema{Type: "string", Format: "byte"}, nil
+			}
+			items, err := b.schema(arg.SliceOf)
+			if err != nil {
+				return nil, err
+			}
+			return &openAPISchema{Type: "array", Items: items, Nullable: true}, nil
+		}
+	case *gocode.Map:
+		{
+			// JSON objects only have string keys; Go encodes integer keys as strings
+			if key, isBasic := arg.KeyType.(*gocode.BasicType); isBasic && (key.Name == "bool" || strings.HasPrefix(key.Name, "float") || strings.HasPrefix(key.Name, "complex")) {
+				return nil, blueprint.Errorf("%v cannot be used as a JSON object key", arg.KeyType)
+			}
+			values, err := b.schema(arg.ValueType)
+			if err != nil {
+				return nil, err
+			}
+			return &openAPISchema{Type: "object", AdditionalProperties: values, Nullable: true}, nil
+		}
+	case *gocode.AnyType, *gocode.InterfaceType:
+		return &openAPISchema{}, nil
+	default:
+		return nil, blueprint.Errorf("%v cannot be encoded as JSON", t.String())
+	}
+}
+
+// Returns a reference to the component schema of a type declared by the application, adding the
+// schema if it hasn't already been added.  Structs are described by their fields, and other named
+// types, e.g. enums, by their underlying types.
+func (b *openAPIBuilder) userTypeSchema(t *gocode.UserType) (*openAPISchema, error) {
+	// Types from the standard library are not parsed, but some have well-known encodings
+	if gocode.IsBuiltinPackage(t.Package) {
+		switch t.String() {
+		case "time.Time":
+			return &openAPISchema{Type: "string", Format: "date-time"}, nil
+		case "time.Duration":
+			return &openAPISchema{Type: "integer", Format: "int64"}, nil
+		}
+		return &openAPISchema{Description: t.String()}, nil
+	}
+
+	if name, exists := b.names[*t]; exists {
+		return &openAPISchema{Ref: "#/components/schemas/" + name}, nil
+	}
+
+	pkg, err := b.code.GetPackage(t.Package)
+	if err != nil {
+		return nil, blueprint.Errorf("could not find package %v for type %v due to: %v", t.Package, t, err)
+	}
+	if named, isNamed := pkg.NamedTypes[t.Name]; isNamed && named.Underlying != nil && len(named.TypeParams) == 0 {
+		underlying, err := b.code.Underlying(t)
+		if err != nil {
+			return nil, blueprint.Errorf("could not resolve the underlying type of %v due to: %v", t, err)
+		}
+		name, schema := b.addComponent(pkg, t)
+		resolved, err := b.schema(underlying)
+		if err != nil {
+			return nil, blueprint.Errorf("cannot describe %v due to %v", t, err.Error())
+		}
+		*schema = *resolved
+		return &openAPISchema{Ref: "#/components/schemas/" + name}, nil
+	}
+
+	struc, hasStruct := pkg.Structs[t.Name]
+	if !hasStruct || len(struc.TypeParams) > 0 {
+		// Generic types and types whose declarations couldn't be resolved aren't described
+		if _, hasTypeDef := pkg.DeclaredTypes[t.Name]; !hasTypeDef {
+			return nil, blueprint.Errorf("could not find %v within %v", t.Name, t.Package)
+		}
+		return &openAPISchema{Description: pkg.ShortName + "." + t.Name}, nil
+	}
+
+	name, schema := b.addComponent(pkg, t)
+	schema.Type = "object"
+	schema.Properties = make(map[string]*openAPISchema)
+
+	var embedded []*openAPISchema
+	for _, field := range struc.FieldsList {
+		fieldName, omitEmpty, isEncoded := jsonField(field)
+		if !isEncoded {
+			continue
+		}
+		fieldSchema, err := b.schema(field.Type)
+		if err != nil {
+			return nil, blueprint.Errorf("cannot describe field %v of %v due to %v", field.Name, t, err.Error())
+		}
+
+		// The fields of untagged embedded structs are promoted into the enclosing JSON object
+		if fieldName == "" {
+			if fieldSchema.Nullable && len(fieldSchema.AllOf) == 1 {
+				fieldSchema = fieldSchema.AllOf[0]
+			}
+			embedded = append(embedded, fieldSchema)
+			continue
+		}
+
+		schema.Properties[fieldName] = fieldSchema
+		if !omitEmpty {
+			schema.Required = append(schema.Required, fieldName)
+		}
+	}
+	if len(embedded) > 0 {
+		own := &openAPISchema{Type: schema.Type, Properties: schema.Properties, Required: schema.Required}
+		*schema = openAPISchema{AllOf: append(embedded, own)}
+	}
+
+	return &openAPISchema{Ref: "#/components/schemas/" + name}, nil
+}
+
+// Adds an empty component schema for t, returning its name and the schema to be filled in.  The
+// schema is added before it is filled in, so that recursive types refer to themselves.
+func (b *openAPIBuilder) addComponent(pkg *goparser.ParsedPackage, t *gocode.UserType) (string, *openAPISchema) {
+	// Types with the same name from different packages are qualified by package name
+	name := t.Name
+	if _, taken := b.schemas[name]; taken {
+		name = pkg.ShortName + "_" + t.Name
+	}
+	for i := 2; b.schemas[name] != nil; i++ {
+		name = fmt.Sprintf("%v_%v_%v", pkg.ShortName, t.Name, i)
+	}
+	schema := &openAPISchema{}
+	b.names[*t] = name
+	b.schemas[name] = schema
+	return name, schema
+}
+
+// Returns the name of the JSON property that encodes field, following the rules of encoding/json.
+// The name is empty for untagged embedded structs, whose fields are promoted.
+func jsonField(field *goparser.ParsedField) (name string, omitEmpty bool, isEncoded bool) {
+	var tag string
+	if field.Ast.Tag != nil {
+		if unquoted, err := strconv.Unquote(field.Ast.Tag.Value); err == nil {
+			tag = reflect.StructTag(unquoted).Get("json")
+		}
+	}
+	if tag == "-" {
+		return "", false, false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	omitEmpty = strings.Contains(","+opts+",", ",omitempty,")
+
+	fieldName := field.Name
+	if fieldName == "" {
+		// Embedded fields are named after their type
+		typeName := field.Type
+		if pointer, isPointer := typeName.(*gocode.Pointer); isPointer {
+			typeName = pointer.PointerTo
+		}
+		user, isUserType := typeName.(*gocode.UserType)
+		if !isUserType {
+			return "", false, false
+		}
+		if name == "" {
+			return "", false, true
+		}
+		fieldName = user.Name
+	}
+	if !isExported(fieldName) {
+		return "", false, false
+	}
+	if name == "" {
+		name = fieldName
+	}
+	return name, omitEmpty, true
+}
+
+func isExported(name string) bool {
+	return name != "" && strings.ToUpper(name[:1]) == name[:1]
+}
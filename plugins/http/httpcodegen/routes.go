@@ -0,0 +1,287 @@
+package httpcodegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/blueprint-uservices/blueprint/plugins/golang/gocode"
+	"github.com/blueprint-uservices/blueprint/plugins/golang/goparser"
+	"golang.org/x/exp/slices"
+)
+
+// The HTTP route of a service method: the verb and path at which the method is served, and how
+// each of the method's arguments is bound to the request.
+type Route struct {
+	Verb   string  // The HTTP verb, e.g. POST, or the empty string for the default route, which accepts any verb
+	Path   string  // The path template, e.g. /orders/{id}
+	Params []Param // The method's arguments, in order
+}
+
+// The binding of a method argument to an HTTP request
+type Param struct {
+	gocode.Variable
+	In    string // One of "path", "query" or "body"
+	Field string // The name of the field of the generated body struct, for body params
+	Raw   bool   // True for path and query params that are bound as is, rather than JSON-encoded; see [BindRawParams]
+}
+
+// The verbs that routes can use
+var verbs = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// The name of the comment directive on workflow interface methods that declares a method's route,
+// e.g. //blueprint:http=POST /orders/{id}
+const RouteDirective = "http"
+
+// Returns the default route of f, which serves f at /<method name> with any verb and binds all
+// of f's arguments to query parameters.
+func DefaultRoute(f gocode.Func) *Route {
+	route := &Route{Path: "/" + f.Name}
+	for _, arg := range f.Arguments {
+		route.Params = append(route.Params, Param{Variable: arg, In: "query"})
+	}
+	return route
+}
+
+// Parses route, which has the form "VERB /path?query&params", as the route of f.
+//
+// Arguments of f named in braces in the path, e.g. /orders/{id}, are bound to path segments, and
+// arguments named in the optional query string are bound to query parameters.  The remaining
+// arguments are bound to query parameters for GET and DELETE routes, and to the fields of a JSON
+// request body for POST, PUT and PATCH routes.
+func ParseRoute(f gocode.Func, route string) (*Route, error) {
+	verb, path, found := strings.Cut(strings.TrimSpace(route), " ")
+	path = strings.TrimSpace(path)
+	if !found || !slices.Contains(verbs, verb) {
+		return nil, fmt.Errorf("invalid route %q for %v; expected one of %v followed by a path", route, f.Name, strings.Join(verbs, ", "))
+	}
+	path, query, _ := strings.Cut(path, "?")
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid route %q for %v; the path must start with /", route, f.Name)
+	}
+
+	bindings := make(map[string]string)
+	segments, err := splitPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid route %q for %v; %v", route, f.Name, err)
+	}
+	for _, segment := range segments {
+		if segment.param == "" {
+			continue
+		}
+		if _, bound := bindings[segment.param]; bound {
+			return nil, fmt.Errorf("invalid route %q for %v; %v is bound more than once", route, f.Name, segment.param)
+		}
+		bindings[segment.param] = "path"
+	}
+	if query != "" {
+		for _, name := range strings.Split(query, "&") {
+			if _, bound := bindings[name]; bound {
+				return nil, fmt.Errorf("invalid route %q for %v; %v is bound more than once", route, f.Name, name)
+			}
+			bindings[name] = "query"
+		}
+	}
+	for name := range bindings {
+		if !slices.ContainsFunc(f.Arguments, func(arg gocode.Variable) bool { return arg.Name == name }) {
+			return nil, fmt.Errorf("invalid route %q for %v; %v is not an argument of %v", route, f.Name, name, f.Name)
+		}
+	}
+
+	r := &Route{Verb: verb, Path: path}
+	for _, arg := range f.Arguments {
+		param := Param{Variable: arg, In: bindings[arg.Name]}
+		if param.In == "" {
+			param.In = "query"
+			if r.HasBody() {
+				param.In = "body"
+				param.Field = fmt.Sprintf("Arg%v", len(r.BodyParams()))
+			}
+		}
+		r.Params = append(r.Params, param)
+	}
+	return r, nil
+}
+
+// Returns the routes of the methods of service, keyed by method name.  A method's route is given
+// by routes, if present, or else by the method's //blueprint:http directive, if present, or else
+// is the method's [DefaultRoute].
+func Routes(service *gocode.ServiceInterface, routes map[string]string) (map[string]*Route, error) {
+	for name := range routes {
+		if _, exists := service.Methods[name]; !exists {
+			return nil, fmt.Errorf("route for unknown method %v of %v", name, service.Name)
+		}
+	}
+
+	parsed := make(map[string]*Route)
+	served := make(map[string]map[string]string) // Methods by verb, by path pattern
+	for _, name := range sortedMethods(service) {
+		f := service.Methods[name]
+		route, hasRoute := routes[name]
+		if !hasRoute {
+			route, hasRoute = f.Directives[RouteDirective]
+		}
+		r := DefaultRoute(f)
+		if hasRoute {
+			var err error
+			if r, err = ParseRoute(f, route); err != nil {
+				return nil, err
+			}
+		}
+
+		// The default route accepts any verb, so it conflicts with every route with the same path
+		pattern := r.pattern()
+		if served[pattern] == nil {
+			served[pattern] = make(map[string]string)
+		}
+		for verb, other := range served[pattern] {
+			if verb == r.Verb || verb == "" || r.Verb == "" {
+				return nil, fmt.Errorf("methods %v and %v of %v have conflicting routes at %v", other, name, service.Name, r.Path)
+			}
+		}
+		served[pattern][r.Verb] = name
+		parsed[name] = r
+	}
+	return parsed, nil
+}
+
+// Marks the path and query params of routes whose underlying type is string, e.g. a type declared
+// as type Name string, to be bound as is.  The values of other path and query params are JSON-encoded.
+//
+// code is used to look up the declarations of the params' types.
+func BindRawParams(code *goparser.ParsedModuleSet, routes map[string]*Route) error {
+	for _, route := range routes {
+		for i := range route.Params {
+			param := &route.Params[i]
+			if param.In == "body" {
+				continue
+			}
+			underlying, err := code.Underlying(param.Type)
+			if err != nil {
+				return fmt.Errorf("unable to resolve the type of %v due to %v", param.Name, err)
+			}
+			basic, isBasic := underlying.(*gocode.BasicType)
+			param.Raw = isBasic && basic.Name == "string"
+		}
+	}
+	return nil
+}
+
+func sortedMethods(service *gocode.ServiceInterface) []string {
+	var names []string
+	for name := range service.Methods {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Returns true if the route has a JSON request body
+func (r *Route) HasBody() bool {
+	return r.Verb == "POST" || r.Verb == "PUT" || r.Verb == "PATCH"
+}
+
+// Returns the params of the route that are bound to fields of the request body
+func (r *Route) BodyParams() []Param {
+	var params []Param
+	for _, param := range r.Params {
+		if param.In == "body" {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// Returns the verb that clients use to call the route
+func (r *Route) ClientVerb() string {
+	if r.Verb == "" {
+		return "GET"
+	}
+	return r.Verb
+}
+
+// Returns a Go expression that evaluates to the route's path, in which each path param is
+// substituted by the variable param_<name>
+func (r *Route) PathExpr() string {
+	segments, _ := splitPath(r.Path)
+	var exprs []string
+	for _, segment := range segments {
+		if segment.param == "" {
+			exprs = append(exprs, strconv.Quote(segment.literal))
+		} else {
+			exprs = append(exprs, "url.PathEscape(param_"+segment.param+")")
+		}
+	}
+	return strings.Join(exprs, " + ")
+}
+
+// Returns the path with params replaced by {}, so that routes that serve the same paths compare equal
+func (r *Route) pattern() string {
+	segments, _ := splitPath(r.Path)
+	var b strings.Builder
+	for _, segment := range segments {
+		if segment.param == "" {
+			b.WriteString(segment.literal)
+		} else {
+			b.WriteString("{}")
+		}
+	}
+	return b.String()
+}
+
+type pathSegment struct {
+	literal string
+	param   string
+}
+
+// Splits a path template into literal text and params
+func splitPath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	for path != "" {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			if strings.Contains(path, "}") {
+				return nil, fmt.Errorf("unbalanced braces in path")
+			}
+			segments = append(segments, pathSegment{literal: path})
+			break
+		}
+		end := strings.Index(path[start:], "}")
+		if end < 0 || strings.Contains(path[:start], "}") {
+			return nil, fmt.Errorf("unbalanced braces in path")
+		}
+		end += start
+		name := path[start+1 : end]
+		if name == "" || strings.ContainsAny(name, ":{/") {
+			return nil, fmt.Errorf("invalid path param {%v}", name)
+		}
+		if start > 0 {
+			segments = append(segments, pathSegment{literal: path[:start]})
+		}
+		segments = append(segments, pathSegment{param: name})
+		path = path[end+1:]
+	}
+	return segments, nil
+}
+
+// Returns true if any of the routes has params that are bound to path segments
+func hasPathParams(routes map[string]*Route) bool {
+	for _, route := range routes {
+		for _, param := range route.Params {
+			if param.In == "path" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns true if any of the routes has params that are bound to the request body
+func hasBody(routes map[string]*Route) bool {
+	for _, route := range routes {
+		if len(route.BodyParams()) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -7,13 +7,16 @@ import (
 	"github.com/blueprint-uservices/blueprint/plugins/golang"
 	"github.com/blueprint-uservices/blueprint/plugins/golang/gocode"
 	"github.com/blueprint-uservices/blueprint/plugins/golang/gogen"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow/workflowspec"
 	"golang.org/x/exp/slog"
 )
 
 /*
 This function is used by the HTTP plugin to generate the server-side HTTP service.
+
+routes overrides the routes of the service's methods; see [Routes].
 */
-func GenerateServerHandler(builder golang.ModuleBuilder, service *gocode.ServiceInterface, outputPackage string) error {
+func GenerateServerHandler(builder golang.ModuleBuilder, service *gocode.ServiceInterface, outputPackage string, routes map[string]string) error {
 	pkg, err := builder.CreatePackage(outputPackage)
 	if err != nil {
 		return err
@@ -25,8 +28,31 @@ func GenerateServerHandler(builder golang.ModuleBuilder, service *gocode.Service
 		Name:    service.BaseName + "_HTTPServerHandler",
 		Imports: gogen.NewImports(pkg.Name),
 	}
+	if server.Routes, err = Routes(service, routes); err != nil {
+		return err
+	}
+
+	// Parse the current output code to resolve the types of arguments
+	modules := workflowspec.Get().Derive().Modules
+	if err := modules.AddWorkspace(builder.Workspace().Info().Path); err != nil {
+		return err
+	}
+	if err := BindRawParams(modules, server.Routes); err != nil {
+		return err
+	}
 
-	server.Imports.AddPackages("context", "encoding/json", "net/http", "github.com/gorilla/mux")
+	server.Imports.AddPackages(
+		"context", "encoding/json", "net", "net/http", "github.com/gorilla/mux",
+		"github.com/blueprint-uservices/blueprint/runtime/core/deadline",
+		"github.com/blueprint-uservices/blueprint/runtime/core/idempotency",
+		"github.com/blueprint-uservices/blueprint/runtime/core/rpcerror",
+	)
+	if hasPathParams(server.Routes) {
+		server.Imports.AddPackages("net/url")
+	}
+	if hasBody(server.Routes) {
+		server.Imports.AddPackages("errors", "io")
+	}
 
 	slog.Info(fmt.Sprintf("Generating %v/%v_HTTPServer.go", server.Package.PackageName, service.BaseName))
 	outputFile := filepath.Join(server.Package.Path, service.BaseName+"_HTTPServer.go")
@@ -41,6 +67,7 @@ type serverArgs struct {
 	Service *gocode.ServiceInterface
 	Name    string         // Name of the generated wrapper class
 	Imports *gogen.Imports // Manages imports for us
+	Routes  map[string]*Route
 }
 
 var serverTemplate = `// Blueprint: Auto-generated by HTTP Plugin
@@ -63,13 +90,18 @@ func New_{{.Name}}(ctx context.Context, service {{.Imports.NameOf .Service.UserT
 // Blueprint: Run is called automatically in a separate goroutine by runtime/plugins/golang/di.go
 func (handler *{{.Name}}) Run(ctx context.Context) error {
 	router := mux.NewRouter()
+	// Match routes against the encoded path, so that path params can contain escaped slashes
+	router.UseEncodedPath()
 	// Add paths for the mux router
 	{{ range $_, $f := .Service.Methods }}
-	router.Path("/{{$f.Name}}").HandlerFunc(handler.{{$f.Name}})
+	{{- $route := index $.Routes $f.Name}}
+	router.Path("{{$route.Path}}"){{if $route.Verb}}.Methods("{{$route.Verb}}"){{end}}.HandlerFunc(handler.{{$f.Name}})
 	{{end}}
 	srv := &http.Server {
 		Addr: handler.Address,
 		Handler: router,
+		// Requests are cancelled when the server shuts down
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
 
 	go func() {
@@ -82,32 +114,67 @@ func (handler *{{.Name}}) Run(ctx context.Context) error {
 	return srv.ListenAndServe()
 }
 
+// Writes the encoded err as the response, with the HTTP status code of err's code
+func (handler *{{.Name}}) writeError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(rpcerror.HTTPStatus(rpcerror.CodeOf(err)))
+	w.Write(rpcerror.Encode(err))
+}
+
 {{$service := .Service.Name -}}
 {{$receiver := .Name -}}
 {{ range $_, $f := .Service.Methods }}
+{{- $route := index $.Routes $f.Name -}}
 func (handler *{{$receiver}}) {{$f.Name -}}
 	(w http.ResponseWriter, r *http.Request) {
 	var err error
 	defer r.Body.Close()
-	{{range $_, $arg := $f.Arguments}}
-	{{if eq (NameOf $arg.Type) "string" -}}
-	{{$arg.Name}} := r.URL.Query().Get("{{$arg.Name}}")
+	{{- if $route.BodyParams}}
+	request_body := struct {
+		{{- range $_, $param := $route.BodyParams}}
+		{{$param.Field}} {{NameOf $param.Type}} ` + "`" + `json:"{{$param.Name}}"` + "`" + `
+		{{- end}}
+	}{}
+	err = json.NewDecoder(r.Body).Decode(&request_body)
+	if err != nil && !errors.Is(err, io.EOF) {
+		handler.writeError(w, rpcerror.Wrap(rpcerror.InvalidArgument, err))
+		return
+	}
+	{{- end}}
+	{{range $_, $param := $route.Params}}
+	{{if eq $param.In "body" -}}
+	{{$param.Name}} := request_body.{{$param.Field}}
 	{{- else -}}
-	request_{{$arg.Name}} := r.URL.Query().Get("{{$arg.Name}}")
-	var {{$arg.Name}} {{NameOf $arg.Type}}
-	if request_{{$arg.Name}} != "" {
-		err = json.Unmarshal([]byte(request_{{$arg.Name}}), &{{$arg.Name}})
+	{{if eq $param.In "path" -}}
+	request_{{$param.Name}}, err := url.PathUnescape(mux.Vars(r)["{{$param.Name}}"])
+	if err != nil {
+		handler.writeError(w, rpcerror.Wrap(rpcerror.InvalidArgument, err))
+		return
+	}
+	{{- else -}}
+	request_{{$param.Name}} := r.URL.Query().Get("{{$param.Name}}")
+	{{- end}}
+	{{if $param.Raw -}}
+	{{$param.Name}} := {{NameOf $param.Type}}(request_{{$param.Name}})
+	{{- else -}}
+	var {{$param.Name}} {{NameOf $param.Type}}
+	if request_{{$param.Name}} != "" {
+		err = json.Unmarshal([]byte(request_{{$param.Name}}), &{{$param.Name}})
 		if err != nil {
-			http.Error(w, err.Error(), 500)
+			handler.writeError(w, rpcerror.Wrap(rpcerror.InvalidArgument, err))
 			return
 		}
 	}
 	{{- end}}
+	{{- end}}
 	{{end}}
-	ctx := context.Background()
+	// The request context is cancelled if the client disconnects
+	ctx, cancel := deadline.NewIncomingContext(r.Context(), deadline.ParseHeader(r.Header.Get(deadline.Header)))
+	defer cancel()
+	ctx = idempotency.NewIncomingContext(ctx, "{{$f.Name}}", r.Header.Get(idempotency.Header))
 	{{RetVars $f "err"}} {{HasNewReturnVars $f}} handler.Service.{{$f.Name}}({{ArgVars $f "ctx"}})
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		handler.writeError(w, err)
 		return
 	}
 	response := struct {
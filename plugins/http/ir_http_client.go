@@ -73,7 +73,7 @@ func (node *GolangHttpClient) GenerateFuncs(builder golang.ModuleBuilder) error
 		return err
 	}
 
-	return httpcodegen.GenerateClient(builder, iface, node.outputPackage)
+	return httpcodegen.GenerateClient(builder, iface, node.outputPackage, node.ServerAddr.Server.Routes)
 }
 
 func (node *GolangHttpClient) AddInstantiation(builder golang.NamespaceBuilder) error {
@@ -3,6 +3,7 @@ package http
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/address"
@@ -11,6 +12,7 @@ import (
 	"github.com/blueprint-uservices/blueprint/plugins/golang"
 	"github.com/blueprint-uservices/blueprint/plugins/golang/gocode"
 	"github.com/blueprint-uservices/blueprint/plugins/http/httpcodegen"
+	"golang.org/x/exp/slices"
 )
 
 // IRNode representing a Golang HTTP server.
@@ -23,6 +25,7 @@ type golangHttpServer struct {
 	InstanceName string
 	Bind         *address.BindConfig
 	Wrapped      golang.Service
+	Routes       map[string]string // Routes of methods given by wiring spec options, keyed by method name
 
 	outputPackage string
 }
@@ -41,7 +44,7 @@ func (i *HttpInterface) GetMethods() []service.Method {
 	return i.Wrapped.GetMethods()
 }
 
-func newGolangHttpServer(name string, wrapped ir.IRNode) (*golangHttpServer, error) {
+func newGolangHttpServer(name string, wrapped ir.IRNode, opts []Option) (*golangHttpServer, error) {
 	service, is_service := wrapped.(golang.Service)
 	if !is_service {
 		return nil, blueprint.Errorf("HTTP server %s expected %s to be a golang service, but got %s", name, wrapped.Name(), reflect.TypeOf(wrapped).String())
@@ -51,11 +54,29 @@ func newGolangHttpServer(name string, wrapped ir.IRNode) (*golangHttpServer, err
 	node.InstanceName = name
 	node.Wrapped = service
 	node.outputPackage = "http"
+	node.Routes = make(map[string]string)
+	for _, opt := range opts {
+		key, value, _ := strings.Cut(string(opt), "=")
+		method, isRoute := strings.CutPrefix(key, "route.")
+		if !isRoute {
+			return nil, blueprint.Errorf("HTTP server %s has unknown option %v", name, opt)
+		}
+		node.Routes[method] = value
+	}
 	return node, nil
 }
 
 func (n *golangHttpServer) String() string {
-	return n.InstanceName + " = HTTPServer(" + n.Wrapped.Name() + ", " + n.Bind.Name() + ")"
+	args := []string{n.Wrapped.Name(), n.Bind.Name()}
+	var methods []string
+	for method := range n.Routes {
+		methods = append(methods, method)
+	}
+	slices.Sort(methods)
+	for _, method := range methods {
+		args = append(args, "\"route."+method+"="+n.Routes[method]+"\"")
+	}
+	return n.InstanceName + " = HTTPServer(" + strings.Join(args, ", ") + ")"
 }
 
 func (n *golangHttpServer) Name() string {
@@ -69,11 +90,11 @@ func (node *golangHttpServer) GenerateFuncs(builder golang.ModuleBuilder) error
 		return err
 	}
 
-	err = httpcodegen.GenerateServerHandler(builder, iface, node.outputPackage)
+	err = httpcodegen.GenerateServerHandler(builder, iface, node.outputPackage, node.Routes)
 	if err != nil {
 		return err
 	}
-	return nil
+	return httpcodegen.GenerateOpenAPI(builder, iface, node.outputPackage, node.Routes)
 }
 
 func (node *golangHttpServer) AddInstantiation(builder golang.NamespaceBuilder) error {
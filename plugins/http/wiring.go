@@ -9,6 +9,33 @@
 //
 // The plugin implements a server-side handler and client-side
 // library that calls the server. This is implemented within the [httpcodegen] package.
+//
+// By default, each method is served at /<MethodName> and its arguments are passed as JSON-encoded query parameters.
+// A method can instead be given a RESTful route, with a verb, a path, and arguments bound to path segments, query
+// parameters, or the fields of a JSON request body, using a comment directive on the method of the workflow
+// service interface:
+//
+//	type OrderService interface {
+//		//blueprint:http=POST /orders/{customerID}?priority
+//		PlaceOrder(ctx context.Context, customerID string, priority int, order Order) (string, error)
+//	}
+//
+// Here customerID is bound to a path segment, priority to a query parameter, and order to the "order" field of the
+// request body.  Path and query parameters whose type is string, or a named type declared as a string, are passed
+// as is rather than JSON-encoded, e.g. /orders/bob.  Routes can also be given, or overridden, with the [Route]
+// option of [Deploy].  See [httpcodegen.ParseRoute] for the route syntax.  The generated client uses the same routes.
+//
+// The generated server derives the context of each call from the incoming request, so that the call is cancelled if
+// the client disconnects, when the server shuts down, or when the deadline of the client's context, which the client
+// transmits in a request header, expires.
+//
+// Errors returned by the service are returned with an HTTP status code that is determined by the error's code, and
+// are reconstructed by the client; see the [rpcerror] package.
+//
+// Alongside the generated server, the plugin writes an OpenAPI 3 document, <Service>_openapi.json, that describes
+// the routes of the server and the JSON schemas of the arguments and return values of its methods.
+//
+// [rpcerror]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/core/rpcerror
 package http
 
 import (
@@ -33,7 +60,11 @@ import (
 //
 // Deploying a service with HTTP increases the visibility of the service within the application.
 // By default, any other service running in any other container or namespace can now contact this service.
-func Deploy(spec wiring.WiringSpec, serviceName string) {
+//
+// [Option]s can give the routes of the service's methods, e.g.
+//
+//	http.Deploy(spec, "order_service", http.Route("GetOrder", "GET /orders/{id}"))
+func Deploy(spec wiring.WiringSpec, serviceName string, opts ...Option) {
 	// The nodes that we are defining
 	httpClient := serviceName + ".http_client"
 	httpServer := serviceName + ".http_server"
@@ -69,7 +100,7 @@ func Deploy(spec wiring.WiringSpec, serviceName string) {
 			return nil, blueprint.Errorf("HTTP server %s expected %s to be a golang.Service, but encountered %s", httpServer, serverNext, err)
 		}
 
-		server, err := newGolangHttpServer(httpServer, wrapped)
+		server, err := newGolangHttpServer(httpServer, wrapped, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -78,3 +109,13 @@ func Deploy(spec wiring.WiringSpec, serviceName string) {
 		return server, err
 	})
 }
+
+// An option that configures how a service is deployed over HTTP
+type Option string
+
+// Serves method at route, which has the form "VERB /path?query&params", e.g. "PUT /orders/{id}", overriding any
+// //blueprint:http directive on the method.  See [httpcodegen.ParseRoute] for how the method's arguments are bound to
+// the request.
+func Route(method string, route string) Option {
+	return Option("route." + method + "=" + route)
+}
@@ -24,7 +24,7 @@ func generateServerWrapper(builder golang.ModuleBuilder, wrapped *gocode.Service
 		Imports: gogen.NewImports(pkg.Name),
 	}
 
-	server.Imports.AddPackages("context", "time")
+	server.Imports.AddPackages("context", "github.com/blueprint-uservices/blueprint/runtime/core/control", "github.com/blueprint-uservices/blueprint/runtime/plugins/latency")
 	slog.Info(fmt.Sprintf("Generating %v/%v", server.Package.PackageName, wrapped.BaseName+"_LatencyInjector"))
 	outputFile := filepath.Join(server.Package.Path, wrapped.BaseName+"_LatencyInjector.go")
 
@@ -45,17 +45,17 @@ package {{.Package.ShortName}}
 
 type {{.Name}} struct {
 	Server {{.Imports.NameOf .Service.UserType}}
-	Latency time.Duration
+	Injector *latency.Injector
 }
 
-func New_{{.Name}} (ctx context.Context, server {{.Imports.NameOf .Service.UserType}}, latency_str string) (*{{.Name}}, error) {
+func New_{{.Name}} (ctx context.Context, server {{.Imports.NameOf .Service.UserType}}, name string, opts ...string) (*{{.Name}}, error) {
 	handler := &{{.Name}}{}
 	handler.Server = server
-	latency, err := time.ParseDuration(latency_str)
+	injector, err := latency.NewInjector(opts...)
 	if err != nil {
 		return nil, err
 	}
-	handler.Latency = latency
+	handler.Injector = control.Register(name, "latency", injector).(*latency.Injector)
 	return handler, nil
 }
 
@@ -63,7 +63,9 @@ func New_{{.Name}} (ctx context.Context, server {{.Imports.NameOf .Service.UserT
 {{$receiver := .Name -}}
 {{ range $_, $f := .Service.Methods }}
 func (server *{{$receiver}}) {{$f.Name -}} ({{ArgVarsAndTypes $f "ctx context.Context"}}) ({{RetVarsAndTypes $f "err error"}}) {
-	time.Sleep(server.Latency)
+	if err = server.Injector.Inject(ctx, "{{$f.Name}}"); err != nil {
+		return
+	}
 	return server.Server.{{$f.Name}}({{ArgVars $f "ctx"}})
 }
 {{end}}
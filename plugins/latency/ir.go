@@ -3,12 +3,14 @@ package latency
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/service"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
 	"github.com/blueprint-uservices/blueprint/plugins/golang"
 	"github.com/blueprint-uservices/blueprint/plugins/golang/gocode"
+	"github.com/blueprint-uservices/blueprint/plugins/golang/gogen"
 )
 
 // Blueprint IR Node representing a server side latency injector
@@ -20,10 +22,10 @@ type LatencyInjectorWrapper struct {
 	InstanceName  string
 	Wrapped       golang.Service
 	outputPackage string
-	LatencyValue  *ir.IRValue
+	Options       []ir.IRNode // Configuration of the runtime latency injector
 }
 
-func newLatencyInjectorWrapper(name string, server ir.IRNode, latency string) (*LatencyInjectorWrapper, error) {
+func newLatencyInjectorWrapper(name string, server ir.IRNode, opts []string) (*LatencyInjectorWrapper, error) {
 	serverNode, is_callable := server.(golang.Service)
 	if !is_callable {
 		return nil, blueprint.Errorf("latency injector wrapper requires %s to be a golang service but got %s", server.Name(), reflect.TypeOf(server).String())
@@ -33,7 +35,9 @@ func newLatencyInjectorWrapper(name string, server ir.IRNode, latency string) (*
 	node.InstanceName = name
 	node.Wrapped = serverNode
 	node.outputPackage = "latencyinjector"
-	node.LatencyValue = &ir.IRValue{Value: latency}
+	for _, opt := range opts {
+		node.Options = append(node.Options, &ir.IRValue{Value: opt})
+	}
 	return node, nil
 }
 
@@ -50,7 +54,11 @@ func (node *LatencyInjectorWrapper) Name() string {
 
 // Implements [ir.IRNode]
 func (node *LatencyInjectorWrapper) String() string {
-	return node.Name() + " = LatencyInjector(" + node.Wrapped.Name() + ")"
+	args := []string{node.Wrapped.Name()}
+	for _, opt := range node.Options {
+		args = append(args, opt.String())
+	}
+	return node.Name() + " = LatencyInjector(" + strings.Join(args, ", ") + ")"
 }
 
 // Implements [golang.Service]
@@ -95,10 +103,16 @@ func (node *LatencyInjectorWrapper) AddInstantiation(builder golang.NamespaceBui
 			Arguments: []gocode.Variable{
 				{Name: "ctx", Type: &gocode.UserType{Package: "context", Name: "Context"}},
 				{Name: "server", Type: iface},
-				{Name: "latency", Type: &gocode.BasicType{Name: "string"}},
+				{Name: "name", Type: &gocode.BasicType{Name: "string"}},
+				{Name: "opts", Type: &gocode.Ellipsis{EllipsisOf: &gocode.BasicType{Name: "string"}}},
 			},
 		},
 	}
 
-	return builder.DeclareConstructor(node.InstanceName, constructor, []ir.IRNode{node.Wrapped, node.LatencyValue})
+	// The samples of an empirical distribution are packaged with the process
+	opts, err := gogen.PackageFileOptions(builder.Module(), node.InstanceName, node.Options, "file")
+	if err != nil {
+		return err
+	}
+	return builder.DeclareConstructor(node.InstanceName, constructor, append([]ir.IRNode{node.Wrapped, &ir.IRValue{Value: node.InstanceName}}, opts...))
 }
@@ -1,23 +1,31 @@
 // Package latencyinjector provides a Blueprint modifier for the server side of service calls.
 //
-// The plugin configures the server side to inject a user-defined amount of latency.
-// Currently latency is injected for all requests and only a pre-defined duration is supported with no variance/noise added.
-// The plugin will generate a wrapper class that will sleep for a fixed amount of time (the specified latency to be injected)
-// before invoking the handler for handling the request.
+// The plugin configures the server side to inject latency before handling requests.  The injected latency is
+// either a fixed duration, or sampled from a distribution: uniform, normal, exponential, Pareto, or an empirical
+// distribution of samples loaded from a CSV file.
+// The plugin will generate a wrapper class that will sleep for the injected latency before invoking the handler
+// for handling the request.
 // Example Usage to add 100ms latency to each request:
-//    import "github.com/blueprint-uservices/blueprint/plugins/latency"
-//    latency.AddFixed(spec, "my_service", "100ms")
 //
-// TODO: Allow latency to be injected selectively to a subset of requests based using
-// random sampling or by matching attributes of a request.
-// TODO: Sample injected latency from a configurable distribution and support choosing
-// the distribution.
+//	import "github.com/blueprint-uservices/blueprint/plugins/latency"
+//	latency.AddFixed(spec, "my_service", "100ms")
 //
-// If you need the above feature(s) consider submitting a PR, raising a feature request,
-// or posting on the Blueprint slack/mailing list.
+// Options can restrict latency injection to a random fraction of requests and to named methods.  Example usage to
+// add exponentially-distributed latency with a mean of 20ms to 10% of calls to GetCart:
+//
+//	latency.AddExponential(spec, "my_service", "20ms", latency.Fraction(0.1), latency.Methods("GetCart"))
+//
+// The plugin utilizes some code in the [runtime/plugins/latency] package.  The options can be changed while the
+// application is running using the [controlplane] plugin.
+//
+// [controlplane]: https://github.com/Blueprint-uServices/blueprint/tree/main/plugins/controlplane
+// [runtime/plugins/latency]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/plugins/latency
 package latency
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/pointer"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
@@ -26,21 +34,94 @@ import (
 	"golang.org/x/exp/slog"
 )
 
+// An Option configures which requests latency is injected into
+type Option string
+
+// [Fraction] injects latency into only a random fraction of requests, where fraction is between 0 and 1.
+func Fraction(fraction float64) Option {
+	return Option(fmt.Sprintf("fraction=%v", fraction))
+}
+
+// [Methods] injects latency into only calls to the named methods of the service.
+func Methods(names ...string) Option {
+	return Option("methods=" + strings.Join(names, ","))
+}
+
 // Adds fixed-amount of latency on the server side during request processing for the specified sevrice.
 // Uses a [blueprint.WiringSpec]
 // Modifies the given service such that the server adds a fixed amount of `latency` while processing the request.
-// The `latency` string must be a sequence of decimal numbers, each with optional fraction and a unit suffix, such as "300ms", "1.5h" or "2h45m". Valid time units are "ns", "us" (or "µs"), "ms", "s", "m", "h". Negative signed values such as "-1.5h" would result in no explicit latency being added; although it might result in the go runtime re-scheduling the running goroutine.
+// The `latency` string must be a sequence of decimal numbers, each with optional fraction and a unit suffix, such as "300ms", "1.5h" or "2h45m". Valid time units are "ns", "us" (or "µs"), "ms", "s", "m", "h". Negative signed values such as "-1.5h" would result in no explicit latency being added.
+// Usage:
+//
+//	AddFixed(spec, "my_service", "100ms")
+func AddFixed(spec wiring.WiringSpec, serviceName string, latency string, opts ...Option) {
+	addLatency(spec, serviceName, opts, "distribution=fixed", "latency="+latency)
+}
+
+// Adds latency sampled uniformly between `min` and `max` on the server side during request processing for the
+// specified service.  `min` and `max` are durations such as "10ms", as for [AddFixed].
+// Usage:
+//
+//	AddUniform(spec, "my_service", "10ms", "30ms")
+func AddUniform(spec wiring.WiringSpec, serviceName string, min string, max string, opts ...Option) {
+	addLatency(spec, serviceName, opts, "distribution=uniform", "min="+min, "max="+max)
+}
+
+// Adds latency sampled from a normal distribution on the server side during request processing for the specified
+// service.  `mean` and `stddev` are durations such as "10ms", as for [AddFixed].  Negative samples result in no
+// latency being added.
 // Usage:
-//   AddFixed(spec, "my_service", "100ms")
-func AddFixed(spec wiring.WiringSpec, serviceName string, latency string) {
+//
+//	AddNormal(spec, "my_service", "20ms", "5ms")
+func AddNormal(spec wiring.WiringSpec, serviceName string, mean string, stddev string, opts ...Option) {
+	addLatency(spec, serviceName, opts, "distribution=normal", "mean="+mean, "stddev="+stddev)
+}
+
+// Adds latency sampled from an exponential distribution on the server side during request processing for the
+// specified service.  `mean` is a duration such as "10ms", as for [AddFixed].
+// Usage:
+//
+//	AddExponential(spec, "my_service", "20ms")
+func AddExponential(spec wiring.WiringSpec, serviceName string, mean string, opts ...Option) {
+	addLatency(spec, serviceName, opts, "distribution=exponential", "mean="+mean)
+}
+
+// Adds latency sampled from a Pareto distribution on the server side during request processing for the specified
+// service, to model heavy-tailed latencies.  `scale` is the minimum latency, a duration such as "10ms" as for
+// [AddFixed], and `shape` is the positive shape parameter; smaller shapes have heavier tails.
+// Usage:
+//
+//	AddPareto(spec, "my_service", "10ms", 2.5)
+func AddPareto(spec wiring.WiringSpec, serviceName string, scale string, shape float64, opts ...Option) {
+	addLatency(spec, serviceName, opts, "distribution=pareto", "scale="+scale, fmt.Sprintf("shape=%v", shape))
+}
+
+// Adds latency sampled from an empirical distribution on the server side during request processing for the
+// specified service.  `samplesFile` is the path to a CSV file of latency samples, which is packaged with the
+// generated process and read when the service starts; samples are selected uniformly at random.  The first column of each row is a sample, either a duration
+// such as "12.5ms", or a number of milliseconds.  A header row is permitted.
+// Usage:
+//
+//	AddEmpirical(spec, "my_service", "latencies.csv")
+func AddEmpirical(spec wiring.WiringSpec, serviceName string, samplesFile string, opts ...Option) {
+	addLatency(spec, serviceName, opts, "distribution=empirical", "file="+samplesFile)
+}
+
+func addLatency(spec wiring.WiringSpec, serviceName string, opts []Option, distribution ...string) {
 	serverWrapper := serviceName + ".server.latency"
 	ptr := pointer.GetPointer(spec, serviceName)
 	if ptr == nil {
 		slog.Error("Unable to add a latencyinjector to " + serviceName + " as it is not a pointer")
+		return
 	}
 
 	serverNext := ptr.AddDstModifier(spec, serverWrapper)
 
+	injectorOpts := distribution
+	for _, opt := range opts {
+		injectorOpts = append(injectorOpts, string(opt))
+	}
+
 	spec.Define(serverWrapper, &LatencyInjectorWrapper{}, func(ns wiring.Namespace) (ir.IRNode, error) {
 		var wrapped golang.Service
 
@@ -48,6 +129,6 @@ func AddFixed(spec wiring.WiringSpec, serviceName string, latency string) {
 			return nil, blueprint.Errorf("LatencyInjector %s expected %s to be a golang.Service, but encountered %s", serverWrapper, serverNext, err)
 		}
 
-		return newLatencyInjectorWrapper(serverWrapper, wrapped, latency)
+		return newLatencyInjectorWrapper(serverWrapper, wrapped, injectorOpts)
 	})
 }
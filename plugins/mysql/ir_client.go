@@ -2,12 +2,14 @@ package mysql
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/address"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/backend"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/service"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
 	"github.com/blueprint-uservices/blueprint/plugins/golang"
+	"github.com/blueprint-uservices/blueprint/plugins/golang/gogen"
 	"github.com/blueprint-uservices/blueprint/plugins/workflow/workflowspec"
 	"github.com/blueprint-uservices/blueprint/runtime/plugins/mysql"
 	"golang.org/x/exp/slog"
@@ -23,11 +25,12 @@ type MySQLDBGoClient struct {
 	Password     *ir.IRValue
 	DBVal        *ir.IRValue
 	Addr         *address.DialConfig
+	Opts         []ir.IRNode // Hard-coded constructor options, e.g. migrations
 
 	Spec *workflowspec.Service
 }
 
-func newMySQLDBGoClient(name string, addr *address.DialConfig, username *ir.IRValue, password *ir.IRValue, dbname *ir.IRValue) (*MySQLDBGoClient, error) {
+func newMySQLDBGoClient(name string, addr *address.DialConfig, username *ir.IRValue, password *ir.IRValue, dbname *ir.IRValue, opts ...ir.IRNode) (*MySQLDBGoClient, error) {
 	spec, err := workflowspec.GetService[mysql.MySqlDB]()
 	client := &MySQLDBGoClient{
 		InstanceName: name,
@@ -35,6 +38,7 @@ func newMySQLDBGoClient(name string, addr *address.DialConfig, username *ir.IRVa
 		Password:     password,
 		DBVal:        dbname,
 		Addr:         addr,
+		Opts:         opts,
 		Spec:         spec,
 	}
 	return client, err
@@ -47,7 +51,11 @@ func (m *MySQLDBGoClient) Name() string {
 
 // Implements ir.IRNode
 func (m *MySQLDBGoClient) String() string {
-	return m.InstanceName + " = MySqlClient(" + m.Addr.Name() + ")"
+	args := []string{m.Addr.Name()}
+	for _, opt := range m.Opts {
+		args = append(args, opt.String())
+	}
+	return m.InstanceName + " = MySqlClient(" + strings.Join(args, ", ") + ")"
 }
 
 // Implements service.ServiceNode
@@ -73,7 +81,12 @@ func (m *MySQLDBGoClient) AddInstantiation(builder golang.NamespaceBuilder) erro
 
 	slog.Info(fmt.Sprintf("Instantiating MySqlClient %v in %v/%v", m.InstanceName, builder.Info().Package.PackageName, builder.Info().FileName))
 
-	return builder.DeclareConstructor(m.InstanceName, m.Spec.Constructor.AsConstructor(), []ir.IRNode{m.Addr, m.DBVal, m.Username, m.Password})
+	opts, err := gogen.PackageFileOptions(builder.Module(), m.InstanceName, m.Opts, "migrations")
+	if err != nil {
+		return err
+	}
+	args := append([]ir.IRNode{m.Addr, m.DBVal, m.Username, m.Password}, opts...)
+	return builder.DeclareConstructor(m.InstanceName, m.Spec.Constructor.AsConstructor(), args)
 }
 
 func (node *MySQLDBGoClient) ImplementsGolangNode()    {}
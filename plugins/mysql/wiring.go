@@ -19,7 +19,11 @@ var mysql_root_password = "pass"
 
 // Container generate the IRNodes for a mysql server docker container that uses the latest mysql/mysql image
 // and the clients needed by the generated application to communicate with the server.
-func Container(spec wiring.WiringSpec, dbName string) string {
+//
+// Options can be provided to apply versioned schema migrations when clients start, e.g.
+//
+//	mysql.Container(spec, "user_db", mysql.Migrations("migrations/user_db"))
+func Container(spec wiring.WiringSpec, dbName string, opts ...Option) string {
 	// The nodes that we are defining
 	ctrName := dbName + ".ctr"
 	clientName := dbName + ".client"
@@ -57,8 +61,26 @@ func Container(spec wiring.WiringSpec, dbName string) string {
 		pwd_val := &ir.IRValue{Value: mysql_root_password}
 		db_val := &ir.IRValue{Value: dbName}
 
-		return newMySQLDBGoClient(clientName, addr.Dial, user_val, pwd_val, db_val)
+		var opt_vals []ir.IRNode
+		for _, opt := range opts {
+			opt_vals = append(opt_vals, &ir.IRValue{Value: string(opt)})
+		}
+
+		return newMySQLDBGoClient(clientName, addr.Dial, user_val, pwd_val, db_val, opt_vals...)
 	})
 
 	return dbName
 }
+
+// An Option configures the clients of the mysql server created by [Container]
+type Option string
+
+// [Migrations] applies the versioned SQL migrations in the directory at path when a client of the database is
+// instantiated.  Migration files are named <version>_<description>.sql, and the applied versions are recorded
+// in the database, so that each migration is only applied once; see [migrations].  The directory is packaged with
+// the process that instantiates the client, which refuses to start if the migrations fail.
+//
+// [migrations]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/core/migrations
+func Migrations(path string) Option {
+	return Option("migrations=" + path)
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/blueprint-uservices/blueprint/plugins/docker"
 	"github.com/blueprint-uservices/blueprint/plugins/golang/goparser"
 	"github.com/blueprint-uservices/blueprint/plugins/workflow/workflowspec"
-	"github.com/blueprint-uservices/blueprint/runtime/plugins/rabbitmq"
 )
 
 // Blueprint IR Node that represents the server side docker container
@@ -36,8 +35,9 @@ func (r *RabbitmqInterface) GetMethods() []service.Method {
 	return r.Wrapped.GetMethods()
 }
 
-func newRabbitmqContainer(name string) (*RabbitmqContainer, error) {
-	spec, err := workflowspec.GetService[rabbitmq.RabbitMQ]()
+// ClientImpl is the runtime client implementation, whose interface is exposed by the container
+func newRabbitmqContainer[ClientImpl any](name string) (*RabbitmqContainer, error) {
+	spec, err := workflowspec.GetService[ClientImpl]()
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,81 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/address"
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/backend"
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/service"
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
+	"github.com/blueprint-uservices/blueprint/plugins/golang"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow/workflowspec"
+	"github.com/blueprint-uservices/blueprint/runtime/plugins/rabbitmq"
+	"golang.org/x/exp/slog"
+)
+
+// Blueprint IR Node that represents the generated pub/sub client for the rabbitmq container
+type RabbitmqPubSubClient struct {
+	golang.Service
+	backend.PubSub
+	InstanceName string
+	Addr         *address.DialConfig
+	Options      []ir.IRNode // Hard-coded "key=value" option strings passed to the client constructor
+	Spec         *workflowspec.Service
+}
+
+func newRabbitmqPubSubClient(name string, addr *address.DialConfig, opts []PubSubOption) (*RabbitmqPubSubClient, error) {
+	spec, err := workflowspec.GetService[rabbitmq.RabbitPubSub]()
+	client := &RabbitmqPubSubClient{
+		InstanceName: name,
+		Addr:         addr,
+		Spec:         spec,
+	}
+	for _, opt := range opts {
+		client.Options = append(client.Options, &ir.IRValue{Value: string(opt)})
+	}
+	return client, err
+}
+
+// Implements ir.IRNode
+func (n *RabbitmqPubSubClient) Name() string {
+	return n.InstanceName
+}
+
+// Implements ir.IRNode
+func (n *RabbitmqPubSubClient) String() string {
+	args := []string{n.Addr.Name()}
+	for _, opt := range n.Options {
+		args = append(args, opt.String())
+	}
+	return n.InstanceName + " = RabbitmqPubSubClient(" + strings.Join(args, ", ") + ")"
+}
+
+// Implements service.ServiceNode
+func (n *RabbitmqPubSubClient) GetInterface(ctx ir.BuildContext) (service.ServiceInterface, error) {
+	return n.Spec.Iface.ServiceInterface(ctx), nil
+}
+
+// Implements golang.ProvidesModule
+func (n *RabbitmqPubSubClient) AddToWorkspace(builder golang.WorkspaceBuilder) error {
+	return n.Spec.AddToWorkspace(builder)
+}
+
+// Implements golang.ProvidesInterface
+func (n *RabbitmqPubSubClient) AddInterfaces(builder golang.ModuleBuilder) error {
+	return n.Spec.AddToModule(builder)
+}
+
+// Implements golang.Instantiable
+func (n *RabbitmqPubSubClient) AddInstantiation(builder golang.NamespaceBuilder) error {
+	if builder.Visited(n.InstanceName) {
+		return nil
+	}
+	slog.Info(fmt.Sprintf("Instantiating RabbitmqPubSubClient %v in %v/%v", n.InstanceName, builder.Info().Package.PackageName, builder.Info().FileName))
+
+	args := append([]ir.IRNode{n.Addr}, n.Options...)
+	return builder.DeclareConstructor(n.InstanceName, n.Spec.Constructor.AsConstructor(), args)
+}
+
+func (n *RabbitmqPubSubClient) ImplementsGolangNode()    {}
+func (n *RabbitmqPubSubClient) ImplementsGolangService() {}
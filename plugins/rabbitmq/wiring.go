@@ -3,20 +3,63 @@
 // The package provides a built-in rabbitmq container that provides the server-side implementation
 // and a go-client for connecting to the client.
 //
-// The applications must use a backend.Queue (runtime/core/backend) as the interface in the workflow.
+// The applications must use a backend.Queue (runtime/core/backend) as the interface in the workflow, or
+// a backend.PubSub if the container is instantiated with [PubSubContainer].
 package rabbitmq
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/address"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/pointer"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/wiring"
+	"github.com/blueprint-uservices/blueprint/runtime/plugins/rabbitmq"
 )
 
 // Container generate the IRNodes for a mysql server docker container that uses the latest mysql/mysql image
 // and the clients needed by the generated application to communicate with the server.
 func Container(spec wiring.WiringSpec, name string, queue_name string) string {
+	return define[*RabbitmqGoClient, rabbitmq.RabbitMQ](spec, name, func(clientName string, addr *address.DialConfig) (*RabbitmqGoClient, error) {
+		queue_val := &ir.IRValue{Value: queue_name}
+		return newRabbitmqGoClient(clientName, addr, queue_val)
+	})
+}
+
+// PubSubContainer generates the IRNodes for a rabbitmq server docker container, and the clients needed by the
+// generated application to use it as a [backend.PubSub] with topics and subscriber groups.
+//
+// Options can be provided to configure the visibility timeout and dead-lettering of messages, e.g.
+//
+//	rabbitmq.PubSubContainer(spec, "events", rabbitmq.VisibilityTimeout(10*time.Second), rabbitmq.MaxDeliveries(5))
+//
+// [backend.PubSub]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/core/backend
+func PubSubContainer(spec wiring.WiringSpec, name string, opts ...PubSubOption) string {
+	return define[*RabbitmqPubSubClient, rabbitmq.RabbitPubSub](spec, name, func(clientName string, addr *address.DialConfig) (*RabbitmqPubSubClient, error) {
+		return newRabbitmqPubSubClient(clientName, addr, opts)
+	})
+}
+
+// A PubSubOption configures the client created by [PubSubContainer]
+type PubSubOption string
+
+// [VisibilityTimeout] sets how long a received message may go unacknowledged before it is redelivered.
+// The default is 30 seconds.
+func VisibilityTimeout(timeout time.Duration) PubSubOption {
+	return PubSubOption("visibility=" + timeout.String())
+}
+
+// [MaxDeliveries] sets how many times a message is delivered before it is moved to the dead-letter topic.
+// By default, or if count is 0, messages are redelivered indefinitely.
+func MaxDeliveries(count int) PubSubOption {
+	return PubSubOption(fmt.Sprintf("maxdeliveries=%d", count))
+}
+
+// Defines a rabbitmq container, and a pointer to it whose client-side node is built by newClient.
+// ClientImpl is the runtime implementation whose interface is exposed by the container.
+func define[ClientType ir.IRNode, ClientImpl any](spec wiring.WiringSpec, name string, newClient func(clientName string, addr *address.DialConfig) (ClientType, error)) string {
 	// The nodes that we are defining
 	ctrName := name + ".ctr"
 	clientName := name + ".client"
@@ -24,7 +67,7 @@ func Container(spec wiring.WiringSpec, name string, queue_name string) string {
 
 	// Define the rabbitmq container
 	spec.Define(ctrName, &RabbitmqContainer{}, func(ns wiring.Namespace) (ir.IRNode, error) {
-		ctr, err := newRabbitmqContainer(ctrName)
+		ctr, err := newRabbitmqContainer[ClientImpl](ctrName)
 		if err != nil {
 			return nil, err
 		}
@@ -34,7 +77,7 @@ func Container(spec wiring.WiringSpec, name string, queue_name string) string {
 	})
 
 	// Create a pointer to the rabbitmq container
-	ptr := pointer.CreatePointer[*RabbitmqGoClient](spec, name, ctrName)
+	ptr := pointer.CreatePointer[ClientType](spec, name, ctrName)
 
 	// Define the address that points to the Rabbitmq container
 	address.Define[*RabbitmqContainer](spec, addrName, ctrName)
@@ -42,15 +85,14 @@ func Container(spec wiring.WiringSpec, name string, queue_name string) string {
 
 	// Define the Rabbitmq client and add it to the client side of the pointer
 	clientNext := ptr.AddSrcModifier(spec, clientName)
-	spec.Define(clientName, &RabbitmqGoClient{}, func(ns wiring.Namespace) (ir.IRNode, error) {
+	var client ClientType
+	spec.Define(clientName, client, func(ns wiring.Namespace) (ir.IRNode, error) {
 		addr, err := address.Dial[*RabbitmqContainer](ns, clientNext)
 		if err != nil {
 			return nil, blueprint.Errorf("%s expected %s to be an address but encountered %s", clientName, clientNext, err)
 		}
 
-		queue_val := &ir.IRValue{Value: queue_name}
-
-		return newRabbitmqGoClient(clientName, addr.Dial, queue_val)
+		return newClient(clientName, addr.Dial)
 	})
 
 	return name
@@ -11,7 +11,8 @@ import (
 )
 
 // code generation function called from the ir.go file.
-func generateClient(builder golang.ModuleBuilder, wrapped *gocode.ServiceInterface, outputPackage string, Max int64) error {
+// kind is the suffix of the generated client's name, e.g. RetrierClient
+func generateClient(builder golang.ModuleBuilder, wrapped *gocode.ServiceInterface, outputPackage string, kind string) error {
 	pkg, err := builder.CreatePackage(outputPackage)
 	if err != nil {
 		return err
@@ -20,60 +21,14 @@ func generateClient(builder golang.ModuleBuilder, wrapped *gocode.ServiceInterfa
 	client := clientArgs{
 		Package: pkg,
 		Service: wrapped,
-		Name:    wrapped.BaseName + "_RetrierClient",
-		Max:     Max,
+		Name:    wrapped.BaseName + "_" + kind,
 		Imports: gogen.NewImports(pkg.Name),
 	}
 
-	client.Imports.AddPackages("context")
+	client.Imports.AddPackages("context", "github.com/blueprint-uservices/blueprint/runtime/core/control", "github.com/blueprint-uservices/blueprint/runtime/plugins/retries")
 
-	return generateClientCommon(&client, clientTemplate)
-}
-
-func generateExpBackoffClient(builder golang.ModuleBuilder, wrapped *gocode.ServiceInterface, outputPackage string, delay string, limit string) error {
-	pkg, err := builder.CreatePackage(outputPackage)
-	if err != nil {
-		return err
-	}
-
-	client := clientArgs{
-		Package: pkg,
-		Service: wrapped,
-		Name:    wrapped.BaseName + "_RetrierExpBackoffClient",
-		Imports: gogen.NewImports(pkg.Name),
-		Delay:   delay,
-		Limit:   limit,
-	}
-
-	client.Imports.AddPackages("context", "time")
-
-	return generateClientCommon(&client, clientExponentialBackoffTemplate)
-}
-
-func generateFixedDelayClient(builder golang.ModuleBuilder, wrapped *gocode.ServiceInterface, outputPackage string, max_tries int64, delay string) error {
-	pkg, err := builder.CreatePackage(outputPackage)
-	if err != nil {
-		return err
-	}
-
-	client := clientArgs{
-		Package: pkg,
-		Service: wrapped,
-		Name:    wrapped.BaseName + "_RetrierFixedDelayClient",
-		Imports: gogen.NewImports(pkg.Name),
-		Delay:   delay,
-		Max:     max_tries,
-	}
-
-	client.Imports.AddPackages("context", "time")
-
-	return generateClientCommon(&client, clientFixedDelayTemplate)
-}
-
-func generateClientCommon(client *clientArgs, clientTemplate string) error {
-	clientName := client.Name
-	slog.Info(fmt.Sprintf("Generating %v/%v", client.Package.PackageName, clientName))
-	outputFile := filepath.Join(client.Package.Path, clientName+".go")
+	slog.Info(fmt.Sprintf("Generating %v/%v", client.Package.PackageName, client.Name))
+	outputFile := filepath.Join(client.Package.Path, client.Name+".go")
 	return gogen.ExecuteTemplateToFile("Retries", clientTemplate, client, outputFile)
 }
 
@@ -81,9 +36,6 @@ type clientArgs struct {
 	Package golang.PackageInfo
 	Service *gocode.ServiceInterface
 	Name    string
-	Max     int64
-	Delay   string
-	Limit   string
 	Imports *gogen.Imports
 }
 
@@ -94,95 +46,17 @@ package {{.Package.ShortName}}
 
 type {{.Name}} struct {
 	Client {{.Imports.NameOf .Service.UserType}}
-	MaxTries int
+	Retrier *retries.Retrier
 }
 
-func New_{{.Name}} (ctx context.Context, client {{.Imports.NameOf .Service.UserType}}) (*{{.Name}}, error) {
+func New_{{.Name}} (ctx context.Context, client {{.Imports.NameOf .Service.UserType}}, name string, opts ...string) (*{{.Name}}, error) {
 	handler := &{{.Name}}{}
 	handler.Client = client
-	handler.MaxTries = {{.Max}}
-	return handler, nil
-}
-
-{{$service := .Service.Name -}}
-{{$receiver := .Name -}}
-{{ range $_, $f := .Service.Methods }}
-func (client *{{$receiver}}) {{$f.Name -}} ({{ArgVarsAndTypes $f "ctx context.Context"}}) ({{RetVarsAndTypes $f "err error"}}) {
-	for i := 0; i < client.MaxTries; i++ {
-		{{RetVars $f "err"}} = client.Client.{{$f.Name}}({{ArgVars $f "ctx"}})
-		if err == nil {
-			return
-		}
-	}
-	return
-}
-{{end}}
-`
-
-var clientFixedDelayTemplate = `// Blueprint: Auto-generated by Retries Plugin
-package {{.Package.ShortName}}
-
-{{.Imports}}
-
-type {{.Name}} struct {
-	Client {{.Imports.NameOf .Service.UserType}}
-	MaxTries int
-	Delay time.Duration
-}
-
-func New_{{.Name}} (ctx context.Context, client {{.Imports.NameOf .Service.UserType}}) (*{{.Name}}, error) {
-	handler := &{{.Name}}{}
-	handler.Client = client
-	handler.MaxTries = {{.Max}}
-	dur := "{{.Delay}}"
-	parsed_dur, err := time.ParseDuration(dur)
-	if err != nil {
-		return nil, err
-	}
-	handler.Delay = parsed_dur
-	return handler, nil
-}
-
-{{$service := .Service.Name -}}
-{{$receiver := .Name -}}
-{{ range $_, $f := .Service.Methods }}
-func (client *{{$receiver}}) {{$f.Name -}} ({{ArgVarsAndTypes $f "ctx context.Context"}}) ({{RetVarsAndTypes $f "err error"}}) {
-	for i := 0; i < client.MaxTries; i++ {
-		{{RetVars $f "err"}} = client.Client.{{$f.Name}}({{ArgVars $f "ctx"}})
-		if err == nil {
-			return
-		}
-		time.Sleep(client.Delay)
-	}
-	return
-}
-{{end}}
-`
-
-var clientExponentialBackoffTemplate = `// Blueprint: Auto-generated by Retries Plugin
-package {{.Package.ShortName}}
-
-{{.Imports}}
-
-type {{.Name}} struct {
-	Client {{.Imports.NameOf .Service.UserType}}
-	Delay time.Duration
-	Limit time.Duration
-}
-
-func New_{{.Name}} (ctx context.Context, client {{.Imports.NameOf .Service.UserType}}) (*{{.Name}}, error) {
-	handler := &{{.Name}}{}
-	handler.Client = client
-	parsed_delay, err := time.ParseDuration("{{.Delay}}")
+	retrier, err := retries.NewRetrier(opts...)
 	if err != nil {
 		return nil, err
 	}
-	handler.Delay = parsed_delay
-	parsed_limit, err := time.ParseDuration("{{.Limit}}")
-	if err != nil {
-		return nil, err
-	}
-	handler.Limit = parsed_limit
+	handler.Retrier = control.Register(name, "retries", retrier).(*retries.Retrier)
 	return handler, nil
 }
 
@@ -190,17 +64,10 @@ func New_{{.Name}} (ctx context.Context, client {{.Imports.NameOf .Service.UserT
 {{$receiver := .Name -}}
 {{ range $_, $f := .Service.Methods }}
 func (client *{{$receiver}}) {{$f.Name -}} ({{ArgVarsAndTypes $f "ctx context.Context"}}) ({{RetVarsAndTypes $f "err error"}}) {
-	delay := client.Delay
-	i := 1
-	for delay < client.Limit {
+	err = client.Retrier.Do(ctx, "{{$f.Name}}", func(ctx context.Context) (err error) {
 		{{RetVars $f "err"}} = client.Client.{{$f.Name}}({{ArgVars $f "ctx"}})
-		if err == nil {
-			return
-		}
-		i += 1
-		time.Sleep(delay)
-		delay = 2 * delay
-	}
+		return
+	})
 	return
 }
 {{end}}
@@ -3,12 +3,15 @@ package retries
 import (
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/service"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
 	"github.com/blueprint-uservices/blueprint/plugins/golang"
 	"github.com/blueprint-uservices/blueprint/plugins/golang/gocode"
+	"golang.org/x/exp/slices"
 )
 
 // Blueprint IR node representing a Retrier
@@ -22,6 +25,7 @@ type RetrierClient struct {
 
 	outputPackage string
 	Max           int64
+	Options       []ir.IRNode // Additional configuration of the runtime retrier, e.g. jitter
 }
 
 func (node *RetrierClient) ImplementsGolangNode() {}
@@ -31,10 +35,10 @@ func (node *RetrierClient) Name() string {
 }
 
 func (node *RetrierClient) String() string {
-	return node.Name() + " = Retrier(" + node.Wrapped.Name() + ")"
+	return node.Name() + " = Retrier(" + retrierArgs(node.Wrapped, node.Options) + ")"
 }
 
-func newRetrierClient(name string, server ir.IRNode, max_clients int64) (*RetrierClient, error) {
+func newRetrierClient(name string, server ir.IRNode, max_clients int64, opts []Option) (*RetrierClient, error) {
 	serverNode, is_callable := server.(golang.Service)
 	if !is_callable {
 		return nil, blueprint.Errorf("retrier server wrapper requires %s to be a golang service but got %s", server.Name(), reflect.TypeOf(server).String())
@@ -45,6 +49,7 @@ func newRetrierClient(name string, server ir.IRNode, max_clients int64) (*Retrie
 	node.Wrapped = serverNode
 	node.outputPackage = "retries"
 	node.Max = max_clients
+	node.Options = optionNodes(opts)
 
 	return node, nil
 }
@@ -67,7 +72,7 @@ func (node *RetrierClient) GenerateFuncs(builder golang.ModuleBuilder) error {
 		return err
 	}
 
-	return generateClient(builder, iface, node.outputPackage, node.Max)
+	return generateClient(builder, iface, node.outputPackage, "RetrierClient")
 }
 
 func (node *RetrierClient) AddInstantiation(builder golang.NamespaceBuilder) error {
@@ -75,23 +80,8 @@ func (node *RetrierClient) AddInstantiation(builder golang.NamespaceBuilder) err
 		return nil
 	}
 
-	iface, err := golang.GetGoInterface(builder, node.Wrapped)
-	if err != nil {
-		return err
-	}
-
-	constructor := &gocode.Constructor{
-		Package: builder.Module().Info().Name + "/" + node.outputPackage,
-		Func: gocode.Func{
-			Name: fmt.Sprintf("New_%v_RetrierClient", iface.BaseName),
-			Arguments: []gocode.Variable{
-				{Name: "ctx", Type: &gocode.UserType{Package: "context", Name: "Context"}},
-				{Name: "client", Type: iface},
-			},
-		},
-	}
-
-	return builder.DeclareConstructor(node.InstanceName, constructor, []ir.IRNode{node.Wrapped})
+	opts := []string{"maxtries=" + strconv.FormatInt(node.Max, 10)}
+	return declareRetrier(builder, node.InstanceName, node.outputPackage, "RetrierClient", node.Wrapped, opts, node.Options)
 }
 
 // Blueprint IR node representing a Retrier with Fixed Delay
@@ -106,6 +96,7 @@ type RetrierFixedDelayClient struct {
 	outputPackage string
 	Max           int64
 	Delay         string
+	Options       []ir.IRNode // Additional configuration of the runtime retrier, e.g. jitter
 }
 
 func (node *RetrierFixedDelayClient) ImplementsGolangNode() {}
@@ -115,10 +106,10 @@ func (node *RetrierFixedDelayClient) Name() string {
 }
 
 func (node *RetrierFixedDelayClient) String() string {
-	return node.Name() + " = Retrier(" + node.Wrapped.Name() + ")"
+	return node.Name() + " = Retrier(" + retrierArgs(node.Wrapped, node.Options) + ")"
 }
 
-func newRetrierFixedDelayClient(name string, server ir.IRNode, max_clients int64, delay string) (*RetrierFixedDelayClient, error) {
+func newRetrierFixedDelayClient(name string, server ir.IRNode, max_clients int64, delay string, opts []Option) (*RetrierFixedDelayClient, error) {
 	serverNode, is_callable := server.(golang.Service)
 	if !is_callable {
 		return nil, blueprint.Errorf("retrier server wrapper requires %s to be a golang service but got %s", server.Name(), reflect.TypeOf(server).String())
@@ -130,6 +121,7 @@ func newRetrierFixedDelayClient(name string, server ir.IRNode, max_clients int64
 	node.outputPackage = "retries"
 	node.Max = max_clients
 	node.Delay = delay
+	node.Options = optionNodes(opts)
 
 	return node, nil
 }
@@ -152,7 +144,7 @@ func (node *RetrierFixedDelayClient) GenerateFuncs(builder golang.ModuleBuilder)
 		return err
 	}
 
-	return generateFixedDelayClient(builder, iface, node.outputPackage, node.Max, node.Delay)
+	return generateClient(builder, iface, node.outputPackage, "RetrierFixedDelayClient")
 }
 
 func (node *RetrierFixedDelayClient) AddInstantiation(builder golang.NamespaceBuilder) error {
@@ -160,23 +152,8 @@ func (node *RetrierFixedDelayClient) AddInstantiation(builder golang.NamespaceBu
 		return nil
 	}
 
-	iface, err := golang.GetGoInterface(builder, node.Wrapped)
-	if err != nil {
-		return err
-	}
-
-	constructor := &gocode.Constructor{
-		Package: builder.Module().Info().Name + "/" + node.outputPackage,
-		Func: gocode.Func{
-			Name: fmt.Sprintf("New_%v_RetrierFixedDelayClient", iface.BaseName),
-			Arguments: []gocode.Variable{
-				{Name: "ctx", Type: &gocode.UserType{Package: "context", Name: "Context"}},
-				{Name: "client", Type: iface},
-			},
-		},
-	}
-
-	return builder.DeclareConstructor(node.InstanceName, constructor, []ir.IRNode{node.Wrapped})
+	opts := []string{"backoff=fixed", "maxtries=" + strconv.FormatInt(node.Max, 10), "delay=" + node.Delay}
+	return declareRetrier(builder, node.InstanceName, node.outputPackage, "RetrierFixedDelayClient", node.Wrapped, opts, node.Options)
 }
 
 // Blueprint IR node representing a Retrier with Exponential Backoff
@@ -191,6 +168,7 @@ type RetrierExponentialBackoffClient struct {
 	outputPackage string
 	StartDelay    string
 	BackoffLimit  string
+	Options       []ir.IRNode // Additional configuration of the runtime retrier, e.g. jitter
 }
 
 func (node *RetrierExponentialBackoffClient) ImplementsGolangNode() {}
@@ -200,10 +178,10 @@ func (node *RetrierExponentialBackoffClient) Name() string {
 }
 
 func (node *RetrierExponentialBackoffClient) String() string {
-	return node.Name() + " = Retrier(" + node.Wrapped.Name() + ")"
+	return node.Name() + " = Retrier(" + retrierArgs(node.Wrapped, node.Options) + ")"
 }
 
-func newRetrierExponentialBackoffClient(name string, server ir.IRNode, delay string, backoff_limit string) (*RetrierExponentialBackoffClient, error) {
+func newRetrierExponentialBackoffClient(name string, server ir.IRNode, delay string, backoff_limit string, opts []Option) (*RetrierExponentialBackoffClient, error) {
 	serverNode, is_callable := server.(golang.Service)
 	if !is_callable {
 		return nil, blueprint.Errorf("retrier server wrapper requires %s to be a golang service but got %s", server.Name(), reflect.TypeOf(server).String())
@@ -215,6 +193,7 @@ func newRetrierExponentialBackoffClient(name string, server ir.IRNode, delay str
 	node.outputPackage = "retries"
 	node.StartDelay = delay
 	node.BackoffLimit = backoff_limit
+	node.Options = optionNodes(opts)
 
 	return node, nil
 }
@@ -237,7 +216,7 @@ func (node *RetrierExponentialBackoffClient) GenerateFuncs(builder golang.Module
 		return err
 	}
 
-	return generateExpBackoffClient(builder, iface, node.outputPackage, node.StartDelay, node.BackoffLimit)
+	return generateClient(builder, iface, node.outputPackage, "RetrierExpBackoffClient")
 }
 
 func (node *RetrierExponentialBackoffClient) AddInstantiation(builder golang.NamespaceBuilder) error {
@@ -245,21 +224,101 @@ func (node *RetrierExponentialBackoffClient) AddInstantiation(builder golang.Nam
 		return nil
 	}
 
-	iface, err := golang.GetGoInterface(builder, node.Wrapped)
+	opts := []string{"backoff=exponential", "delay=" + node.StartDelay, "limit=" + node.BackoffLimit}
+	return declareRetrier(builder, node.InstanceName, node.outputPackage, "RetrierExpBackoffClient", node.Wrapped, opts, node.Options)
+}
+
+func optionNodes(opts []Option) []ir.IRNode {
+	var nodes []ir.IRNode
+	for _, opt := range opts {
+		nodes = append(nodes, &ir.IRValue{Value: string(opt)})
+	}
+	return nodes
+}
+
+func retrierArgs(wrapped ir.IRNode, opts []ir.IRNode) string {
+	args := []string{wrapped.Name()}
+	for _, opt := range opts {
+		args = append(args, opt.String())
+	}
+	return strings.Join(args, ", ")
+}
+
+// Declares the constructor of a generated retrier client.  The options of the runtime retrier are
+// opts, which configure the kind of retrier, followed by the options provided in the wiring spec.
+func declareRetrier(builder golang.NamespaceBuilder, name string, outputPackage string, kind string, wrapped golang.Service, opts []string, extra []ir.IRNode) error {
+	iface, err := golang.GetGoInterface(builder, wrapped)
 	if err != nil {
 		return err
 	}
 
 	constructor := &gocode.Constructor{
-		Package: builder.Module().Info().Name + "/" + node.outputPackage,
+		Package: builder.Module().Info().Name + "/" + outputPackage,
 		Func: gocode.Func{
-			Name: fmt.Sprintf("New_%v_RetrierExpBackoffClient", iface.BaseName),
+			Name: fmt.Sprintf("New_%v_%v", iface.BaseName, kind),
 			Arguments: []gocode.Variable{
 				{Name: "ctx", Type: &gocode.UserType{Package: "context", Name: "Context"}},
 				{Name: "client", Type: iface},
+				{Name: "name", Type: &gocode.BasicType{Name: "string"}},
+				{Name: "opts", Type: &gocode.Ellipsis{EllipsisOf: &gocode.BasicType{Name: "string"}}},
 			},
 		},
 	}
 
-	return builder.DeclareConstructor(node.InstanceName, constructor, []ir.IRNode{node.Wrapped})
+	args := []ir.IRNode{wrapped, &ir.IRValue{Value: name}}
+	for _, opt := range opts {
+		args = append(args, &ir.IRValue{Value: opt})
+	}
+	return builder.DeclareConstructor(name, constructor, append(args, idempotencyOptions(iface, extra)...))
+}
+
+// Merges the idempotency of methods declared by //blueprint:idempotent and //blueprint:nonidempotent
+// directives in the workflow spec with the Idempotent and NonIdempotent options of the wiring spec, which
+// take precedence.  Returns the options with the merged idempotent and nonidempotent options appended.
+func idempotencyOptions(iface *gocode.ServiceInterface, opts []ir.IRNode) []ir.IRNode {
+	safe := make(map[string]bool)
+	for name, method := range iface.Methods {
+		if _, found := method.Directives["nonidempotent"]; found {
+			safe[name] = false
+		} else if _, found := method.Directives["idempotent"]; found {
+			safe[name] = true
+		}
+	}
+
+	var merged []ir.IRNode
+	for _, opt := range opts {
+		value, isValue := opt.(*ir.IRValue)
+		if !isValue {
+			merged = append(merged, opt)
+			continue
+		}
+		key, methods, _ := strings.Cut(value.Value, "=")
+		if key != "idempotent" && key != "nonidempotent" {
+			merged = append(merged, opt)
+			continue
+		}
+		for _, method := range strings.Split(methods, ",") {
+			if method = strings.TrimSpace(method); method != "" {
+				safe[method] = key == "idempotent"
+			}
+		}
+	}
+
+	var idempotent, nonIdempotent []string
+	for method, isSafe := range safe {
+		if isSafe {
+			idempotent = append(idempotent, method)
+		} else {
+			nonIdempotent = append(nonIdempotent, method)
+		}
+	}
+	if len(idempotent) > 0 {
+		slices.Sort(idempotent)
+		merged = append(merged, &ir.IRValue{Value: "idempotent=" + strings.Join(idempotent, ",")})
+	}
+	if len(nonIdempotent) > 0 {
+		slices.Sort(nonIdempotent)
+		merged = append(merged, &ir.IRValue{Value: "nonidempotent=" + strings.Join(nonIdempotent, ",")})
+	}
+	return merged
 }
@@ -10,9 +10,40 @@
 //	 retries.AddRetriesWithTimeouts(spec, "my_service", 10, "1s") // Adds retries and timeouts
 //	 retries.AddRetriesWithFixedDelay(spec, "my_service", 10, "50ms") // Adds retries with a maximum number of retries and a fixed delay between any two tries.
 //	 retries.AddRetriesWithExponentialBackoff(spec, "my_service", "100ms", "1s") // Adds retries with exponential backoff delay strategy between retries.
+//
+// Options can add jitter to the delay between retries, limit retries with a retry budget or a maximum total
+// elapsed time, and restrict retries to particular errors, e.g.
+//
+//	retries.AddRetriesWithExponentialBackoff(spec, "my_service", "10ms", "1s", retries.FullJitter(), retries.Budget(0.1), retries.RetryOn("timeout"))
+//
+// Methods that are not safe to retry, such as placing an order, can be marked with a comment directive on the
+// method of the workflow service interface, and are then called only once:
+//
+//	type OrderService interface {
+//		//blueprint:nonidempotent
+//		PlaceOrder(ctx context.Context, order Order) (string, error)
+//
+//		//blueprint:idempotent
+//		GetOrder(ctx context.Context, id string) (Order, error)
+//	}
+//
+// The [Idempotent], [NonIdempotent] and [IdempotentOnly] options override or complement the directives.  Each
+// logical call is given an idempotency key, which is the same for every retry of the call and is propagated to
+// the server by the gRPC, HTTP and Thrift plugins, so that services can deduplicate retried calls; see the
+// [runtime/core/idempotency] package.
+//
+// The plugin utilizes some code in the [runtime/plugins/retries] package.  The maximum number of tries and the delays can be changed while the application is running using the [controlplane] plugin.
+//
+// [controlplane]: https://github.com/Blueprint-uServices/blueprint/tree/main/plugins/controlplane
+// [runtime/core/idempotency]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/core/idempotency
+// [runtime/plugins/retries]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/plugins/retries
 package retries
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/pointer"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
@@ -25,10 +56,11 @@ import (
 // Add retrier functionality to all clients of the specified service.
 // Uses a [blueprint.WiringSpec]
 // Modifies the given service such that all clients to that service retry `max_retries` number of times on error.
+// Additional [Option]s can further configure the retrier.
 // Usage:
 //
 //	AddRetries(spec, "my_service", 10)
-func AddRetries(spec wiring.WiringSpec, serviceName string, max_retries int64) {
+func AddRetries(spec wiring.WiringSpec, serviceName string, max_retries int64, opts ...Option) {
 	clientWrapper := serviceName + ".client.retrier"
 
 	ptr := pointer.GetPointer(spec, serviceName)
@@ -46,7 +78,7 @@ func AddRetries(spec wiring.WiringSpec, serviceName string, max_retries int64) {
 			return nil, blueprint.Errorf("Retries %s expected %s to be a golang.Service, but encountered %s", clientWrapper, clientNext, err)
 		}
 
-		return newRetrierClient(clientWrapper, wrapped, max_retries)
+		return newRetrierClient(clientWrapper, wrapped, max_retries, opts)
 	})
 }
 
@@ -79,7 +111,7 @@ func AddRetriesWithTimeouts(spec wiring.WiringSpec, serviceName string, max_retr
 // Usage:
 //
 //	AddRetriesWithFixedDelay(spec, "my_service", 10, "50ms")
-func AddRetriesWithFixedDelay(spec wiring.WiringSpec, serviceName string, max_retries int64, delay string) {
+func AddRetriesWithFixedDelay(spec wiring.WiringSpec, serviceName string, max_retries int64, delay string, opts ...Option) {
 	clientWrapper := serviceName + ".client.retrierfd"
 
 	ptr := pointer.GetPointer(spec, serviceName)
@@ -97,7 +129,7 @@ func AddRetriesWithFixedDelay(spec wiring.WiringSpec, serviceName string, max_re
 			return nil, blueprint.Errorf("Retries %s expected %s to be a golang.Service, but encountered %s", clientWrapper, clientNext, err)
 		}
 
-		return newRetrierFixedDelayClient(clientWrapper, wrapped, max_retries, delay)
+		return newRetrierFixedDelayClient(clientWrapper, wrapped, max_retries, delay, opts)
 	})
 }
 
@@ -109,7 +141,7 @@ func AddRetriesWithFixedDelay(spec wiring.WiringSpec, serviceName string, max_re
 // Usage:
 //
 //	AddRetriesWithExponentialBackoff(spec, "my_service", "100ms", "1s")
-func AddRetriesWithExponentialBackoff(spec wiring.WiringSpec, serviceName string, starting_delay string, backoff_limit string) {
+func AddRetriesWithExponentialBackoff(spec wiring.WiringSpec, serviceName string, starting_delay string, backoff_limit string, opts ...Option) {
 	clientWrapper := serviceName + ".client.retrierfd"
 
 	ptr := pointer.GetPointer(spec, serviceName)
@@ -127,6 +159,79 @@ func AddRetriesWithExponentialBackoff(spec wiring.WiringSpec, serviceName string
 			return nil, blueprint.Errorf("Retries %s expected %s to be a golang.Service, but encountered %s", clientWrapper, clientNext, err)
 		}
 
-		return newRetrierExponentialBackoffClient(clientWrapper, wrapped, starting_delay, backoff_limit)
+		return newRetrierExponentialBackoffClient(clientWrapper, wrapped, starting_delay, backoff_limit, opts)
 	})
 }
+
+// An option that configures a retrier; see [runtime/plugins/retries] for the full list of options.
+//
+// [runtime/plugins/retries]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/plugins/retries
+type Option string
+
+// Waits a random delay between zero and the backoff delay between tries
+func FullJitter() Option {
+	return "jitter=full"
+}
+
+// Waits a random delay between half the backoff delay and the backoff delay between tries
+func EqualJitter() Option {
+	return "jitter=equal"
+}
+
+// Waits a random delay between the initial delay and three times the previous delay between tries, capped at the backoff limit
+func DecorrelatedJitter() Option {
+	return "jitter=decorrelated"
+}
+
+// Limits retries to a fraction of successful calls; e.g. with a ratio of 0.1, each client retries at most one call
+// for every ten successful calls, plus an initial allowance of retries that can be set with [BudgetBurst].
+func Budget(ratio float64) Option {
+	return Option(fmt.Sprintf("budget=%v", ratio))
+}
+
+// Sets the number of retries that a retry budget allows before any calls have succeeded, which is also the maximum
+// number of retries that the budget can accumulate
+func BudgetBurst(retries int) Option {
+	return Option(fmt.Sprintf("budgetburst=%v", retries))
+}
+
+// Stops retrying a call if the next try would start more than d after the first try
+func MaxElapsed(d time.Duration) Option {
+	return Option(fmt.Sprintf("maxelapsed=%v", d))
+}
+
+// Sets the maximum number of tries, including the first.  For exponential backoff, the delay is then capped at the
+// backoff limit rather than retries stopping when the delay reaches the limit.
+func MaxTries(n int64) Option {
+	return Option(fmt.Sprintf("maxtries=%v", n))
+}
+
+// Retries only errors that match one of the named predicates.  The built-in predicates are "all", "timeout" and
+// "network"; applications can register their own with the runtime RegisterPredicate function.
+func RetryOn(predicates ...string) Option {
+	return Option("retryon=" + strings.Join(predicates, ","))
+}
+
+// Retries only errors whose messages match the regular expression pattern.  Can be combined with [RetryOn], in
+// which case errors that match either are retried.
+func RetryOnMatch(pattern string) Option {
+	return Option("retryonmatch=" + pattern)
+}
+
+// Declares that the named methods are safe to retry, overriding any //blueprint:nonidempotent directive on the
+// methods in the workflow spec.
+func Idempotent(methods ...string) Option {
+	return Option("idempotent=" + strings.Join(methods, ","))
+}
+
+// Declares that the named methods are not safe to retry, e.g. because they place an order, overriding any
+// //blueprint:idempotent directive on the methods in the workflow spec.  Calls of the methods are tried once.
+func NonIdempotent(methods ...string) Option {
+	return Option("nonidempotent=" + strings.Join(methods, ","))
+}
+
+// Retries only the methods that are declared idempotent, either with a //blueprint:idempotent directive in the
+// workflow spec or with [Idempotent].  By default, methods are retried unless they are declared non-idempotent.
+func IdempotentOnly() Option {
+	return "idempotentonly=true"
+}
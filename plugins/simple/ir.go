@@ -2,11 +2,13 @@ package simple
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/service"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
 	"github.com/blueprint-uservices/blueprint/plugins/golang"
 	"github.com/blueprint-uservices/blueprint/plugins/golang/gocode"
+	"github.com/blueprint-uservices/blueprint/plugins/golang/gogen"
 	"github.com/blueprint-uservices/blueprint/plugins/workflow/workflowspec"
 	"golang.org/x/exp/slog"
 )
@@ -26,8 +28,9 @@ type SimpleBackend struct {
 	golang.Instantiable
 
 	InstanceName string
-	BackendType  string // e.g. "NoSQLDatabase"
-	BackendImpl  string // e.g. "SimpleNoSQLDB"
+	BackendType  string      // e.g. "NoSQLDatabase"
+	BackendImpl  string      // e.g. "SimpleNoSQLDB"
+	Args         []ir.IRNode // Hard-coded constructor args, e.g. options
 
 	Spec *workflowspec.Service // The backend's interface and implementation
 }
@@ -36,7 +39,8 @@ type SimpleBackend struct {
 //   - name should be a name for the instance, e.g. "my_nosql_db"
 //   - BackendIface should be the the interface this backend implements, e.g. "NoSQLDatabase"
 //   - BackendImpl should be the the implementation, e.g. "SimpleNoSQLDB"
-func newSimpleBackend[BackendImpl any](name string) (*SimpleBackend, error) {
+//   - args are string values passed to the implementation's constructor
+func newSimpleBackend[BackendImpl any](name string, args ...string) (*SimpleBackend, error) {
 	spec, err := workflowspec.GetService[BackendImpl]()
 	if err != nil {
 		return nil, err
@@ -47,6 +51,9 @@ func newSimpleBackend[BackendImpl any](name string) (*SimpleBackend, error) {
 		BackendType:  spec.Iface.Name,
 		BackendImpl:  gocode.NameOf[BackendImpl](),
 	}
+	for _, arg := range args {
+		node.Args = append(node.Args, &ir.IRValue{Value: arg})
+	}
 
 	return node, nil
 }
@@ -78,13 +85,24 @@ func (node *SimpleBackend) AddInstantiation(builder golang.NamespaceBuilder) err
 		return nil
 	}
 
+	// Input files read by the backend are packaged with the process, e.g. the seed files of a NoSQLDB.
+	// Runtime state (the NoSQLDB dir and SQLite file) is left where the options point.
+	args, err := gogen.PackageFileOptions(builder.Module(), node.InstanceName, node.Args, "seed", "schema", "migrations")
+	if err != nil {
+		return err
+	}
+
 	slog.Info(fmt.Sprintf("Instantiating %v %v in %v/%v", node.BackendImpl, node.InstanceName, builder.Info().Package.PackageName, builder.Info().FileName))
-	return builder.DeclareConstructor(node.InstanceName, node.Spec.Constructor.AsConstructor(), nil)
+	return builder.DeclareConstructor(node.InstanceName, node.Spec.Constructor.AsConstructor(), args)
 }
 
 // Implements ir.IRNode
 func (node *SimpleBackend) String() string {
-	return fmt.Sprintf("%v = %v()", node.InstanceName, node.BackendImpl)
+	var args []string
+	for _, arg := range node.Args {
+		args = append(args, arg.String())
+	}
+	return fmt.Sprintf("%v = %v(%v)", node.InstanceName, node.BackendImpl, strings.Join(args, ", "))
 }
 
 func (node *SimpleBackend) ImplementsGolangNode()    {}
@@ -1,4 +1,4 @@
-// Package simple provides basic in-memory implementations of the Cache, Queue, NoSQLDB, and RelationalDB [backends]
+// Package simple provides basic in-memory implementations of the Cache, Queue, PubSub, NoSQLDB, and RelationalDB [backends]
 // that are used by workflow services.
 //
 // The simple backend implementations are alternatives to the heavyweight "full system" implementations such as
@@ -15,8 +15,15 @@
 //	simple.NoSQLDB(spec, "my_nosql_db")
 //	simple.RelationalDB(spec, "my_relational_db")
 //	simple.Queue(spec, "my_queue")
+//	simple.PubSub(spec, "my_pubsub")
 //	simple.Cache(spec, "my_cache")
 //
+// Some backends accept options, e.g. to bound the size of the cache; see [Cache], [Queue], [PubSub], [NoSQLDB] and
+// [RelationalDB].  Input files and directories named by options, such as seed data, schemas and migrations, are
+// packaged with the generated process, and with its container, if they exist when the application is compiled;
+// otherwise they are resolved by the process when it runs.  Paths where a database stores its data are always
+// resolved by the process when it runs.
+//
 // After instantiating a backend, it can be provided as argument to a workflow service.
 //
 // # Wiring Spec Example
@@ -45,6 +52,7 @@
 //   - NoSQLDB: [runtime/plugins/simplenosqldb]
 //   - RelationalDB: [runtime/plugins/sqlitereldb]
 //   - Queue: [runtime/plugins/simplequeue]
+//   - PubSub: [runtime/plugins/simplequeue]
 //   - Cache: [runtime/plugins/simplecache]
 //
 // [mongodb]: https://github.com/Blueprint-uServices/blueprint/tree/main/plugins/mongodb
@@ -60,6 +68,9 @@
 package simple
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/coreplugins/pointer"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/wiring"
@@ -73,36 +84,196 @@ import (
 // [NoSQLDB] can be used by wiring specs to create an in-memory [backend.NoSQLDatabase] instance with the specified name.
 // In the compiled application, uses the [simplenosqldb.SimpleNoSQLDB] implementation from the Blueprint runtime package
 // The SimpleNoSQLDB has limited support for query and update operations.
-func NoSQLDB(spec wiring.WiringSpec, name string) string {
-	return define[backend.NoSQLDatabase, simplenosqldb.SimpleNoSQLDB](spec, name)
+//
+// By default the database is in-memory only.  Options can be provided to persist the database to disk, or to seed
+// it from fixture files, e.g.
+//
+//	simple.NoSQLDB(spec, "my_nosql_db", simple.NoSQLDBDataDir("/var/lib/my_nosql_db"), simple.NoSQLDBSeed("fixtures"))
+func NoSQLDB(spec wiring.WiringSpec, name string, opts ...NoSQLDBOption) string {
+	var args []string
+	for _, opt := range opts {
+		args = append(args, string(opt))
+	}
+	return define[backend.NoSQLDatabase, simplenosqldb.SimpleNoSQLDB](spec, name, args...)
+}
+
+// A NoSQLDBOption configures the database created by [NoSQLDB]
+type NoSQLDBOption string
+
+// [NoSQLDBDataDir] persists the database to the directory at path, using a write-ahead log and periodic
+// snapshots.  The database is restored from the directory when the process restarts.
+func NoSQLDBDataDir(path string) NoSQLDBOption {
+	return NoSQLDBOption("dir=" + path)
+}
+
+// [NoSQLDBSnapshotInterval] sets the minimum interval between snapshots of a persistent database.  The default
+// is one minute.  An interval of 0 disables automatic snapshots.
+func NoSQLDBSnapshotInterval(interval time.Duration) NoSQLDBOption {
+	return NoSQLDBOption("snapshot=" + interval.String())
+}
+
+// [NoSQLDBSeed] imports the collection files in the directory at path when the database starts empty.  Files
+// must be laid out as path/<db_name>/<collection_name>.<json|bson>; see [simplenosqldb.SimpleNoSQLDB.Import].
+func NoSQLDBSeed(path string) NoSQLDBOption {
+	return NoSQLDBOption("seed=" + path)
 }
 
 // [RelationalDB] can be used by wiring specs to create an in-memory [backend.RelationalDB] instance with the specified name.
 // In the compiled application, uses the [sqlitereldb.SqliteRelDB] implementation from the Blueprint runtime package
 // The compiled application might fail to run if gcc is not installed and CGO_ENABLED is not set.
-func RelationalDB(spec wiring.WiringSpec, name string) string {
-	return define[backend.RelationalDB, sqlitereldb.SqliteRelDB](spec, name)
+//
+// Each instance has its own database.  By default the database is in-memory only.  Options can be provided to store
+// the database in a file, or to initialize it from SQL files, e.g.
+//
+//	simple.RelationalDB(spec, "my_relational_db", simple.RelationalDBSchema("schema.sql"), simple.RelationalDBSeed("seed.sql"))
+func RelationalDB(spec wiring.WiringSpec, name string, opts ...RelationalDBOption) string {
+	args := []string{"name=" + name}
+	for _, opt := range opts {
+		args = append(args, string(opt))
+	}
+	return define[backend.RelationalDB, sqlitereldb.SqliteRelDB](spec, name, args...)
+}
+
+// A RelationalDBOption configures the database created by [RelationalDB]
+type RelationalDBOption string
+
+// [RelationalDBFile] stores the database in the file at path, so that its contents survive process restarts.
+// The file is created if it does not exist.
+func RelationalDBFile(path string) RelationalDBOption {
+	return RelationalDBOption("file=" + path)
+}
+
+// [RelationalDBSchema] executes the SQL statements in the file at path whenever the database starts.  The
+// statements should be idempotent, e.g. CREATE TABLE IF NOT EXISTS.
+func RelationalDBSchema(path string) RelationalDBOption {
+	return RelationalDBOption("schema=" + path)
+}
+
+// [RelationalDBMigrations] applies the versioned SQL migrations in the directory at path when the database starts,
+// after any schema statements.  Migration files are named <version>_<description>.sql, and the applied versions are
+// recorded in the database, so that each migration is only applied once; see [migrations].  The process that runs
+// the database refuses to start if the migrations fail.
+//
+// [migrations]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/core/migrations
+func RelationalDBMigrations(path string) RelationalDBOption {
+	return RelationalDBOption("migrations=" + path)
+}
+
+// [RelationalDBSeed] executes the SQL statements in the file at path when the database is created, after any
+// schema statements and migrations.  A file-backed database is only seeded if its file did not already exist.
+func RelationalDBSeed(path string) RelationalDBOption {
+	return RelationalDBOption("seed=" + path)
 }
 
 // [Queue] can be used by wiring specs to create an in-memory [backend.Queue] instance with the specified name.
 // In the compiled application, uses the [simplequeue.SimpleQueue] implementation from the Blueprint runtime package
-func Queue(spec wiring.WiringSpec, name string) string {
-	return define[backend.Queue, simplequeue.SimpleQueue](spec, name)
+//
+// By default the queue has capacity 10 and Push blocks when the queue is full.  Options can be provided to change
+// the capacity and the overflow policy, e.g. to model back-pressure by dropping items:
+//
+//	simple.Queue(spec, "my_queue", simple.QueueCapacity(100), simple.QueueOverflowPolicy(simplequeue.DropOldest))
+//
+// Queue depth and dropped items are reported through the process's metric collector.
+func Queue(spec wiring.WiringSpec, name string, opts ...QueueOption) string {
+	var args []string
+	for _, opt := range opts {
+		args = append(args, string(opt))
+	}
+	return define[backend.Queue, simplequeue.SimpleQueue](spec, name, args...)
+}
+
+// A QueueOption configures the queue created by [Queue]
+type QueueOption string
+
+// [QueueCapacity] sets the maximum number of items in the queue.  When the queue is full, pushes are handled
+// according to the queue's overflow policy.
+func QueueCapacity(capacity int) QueueOption {
+	return QueueOption(fmt.Sprintf("capacity=%d", capacity))
+}
+
+// [QueueUnbounded] removes the queue's capacity limit, so that pushes never block or drop items.
+func QueueUnbounded() QueueOption {
+	return QueueCapacity(0)
+}
+
+// [QueueOverflowPolicy] sets how pushes are handled when the queue is full.  The default policy is [simplequeue.Block].
+func QueueOverflowPolicy(policy simplequeue.OverflowPolicy) QueueOption {
+	return QueueOption("overflow=" + string(policy))
+}
+
+// [PubSub] can be used by wiring specs to create an in-memory [backend.PubSub] instance with the specified name.
+// In the compiled application, uses the [simplequeue.SimplePubSub] implementation from the Blueprint runtime package
+//
+// Options can be provided to configure the visibility timeout and dead-lettering of messages, e.g.
+//
+//	simple.PubSub(spec, "my_pubsub", simple.VisibilityTimeout(10*time.Second), simple.MaxDeliveries(5))
+func PubSub(spec wiring.WiringSpec, name string, opts ...PubSubOption) string {
+	var args []string
+	for _, opt := range opts {
+		args = append(args, string(opt))
+	}
+	return define[backend.PubSub, simplequeue.SimplePubSub](spec, name, args...)
+}
+
+// A PubSubOption configures the pub/sub backend created by [PubSub]
+type PubSubOption string
+
+// [VisibilityTimeout] sets how long a received message may go unacknowledged before it is redelivered.
+// The default is 30 seconds.
+func VisibilityTimeout(timeout time.Duration) PubSubOption {
+	return PubSubOption("visibility=" + timeout.String())
+}
+
+// [MaxDeliveries] sets how many times a message is delivered before it is moved to the dead-letter topic.
+// By default, messages are redelivered indefinitely.
+func MaxDeliveries(count int) PubSubOption {
+	return PubSubOption(fmt.Sprintf("maxdeliveries=%d", count))
 }
 
 // [Cache] can be used by wiring specs to create an in-memory [backend.Cache] instance with the specified name.
 // In the compiled application, uses the [simplecache.SimpleCache] implementation from the Blueprint runtime package
-func Cache(spec wiring.WiringSpec, name string) string {
-	return define[backend.Cache, simplecache.SimpleCache](spec, name)
+//
+// By default the cache is unbounded.  Options can be provided to bound the cache and choose an eviction policy, e.g.
+//
+//	simple.Cache(spec, "my_cache", simple.CacheCapacity(1000), simple.CacheEvictionPolicy(simplecache.LFU))
+//
+// Hit, miss and eviction counts are reported through the process's metric collector, labelled with the cache's name.
+func Cache(spec wiring.WiringSpec, name string, opts ...CacheOption) string {
+	args := []string{"name=" + name}
+	for _, opt := range opts {
+		args = append(args, string(opt))
+	}
+	return define[backend.Cache, simplecache.SimpleCache](spec, name, args...)
+}
+
+// A CacheOption configures the cache created by [Cache]
+type CacheOption string
+
+// [CacheCapacity] bounds the number of keys held by the cache.  When the cache is full, keys are
+// evicted according to the cache's eviction policy.
+func CacheCapacity(capacity int) CacheOption {
+	return CacheOption(fmt.Sprintf("capacity=%d", capacity))
+}
+
+// [CacheMaxBytes] bounds the approximate memory used by the cache contents.  When the cache is full,
+// keys are evicted according to the cache's eviction policy.
+func CacheMaxBytes(maxBytes int64) CacheOption {
+	return CacheOption(fmt.Sprintf("maxbytes=%d", maxBytes))
+}
+
+// [CacheEvictionPolicy] sets the eviction policy of a bounded cache.  The default policy is [simplecache.LRU].
+func CacheEvictionPolicy(policy simplecache.EvictionPolicy) CacheOption {
+	return CacheOption("policy=" + string(policy))
 }
 
-func define[BackendInterface any, BackendImpl any](spec wiring.WiringSpec, name string) string {
+// args are string values passed as the trailing arguments of the backend's constructor
+func define[BackendInterface any, BackendImpl any](spec wiring.WiringSpec, name string, args ...string) string {
 	// The nodes that we are defining
 	backendName := name + ".backend"
 
 	// Define the backend instance
 	spec.Define(backendName, &SimpleBackend{}, func(namespace wiring.Namespace) (ir.IRNode, error) {
-		return newSimpleBackend[BackendImpl](name)
+		return newSimpleBackend[BackendImpl](name, args...)
 	})
 
 	// Create a pointer to the backend instance
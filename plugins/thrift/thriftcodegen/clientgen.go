@@ -35,6 +35,9 @@ func GenerateClient(builder golang.ModuleBuilder, service *gocode.ServiceInterfa
 	client.Imports.AddPackages(
 		"context", "time", "errors",
 		"github.com/apache/thrift/lib/go/thrift",
+		"github.com/blueprint-uservices/blueprint/runtime/core/deadline",
+		"github.com/blueprint-uservices/blueprint/runtime/core/idempotency",
+		"github.com/blueprint-uservices/blueprint/runtime/core/rpcerror",
 		innerPkgPath,
 	)
 
@@ -109,6 +112,8 @@ func (client *{{$receiver}}) {{SignatureWithRetVars $f}} {
 
 	ctx, cancel := context.WithTimeout(ctx, client.Timeout)
 	defer cancel()
+	req.BlueprintTimeout = int64(deadline.Remaining(ctx))
+	req.BlueprintIdempotencyKey = idempotency.OutgoingKey(ctx, "{{$f.Name}}")
 
 	rsp, err := client.Client.{{$f.Name}}(ctx, req)
 	if err != nil {
@@ -121,6 +126,11 @@ func (client *{{$receiver}}) {{SignatureWithRetVars $f}} {
 		err = errors.New("Response object is nil")
 		return
 	}
+	if len(rsp.BlueprintError) > 0 {
+		// Reconstruct the error returned by the service
+		err = rpcerror.Decode(rsp.BlueprintError)
+		return
+	}
 
 	{{RetVarsEquals $f}} unmarshall_{{$f.Name}}_rsp(rsp)
 	return
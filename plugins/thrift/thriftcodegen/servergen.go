@@ -32,7 +32,14 @@ func GenerateServerHandler(builder golang.ModuleBuilder, service *gocode.Service
 
 	innerPkgPath := builder.Info().Name + "/" + outputPackage + "/" + innerPkg
 
-	server.Imports.AddPackages("context", "github.com/apache/thrift/lib/go/thrift", innerPkgPath)
+	server.Imports.AddPackages(
+		"context", "time",
+		"github.com/apache/thrift/lib/go/thrift",
+		"github.com/blueprint-uservices/blueprint/runtime/core/deadline",
+		"github.com/blueprint-uservices/blueprint/runtime/core/idempotency",
+		"github.com/blueprint-uservices/blueprint/runtime/core/rpcerror",
+		innerPkgPath,
+	)
 
 	slog.Info(fmt.Sprintf("Generating %v/%v_ThriftServer.go", server.Package.PackageName, service.Name))
 	outputFile := filepath.Join(server.Package.Path, service.Name+
@@ -97,10 +104,15 @@ func (handler *{{.Name}}) Run(ctx context.Context) error {
 {{$prefix := .ImportPrefix -}}
 {{ range $_, $f := .Service.Methods }}
 func (handler *{{$receiver}}) {{$f.Name -}}(ctx context.Context, req *{{$prefix}}.{{$service}}_{{$f.Name}}_Request) (*{{$prefix}}.{{$service}}_{{$f.Name}}_Response, error) {
+	// Thrift (0.14 and later) cancels ctx if the client disconnects
+	ctx, cancel := deadline.NewIncomingContext(ctx, time.Duration(req.BlueprintTimeout))
+	defer cancel()
+	ctx = idempotency.NewIncomingContext(ctx, "{{$f.Name}}", req.BlueprintIdempotencyKey)
 	{{ArgVarsEquals $f}} unmarshall_{{$f.Name}}_req(req)
 	{{RetVars $f "err"}} := handler.Service.{{$f.Name}}({{ArgVars $f "ctx"}})
 	if err != nil {
-		return nil, err
+		// Thrift errors only have a message, so the encoded error is returned in the response instead
+		return &{{$prefix}}.{{$service}}_{{$f.Name}}_Response{BlueprintError: rpcerror.Encode(err)}, nil
 	}
 	rsp := &{{$prefix}}.{{$service}}_{{$f.Name}}_Response{}
 	marshall_{{$f.Name}}_rsp(rsp, {{RetVars $f}})
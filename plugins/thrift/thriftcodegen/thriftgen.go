@@ -107,6 +107,8 @@ type ThriftStructDecl struct {
 	Name       string
 	ThriftType *gocode.UserType
 	FieldList  []*ThriftField
+	IsRequest  bool // Requests also carry the client's timeout, in nanoseconds, and the call's idempotency key, if any, in blueprint_timeout and blueprint_idempotency_key fields
+	IsResponse bool // Responses also carry the encoded error, if any, returned by the service in a blueprint_error field
 }
 
 type ThriftMethodDecl struct {
@@ -149,6 +151,13 @@ struct {{$struct.Name}} {
 	{{- range $_, $field := $struct.FieldList}}
 	{{$field.Position}}: {{$field.ThriftType}} {{$field.Name}},
 	{{- end}}
+	{{- if $struct.IsRequest}}
+	32767: i64 blueprint_timeout,
+	32766: string blueprint_idempotency_key,
+	{{- end}}
+	{{- if $struct.IsResponse}}
+	32767: binary blueprint_error,
+	{{- end}}
 }
 {{end}}
 
@@ -189,7 +198,9 @@ func (s *ThriftServiceDecl) newMethod(name string) *ThriftMethodDecl {
 	m.Service = s
 	m.Name = name
 	m.Request = s.Builder.newStruct(fmt.Sprintf("%s_%s_Request", s.Name, name))
+	m.Request.IsRequest = true
 	m.Response = s.Builder.newStruct(fmt.Sprintf("%s_%s_Response", s.Name, name))
+	m.Response.IsResponse = true
 	s.Methods[name] = m
 	return m
 }
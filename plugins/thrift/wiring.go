@@ -11,8 +11,13 @@
 // and a client-side library that calls the server.
 // This is implemented within the [thriftcodegen] pacakge.
 //
+// Errors returned by the service, including the codes and details of errors from the [rpcerror] package, are
+// reconstructed by the client.
+//
 // To use this plugin, the thrift compiler and version-matching go bindings are required to be installed on the machine that is compiling the Blueprint wiring spec.
 // Installation instructions can be found: https://thrift.apache.org/download
+//
+// [rpcerror]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/core/rpcerror
 package thrift
 
 import (
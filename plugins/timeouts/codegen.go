@@ -24,7 +24,7 @@ func generateClient(builder golang.ModuleBuilder, wrapped *gocode.ServiceInterfa
 		Imports: gogen.NewImports(pkg.Name),
 	}
 
-	client.Imports.AddPackages("context", "time", "errors")
+	client.Imports.AddPackages("context", "time", "errors", "github.com/blueprint-uservices/blueprint/runtime/core/control")
 	slog.Info(fmt.Sprintf("Generating %v/%v", client.Package.PackageName, wrapped.BaseName+"_TimeoutClient"))
 	outputFile := filepath.Join(client.Package.Path, wrapped.BaseName+"_TimeoutClient.go")
 
@@ -45,17 +45,19 @@ package {{.Package.ShortName}}
 
 type {{.Name}} struct {
 	Client {{.Imports.NameOf .Service.UserType}}
-	Timeout time.Duration
+	Timeout *control.Duration
 }
 
-func New_{{.Name}} (ctx context.Context, client {{.Imports.NameOf .Service.UserType}}, timeout string) (*{{.Name}}, error) {
+func New_{{.Name}} (ctx context.Context, client {{.Imports.NameOf .Service.UserType}}, name string, timeout string) (*{{.Name}}, error) {
 	handler := &{{.Name}}{}
-	tout, err := time.ParseDuration(timeout)
-	if err != nil {
+	handler.Timeout = &control.Duration{}
+	if err := handler.Timeout.Set(timeout); err != nil {
 		return nil, err
 	}
-	handler.Timeout = tout
 	handler.Client = client
+	// The timeout is shared by every instance of the modifier
+	params := control.Register(name, "timeouts", control.Params{"timeout": handler.Timeout}).(control.Params)
+	handler.Timeout = params["timeout"].(*control.Duration)
 	return handler, nil
 }
 
@@ -63,7 +65,7 @@ func New_{{.Name}} (ctx context.Context, client {{.Imports.NameOf .Service.UserT
 {{$receiver := .Name -}}
 {{ range $_, $f := .Service.Methods }}
 func (client *{{$receiver}}) {{$f.Name -}} ({{ArgVarsAndTypes $f "ctx context.Context"}}) ({{RetVarsAndTypes $f "err error"}}) {
-	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(client.Timeout))
+	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(client.Timeout.Get()))
 	defer cancel()
 	is_complete := make(chan bool)
 	go func() {
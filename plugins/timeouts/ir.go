@@ -96,10 +96,11 @@ func (node *TimeoutClient) AddInstantiation(builder golang.NamespaceBuilder) err
 			Arguments: []gocode.Variable{
 				{Name: "ctx", Type: &gocode.UserType{Package: "context", Name: "Context"}},
 				{Name: "client", Type: iface},
+				{Name: "name", Type: &gocode.BasicType{Name: "string"}},
 				{Name: "timeout_val", Type: &gocode.BasicType{Name: "string"}},
 			},
 		},
 	}
 
-	return builder.DeclareConstructor(node.InstanceName, constructor, []ir.IRNode{node.Wrapped, node.TimeoutValue})
+	return builder.DeclareConstructor(node.InstanceName, constructor, []ir.IRNode{node.Wrapped, &ir.IRValue{Value: node.InstanceName}, node.TimeoutValue})
 }
@@ -3,8 +3,15 @@
 // The plugin configures clients with a timeout mechanism using contexts.
 // The plugin will generate a wrapper client class that will wait for a fixed amount of time (the specified timeout value) before canceling the context. Once the context is cancelled, the execution returns to the caller.
 //
+// The HTTP, Thrift and gRPC plugins transmit the deadline of the context to the server, so that the server also
+// cancels its handling of the call once the timeout has elapsed.
+//
 // Example Usage to add a "1s" timeout to each request:
 //  timeouts.Add(spec, "my_service", "1s")
+//
+// The timeout can be changed while the application is running using the [controlplane] plugin.
+//
+// [controlplane]: https://github.com/Blueprint-uServices/blueprint/tree/main/plugins/controlplane
 package timeouts
 
 import (
@@ -119,9 +119,15 @@ func (spec *WorkflowSpec) makeServiceFromInterface(iface *goparser.ParsedInterfa
 
 /*
 A service interface is only valid if all methods receive ctx as
-first argument and return error as final retval
+first argument and return error as final retval.
+
+Interfaces that embed other interfaces are not valid, since the
+methods of embedded interfaces are not parsed.
 */
 func isInterfaceAValidService(iface *goparser.ParsedInterface) (bool, error) {
+	if len(iface.Embedded) > 0 {
+		return false, blueprint.Errorf("%v embeds %v interfaces, which is not supported for services", iface.Name, len(iface.Embedded))
+	}
 	for _, method := range iface.Methods {
 		if len(method.Arguments) == 0 {
 			return false, blueprint.Errorf("first argument of %v.%v must be context.Context", iface.Name, method.Name)
@@ -1,8 +1,14 @@
 package backend
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Represents a key-value cache.
+//
+// Incr, Decr, IncrBy and CompareAndSwap are atomic, so they can be safely used by
+// concurrent callers to implement counters and optimistic updates.
 type Cache interface {
 	// Store a key-value pair in the cache
 	Put(ctx context.Context, key string, value interface{}) error
@@ -34,4 +40,36 @@ type Cache interface {
 
 	// Treats the value mapped to key as an integer, and increments it
 	Incr(ctx context.Context, key string) (int64, error)
+
+	// Treats the value mapped to key as an integer, and decrements it
+	Decr(ctx context.Context, key string) (int64, error)
+
+	// Treats the value mapped to key as an integer, and adds delta to it.  delta can be negative.
+	IncrBy(ctx context.Context, key string, delta int64) (int64, error)
+
+	// Atomically replaces the value mapped to key with newValue, but only if the
+	// current value equals oldValue.  Values are compared by their JSON encodings.
+	//
+	// Like Put, a successful swap removes any expiry of the key.
+	//
+	// Reports whether the swap happened.  If the key does not exist, the swap does not happen.
+	CompareAndSwap(ctx context.Context, key string, oldValue interface{}, newValue interface{}) (bool, error)
+
+	// Store a key-value pair in the cache that will expire after ttl.
+	//
+	// Once expired, the key behaves as if it had been deleted.  A ttl of 0 stores
+	// the key without an expiry, equivalent to calling Put.
+	PutWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+
+	// Sets the expiry of an existing key to ttl from now.  A ttl of 0 removes any
+	// existing expiry, so that the key persists until it is deleted.
+	//
+	// Reports whether the key existed in the cache
+	Expire(ctx context.Context, key string, ttl time.Duration) (bool, error)
+
+	// Returns the remaining time-to-live of key.  If the key exists but has no expiry,
+	// the returned duration is 0.
+	//
+	// Reports whether the key existed in the cache
+	TTL(ctx context.Context, key string) (time.Duration, bool, error)
 }
@@ -3,8 +3,10 @@ package backend
 import (
 	"context"
 	"errors"
+	"sync"
 
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
 	"golang.org/x/exp/slog"
 )
 
@@ -55,3 +57,25 @@ func Meter(ctx context.Context, name string, opts ...metric.MeterOption) (metric
 	}
 	return mp.Meter(name, opts...), nil
 }
+
+// Creates the metric instruments of a runtime component on first use rather than in the component's
+// constructor, because the process's metric collector might not have been instantiated yet when the
+// component is built.  The zero value is ready to use.
+type LazyMeter struct {
+	once sync.Once
+}
+
+// Calls register, at most once, with the meter named name.  If no metric collector is configured, e.g. in
+// unit tests, or register returns an error, then register is called with a no-op meter instead, so that the
+// component's instruments are always usable.
+func (m *LazyMeter) Init(ctx context.Context, name string, register func(metric.Meter) error) {
+	m.once.Do(func() {
+		meter, err := Meter(ctx, name)
+		if err == nil {
+			err = register(meter)
+		}
+		if err != nil {
+			register(noop.NewMeterProvider().Meter(name))
+		}
+	})
+}
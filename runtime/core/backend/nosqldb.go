@@ -26,6 +26,42 @@ type NoSQLCursor interface {
 	// Returns the number of results copied.
 	// Returns an error if obj is not a compatible type.
 	All(ctx context.Context, obj interface{}) error //similar logic to Decode, but for multiple documents
+
+	// Copies the next result into the target pointer and advances the cursor.
+	// If there are no more results, returns false; otherwise returns true.
+	// Returns an error if obj is not a compatible type.
+	//
+	// Unlike All, Next retrieves results incrementally, so the full result set
+	// need not be held in memory.
+	Next(ctx context.Context, obj interface{}) (bool, error)
+
+	// Releases any resources held by the cursor.  Cursors that have been exhausted
+	// by Next or read using All do not need to be closed.
+	Close(ctx context.Context) error
+}
+
+// Options for [NoSQLCollection.Find].
+//
+// The zero value returns all matching documents, in an unspecified order.
+type FindOptions struct {
+	// Optional projection, with the same semantics as mongodb.
+	Projection bson.D
+
+	// Optional sort specification, with the same semantics as mongodb.
+	// Each element maps a field to 1 for ascending or -1 for descending order, e.g.
+	// bson.D{{"timestamp", -1}, {"id", 1}}.
+	// https://www.mongodb.com/docs/manual/reference/method/cursor.sort/
+	Sort bson.D
+
+	// The number of matching documents to skip before returning results.
+	Skip int64
+
+	// The maximum number of documents to return; 0 means no limit.
+	Limit int64
+
+	// The number of documents the cursor retrieves from the database at a time; 0 uses the
+	// database's default.  This only affects performance, not the results returned.
+	BatchSize int32
 }
 
 type NoSQLCollection interface {
@@ -63,6 +99,24 @@ type NoSQLCollection interface {
 	// Projections are optional and behave with mongodb semantics.
 	FindMany(ctx context.Context, filter bson.D, projection ...bson.D) (NoSQLCursor, error) // Result is not a slice -> it is an object we can use to retrieve documents using res.All().
 
+	// Finds all documents that match the filter, sorted, skipped and limited according to opts.
+	//
+	// We use the same filter semantics as mongodb
+	// https://www.mongodb.com/docs/manual/tutorial/query-documents/
+	//
+	// Results can be paginated using opts.Skip and opts.Limit, and iterated using
+	// [NoSQLCursor.Next] rather than read into memory all at once.
+	Find(ctx context.Context, filter bson.D, opts FindOptions) (NoSQLCursor, error)
+
+	// Runs an aggregation pipeline over the collection and returns its results.
+	//
+	// We use the same pipeline semantics as mongodb; each element of pipeline is a single stage,
+	// e.g. bson.D{{"$match", filter}} or bson.D{{"$group", bson.D{{"_id", "$vendor"}}}}.
+	// https://www.mongodb.com/docs/manual/core/aggregation-pipeline/
+	//
+	// Implementations may support only a subset of mongodb's stages and expressions.
+	Aggregate(ctx context.Context, pipeline []bson.D) (NoSQLCursor, error)
+
 	// Applies the provided update to the first document that matches filter
 	//
 	// We use the same filter semantics as mongodb
@@ -116,4 +170,17 @@ type NoSQLCollection interface {
 	//
 	// Returns the number of replaced documents.
 	ReplaceMany(ctx context.Context, filter bson.D, replacements ...interface{}) (int, error)
+
+	// Creates an index on the collection, if an identical index does not already exist.
+	//
+	// keys specifies the indexed fields and their sort order, using the same index specification
+	// as mongodb, e.g. bson.D{{"user", 1}, {"created", -1}} creates a compound index.
+	// https://www.mongodb.com/docs/manual/indexes/
+	//
+	// If unique is true, then the index rejects documents whose indexed fields duplicate those of
+	// an existing document.  As with mongodb, such writes fail with a duplicate key error that can
+	// be checked with mongo.IsDuplicateKeyError.
+	//
+	// Returns the name of the index.
+	CreateIndex(ctx context.Context, keys bson.D, unique bool) (string, error)
 }
@@ -0,0 +1,58 @@
+package backend
+
+import (
+	"context"
+)
+
+// A PubSub backend is used for publishing messages to topics that are consumed by subscriber groups.
+//
+// Unlike a [Queue], where every consumer competes for every item, each message published to a topic
+// is delivered to every subscriber group of the topic.  Within a group, consumers compete for messages,
+// so each message is processed by only one consumer of the group.
+//
+// Messages must be explicitly acknowledged with Ack once processed.  A message that is not acknowledged
+// within the backend's visibility timeout, or that is explicitly rejected with Nack, is redelivered to the
+// group.  If the backend is configured with a maximum number of deliveries, then a message that exceeds
+// it is moved to the topic's dead-letter topic (see [DeadLetterTopic]) instead of being redelivered.
+type PubSub interface {
+
+	// Publishes an item to topic.  Every subscriber group of topic receives a copy of the item.
+	//
+	// Items published before a group subscribes are not delivered to that group.
+	Publish(ctx context.Context, topic string, item interface{}) error
+
+	// Creates the subscriber group for topic, if it doesn't already exist.  Subscribing is idempotent.
+	//
+	// Once a group has subscribed, messages published to the topic are retained for the group until
+	// they are acknowledged, even if no consumer is currently receiving.
+	Subscribe(ctx context.Context, topic string, group string) error
+
+	// Receives the next message for the subscriber group, implicitly subscribing the group if needed.
+	//
+	// This call will block until a message is received, or until the context is cancelled.
+	//
+	// dst must be a pointer type that can receive the message.
+	//
+	// Returns a delivery id that must be passed to Ack or Nack, and reports whether a message was
+	// received.  A context cancellation/timeout is not considered an error.
+	Receive(ctx context.Context, topic string, group string, dst interface{}) (string, bool, error)
+
+	// Acknowledges that the message with the given delivery id has been processed, so that it will
+	// not be redelivered.
+	//
+	// Returns an error if the delivery id is unknown or its visibility timeout already expired.
+	Ack(ctx context.Context, topic string, group string, id string) error
+
+	// Rejects the message with the given delivery id, so that it is immediately redelivered to the
+	// group, or moved to the dead-letter topic if it has exceeded the maximum number of deliveries.
+	//
+	// Returns an error if the delivery id is unknown or its visibility timeout already expired.
+	Nack(ctx context.Context, topic string, group string, id string) error
+}
+
+// Returns the name of the topic that receives messages from topic that exceeded their maximum
+// number of deliveries.  Dead-lettered messages can be consumed like those of any other topic,
+// by subscribing a group to the dead-letter topic.
+func DeadLetterTopic(topic string) string {
+	return topic + ".dlq"
+}
@@ -39,4 +39,76 @@ type RelationalDB interface {
 	//
 	// Uses [github.com/jmoiron/sqlx] to marshal query results into dst.
 	Get(ctx context.Context, dst interface{}, query string, args ...any) error
+
+	// BeginTx starts a transaction.  Queries made using the returned [RelationalTx] are part of the
+	// transaction, which must be ended by calling Commit or Rollback.  Queries made directly on the
+	// RelationalDB are not part of the transaction.
+	//
+	// opts are optional and have the same semantics as in [database/sql], e.g. to select an isolation
+	// level.  If opts is nil then the database's default isolation level is used.  Implementations may
+	// run a transaction at a stronger isolation level than requested, and may not enforce ReadOnly.
+	//
+	// The provided context is used until the transaction is committed or rolled back; if the context
+	// is canceled, the transaction is rolled back.
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (RelationalTx, error)
+}
+
+// A transaction on a [RelationalDB], started by [RelationalDB.BeginTx].
+//
+// The query methods behave the same as those of [RelationalDB], except that they are executed within the
+// transaction.  After Commit or Rollback, all methods return [sql.ErrTxDone].
+type RelationalTx interface {
+	// Exec executes a query without returning any rows.  See [RelationalDB.Exec].
+	Exec(ctx context.Context, query string, args ...any) (sql.Result, error)
+
+	// Query executes a query that returns rows, typically a SELECT.  See [RelationalDB.Query].
+	Query(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+
+	// Prepare creates a prepared statement for use within the transaction.  See [RelationalDB.Prepare].
+	Prepare(ctx context.Context, query string) (*sql.Stmt, error)
+
+	// Select using this transaction.  See [RelationalDB.Select].
+	Select(ctx context.Context, dst interface{}, query string, args ...any) error
+
+	// Get using this transaction.  See [RelationalDB.Get].
+	Get(ctx context.Context, dst interface{}, query string, args ...any) error
+
+	// Commit commits the transaction.
+	Commit() error
+
+	// Rollback aborts the transaction.
+	Rollback() error
+}
+
+// WithTx runs f within a transaction on db, started with the provided opts.
+//
+// If f returns nil then the transaction is committed and the result of the commit is returned.  If f returns
+// an error or panics then the transaction is rolled back; the error is returned, or the panic is propagated.
+//
+// f should not commit or roll back the transaction itself.  For example:
+//
+//	err := backend.WithTx(ctx, db, nil, func(tx backend.RelationalTx) error {
+//		if _, err := tx.Exec(ctx, "INSERT INTO orders (id, price) VALUES (?, ?)", id, price); err != nil {
+//			return err
+//		}
+//		_, err := tx.Exec(ctx, "UPDATE accounts SET balance = balance - ? WHERE id = ?", price, account)
+//		return err
+//	})
+func WithTx(ctx context.Context, db RelationalDB, opts *sql.TxOptions, f func(tx RelationalTx) error) (err error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			// The original error or panic takes precedence over any error rolling back
+			tx.Rollback()
+		}
+	}()
+	if err = f(tx); err != nil {
+		return err
+	}
+	committed = true
+	return tx.Commit()
 }
@@ -0,0 +1,116 @@
+// Package control provides a registry of the modifiers in a process whose parameters can be
+// changed while the process is running, e.g. retry counts, timeouts, circuit breaker thresholds
+// and injected latencies.
+//
+// This package is primarily used by the code generated by modifier plugins, which register
+// a [Tunable] for each modifier when it is instantiated, and by the control plane
+// plugin, which serves [Handler] at a per-process admin address so that experiments can
+// sweep parameters without recompiling or redeploying the application.
+package control
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"golang.org/x/exp/slog"
+)
+
+// A modifier whose parameters can be inspected and changed at runtime.  Implementations
+// must be safe for concurrent use.
+type Tunable interface {
+	// Returns the current value of each of the modifier's parameters
+	Parameters() map[string]string
+
+	// Changes the values of the named parameters, leaving unnamed parameters unchanged.
+	// Returns an error, leaving all parameters unchanged, if any parameter is unknown or
+	// any value is invalid.
+	SetParameters(params map[string]string) error
+}
+
+// Describes a registered modifier
+type Modifier struct {
+	Name       string            `json:"name"`
+	Kind       string            `json:"kind"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+// The error returned when changing the parameters of a modifier that is not registered
+var ErrUnknownModifier = errors.New("unknown modifier")
+
+type registration struct {
+	kind    string
+	tunable Tunable
+}
+
+var modifiers = struct {
+	sync.RWMutex
+	byName map[string]*registration
+}{byName: make(map[string]*registration)}
+
+// Registers the modifier named name, of the given kind, e.g. "retries", and returns the instance
+// of the modifier that the caller should use, which has the same type as t.
+//
+// A modifier can be instantiated more than once in a process, e.g. by a client pool that rebuilds
+// its clients.  Like the injectors of the faults package, the instances share the state of the
+// first instance registered with name, which is returned in place of t, so that parameters changed
+// at runtime apply to every instance, including instances created after the change.  If name is
+// already registered by a modifier of a different type, t is returned but is not registered.
+func Register(name, kind string, t Tunable) Tunable {
+	modifiers.Lock()
+	defer modifiers.Unlock()
+	if r, exists := modifiers.byName[name]; exists {
+		if reflect.TypeOf(r.tunable) == reflect.TypeOf(t) {
+			return r.tunable
+		}
+		slog.Error(fmt.Sprintf("Unable to register %v modifier %v with the control plane; a %v modifier has the same name", kind, name, r.kind))
+		return t
+	}
+	slog.Info(fmt.Sprintf("Registered %v modifier %v with the control plane", kind, name))
+	modifiers.byName[name] = &registration{kind: kind, tunable: t}
+	return t
+}
+
+// Returns the modifiers registered in this process, sorted by name
+func Modifiers() []Modifier {
+	modifiers.RLock()
+	defer modifiers.RUnlock()
+	var list []Modifier
+	for name, r := range modifiers.byName {
+		list = append(list, Modifier{Name: name, Kind: r.kind, Parameters: r.tunable.Parameters()})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Returns the modifier named name, or false if no such modifier is registered
+func Get(name string) (Modifier, bool) {
+	modifiers.RLock()
+	defer modifiers.RUnlock()
+	r, exists := modifiers.byName[name]
+	if !exists {
+		return Modifier{}, false
+	}
+	return Modifier{Name: name, Kind: r.kind, Parameters: r.tunable.Parameters()}, true
+}
+
+// Changes the parameters of the modifier named name, which apply to every instance of the
+// modifier.  Returns an error, leaving the parameters unchanged, if the modifier rejects the change.
+func Set(name string, params map[string]string) error {
+	modifiers.Lock()
+	defer modifiers.Unlock()
+	r, exists := modifiers.byName[name]
+	if !exists {
+		return fmt.Errorf("%w %v", ErrUnknownModifier, name)
+	}
+	return r.tunable.SetParameters(params)
+}
+
+// Removes all registered modifiers; used by tests
+func Reset() {
+	modifiers.Lock()
+	defer modifiers.Unlock()
+	modifiers.byName = make(map[string]*registration)
+}
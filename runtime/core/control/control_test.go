@@ -0,0 +1,143 @@
+package control_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/control"
+	"github.com/stretchr/testify/require"
+)
+
+// Registers a retrier-like modifier with maxtries and delay parameters
+func newRetrier(t *testing.T, name string, maxtries, delay string) (*control.Int, *control.Duration) {
+	tries, wait := &control.Int{}, &control.Duration{}
+	require.NoError(t, tries.Set(maxtries))
+	require.NoError(t, wait.Set(delay))
+	params := control.Register(name, "retries", control.Params{"maxtries": tries, "delay": wait}).(control.Params)
+	return params["maxtries"].(*control.Int), params["delay"].(*control.Duration)
+}
+
+func TestParams(t *testing.T) {
+	rate := &control.Fraction{}
+	params := control.Params{"rate": rate}
+	require.NoError(t, params.SetParameters(map[string]string{"rate": "0.25"}))
+	require.Equal(t, 0.25, rate.Get())
+	require.Equal(t, map[string]string{"rate": "0.25"}, params.Parameters())
+
+	require.Error(t, params.SetParameters(map[string]string{"rate": "1.5"}))
+	require.Error(t, params.SetParameters(map[string]string{"rate": "high"}))
+	require.Error(t, params.SetParameters(map[string]string{"burst": "3"}))
+	require.Equal(t, 0.25, rate.Get())
+
+	// A change is all or nothing
+	count, timeout := &control.Int{}, &control.Duration{}
+	params = control.Params{"count": count, "timeout": timeout}
+	require.Error(t, params.SetParameters(map[string]string{"count": "3", "timeout": "-1s"}))
+	require.Equal(t, int64(0), count.Get())
+	require.NoError(t, params.SetParameters(map[string]string{"count": "3", "timeout": "1s"}))
+	require.Equal(t, int64(3), count.Get())
+	require.Equal(t, time.Second, timeout.Get())
+}
+
+func TestSetAppliesToEveryInstance(t *testing.T) {
+	control.Reset()
+	defer control.Reset()
+
+	tries1, delay1 := newRetrier(t, "leaf.client.retrier", "3", "10ms")
+	tries2, delay2 := newRetrier(t, "leaf.client.retrier", "3", "10ms")
+	newRetrier(t, "a.client.retrier", "1", "0s")
+
+	modifiers := control.Modifiers()
+	require.Len(t, modifiers, 2)
+	require.Equal(t, "a.client.retrier", modifiers[0].Name)
+	require.Equal(t, control.Modifier{
+		Name:       "leaf.client.retrier",
+		Kind:       "retries",
+		Parameters: map[string]string{"maxtries": "3", "delay": "10ms"},
+	}, modifiers[1])
+
+	require.NoError(t, control.Set("leaf.client.retrier", map[string]string{"maxtries": "5"}))
+	require.Equal(t, int64(5), tries1.Get())
+	require.Equal(t, int64(5), tries2.Get())
+	require.Equal(t, 10*time.Millisecond, delay1.Get())
+
+	require.Error(t, control.Set("leaf.client.retrier", map[string]string{"maxtries": "7", "delay": "soon"}))
+	require.Equal(t, int64(5), tries1.Get())
+	require.Equal(t, int64(5), tries2.Get())
+	require.Equal(t, 10*time.Millisecond, delay2.Get())
+
+	// Instances created after a change, e.g. when a client pool rebuilds a client, use the changed parameters
+	tries3, _ := newRetrier(t, "leaf.client.retrier", "3", "10ms")
+	require.Equal(t, int64(5), tries3.Get())
+	require.Len(t, control.Modifiers(), 2)
+
+	require.ErrorIs(t, control.Set("missing", map[string]string{}), control.ErrUnknownModifier)
+}
+
+func TestRegisterDifferentType(t *testing.T) {
+	control.Reset()
+	defer control.Reset()
+
+	tries, _ := newRetrier(t, "leaf.client.retrier", "3", "10ms")
+	other := &tunable{}
+	require.Same(t, other, control.Register("leaf.client.retrier", "latency", other))
+	require.NoError(t, control.Set("leaf.client.retrier", map[string]string{"maxtries": "4"}))
+	require.Equal(t, int64(4), tries.Get())
+	require.Nil(t, other.set)
+}
+
+type tunable struct{ set map[string]string }
+
+func (t *tunable) Parameters() map[string]string                { return t.set }
+func (t *tunable) SetParameters(params map[string]string) error { t.set = params; return nil }
+
+func TestHandler(t *testing.T) {
+	control.Reset()
+	defer control.Reset()
+
+	tries, delay := newRetrier(t, "leaf.client.retrier", "3", "10ms")
+	server := httptest.NewServer(control.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/modifiers")
+	require.NoError(t, err)
+	var modifiers []control.Modifier
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&modifiers))
+	resp.Body.Close()
+	require.Len(t, modifiers, 1)
+	require.Equal(t, "3", modifiers[0].Parameters["maxtries"])
+
+	put := func(name, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPut, server.URL+"/modifiers/"+name, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	require.Equal(t, http.StatusOK, put("leaf.client.retrier", `{"maxtries": 8, "delay": "50ms"}`).StatusCode)
+	require.Equal(t, int64(8), tries.Get())
+	require.Equal(t, 50*time.Millisecond, delay.Get())
+
+	require.Equal(t, http.StatusBadRequest, put("leaf.client.retrier", `{"maxtries": -1}`).StatusCode)
+	require.Equal(t, http.StatusBadRequest, put("leaf.client.retrier", `{"maxtries": [1]}`).StatusCode)
+	require.Equal(t, http.StatusNotFound, put("missing", `{}`).StatusCode)
+	require.Equal(t, int64(8), tries.Get())
+
+	resp, err = http.Get(server.URL + "/modifiers/leaf.client.retrier")
+	require.NoError(t, err)
+	var modifier control.Modifier
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&modifier))
+	resp.Body.Close()
+	require.Equal(t, map[string]string{"maxtries": "8", "delay": "50ms"}, modifier.Parameters)
+
+	resp, err = http.Get(server.URL + "/modifiers/missing")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,89 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Returns an HTTP handler for inspecting and changing the modifiers registered in this process.
+// Modifiers are JSON-encoded [Modifier] values.
+//   - GET /modifiers returns every registered modifier, sorted by name
+//   - GET /modifiers/<name> returns the named modifier
+//   - PUT /modifiers/<name> changes the named modifier's parameters; the body is a JSON object of
+//     parameter names to values, e.g. {"maxtries": 5, "delay": "10ms"}, and parameters that are omitted are unchanged.
+//     If any parameter is unknown or invalid, no parameters are changed.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/modifiers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		list := Modifiers()
+		if list == nil {
+			list = []Modifier{}
+		}
+		writeJSON(w, list)
+	})
+	mux.HandleFunc("/modifiers/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/modifiers/")
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			params, err := decodeParams(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := Set(name, params); errors.Is(err, ErrUnknownModifier) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		modifier, exists := Get(name)
+		if !exists {
+			http.Error(w, fmt.Sprintf("%v %v", ErrUnknownModifier, name), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, modifier)
+	})
+	return mux
+}
+
+// Decodes a JSON object of parameter values, which may be strings, numbers or booleans
+func decodeParams(r *http.Request) (map[string]string, error) {
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	var values map[string]any
+	if err := decoder.Decode(&values); err != nil {
+		return nil, err
+	}
+	params := make(map[string]string)
+	for key, value := range values {
+		switch v := value.(type) {
+		case string:
+			params[key] = v
+		case json.Number, bool:
+			params[key] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("invalid value for parameter %v: expected a string, number or boolean", key)
+		}
+	}
+	return params, nil
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,127 @@
+package control
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// A parameter value that can be read and changed concurrently.  The String and Set methods
+// are compatible with flag.Value.
+type Value interface {
+	String() string
+	Set(value string) error
+}
+
+// A [Tunable] made up of named [Value] parameters; the common case for generated modifiers, e.g.
+//
+//	params := control.Register(name, "retries", control.Params{"maxtries": handler.MaxTries}).(control.Params)
+//	handler.MaxTries = params["maxtries"].(*control.Int)
+type Params map[string]Value
+
+// Implements [Tunable]
+func (params Params) Parameters() map[string]string {
+	values := make(map[string]string)
+	for key, value := range params {
+		values[key] = value.String()
+	}
+	return values
+}
+
+// Implements [Tunable].  Values are set in sorted order of parameter name; if any value is
+// invalid, the values that were already set are reverted.
+func (params Params) SetParameters(values map[string]string) error {
+	var keys []string
+	for key := range values {
+		if _, exists := params[key]; !exists {
+			var known []string
+			for k := range params {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("unknown parameter %v; expected one of %v", key, strings.Join(known, ", "))
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	previous := params.Parameters()
+	for i, key := range keys {
+		if err := params[key].Set(values[key]); err != nil {
+			for _, set := range keys[:i] {
+				params[set].Set(previous[set])
+			}
+			return fmt.Errorf("invalid value for parameter %v: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// An integer parameter that must not be negative
+type Int struct{ v atomic.Int64 }
+
+// Returns the current value
+func (i *Int) Get() int64 { return i.v.Load() }
+
+// Implements [Value]
+func (i *Int) String() string { return strconv.FormatInt(i.v.Load(), 10) }
+
+// Implements [Value]
+func (i *Int) Set(value string) error {
+	parsed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return err
+	}
+	if parsed < 0 {
+		return fmt.Errorf("%v is negative", parsed)
+	}
+	i.v.Store(parsed)
+	return nil
+}
+
+// A floating point parameter that must be between 0 and 1, e.g. a rate or a fraction
+type Fraction struct{ bits atomic.Uint64 }
+
+// Returns the current value
+func (f *Fraction) Get() float64 { return math.Float64frombits(f.bits.Load()) }
+
+// Implements [Value]
+func (f *Fraction) String() string { return strconv.FormatFloat(f.Get(), 'g', -1, 64) }
+
+// Implements [Value]
+func (f *Fraction) Set(value string) error {
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return err
+	}
+	if parsed < 0 || parsed > 1 {
+		return fmt.Errorf("%v is not between 0 and 1", parsed)
+	}
+	f.bits.Store(math.Float64bits(parsed))
+	return nil
+}
+
+// A duration parameter that must not be negative, e.g. 100ms
+type Duration struct{ v atomic.Int64 }
+
+// Returns the current value
+func (d *Duration) Get() time.Duration { return time.Duration(d.v.Load()) }
+
+// Implements [Value]
+func (d *Duration) String() string { return d.Get().String() }
+
+// Implements [Value]
+func (d *Duration) Set(value string) error {
+	parsed, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return err
+	}
+	if parsed < 0 {
+		return fmt.Errorf("%v is negative", parsed)
+	}
+	d.v.Store(int64(parsed))
+	return nil
+}
@@ -0,0 +1,67 @@
+// Package deadline propagates the deadlines of calls from clients to servers.
+//
+// Transport plugins such as HTTP and Thrift transmit the time remaining until the deadline of
+// a call's context, as returned by [Remaining], along with the call.  Servers derive the
+// context of the call's handler from the received timeout with [NewIncomingContext], so that
+// the workflow code that handles the call, and the calls that it makes in turn, are cancelled
+// once the caller has given up.
+//
+// Timeouts are transmitted rather than absolute deadlines, so that they are unaffected by clock
+// skew between clients and servers.
+package deadline
+
+import (
+	"context"
+	"time"
+)
+
+// The header in which transports propagate the time remaining until the deadline of a call,
+// formatted as a Go duration, e.g. 1.5s
+const Header = "Blueprint-Timeout"
+
+// Returns the time remaining until the deadline of ctx, or 0 if ctx has no deadline.  If the
+// deadline has already passed, the smallest positive duration is returned, so that the call
+// still times out.
+func Remaining(ctx context.Context) time.Duration {
+	deadline, hasDeadline := ctx.Deadline()
+	if !hasDeadline {
+		return 0
+	}
+	if remaining := time.Until(deadline); remaining > 0 {
+		return remaining
+	}
+	return time.Nanosecond
+}
+
+// Returns the value of the [Header] for a call made with ctx, or the empty string if ctx has
+// no deadline.
+func FormatHeader(ctx context.Context) string {
+	if remaining := Remaining(ctx); remaining > 0 {
+		return remaining.String()
+	}
+	return ""
+}
+
+// Parses the value of a received [Header].  Returns 0, i.e. no deadline, if the value is
+// empty or invalid.
+func ParseHeader(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0
+	}
+	return timeout
+}
+
+// Returns a context for the handler of a received call, that is cancelled when ctx is cancelled,
+// when timeout elapses if timeout is positive, or when the returned cancel func is called.
+// Server transports derive ctx from the incoming request, so that the handler is also cancelled
+// if the caller disconnects.
+func NewIncomingContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,58 @@
+package deadline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/deadline"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemaining(t *testing.T) {
+	require.Equal(t, time.Duration(0), deadline.Remaining(context.Background()))
+	require.Equal(t, "", deadline.FormatHeader(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	remaining := deadline.Remaining(ctx)
+	require.Greater(t, remaining, 59*time.Second)
+	require.LessOrEqual(t, remaining, time.Minute)
+
+	// Expired deadlines are still transmitted
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	require.Equal(t, time.Nanosecond, deadline.Remaining(expired))
+}
+
+func TestHeader(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	timeout := deadline.ParseHeader(deadline.FormatHeader(ctx))
+	require.Greater(t, timeout, time.Second)
+	require.LessOrEqual(t, timeout, 1500*time.Millisecond)
+
+	require.Equal(t, time.Duration(0), deadline.ParseHeader(""))
+	require.Equal(t, time.Duration(0), deadline.ParseHeader("soon"))
+	require.Equal(t, time.Duration(0), deadline.ParseHeader("-1s"))
+}
+
+func TestIncomingContext(t *testing.T) {
+	// The handler times out when the received timeout elapses
+	ctx, cancel := deadline.NewIncomingContext(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, hasDeadline := ctx.Deadline()
+	require.True(t, hasDeadline)
+	<-ctx.Done()
+	require.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+
+	// Without a timeout, the handler is cancelled with the request
+	request, cancelRequest := context.WithCancel(context.Background())
+	ctx, cancel = deadline.NewIncomingContext(request, 0)
+	defer cancel()
+	_, hasDeadline = ctx.Deadline()
+	require.False(t, hasDeadline)
+	cancelRequest()
+	<-ctx.Done()
+	require.ErrorIs(t, ctx.Err(), context.Canceled)
+}
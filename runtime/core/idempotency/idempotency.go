@@ -0,0 +1,181 @@
+// Package idempotency provides idempotency keys that identify the tries of a logical call, so
+// that services can deduplicate calls that are retried.
+//
+// Keys are created by the retries plugin, which gives every logical call a fresh key and reuses
+// it for each retry of that call.  Transport plugins such as gRPC, HTTP and Thrift propagate the
+// outgoing key of a call with the request, and servers install the received key in the
+// context of the call's handler, where it can be retrieved with [Key].
+//
+// A key is scoped to the method of the call that it was created for.  Handlers make their own
+// calls with the context of the call that they received, which is the caller's context if the
+// callee is in the same process; calls of other methods neither propagate the key nor see it as
+// their current key.
+//
+// Services that have side effects can deduplicate the tries of a call with a [Deduplicator], e.g.
+//
+//	result, err := s.dedup.Do(ctx, "PlaceOrder", func() (any, error) {
+//		return s.placeOrder(ctx, order)
+//	})
+//	if err != nil {
+//		return "", err
+//	}
+//	return result.(string), nil
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// The header or metadata key in which transports propagate idempotency keys
+const Header = "Blueprint-Idempotency-Key"
+
+type keyType struct{}
+type outgoingKeyType struct{}
+
+var (
+	currentKey  keyType
+	outgoingKey outgoingKeyType
+)
+
+// An idempotency key and the method that it is scoped to
+type scopedKey struct {
+	method string
+	key    string
+}
+
+// Returns a new random idempotency key
+func NewKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Returns a context in which key is the idempotency key of calls of method made with ctx; used by
+// the retries plugin.  The key is propagated by the transport of such calls and, if the callee is
+// in the same process, is the current key of the call's handler.
+func WithKey(ctx context.Context, method string, key string) context.Context {
+	ctx = context.WithValue(ctx, currentKey, scopedKey{method, key})
+	return context.WithValue(ctx, outgoingKey, scopedKey{method, key})
+}
+
+// Returns a context in which key is the current idempotency key of method; used by server
+// transports to install the key received with a call of method.  The key is not propagated to the
+// calls that the handler makes, since those calls are distinct from the call that was received.
+func NewIncomingContext(ctx context.Context, method string, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, currentKey, scopedKey{method, key})
+	return context.WithValue(ctx, outgoingKey, scopedKey{})
+}
+
+// Returns the current idempotency key of method in ctx, or the empty string if there is none.
+func Key(ctx context.Context, method string) string {
+	scoped, _ := ctx.Value(currentKey).(scopedKey)
+	if scoped.method != method {
+		return ""
+	}
+	return scoped.key
+}
+
+// Returns the idempotency key to be propagated with an outgoing call of method made with ctx, or
+// the empty string if there is none.
+func OutgoingKey(ctx context.Context, method string) string {
+	scoped, _ := ctx.Value(outgoingKey).(scopedKey)
+	if scoped.method != method {
+		return ""
+	}
+	return scoped.key
+}
+
+// Deduplicates the tries of calls that have the same idempotency key, by remembering the results
+// of successful calls.  Safe for concurrent use.
+type Deduplicator struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*entry
+	swept   time.Time // When expired results were last removed
+}
+
+type entry struct {
+	done    chan struct{}
+	result  any
+	err     error
+	expires time.Time
+}
+
+// Instantiates a [Deduplicator] that remembers the result of a call for ttl after it completes.
+func NewDeduplicator(ttl time.Duration) *Deduplicator {
+	return &Deduplicator{ttl: ttl, entries: make(map[string]*entry)}
+}
+
+// Calls call, unless a call of method with the same idempotency key as ctx has already succeeded,
+// in which case the result of that call is returned instead.  If such a call is in progress, waits
+// for it to complete.  Calls that fail are not remembered, so that they can be retried.  If ctx has
+// no idempotency key, call is always called.
+func (d *Deduplicator) Do(ctx context.Context, method string, call func() (any, error)) (any, error) {
+	key := Key(ctx, method)
+	if key == "" {
+		return call()
+	}
+	id := method + "/" + key
+
+	var mine *entry
+	for mine == nil {
+		d.mu.Lock()
+		d.expire()
+		e, found := d.entries[id]
+		if found && !e.expires.IsZero() && time.Now().After(e.expires) {
+			delete(d.entries, id)
+			found = false
+		}
+		if !found {
+			mine = &entry{done: make(chan struct{})}
+			d.entries[id] = mine
+			d.mu.Unlock()
+			continue
+		}
+		d.mu.Unlock()
+
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if e.err == nil {
+			return e.result, nil
+		}
+		// The call failed; try again, unless another try has already started
+	}
+
+	result, err := call()
+
+	d.mu.Lock()
+	mine.result, mine.err, mine.expires = result, err, time.Now().Add(d.ttl)
+	if err != nil {
+		delete(d.entries, id)
+	}
+	d.mu.Unlock()
+	close(mine.done)
+	return result, err
+}
+
+// Removes the results that have expired, at most once per ttl; must be called with d.mu held
+func (d *Deduplicator) expire() {
+	now := time.Now()
+	if now.Sub(d.swept) < d.ttl {
+		return
+	}
+	d.swept = now
+	for id, e := range d.entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(d.entries, id)
+		}
+	}
+}
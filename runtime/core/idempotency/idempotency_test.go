@@ -0,0 +1,123 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/idempotency"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeys(t *testing.T) {
+	ctx := context.Background()
+	require.Equal(t, "", idempotency.Key(ctx, "PlaceOrder"))
+	require.Equal(t, "", idempotency.OutgoingKey(ctx, "PlaceOrder"))
+
+	key := idempotency.NewKey()
+	require.Len(t, key, 32)
+	require.NotEqual(t, key, idempotency.NewKey())
+
+	ctx = idempotency.WithKey(ctx, "PlaceOrder", key)
+	require.Equal(t, key, idempotency.Key(ctx, "PlaceOrder"))
+	require.Equal(t, key, idempotency.OutgoingKey(ctx, "PlaceOrder"))
+
+	// Keys are scoped to the method of the call, e.g. when an in-process handler makes other calls
+	require.Equal(t, "", idempotency.Key(ctx, "ChargeCard"))
+	require.Equal(t, "", idempotency.OutgoingKey(ctx, "ChargeCard"))
+
+	// Received keys are not propagated to the calls made by the handler
+	ctx = idempotency.NewIncomingContext(ctx, "PlaceOrder", "received")
+	require.Equal(t, "received", idempotency.Key(ctx, "PlaceOrder"))
+	require.Equal(t, "", idempotency.OutgoingKey(ctx, "PlaceOrder"))
+}
+
+func TestDedupe(t *testing.T) {
+	d := idempotency.NewDeduplicator(time.Minute)
+	calls := 0
+	call := func() (any, error) {
+		calls++
+		return calls, nil
+	}
+
+	// Calls without a key are not deduplicated
+	ctx := context.Background()
+	for i := 1; i <= 2; i++ {
+		result, err := d.Do(ctx, "PlaceOrder", call)
+		require.NoError(t, err)
+		require.Equal(t, i, result)
+	}
+
+	ctx = idempotency.NewIncomingContext(ctx, "PlaceOrder", idempotency.NewKey())
+	for i := 0; i < 2; i++ {
+		result, err := d.Do(ctx, "PlaceOrder", call)
+		require.NoError(t, err)
+		require.Equal(t, 3, result)
+	}
+
+	// Keys are scoped to methods
+	result, err := d.Do(ctx, "CancelOrder", call)
+	require.NoError(t, err)
+	require.Equal(t, 4, result)
+}
+
+func TestDedupeFailures(t *testing.T) {
+	d := idempotency.NewDeduplicator(time.Minute)
+	ctx := idempotency.NewIncomingContext(context.Background(), "PlaceOrder", idempotency.NewKey())
+	errBoom := errors.New("boom")
+
+	_, err := d.Do(ctx, "PlaceOrder", func() (any, error) { return "", errBoom })
+	require.ErrorIs(t, err, errBoom)
+
+	// Failed calls are not remembered
+	result, err := d.Do(ctx, "PlaceOrder", func() (any, error) { return "placed", nil })
+	require.NoError(t, err)
+	require.Equal(t, "placed", result)
+}
+
+func TestDedupeConcurrent(t *testing.T) {
+	d := idempotency.NewDeduplicator(time.Minute)
+	ctx := idempotency.NewIncomingContext(context.Background(), "PlaceOrder", idempotency.NewKey())
+	var calls atomic.Int64
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	results := make([]any, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = d.Do(ctx, "PlaceOrder", func() (any, error) {
+				<-release
+				return calls.Add(1), nil
+			})
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, int64(1), calls.Load())
+	for _, result := range results {
+		require.Equal(t, int64(1), result)
+	}
+}
+
+func TestDedupeExpiry(t *testing.T) {
+	d := idempotency.NewDeduplicator(time.Millisecond)
+	ctx := idempotency.NewIncomingContext(context.Background(), "PlaceOrder", idempotency.NewKey())
+	calls := 0
+	call := func() (any, error) {
+		calls++
+		return calls, nil
+	}
+
+	d.Do(ctx, "PlaceOrder", call)
+	time.Sleep(5 * time.Millisecond)
+	result, err := d.Do(ctx, "PlaceOrder", call)
+	require.NoError(t, err)
+	require.Equal(t, 2, result)
+}
@@ -0,0 +1,205 @@
+// Package migrations applies versioned SQL schema migrations to a [backend.RelationalDB].
+//
+// Migrations are SQL files in a directory, named <version>_<description>.sql, e.g.
+//
+//	migrations/
+//	  0001_create_users.sql
+//	  0002_add_user_email.sql
+//
+// where version is a positive integer.  Migrations are applied in order of version, and the versions that have
+// been applied are recorded in the database's schema_migrations table, so that each migration is applied only
+// once.  Each migration is applied in its own transaction together with its schema_migrations record.  Note that
+// some databases, including MySQL, implicitly commit schema changes such as CREATE TABLE, in which case a
+// migration that fails part-way can leave the database partially migrated.
+//
+// Backends use this package to apply migrations when they start, e.g. see [sqlitereldb.NewSqliteRelDB] and
+// [mysql.NewMySqlDB]; a process whose migrations fail will then refuse to start.
+//
+// [sqlitereldb.NewSqliteRelDB]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/plugins/sqlitereldb
+// [mysql.NewMySqlDB]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/plugins/mysql
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"golang.org/x/exp/slog"
+)
+
+// A versioned schema migration
+type Migration struct {
+	Version    int64
+	Name       string   // The migration's file name
+	Statements []string // The SQL statements of the migration
+}
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL);`
+
+// Loads the migrations in dir, sorted by version.  Files that do not have a .sql extension are ignored.
+func Load(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	versions := make(map[int64]string)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		prefix, _, _ := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %v; expected <version>_<description>.sql with a positive integer version", entry.Name())
+		}
+		if other, exists := versions[version]; exists {
+			return nil, fmt.Errorf("migrations %v and %v have the same version %v", other, entry.Name(), version)
+		}
+		versions[version] = entry.Name()
+
+		contents, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: entry.Name(), Statements: Split(string(contents))})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Returns the versions of the migrations that have been applied to db, in ascending order
+func Applied(ctx context.Context, db backend.RelationalDB) ([]int64, error) {
+	if _, err := db.Exec(ctx, createTable); err != nil {
+		return nil, err
+	}
+	var versions []int64
+	err := db.Select(ctx, &versions, `SELECT version FROM schema_migrations ORDER BY version;`)
+	return versions, err
+}
+
+// Applies the migrations that have not yet been applied to db, in order of version.  migrations must be
+// sorted by version, as returned by [Load].  Returns the number of migrations applied.
+//
+// Returns an error without applying any migrations if a pending migration has a lower version than an
+// applied migration, since it was presumably added out of order.  If a migration fails, the migrations
+// before it remain applied.
+//
+// Apply does not coordinate with other processes that migrate the same database, e.g. replicas that start
+// together.  Such callers should hold a lock while calling Apply, e.g. a MySQL advisory lock, since Apply
+// reads the applied migrations afresh.
+func Apply(ctx context.Context, db backend.RelationalDB, migrations []Migration) (int, error) {
+	applied, err := Applied(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	isApplied := make(map[int64]bool)
+	var latest int64
+	for _, version := range applied {
+		isApplied[version] = true
+		latest = max(latest, version)
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if isApplied[m.Version] {
+			continue
+		}
+		if m.Version < latest {
+			return 0, fmt.Errorf("migration %v has not been applied but is older than applied migration version %v", m.Name, latest)
+		}
+		pending = append(pending, m)
+	}
+
+	for i, m := range pending {
+		err := backend.WithTx(ctx, db, nil, func(tx backend.RelationalTx) error {
+			for _, statement := range m.Statements {
+				if _, err := tx.Exec(ctx, statement); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES (?, ?);`, m.Version, m.Name)
+			return err
+		})
+		if err != nil {
+			return i, fmt.Errorf("migration %v failed: %w", m.Name, err)
+		}
+		slog.Info(fmt.Sprintf("Applied migration %v", m.Name))
+	}
+	return len(pending), nil
+}
+
+// Loads the migrations in dir and applies any that have not yet been applied to db; see [Load] and [Apply]
+func Migrate(ctx context.Context, db backend.RelationalDB, dir string) error {
+	migrations, err := Load(dir)
+	if err != nil {
+		return err
+	}
+	_, err = Apply(ctx, db, migrations)
+	return err
+}
+
+// Splits sql into its individual statements, which are separated by semicolons.  Semicolons within quoted
+// strings, quoted identifiers and comments do not separate statements.  Empty statements are omitted.
+//
+// Quotes within strings can be escaped by doubling them or, as in MySQL, with a backslash, and comments
+// start with --, # or /*.
+//
+// Not all database drivers support executing multiple statements at once, so migrations are executed
+// one statement at a time.
+func Split(sql string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == ';':
+			flush()
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			// Doubled quotes are handled as two adjacent quoted strings; identifiers have no backslash escapes
+			end := i + 1
+			for ; end < len(sql) && sql[end] != c; end++ {
+				if sql[end] == '\\' && c != '`' {
+					end++
+				}
+			}
+			end = min(end, len(sql)-1)
+			current.WriteString(sql[i : end+1])
+			i = end
+			continue
+		case strings.HasPrefix(sql[i:], "--") || c == '#':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			i += end
+			current.WriteByte(' ')
+			continue
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql) - i - 2
+			}
+			i += end + 3
+			current.WriteByte(' ')
+			continue
+		}
+		current.WriteByte(c)
+	}
+	flush()
+	return statements
+}
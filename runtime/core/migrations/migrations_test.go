@@ -0,0 +1,148 @@
+package migrations_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/migrations"
+	"github.com/blueprint-uservices/blueprint/runtime/plugins/sqlitereldb"
+	"github.com/stretchr/testify/require"
+)
+
+func writeMigrations(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+}
+
+func TestSplit(t *testing.T) {
+	require.Equal(t, []string{
+		"CREATE TABLE a (id INT)",
+		"INSERT INTO a VALUES ('x;y')",
+		"INSERT INTO a VALUES ('it''s')",
+		"SELECT \"a;b\", `c;d` FROM a",
+		`INSERT INTO a VALUES ('it\'s;', "\\", '#')`,
+		"SELECT `c\\`",
+	}, migrations.Split(`
+		-- a comment; with a semicolon
+		CREATE TABLE a (id INT);
+		INSERT INTO a VALUES ('x;y');;
+		/* another; comment */
+		INSERT INTO a VALUES ('it''s');
+		SELECT "a;b", `+"`c;d`"+` FROM a;
+		# a MySQL comment; with a semicolon
+		INSERT INTO a VALUES ('it\'s;', "\\", '#');
+		SELECT `+"`c\\`"+`
+	`))
+	require.Empty(t, migrations.Split(" ; -- nothing here"))
+	require.Empty(t, migrations.Split("# nothing here either;"))
+	require.Equal(t, []string{"SELECT 'unterminated\\"}, migrations.Split("SELECT 'unterminated\\"))
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeMigrations(t, dir, map[string]string{
+		"0010_add_email.sql":   `ALTER TABLE user ADD COLUMN email TEXT;`,
+		"0002_create_user.sql": `CREATE TABLE user (id INT PRIMARY KEY); CREATE INDEX user_id ON user (id);`,
+		"README.md":            `not a migration`,
+	})
+	loaded, err := migrations.Load(dir)
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+	require.Equal(t, int64(2), loaded[0].Version)
+	require.Equal(t, "0002_create_user.sql", loaded[0].Name)
+	require.Len(t, loaded[0].Statements, 2)
+	require.Equal(t, int64(10), loaded[1].Version)
+
+	// Duplicate and invalid versions are rejected
+	writeMigrations(t, dir, map[string]string{"10_duplicate.sql": ``})
+	_, err = migrations.Load(dir)
+	require.Error(t, err)
+
+	invalid := t.TempDir()
+	writeMigrations(t, invalid, map[string]string{"create_user.sql": ``})
+	_, err = migrations.Load(invalid)
+	require.Error(t, err)
+
+	_, err = migrations.Load(filepath.Join(dir, "missing"))
+	require.Error(t, err)
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlitereldb.NewSqliteRelDB(ctx)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	writeMigrations(t, dir, map[string]string{
+		"1_create_item.sql": `CREATE TABLE item (id INT PRIMARY KEY);`,
+		"2_add_name.sql":    `ALTER TABLE item ADD COLUMN name TEXT; INSERT INTO item (id, name) VALUES (1, 'tea');`,
+	})
+	require.NoError(t, migrations.Migrate(ctx, db, dir))
+
+	applied, err := migrations.Applied(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, applied)
+
+	// Applied migrations are not reapplied
+	loaded, err := migrations.Load(dir)
+	require.NoError(t, err)
+	n, err := migrations.Apply(ctx, db, loaded)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	writeMigrations(t, dir, map[string]string{"3_add_price.sql": `ALTER TABLE item ADD COLUMN price INT;`})
+	loaded, err = migrations.Load(dir)
+	require.NoError(t, err)
+	n, err = migrations.Apply(ctx, db, loaded)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	var count int
+	require.NoError(t, db.Get(ctx, &count, `SELECT COUNT(*) FROM item WHERE name = 'tea' AND price IS NULL;`))
+	require.Equal(t, 1, count)
+}
+
+func TestApplyOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlitereldb.NewSqliteRelDB(ctx)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	writeMigrations(t, dir, map[string]string{"2_create_item.sql": `CREATE TABLE item (id INT PRIMARY KEY);`})
+	require.NoError(t, migrations.Migrate(ctx, db, dir))
+
+	writeMigrations(t, dir, map[string]string{"1_create_other.sql": `CREATE TABLE other (id INT PRIMARY KEY);`})
+	require.Error(t, migrations.Migrate(ctx, db, dir))
+
+	applied, err := migrations.Applied(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []int64{2}, applied)
+}
+
+func TestApplyFailure(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlitereldb.NewSqliteRelDB(ctx)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	writeMigrations(t, dir, map[string]string{
+		"1_create_item.sql": `CREATE TABLE item (id INT PRIMARY KEY);`,
+		"2_broken.sql":      `INSERT INTO item (id) VALUES (1); INSERT INTO missing (id) VALUES (1);`,
+	})
+	loaded, err := migrations.Load(dir)
+	require.NoError(t, err)
+	n, err := migrations.Apply(ctx, db, loaded)
+	require.Error(t, err)
+	require.Equal(t, 1, n)
+
+	// The failed migration is rolled back and not recorded, but earlier migrations remain applied
+	applied, err := migrations.Applied(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1}, applied)
+	var count int
+	require.NoError(t, db.Get(ctx, &count, `SELECT COUNT(*) FROM item;`))
+	require.Equal(t, 0, count)
+}
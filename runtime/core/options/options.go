@@ -0,0 +1,42 @@
+// Package options parses the "key=value" options with which wiring specs configure runtime
+// components, e.g. the capacity of a cache or the backoff of a retrier.
+package options
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A single key=value option
+type Option struct {
+	Key   string
+	Value string
+}
+
+// Parses opts, each of which must be of the form key=value, in order.  component names the
+// component being configured, e.g. "simplecache", in the error returned for a malformed option.
+func Parse(component string, opts []string) ([]Option, error) {
+	var parsed []Option
+	for _, opt := range opts {
+		key, value, found := strings.Cut(opt, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid %v option %q; expected key=value", component, opt)
+		}
+		parsed = append(parsed, Option{Key: key, Value: value})
+	}
+	return parsed, nil
+}
+
+// Like [Parse], but returns the options as a map from key to value.  If a key is given more than
+// once, its last value is used.
+func Map(component string, opts []string) (map[string]string, error) {
+	parsed, err := Parse(component, opts)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(parsed))
+	for _, opt := range parsed {
+		values[opt.Key] = opt.Value
+	}
+	return values, nil
+}
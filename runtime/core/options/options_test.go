@@ -0,0 +1,25 @@
+package options
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	opts, err := Parse("simplecache", []string{"capacity=10", "policy=lru", "name=a=b", "empty="})
+	require.NoError(t, err)
+	require.Equal(t, []Option{{"capacity", "10"}, {"policy", "lru"}, {"name", "a=b"}, {"empty", ""}}, opts)
+
+	_, err = Parse("simplecache", []string{"capacity=10", "policy"})
+	require.EqualError(t, err, `invalid simplecache option "policy"; expected key=value`)
+}
+
+func TestMap(t *testing.T) {
+	values, err := Map("retries", []string{"max=3", "backoff=1ms", "max=5"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"max": "5", "backoff": "1ms"}, values)
+
+	_, err = Map("retries", []string{"max"})
+	require.Error(t, err)
+}
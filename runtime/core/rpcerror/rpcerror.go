@@ -0,0 +1,257 @@
+// Package rpcerror provides errors with codes and details that are preserved when they are
+// returned across RPC boundaries.
+//
+// Workflow services return an [*Error] to tell callers why a call failed, e.g.
+//
+//	return nil, rpcerror.New(rpcerror.NotFound, "no order with id %v", id).WithDetail("id", id)
+//
+// The servers generated by the gRPC, HTTP and Thrift plugins encode the code, message and details
+// of errors returned by services, and the generated clients reconstruct them as an [*Error], so
+// that callers can check for codes with [errors.Is] and the sentinel errors of this package, e.g.
+//
+//	if errors.Is(err, rpcerror.ErrNotFound) { ... }
+//
+// or retrieve the details with [errors.As].  Errors that do not wrap an [*Error] are transmitted
+// with the [Unknown] code, apart from context errors, which are transmitted with the [Canceled]
+// and [DeadlineExceeded] codes.
+package rpcerror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// The code of an [Error], which tells callers why a call failed.  The codes follow those of gRPC.
+type Code string
+
+const (
+	Unknown            Code = "unknown"             // The error has no more specific code
+	InvalidArgument    Code = "invalid_argument"    // The caller provided invalid arguments
+	NotFound           Code = "not_found"           // A requested entity was not found
+	AlreadyExists      Code = "already_exists"      // An entity that the caller tried to create already exists
+	PermissionDenied   Code = "permission_denied"   // The caller is not permitted to make the call
+	Unauthenticated    Code = "unauthenticated"     // The caller could not be authenticated
+	FailedPrecondition Code = "failed_precondition" // The system is not in a state required for the call
+	Aborted            Code = "aborted"             // The call was aborted, typically due to a concurrency conflict
+	ResourceExhausted  Code = "resource_exhausted"  // A resource, such as a quota, has been exhausted
+	Unavailable        Code = "unavailable"         // The service is currently unavailable; the call can be retried
+	DeadlineExceeded   Code = "deadline_exceeded"   // The deadline of the call expired
+	Canceled           Code = "canceled"            // The call was cancelled by the caller
+	Unimplemented      Code = "unimplemented"       // The call is not implemented by the service
+	Internal           Code = "internal"            // An internal invariant of the service was broken
+)
+
+// Sentinel errors for use with [errors.Is], which match any [*Error] with the same code
+var (
+	ErrInvalidArgument    = &Error{Code: InvalidArgument}
+	ErrNotFound           = &Error{Code: NotFound}
+	ErrAlreadyExists      = &Error{Code: AlreadyExists}
+	ErrPermissionDenied   = &Error{Code: PermissionDenied}
+	ErrUnauthenticated    = &Error{Code: Unauthenticated}
+	ErrFailedPrecondition = &Error{Code: FailedPrecondition}
+	ErrAborted            = &Error{Code: Aborted}
+	ErrResourceExhausted  = &Error{Code: ResourceExhausted}
+	ErrUnavailable        = &Error{Code: Unavailable}
+	ErrUnimplemented      = &Error{Code: Unimplemented}
+	ErrInternal           = &Error{Code: Internal}
+)
+
+// An error with a code and details that are preserved across RPC boundaries
+type Error struct {
+	Code    Code              `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+	cause   error             // The wrapped error, if any; not transmitted
+}
+
+// Returns a new [*Error] with the code and a message formatted from format and args.
+func New(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Returns a new [*Error] with the code that wraps err, with the same message as err.
+func Wrap(code Code, err error) *Error {
+	return &Error{Code: code, Message: err.Error(), cause: err}
+}
+
+// Returns a copy of e with the detail key set to value.
+func (e *Error) WithDetail(key string, value any) *Error {
+	copied := *e
+	copied.Details = make(map[string]string, len(e.Details)+1)
+	for k, v := range e.Details {
+		copied.Details[k] = v
+	}
+	copied.Details[key] = fmt.Sprint(value)
+	return &copied
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return strings.ReplaceAll(string(e.Code), "_", " ")
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Reports whether e matches target.  An [*Error] matches any [*Error] with the same code and
+// either no message, like the sentinel errors of this package, or the same message.  Errors with
+// the [Canceled] and [DeadlineExceeded] codes also match the corresponding context errors.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case context.Canceled:
+		return e.Code == Canceled
+	case context.DeadlineExceeded:
+		return e.Code == DeadlineExceeded
+	}
+	t, isError := target.(*Error)
+	return isError && t.Code == e.Code && (t.Message == "" || t.Message == e.Message)
+}
+
+// Returns the code of err: the code of the first [*Error] in err's chain, or [Canceled] or
+// [DeadlineExceeded] for context errors, or else [Unknown].  Returns the empty code if err is nil.
+func CodeOf(err error) Code {
+	var e *Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &e):
+		return e.Code
+	case errors.Is(err, context.Canceled):
+		return Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return DeadlineExceeded
+	default:
+		return Unknown
+	}
+}
+
+// Encodes err for transmission to a caller.  The encoding has the message of err, which includes
+// the messages of the errors that wrap an [*Error], and the code and details of the [*Error].
+func Encode(err error) []byte {
+	if err == nil {
+		return nil
+	}
+	encoded := &Error{Code: CodeOf(err), Message: err.Error()}
+	var e *Error
+	if errors.As(err, &e) {
+		encoded.Details = e.Details
+	}
+	b, _ := json.Marshal(encoded)
+	return b
+}
+
+// Reconstructs an error encoded by [Encode] as an [*Error].  If data is not an encoded error, it
+// is taken to be the message of an error with the [Unknown] code.
+func Decode(data []byte) error {
+	e := &Error{}
+	if err := json.Unmarshal(data, e); err != nil || e.Code == "" {
+		return &Error{Code: Unknown, Message: string(data)}
+	}
+	return e
+}
+
+var httpStatuses = map[Code]int{
+	Unknown:            http.StatusInternalServerError,
+	InvalidArgument:    http.StatusBadRequest,
+	NotFound:           http.StatusNotFound,
+	AlreadyExists:      http.StatusConflict,
+	PermissionDenied:   http.StatusForbidden,
+	Unauthenticated:    http.StatusUnauthorized,
+	FailedPrecondition: http.StatusPreconditionFailed,
+	Aborted:            http.StatusConflict,
+	ResourceExhausted:  http.StatusTooManyRequests,
+	Unavailable:        http.StatusServiceUnavailable,
+	DeadlineExceeded:   http.StatusGatewayTimeout,
+	Canceled:           499, // Client Closed Request
+	Unimplemented:      http.StatusNotImplemented,
+	Internal:           http.StatusInternalServerError,
+}
+
+// Returns the HTTP status code with which HTTP servers return errors with the code.
+func HTTPStatus(code Code) int {
+	if status, exists := httpStatuses[code]; exists {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Returns the code of errors returned with the HTTP status code, for responses that do not
+// contain an encoded error.
+func FromHTTPStatus(status int) Code {
+	switch status {
+	case http.StatusConflict:
+		return AlreadyExists
+	case http.StatusInternalServerError:
+		return Unknown
+	}
+	for code, s := range httpStatuses {
+		if s == status {
+			return code
+		}
+	}
+	if status >= 400 && status < 500 {
+		return InvalidArgument
+	}
+	return Unknown
+}
+
+// Reconstructs the error returned by an HTTP server with the status code and response body.
+func DecodeHTTP(status int, body []byte) error {
+	e := &Error{}
+	if err := json.Unmarshal(body, e); err == nil && e.Code != "" {
+		return e
+	}
+	message := strings.TrimSpace(string(body))
+	if message == "" {
+		message = fmt.Sprintf("StatusCode was %d", status)
+	}
+	return &Error{Code: FromHTTPStatus(status), Message: message}
+}
+
+// gRPC status codes, by code; see google.golang.org/grpc/codes
+var grpcCodes = map[Code]uint32{
+	Canceled:           1,
+	Unknown:            2,
+	InvalidArgument:    3,
+	DeadlineExceeded:   4,
+	NotFound:           5,
+	AlreadyExists:      6,
+	PermissionDenied:   7,
+	ResourceExhausted:  8,
+	FailedPrecondition: 9,
+	Aborted:            10,
+	Unimplemented:      12,
+	Internal:           13,
+	Unavailable:        14,
+	Unauthenticated:    16,
+}
+
+// The key of the gRPC trailer in which gRPC servers transmit encoded errors.  The -bin suffix
+// tells gRPC that the value is binary.
+const GRPCTrailer = "blueprint-error-bin"
+
+// Returns the gRPC status code with which gRPC servers return errors with the code.
+func GRPCCode(code Code) uint32 {
+	if c, exists := grpcCodes[code]; exists {
+		return c
+	}
+	return grpcCodes[Unknown]
+}
+
+// Returns the code of errors returned with the gRPC status code, for responses that do not have
+// an encoded error.
+func FromGRPCCode(c uint32) Code {
+	for code, grpcCode := range grpcCodes {
+		if grpcCode == c {
+			return code
+		}
+	}
+	return Unknown
+}
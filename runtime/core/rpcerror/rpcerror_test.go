@@ -0,0 +1,112 @@
+package rpcerror_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/rpcerror"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsAndAs(t *testing.T) {
+	err := fmt.Errorf("loading order: %w", rpcerror.New(rpcerror.NotFound, "no order with id %v", 5).WithDetail("id", 5))
+	require.Equal(t, "loading order: no order with id 5", err.Error())
+	require.ErrorIs(t, err, rpcerror.ErrNotFound)
+	require.NotErrorIs(t, err, rpcerror.ErrInvalidArgument)
+	require.Equal(t, rpcerror.NotFound, rpcerror.CodeOf(err))
+
+	var e *rpcerror.Error
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, map[string]string{"id": "5"}, e.Details)
+
+	// Wrapped errors remain in the chain
+	cause := errors.New("disk full")
+	wrapped := rpcerror.Wrap(rpcerror.Unavailable, cause)
+	require.ErrorIs(t, wrapped, cause)
+	require.ErrorIs(t, wrapped, rpcerror.ErrUnavailable)
+	require.Equal(t, "disk full", wrapped.Error())
+
+	require.Equal(t, "not found", rpcerror.ErrNotFound.Error())
+	require.Equal(t, rpcerror.Code(""), rpcerror.CodeOf(nil))
+	require.Equal(t, rpcerror.Unknown, rpcerror.CodeOf(cause))
+	require.Equal(t, rpcerror.DeadlineExceeded, rpcerror.CodeOf(fmt.Errorf("calling: %w", context.DeadlineExceeded)))
+}
+
+func TestEncodeDecode(t *testing.T) {
+	err := fmt.Errorf("loading order: %w", rpcerror.New(rpcerror.NotFound, "no order with id %v", 5).WithDetail("id", 5))
+	decoded := rpcerror.Decode(rpcerror.Encode(err))
+	require.Equal(t, err.Error(), decoded.Error())
+	require.ErrorIs(t, decoded, rpcerror.ErrNotFound)
+	var e *rpcerror.Error
+	require.ErrorAs(t, decoded, &e)
+	require.Equal(t, "5", e.Details["id"])
+
+	// Errors without a code are unknown, apart from context errors
+	decoded = rpcerror.Decode(rpcerror.Encode(errors.New("boom")))
+	require.Equal(t, rpcerror.Unknown, rpcerror.CodeOf(decoded))
+	require.Equal(t, "boom", decoded.Error())
+
+	decoded = rpcerror.Decode(rpcerror.Encode(context.Canceled))
+	require.ErrorIs(t, decoded, context.Canceled)
+	require.ErrorIs(t, rpcerror.Decode(rpcerror.Encode(context.DeadlineExceeded)), context.DeadlineExceeded)
+
+	decoded = rpcerror.Decode([]byte("not json"))
+	require.Equal(t, rpcerror.Unknown, rpcerror.CodeOf(decoded))
+	require.Equal(t, "not json", decoded.Error())
+
+	require.Nil(t, rpcerror.Encode(nil))
+}
+
+func TestHTTP(t *testing.T) {
+	require.Equal(t, http.StatusNotFound, rpcerror.HTTPStatus(rpcerror.NotFound))
+	require.Equal(t, http.StatusBadRequest, rpcerror.HTTPStatus(rpcerror.InvalidArgument))
+	require.Equal(t, http.StatusInternalServerError, rpcerror.HTTPStatus(rpcerror.Unknown))
+	require.Equal(t, http.StatusInternalServerError, rpcerror.HTTPStatus("made_up"))
+
+	require.Equal(t, rpcerror.NotFound, rpcerror.FromHTTPStatus(http.StatusNotFound))
+	require.Equal(t, rpcerror.AlreadyExists, rpcerror.FromHTTPStatus(http.StatusConflict))
+	require.Equal(t, rpcerror.Unknown, rpcerror.FromHTTPStatus(http.StatusInternalServerError))
+	require.Equal(t, rpcerror.InvalidArgument, rpcerror.FromHTTPStatus(http.StatusTeapot))
+
+	// Encoded errors are reconstructed regardless of the status
+	err := rpcerror.New(rpcerror.Aborted, "conflicting update")
+	decoded := rpcerror.DecodeHTTP(http.StatusConflict, rpcerror.Encode(err))
+	require.ErrorIs(t, decoded, rpcerror.ErrAborted)
+	require.Equal(t, "conflicting update", decoded.Error())
+
+	// Other responses are given the code of their status
+	decoded = rpcerror.DecodeHTTP(http.StatusNotFound, []byte("404 page not found\n"))
+	require.ErrorIs(t, decoded, rpcerror.ErrNotFound)
+	require.Equal(t, "404 page not found", decoded.Error())
+	require.Equal(t, "StatusCode was 503", rpcerror.DecodeHTTP(http.StatusServiceUnavailable, nil).Error())
+}
+
+func TestGRPC(t *testing.T) {
+	for _, code := range []rpcerror.Code{
+		rpcerror.Unknown, rpcerror.InvalidArgument, rpcerror.NotFound, rpcerror.AlreadyExists,
+		rpcerror.PermissionDenied, rpcerror.Unauthenticated, rpcerror.FailedPrecondition, rpcerror.Aborted,
+		rpcerror.ResourceExhausted, rpcerror.Unavailable, rpcerror.DeadlineExceeded, rpcerror.Canceled,
+		rpcerror.Unimplemented, rpcerror.Internal,
+	} {
+		require.Equal(t, code, rpcerror.FromGRPCCode(rpcerror.GRPCCode(code)))
+	}
+	require.Equal(t, uint32(5), rpcerror.GRPCCode(rpcerror.NotFound))
+	require.Equal(t, rpcerror.Unknown, rpcerror.FromGRPCCode(15))
+}
+
+func TestStreamError(t *testing.T) {
+	failed := make(chan int)
+	complete := make(chan int)
+	rpcerror.SetStreamError(failed, rpcerror.New(rpcerror.Unavailable, "connection lost"))
+	close(failed)
+	close(complete)
+
+	// Errors are found by the receive-only channels returned by clients
+	var received <-chan int = failed
+	require.ErrorIs(t, rpcerror.StreamError(received), rpcerror.ErrUnavailable)
+	require.NoError(t, rpcerror.StreamError(received))
+	require.NoError(t, rpcerror.StreamError(complete))
+}
@@ -0,0 +1,42 @@
+package rpcerror
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Errors that ended streams, keyed by the address of the stream's channel.  The channel is saved
+// alongside the error, so that its address cannot be reused while the error is saved.
+var streamErrors sync.Map
+
+type streamError struct {
+	ch  any
+	err error
+}
+
+// Records err as the error that ended the stream of values received on the channel ch.  Generated
+// clients call SetStreamError before closing a channel of values streamed by a server, if the
+// stream fails before the server has sent all of its values.
+func SetStreamError(ch any, err error) {
+	streamErrors.Store(reflect.ValueOf(ch).Pointer(), streamError{ch: ch, err: err})
+}
+
+// Returns the error that ended the stream of values received on the channel ch, or nil if the
+// stream ended normally, once the server had sent all of its values.  ch is a channel returned by
+// a generated client, which is closed either way, so callers use StreamError once ch is closed to
+// tell a complete stream from one that failed part-way, e.g.
+//
+//	for order := range orders {
+//		...
+//	}
+//	if err := rpcerror.StreamError(orders); err != nil {
+//		...
+//	}
+//
+// The error is forgotten once it has been returned.
+func StreamError(ch any) error {
+	if saved, exists := streamErrors.LoadAndDelete(reflect.ValueOf(ch).Pointer()); exists {
+		return saved.(streamError).err
+	}
+	return nil
+}
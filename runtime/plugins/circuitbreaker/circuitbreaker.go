@@ -0,0 +1,387 @@
+// Package circuitbreaker implements the runtime components of Blueprint's circuitbreaker plugin.
+//
+// The breaker does not need to be used directly by application workflow specs.  Instead, this
+// code is included in a compiled application by applying the circuitbreaker modifier to the wiring spec.
+//
+// A [Breaker] keeps a separate circuit for each method of the client that it wraps, unless
+// configured to share one circuit between all methods.  A circuit starts closed and opens when
+// too many calls fail; calls of an open circuit fail immediately with [ErrOpen], or return the
+// method's fallback values if it has any.  After a cool-down the circuit becomes half-open and
+// lets a limited number of probe calls through; if they succeed the circuit closes again, and if
+// any of them fails the circuit re-opens.
+//
+// State transitions are logged with the process's logger, added as events to the span of the
+// call that caused them, and counted in the circuitbreaker_transitions metric, so that trips can
+// be correlated with traces.
+package circuitbreaker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/blueprint-uservices/blueprint/runtime/core/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Returned by calls that are rejected because their circuit is open, or half-open with its
+// maximum number of probes in progress.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// The state of a circuit
+type State int
+
+const (
+	Closed State = iota
+	HalfOpen
+	Open
+)
+
+func (s State) String() string {
+	return [...]string{"closed", "half-open", "open"}[s]
+}
+
+// Default values of the breaker's options
+const (
+	DefaultMinReqs        = 1
+	DefaultFailureRate    = 0.5
+	DefaultInterval       = 10 * time.Second
+	DefaultHalfOpenProbes = 1
+)
+
+// Opens, closes and half-opens the circuits of the methods of a client; safe for concurrent use,
+// including reconfiguration.
+type Breaker struct {
+	name     string
+	mu       sync.Mutex
+	options  map[string]string
+	config   *config
+	circuits map[string]*circuit
+	metrics  breakerMetrics
+	epochs   uint64 // The number of circuit epochs started so far
+}
+
+type config struct {
+	minReqs     int64
+	failureRate float64
+	interval    time.Duration
+	cooldown    time.Duration
+	probes      int64
+	perMethod   bool
+	fallbacks   map[string][]json.RawMessage
+}
+
+// The state of one circuit.  Guarded by the breaker's mutex.
+type circuit struct {
+	state       State
+	epoch       uint64    // Identifies the circuit's current state; changes on every transition
+	windowStart time.Time // When the counters of a closed circuit were last reset
+	successes   int64
+	failures    int64
+	openedAt    time.Time
+	probing     int64 // Probes in progress in the half-open state
+	probed      int64 // Successful probes in the half-open state
+}
+
+// Instantiates a [Breaker]; name identifies the breaker in its logs and metrics.
+//
+// opts are "key=value" strings that configure the breaker:
+//   - minreqs=<int> is the minimum number of calls in an interval before the circuit can open; defaults to
+//     [DefaultMinReqs]
+//   - failurerate=<float> opens the circuit when at least this fraction of the calls in an interval fail;
+//     defaults to [DefaultFailureRate]
+//   - interval=<duration> is how often the call counters of a closed circuit are reset; defaults to
+//     [DefaultInterval]
+//   - cooldown=<duration> is how long an open circuit stays open before it becomes half-open; defaults to
+//     interval
+//   - halfopenprobes=<int> is the number of calls that a half-open circuit lets through, all of which must succeed
+//     for the circuit to close; defaults to [DefaultHalfOpenProbes]
+//   - permethod=true|false keeps a separate circuit for each method, or one circuit for all methods;
+//     defaults to true
+//   - fallback.<method>=<json array> is returned by calls of method that are rejected, instead of [ErrOpen];
+//     the array has one element for each of the method's return values, excluding the error.  See [Breaker.Fallback].
+//
+// The options can be changed while the process is running with [Breaker.SetParameters].
+func NewBreaker(name string, opts ...string) (*Breaker, error) {
+	values, err := options.Map("circuitbreaker", opts)
+	if err != nil {
+		return nil, err
+	}
+	b := &Breaker{name: name, circuits: make(map[string]*circuit)}
+	b.metrics.breaker = b
+	if err := b.configure(values); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Replaces the breaker's configuration with options.  Returns an error, leaving the configuration
+// unchanged, if any of the options are invalid.
+func (b *Breaker) configure(options map[string]string) error {
+	c := &config{
+		minReqs:     DefaultMinReqs,
+		failureRate: DefaultFailureRate,
+		interval:    DefaultInterval,
+		probes:      DefaultHalfOpenProbes,
+		perMethod:   true,
+		fallbacks:   make(map[string][]json.RawMessage),
+	}
+	var err error
+	for key, value := range options {
+		switch key {
+		case "minreqs":
+			c.minReqs, err = strconv.ParseInt(value, 10, 64)
+			if err == nil && c.minReqs < 1 {
+				err = fmt.Errorf("%v is less than 1", value)
+			}
+		case "failurerate":
+			c.failureRate, err = strconv.ParseFloat(value, 64)
+			if err == nil && (c.failureRate <= 0 || c.failureRate > 1) {
+				err = fmt.Errorf("%v is not in (0, 1]", value)
+			}
+		case "interval":
+			c.interval, err = parsePositiveDuration(value)
+		case "cooldown":
+			c.cooldown, err = parsePositiveDuration(value)
+		case "halfopenprobes":
+			c.probes, err = strconv.ParseInt(value, 10, 64)
+			if err == nil && c.probes < 1 {
+				err = fmt.Errorf("%v is less than 1", value)
+			}
+		case "permethod":
+			c.perMethod, err = strconv.ParseBool(value)
+		default:
+			method, isFallback := strings.CutPrefix(key, "fallback.")
+			if !isFallback || method == "" {
+				return fmt.Errorf("unknown circuitbreaker option %v", key)
+			}
+			var values []json.RawMessage
+			err = json.Unmarshal([]byte(value), &values)
+			c.fallbacks[method] = values
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for circuitbreaker option %v: %v", key, err)
+		}
+	}
+	if _, hasCooldown := options["cooldown"]; !hasCooldown {
+		c.cooldown = c.interval
+	}
+
+	copied := make(map[string]string)
+	for key, value := range options {
+		copied[key] = value
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.config != nil && b.config.perMethod != c.perMethod {
+		b.circuits = make(map[string]*circuit)
+	}
+	b.options = copied
+	b.config = c
+	return nil
+}
+
+func parsePositiveDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("%v is not positive", value)
+	}
+	return d, err
+}
+
+// Returns the breaker's current options.  Implements control.Tunable.
+func (b *Breaker) Parameters() map[string]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	params := make(map[string]string)
+	for key, value := range b.options {
+		params[key] = value
+	}
+	return params
+}
+
+// Changes the named options, leaving the others unchanged, e.g. {"failurerate": "0.2"}.  An empty
+// value removes an option, restoring its default.  Returns an error, leaving the configuration
+// unchanged, if any of the options are invalid.  Implements control.Tunable.
+func (b *Breaker) SetParameters(params map[string]string) error {
+	options := b.Parameters()
+	for key, value := range params {
+		if value == "" {
+			delete(options, key)
+		} else {
+			options[key] = value
+		}
+	}
+	return b.configure(options)
+}
+
+// Returns the current state of method's circuit
+func (b *Breaker) State(method string) State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if cb, exists := b.circuits[b.circuitName(method)]; exists {
+		if cb.state == Open && time.Since(cb.openedAt) >= b.config.cooldown {
+			return HalfOpen
+		}
+		return cb.state
+	}
+	return Closed
+}
+
+// Calls call, a call of method, unless method's circuit is open, in which case returns [ErrOpen]
+// without calling call.  The outcome of the call is recorded in the circuit, unless the circuit
+// changed state during the call; any error counts as a failure, including the cancellation of ctx.
+func (b *Breaker) Do(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	epoch, allowed := b.allow(ctx, method)
+	if !allowed {
+		b.metrics.rejected(ctx, method)
+		return ErrOpen
+	}
+	err := call(ctx)
+	b.record(ctx, method, epoch, err == nil)
+	return err
+}
+
+// Handles err, the error of a call of method.  If err is [ErrOpen] and method has fallback values,
+// unmarshals the fallback values into rets, which point to the method's return values, and returns
+// nil; otherwise returns err.
+func (b *Breaker) Fallback(ctx context.Context, method string, err error, rets ...any) error {
+	if !errors.Is(err, ErrOpen) {
+		return err
+	}
+	b.mu.Lock()
+	values, hasFallback := b.config.fallbacks[method]
+	b.mu.Unlock()
+	if !hasFallback {
+		return err
+	}
+	if len(values) != len(rets) {
+		return fmt.Errorf("circuitbreaker %v has %v fallback values for %v but it returns %v values", b.name, len(values), method, len(rets))
+	}
+	for i, value := range values {
+		if unmarshalErr := json.Unmarshal(value, rets[i]); unmarshalErr != nil {
+			return fmt.Errorf("invalid circuitbreaker fallback value %v for %v: %v", string(value), method, unmarshalErr)
+		}
+	}
+	b.metrics.fellBack(ctx, method)
+	return nil
+}
+
+// The name of the circuit that method's calls use
+func (b *Breaker) circuitName(method string) string {
+	if b.config.perMethod {
+		return method
+	}
+	return "*"
+}
+
+// Returns whether a call of method may proceed, and the epoch of the circuit that allowed it; if
+// the call is a probe of a half-open circuit, counts it as in progress.
+func (b *Breaker) allow(ctx context.Context, method string) (uint64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	name := b.circuitName(method)
+	cb, exists := b.circuits[name]
+	if !exists {
+		b.epochs++
+		cb = &circuit{epoch: b.epochs, windowStart: time.Now()}
+		b.circuits[name] = cb
+	}
+
+	switch cb.state {
+	case Closed:
+		if time.Since(cb.windowStart) >= b.config.interval {
+			cb.windowStart, cb.successes, cb.failures = time.Now(), 0, 0
+		}
+		return cb.epoch, true
+	case Open:
+		if time.Since(cb.openedAt) < b.config.cooldown {
+			return 0, false
+		}
+		b.transition(ctx, name, cb, HalfOpen)
+	}
+	if cb.probing+cb.probed >= b.config.probes {
+		return 0, false
+	}
+	cb.probing++
+	return cb.epoch, true
+}
+
+// Records the outcome of a call of method that was allowed in the given epoch of its circuit.  The
+// outcomes of calls allowed before the circuit last changed state are ignored, so that e.g. a slow
+// call allowed while the circuit was closed doesn't count as a probe of the half-open circuit.
+func (b *Breaker) record(ctx context.Context, method string, epoch uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	name := b.circuitName(method)
+	cb, exists := b.circuits[name]
+	if !exists || cb.epoch != epoch {
+		// The circuit changed state, or the breaker was reconfigured, during the call
+		return
+	}
+
+	switch cb.state {
+	case Closed:
+		if success {
+			cb.successes++
+			return
+		}
+		cb.failures++
+		total := cb.successes + cb.failures
+		if total >= b.config.minReqs && float64(cb.failures)/float64(total) >= b.config.failureRate {
+			b.transition(ctx, name, cb, Open)
+		}
+	case HalfOpen:
+		if cb.probing > 0 {
+			cb.probing--
+		}
+		if !success {
+			b.transition(ctx, name, cb, Open)
+		} else if cb.probed++; cb.probed >= b.config.probes {
+			b.transition(ctx, name, cb, Closed)
+		}
+	}
+}
+
+// Moves cb to state to, resetting its counters, and reports the transition.  Must be called with
+// b.mu held.
+func (b *Breaker) transition(ctx context.Context, name string, cb *circuit, to State) {
+	from := cb.state
+	b.epochs++
+	*cb = circuit{state: to, epoch: b.epochs, windowStart: time.Now()}
+	if to == Open {
+		cb.openedAt = time.Now()
+	}
+
+	message := fmt.Sprintf("circuitbreaker %v: circuit of %v changed from %v to %v", b.name, name, from, to)
+	if to == Open {
+		backend.GetLogger().Warn(ctx, message)
+	} else {
+		backend.GetLogger().Info(ctx, message)
+	}
+	trace.SpanFromContext(ctx).AddEvent("circuitbreaker."+strings.ReplaceAll(to.String(), "-", ""), trace.WithAttributes(
+		attribute.String("breaker", b.name),
+		attribute.String("method", name),
+		attribute.String("from", from.String()),
+	))
+	b.metrics.transitioned(ctx, name, from, to)
+}
+
+// Returns the current states of the breaker's circuits, keyed by method, or by "*" if the breaker
+// does not keep a circuit for each method
+func (b *Breaker) states() map[string]State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	states := make(map[string]State)
+	for name, cb := range b.circuits {
+		states[name] = cb.state
+	}
+	return states
+}
@@ -0,0 +1,246 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/blueprint-uservices/blueprint/runtime/plugins/circuitbreaker"
+	"github.com/stretchr/testify/require"
+)
+
+var errBoom = errors.New("boom")
+
+// Records the messages that are logged
+type testLogger struct {
+	sync.Mutex
+	messages []string
+}
+
+func (l *testLogger) Logf(ctx context.Context, opts backend.LogOptions, format string, args ...any) (context.Context, error) {
+	l.Lock()
+	defer l.Unlock()
+	l.messages = append(l.messages, opts.Level.String()+" "+fmt.Sprintf(format, args...))
+	return ctx, nil
+}
+
+func (l *testLogger) Debug(ctx context.Context, format string, args ...any) (context.Context, error) {
+	return l.Logf(ctx, backend.LogOptions{Level: backend.DEBUG}, format, args...)
+}
+
+func (l *testLogger) Info(ctx context.Context, format string, args ...any) (context.Context, error) {
+	return l.Logf(ctx, backend.LogOptions{Level: backend.INFO}, format, args...)
+}
+
+func (l *testLogger) Warn(ctx context.Context, format string, args ...any) (context.Context, error) {
+	return l.Logf(ctx, backend.LogOptions{Level: backend.WARN}, format, args...)
+}
+
+func (l *testLogger) Error(ctx context.Context, format string, args ...any) (context.Context, error) {
+	return l.Logf(ctx, backend.LogOptions{Level: backend.ERROR}, format, args...)
+}
+
+func useTestLogger() *testLogger {
+	logger := &testLogger{}
+	backend.SetDefaultLogger(logger)
+	return logger
+}
+
+func fail(context.Context) error    { return errBoom }
+func succeed(context.Context) error { return nil }
+
+func TestTrip(t *testing.T) {
+	logger := useTestLogger()
+	b, err := circuitbreaker.NewBreaker("leaf.client.cb", "minreqs=4", "failurerate=0.5", "interval=1h")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Too few requests to trip
+	require.NoError(t, b.Do(ctx, "Hello", succeed))
+	require.ErrorIs(t, b.Do(ctx, "Hello", fail), errBoom)
+	require.ErrorIs(t, b.Do(ctx, "Hello", fail), errBoom)
+	require.Equal(t, circuitbreaker.Closed, b.State("Hello"))
+
+	require.ErrorIs(t, b.Do(ctx, "Hello", fail), errBoom)
+	require.Equal(t, circuitbreaker.Open, b.State("Hello"))
+
+	called := false
+	require.ErrorIs(t, b.Do(ctx, "Hello", func(context.Context) error { called = true; return nil }), circuitbreaker.ErrOpen)
+	require.False(t, called)
+
+	// Other methods have their own circuits
+	require.NoError(t, b.Do(ctx, "Goodbye", succeed))
+	require.Equal(t, circuitbreaker.Closed, b.State("Goodbye"))
+
+	require.Equal(t, []string{"WARN circuitbreaker leaf.client.cb: circuit of Hello changed from closed to open"}, logger.messages)
+}
+
+func TestSharedCircuit(t *testing.T) {
+	useTestLogger()
+	b, err := circuitbreaker.NewBreaker("leaf.client.cb", "minreqs=1", "failurerate=1", "permethod=false")
+	require.NoError(t, err)
+
+	require.Error(t, b.Do(context.Background(), "Hello", fail))
+	require.ErrorIs(t, b.Do(context.Background(), "Goodbye", succeed), circuitbreaker.ErrOpen)
+	require.Equal(t, circuitbreaker.Open, b.State("Goodbye"))
+}
+
+func TestHalfOpen(t *testing.T) {
+	logger := useTestLogger()
+	b, err := circuitbreaker.NewBreaker("leaf.client.cb", "minreqs=1", "failurerate=1", "cooldown=10ms", "halfopenprobes=2")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.Error(t, b.Do(ctx, "Hello", fail))
+	require.Equal(t, circuitbreaker.Open, b.State("Hello"))
+	time.Sleep(15 * time.Millisecond)
+	require.Equal(t, circuitbreaker.HalfOpen, b.State("Hello"))
+
+	// A failed probe re-opens the circuit
+	require.Error(t, b.Do(ctx, "Hello", fail))
+	require.Equal(t, circuitbreaker.Open, b.State("Hello"))
+	require.ErrorIs(t, b.Do(ctx, "Hello", succeed), circuitbreaker.ErrOpen)
+	time.Sleep(15 * time.Millisecond)
+
+	// Only two probes are let through at a time
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Do(ctx, "Hello", func(context.Context) error { <-release; return nil })
+		}()
+	}
+	time.Sleep(5 * time.Millisecond)
+	require.ErrorIs(t, b.Do(ctx, "Hello", succeed), circuitbreaker.ErrOpen)
+	close(release)
+	wg.Wait()
+
+	// Both probes succeeded, so the circuit is closed
+	require.Equal(t, circuitbreaker.Closed, b.State("Hello"))
+	require.NoError(t, b.Do(ctx, "Hello", succeed))
+
+	require.Equal(t, []string{
+		"WARN circuitbreaker leaf.client.cb: circuit of Hello changed from closed to open",
+		"INFO circuitbreaker leaf.client.cb: circuit of Hello changed from open to half-open",
+		"WARN circuitbreaker leaf.client.cb: circuit of Hello changed from half-open to open",
+		"INFO circuitbreaker leaf.client.cb: circuit of Hello changed from open to half-open",
+		"INFO circuitbreaker leaf.client.cb: circuit of Hello changed from half-open to closed",
+	}, logger.messages)
+}
+
+func TestSlowCallDuringHalfOpen(t *testing.T) {
+	useTestLogger()
+	b, err := circuitbreaker.NewBreaker("leaf.client.cb", "minreqs=1", "failurerate=1", "cooldown=10ms")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// A slow call is allowed while the circuit is closed
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- b.Do(ctx, "Hello", func(context.Context) error { <-release; return nil })
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	require.Error(t, b.Do(ctx, "Hello", fail))
+	require.Equal(t, circuitbreaker.Open, b.State("Hello"))
+	time.Sleep(15 * time.Millisecond)
+	require.Equal(t, circuitbreaker.HalfOpen, b.State("Hello"))
+
+	// A probe is in progress when the slow call succeeds
+	probe := make(chan struct{})
+	probed := make(chan error)
+	go func() {
+		probed <- b.Do(ctx, "Hello", func(context.Context) error { <-probe; return nil })
+	}()
+	time.Sleep(5 * time.Millisecond)
+	close(release)
+	require.NoError(t, <-done)
+
+	// The slow call isn't a probe, so the circuit stays half-open until the probe succeeds
+	require.Equal(t, circuitbreaker.HalfOpen, b.State("Hello"))
+	require.ErrorIs(t, b.Do(ctx, "Hello", succeed), circuitbreaker.ErrOpen)
+	close(probe)
+	require.NoError(t, <-probed)
+	require.Equal(t, circuitbreaker.Closed, b.State("Hello"))
+}
+
+func TestInterval(t *testing.T) {
+	useTestLogger()
+	b, err := circuitbreaker.NewBreaker("leaf.client.cb", "minreqs=2", "failurerate=1", "interval=10ms")
+	require.NoError(t, err)
+
+	// The counters are reset after each interval
+	require.Error(t, b.Do(context.Background(), "Hello", fail))
+	time.Sleep(15 * time.Millisecond)
+	require.Error(t, b.Do(context.Background(), "Hello", fail))
+	require.Equal(t, circuitbreaker.Closed, b.State("Hello"))
+	require.Error(t, b.Do(context.Background(), "Hello", fail))
+	require.Equal(t, circuitbreaker.Open, b.State("Hello"))
+}
+
+func TestFallback(t *testing.T) {
+	useTestLogger()
+	b, err := circuitbreaker.NewBreaker("leaf.client.cb", "minreqs=1", "failurerate=1", `fallback.GetUser=[{"Name":"anonymous"},3]`, "fallback.Ping=[]")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	type User struct{ Name string }
+	var user User
+	var count int
+	err = b.Do(ctx, "GetUser", fail)
+	require.ErrorIs(t, b.Fallback(ctx, "GetUser", err, &user, &count), errBoom)
+
+	err = b.Do(ctx, "GetUser", succeed)
+	require.NoError(t, b.Fallback(ctx, "GetUser", err, &user, &count))
+	require.Equal(t, User{"anonymous"}, user)
+	require.Equal(t, 3, count)
+
+	// Methods without fallbacks return ErrOpen
+	b.Do(ctx, "Hello", fail)
+	err = b.Do(ctx, "Hello", succeed)
+	require.ErrorIs(t, b.Fallback(ctx, "Hello", err), circuitbreaker.ErrOpen)
+
+	b.Do(ctx, "Ping", fail)
+	err = b.Do(ctx, "Ping", succeed)
+	require.NoError(t, b.Fallback(ctx, "Ping", err))
+}
+
+func TestSetParameters(t *testing.T) {
+	useTestLogger()
+	b, err := circuitbreaker.NewBreaker("leaf.client.cb", "minreqs=1", "failurerate=1")
+	require.NoError(t, err)
+	require.NoError(t, b.SetParameters(map[string]string{"minreqs": "2", "halfopenprobes": "3"}))
+	require.Equal(t, map[string]string{"minreqs": "2", "failurerate": "1", "halfopenprobes": "3"}, b.Parameters())
+
+	require.Error(t, b.Do(context.Background(), "Hello", fail))
+	require.Equal(t, circuitbreaker.Closed, b.State("Hello"))
+
+	require.Error(t, b.SetParameters(map[string]string{"minreqs": "1", "failurerate": "2"}))
+	require.Equal(t, "2", b.Parameters()["minreqs"])
+}
+
+func TestInvalidOptions(t *testing.T) {
+	for _, opts := range [][]string{
+		{"minreqs"},
+		{"minreqs=0"},
+		{"failurerate=0"},
+		{"failurerate=1.5"},
+		{"interval=0s"},
+		{"cooldown=soon"},
+		{"halfopenprobes=0"},
+		{"permethod=sometimes"},
+		{"fallback.Hello={}"},
+		{"fallback.=[]"},
+		{"threshold=3"},
+	} {
+		_, err := circuitbreaker.NewBreaker("leaf.client.cb", opts...)
+		require.Error(t, err, "options %v", opts)
+	}
+}
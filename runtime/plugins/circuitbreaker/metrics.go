@@ -0,0 +1,67 @@
+package circuitbreaker
+
+import (
+	"context"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Transition, rejection and fallback counters for a [Breaker], plus a gauge reporting the state of
+// each of its circuits.  All measurements are attributed with the breaker's name and the method.
+//
+// The instruments are created on first use; see [backend.LazyMeter].
+type breakerMetrics struct {
+	breaker     *Breaker
+	meter       backend.LazyMeter
+	transitions metric.Int64Counter
+	rejections  metric.Int64Counter
+	fallbacks   metric.Int64Counter
+}
+
+func (m *breakerMetrics) init(ctx context.Context) {
+	m.meter.Init(ctx, "circuitbreaker", m.register)
+}
+
+func (m *breakerMetrics) register(meter metric.Meter) (err error) {
+	if m.transitions, err = meter.Int64Counter("circuitbreaker_transitions", metric.WithDescription("Number of times a circuit changed state")); err != nil {
+		return err
+	}
+	if m.rejections, err = meter.Int64Counter("circuitbreaker_rejections", metric.WithDescription("Number of calls rejected because their circuit was open")); err != nil {
+		return err
+	}
+	if m.fallbacks, err = meter.Int64Counter("circuitbreaker_fallbacks", metric.WithDescription("Number of rejected calls that returned fallback values")); err != nil {
+		return err
+	}
+	state, err := meter.Int64ObservableGauge("circuitbreaker_state", metric.WithDescription("State of a circuit: 0 is closed, 1 is half-open and 2 is open"))
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		for method, s := range m.breaker.states() {
+			o.ObserveInt64(state, int64(s), m.attrs(method))
+		}
+		return nil
+	}, state)
+	return err
+}
+
+func (m *breakerMetrics) attrs(method string) metric.MeasurementOption {
+	return metric.WithAttributes(attribute.String("breaker", m.breaker.name), attribute.String("method", method))
+}
+
+func (m *breakerMetrics) transitioned(ctx context.Context, method string, from, to State) {
+	m.init(ctx)
+	m.transitions.Add(ctx, 1, m.attrs(method), metric.WithAttributes(attribute.String("from", from.String()), attribute.String("to", to.String())))
+}
+
+func (m *breakerMetrics) rejected(ctx context.Context, method string) {
+	m.init(ctx)
+	m.rejections.Add(ctx, 1, m.attrs(method))
+}
+
+func (m *breakerMetrics) fellBack(ctx context.Context, method string) {
+	m.init(ctx)
+	m.fallbacks.Add(ctx, 1, m.attrs(method))
+}
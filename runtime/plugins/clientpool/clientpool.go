@@ -2,87 +2,451 @@
 //
 // ClientPools do not need to be used directly by application workflow specs.  Instead, this
 // code is included in a compiled application by applying the ClientPool modifier to the wiring spec.
+//
+// Pooled clients can be health-checked before reuse, evicted after an idle timeout or maximum
+// lifetime, and evicted when a call using them fails.  Pool statistics are reported as metrics
+// through [backend.Meter].
 package clientpool
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"errors"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/options"
 )
 
+// Clients can implement HealthChecker to be health-checked by a [ClientPool] before they are reused.
+// A client whose health check fails is evicted from the pool.  The health check can be overridden
+// with [ClientPool.SetHealthCheck].
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
 // A ClientPool that contains up to Capacity clients. Clients are acquired with
 // Pop and returned with Push.
+//
+// Evicted clients that implement [io.Closer] are closed.
 type ClientPool[T any] struct {
-	clients   chan T
-	build     func() (T, error)
-	capacity  int64
-	size      int64
-	available int64
+	build       func() (T, error)
+	healthCheck func(ctx context.Context, client T) error
+	capacity    int64
+
+	name         string
+	idleTimeout  time.Duration
+	maxLifetime  time.Duration
+	evictOnError bool
+
+	// Holds a token for each client that is in use or being built
+	slots chan struct{}
+
+	mu     sync.Mutex
+	idle   []pooledClient[T] // Idle clients, least recently returned first
+	inUse  []pooledClient[T] // Clients acquired with Pop that haven't been returned, least recently acquired first
+	size   int64
+	closed bool
+	done   chan struct{}
+
 	waiting   int64
+	waits     int64
+	waitTime  int64 // nanoseconds
+	builds    int64
+	evictions int64
+
+	metrics poolMetrics[T]
+}
+
+type pooledClient[T any] struct {
+	client   T
+	created  time.Time
+	returned time.Time
+}
+
+// A snapshot of the statistics of a [ClientPool]
+type Stats struct {
+	Capacity  int           // Maximum number of clients
+	Size      int           // Number of clients, whether in use or idle
+	Available int           // Number of idle clients
+	Waiting   int           // Number of callers currently waiting for a client
+	Waits     int64         // Total number of times a caller had to wait for a client
+	WaitTime  time.Duration // Total time callers spent waiting for clients
+	Builds    int64         // Total number of clients built
+	Evictions int64         // Total number of clients evicted
 }
 
-// Instantiates a [ClientPool] that will have up to maxClients client instances.
-// The provided function fn is used to instantiate clients.
+// Instantiates a [ClientPool] that will have up to capacity client instances.
+// The provided function build is used to instantiate clients.
 //
 // Callers acquire a client instance by calling [ClientPool.Pop], and when they
 // are finished with a client, return it to the pool by calling [ClientPool.Push]
-func NewClientPool[T any](capacity int, build func() (T, error)) *ClientPool[T] {
+// or [ClientPool.Release].
+//
+// opts are optional "key=value" strings that configure the pool:
+//   - name=<string> names the pool in its metrics
+//   - idletimeout=<duration> evicts clients that have been idle for longer than the timeout
+//   - maxlifetime=<duration> evicts clients once they are older than the lifetime
+//   - evictonerror=<bool> evicts clients that are released with an error by [ClientPool.Release]
+//
+// If an idle timeout or max lifetime is configured, a background goroutine evicts expired
+// idle clients until the pool is closed with [ClientPool.Close].
+func NewClientPool[T any](capacity int, build func() (T, error), opts ...string) (*ClientPool[T], error) {
 	pool := &ClientPool[T]{
-		clients:   make(chan T, capacity),
-		build:     build,
-		capacity:  int64(capacity),
-		size:      0,
-		available: 0,
-		waiting:   0,
+		build:    build,
+		capacity: int64(capacity),
+		name:     "clientpool",
+		slots:    make(chan struct{}, capacity),
+		done:     make(chan struct{}),
+	}
+	pool.metrics.pool = pool
+	if err := pool.configure(opts); err != nil {
+		return nil, err
+	}
+	if interval := pool.reapInterval(); interval > 0 {
+		go pool.reap(interval)
+	}
+	return pool, nil
+}
+
+func (pool *ClientPool[T]) configure(opts []string) error {
+	if pool.capacity <= 0 {
+		return fmt.Errorf("clientpool capacity must be positive; got %v", pool.capacity)
 	}
-	return pool
+	parsed, err := options.Parse("clientpool", opts)
+	if err != nil {
+		return err
+	}
+	for _, opt := range parsed {
+		key, value := opt.Key, opt.Value
+		var err error
+		switch key {
+		case "name":
+			pool.name = value
+		case "idletimeout":
+			pool.idleTimeout, err = time.ParseDuration(value)
+		case "maxlifetime":
+			pool.maxLifetime, err = time.ParseDuration(value)
+		case "evictonerror":
+			pool.evictOnError, err = strconv.ParseBool(value)
+		default:
+			return fmt.Errorf("unknown clientpool option %v", key)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for clientpool option %v: %v", key, err)
+		}
+	}
+	if pool.idleTimeout < 0 || pool.maxLifetime < 0 {
+		return fmt.Errorf("clientpool idletimeout and maxlifetime must be non-negative")
+	}
+	return nil
+}
+
+// Sets the function used to health-check idle clients before they are reused, overriding
+// [HealthChecker].  A client whose health check returns an error is evicted.
+func (pool *ClientPool[T]) SetHealthCheck(check func(ctx context.Context, client T) error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.healthCheck = check
 }
 
 // Acquires a client from the pool, blocking if all clients are currently in use.
 //
-// When a caller has finished using a client, it *must* call [ClientPool.Push] to
-// return the client to the pool.
+// When a caller has finished using a client, it *must* call [ClientPool.Push] or
+// [ClientPool.Release] to return the client to the pool.
 func (pool *ClientPool[T]) Pop(ctx context.Context) (client T, err error) {
-	// Attempt to immediately reuse an existing client
-	select {
-	case <-ctx.Done():
-		err = errors.New("timeout before client was available")
+	if err = pool.acquire(ctx); err != nil {
 		return
-	case client = <-pool.clients:
-		atomic.AddInt64(&pool.available, -1)
-		return client, nil
-	default:
+	}
+	pool.mu.Lock()
+	closed := pool.closed
+	pool.mu.Unlock()
+	if closed {
+		<-pool.slots
+		return client, errors.New("clientpool is closed")
 	}
 
-	// If the pool isn't at capacity, we can instantiate a new client
-	for curSize := pool.size; curSize < pool.capacity; {
-		if atomic.CompareAndSwapInt64(&pool.size, curSize, curSize+1) {
-			client, err = pool.build()
-			if err != nil {
-				atomic.AddInt64(&pool.size, -1)
-			}
-			return
+	// Reuse an idle client if there is a healthy one
+	for {
+		pooled, found := pool.takeIdle(time.Now())
+		if !found {
+			break
+		}
+		if err := pool.check(ctx, pooled.client); err != nil {
+			// Keep the slot; it is used by the next idle client or a replacement
+			pool.mu.Lock()
+			pool.checkIn(pooled.client)
+			pool.mu.Unlock()
+			pool.discard(ctx, pooled.client, "unhealthy")
+			continue
 		}
-		curSize = pool.size
+		return pooled.client, nil
+	}
+
+	// Otherwise build a new client; holding a slot guarantees the pool isn't at capacity
+	atomic.AddInt64(&pool.size, 1)
+	client, err = pool.build()
+	if err != nil {
+		atomic.AddInt64(&pool.size, -1)
+		<-pool.slots
+		return
+	}
+	pool.mu.Lock()
+	pool.inUse = append(pool.inUse, pooledClient[T]{client: client, created: time.Now()})
+	pool.mu.Unlock()
+	atomic.AddInt64(&pool.builds, 1)
+	pool.metrics.built(ctx)
+	return client, nil
+}
+
+// Acquires a slot, waiting if all clients are in use
+func (pool *ClientPool[T]) acquire(ctx context.Context) error {
+	pool.metrics.init(ctx)
+	select {
+	case <-ctx.Done():
+		return errors.New("timeout before client was available")
+	case pool.slots <- struct{}{}:
+		return nil
+	default:
 	}
 
 	// Pool is at capacity; wait to reuse an existing client
 	atomic.AddInt64(&pool.waiting, 1)
+	defer atomic.AddInt64(&pool.waiting, -1)
+	start := time.Now()
+	defer func() {
+		waited := time.Since(start)
+		atomic.AddInt64(&pool.waits, 1)
+		atomic.AddInt64(&pool.waitTime, int64(waited))
+		pool.metrics.waited(ctx, waited)
+	}()
 	select {
 	case <-ctx.Done():
-		err = errors.New("timeout before client was available")
-	case client = <-pool.clients:
-		atomic.AddInt64(&pool.available, -1)
+		return errors.New("timeout before client was available")
+	case pool.slots <- struct{}{}:
+		return nil
+	}
+}
+
+// Removes and returns the least recently returned idle client, which is then in use, evicting any expired clients
+func (pool *ClientPool[T]) takeIdle(now time.Time) (pooledClient[T], bool) {
+	pool.mu.Lock()
+	var expired []T
+	defer func() {
+		pool.mu.Unlock()
+		for _, client := range expired {
+			pool.discard(context.Background(), client, "expired")
+		}
+	}()
+	for len(pool.idle) > 0 {
+		pooled := pool.idle[0]
+		pool.idle = pool.idle[1:]
+		if pool.expired(pooled, now) {
+			expired = append(expired, pooled.client)
+			continue
+		}
+		pool.inUse = append(pool.inUse, pooled)
+		return pooled, true
 	}
-	atomic.AddInt64(&pool.waiting, -1)
-	return
+	return pooledClient[T]{}, false
+}
+
+// Returns whether an idle client has exceeded the idle timeout or max lifetime; requires pool.mu
+func (pool *ClientPool[T]) expired(pooled pooledClient[T], now time.Time) bool {
+	if pool.idleTimeout > 0 && now.Sub(pooled.returned) > pool.idleTimeout {
+		return true
+	}
+	return pool.maxLifetime > 0 && now.Sub(pooled.created) > pool.maxLifetime
+}
+
+// Removes and returns the entry of an in-use client that is being returned to the pool, or false if no
+// clients are in use; requires pool.mu.  Clients are matched by equality if they are comparable.  Otherwise,
+// or if no entry is equal to client, the least recently acquired entry is used; as the entries only differ in
+// when their clients were created, this can only cause a client to be evicted earlier than its max lifetime.
+func (pool *ClientPool[T]) checkIn(client T) (pooledClient[T], bool) {
+	if len(pool.inUse) == 0 {
+		return pooledClient[T]{}, false
+	}
+	i := 0
+	if v := reflect.ValueOf(any(client)); v.IsValid() && v.Comparable() {
+		for j, pooled := range pool.inUse {
+			if any(pooled.client) == any(client) {
+				i = j
+				break
+			}
+		}
+	}
+	pooled := pool.inUse[i]
+	pool.inUse = append(pool.inUse[:i], pool.inUse[i+1:]...)
+	pooled.client = client
+	return pooled, true
+}
+
+func (pool *ClientPool[T]) check(ctx context.Context, client T) error {
+	pool.mu.Lock()
+	check := pool.healthCheck
+	pool.mu.Unlock()
+	if check != nil {
+		return check(ctx, client)
+	}
+	if checker, isChecker := any(client).(HealthChecker); isChecker {
+		return checker.HealthCheck(ctx)
+	}
+	return nil
 }
 
 // Returns a client to the pool.
+//
+// A client that has exceeded the pool's max lifetime is evicted rather than returned.  Push never
+// blocks; a client pushed when no clients are in use did not come from the pool, and is evicted if the
+// pool is already at capacity.
 func (pool *ClientPool[T]) Push(client T) {
-	atomic.AddInt64(&pool.available, 1)
-	pool.clients <- client
+	now := time.Now()
+	pool.mu.Lock()
+	pooled, fromPool := pool.checkIn(client)
+	if !fromPool {
+		if pool.closed || atomic.LoadInt64(&pool.size) >= pool.capacity {
+			pool.mu.Unlock()
+			pool.close(client)
+			return
+		}
+		atomic.AddInt64(&pool.size, 1)
+		pooled = pooledClient[T]{client: client, created: now}
+	}
+	pooled.returned = now
+	if pool.closed || pool.expired(pooled, now) {
+		pool.mu.Unlock()
+		pool.discard(context.Background(), client, "expired")
+		if fromPool {
+			pool.release()
+		}
+		return
+	}
+	// The client must be idle before its slot is released, so that a waiter reuses it rather than building another
+	pool.idle = append(pool.idle, pooled)
+	pool.mu.Unlock()
+	if fromPool {
+		pool.release()
+	}
+}
+
+// Returns a client to the pool after a call that returned err.  If the pool was configured
+// to evict clients on error and err is not nil, the client is evicted; otherwise this is
+// equivalent to [ClientPool.Push].
+func (pool *ClientPool[T]) Release(client T, err error) {
+	if err != nil && pool.evictOnError {
+		pool.evict(context.Background(), client, "error")
+		return
+	}
+	pool.Push(client)
+}
+
+// Evicts a client acquired with [ClientPool.Pop] rather than returning it to the pool,
+// e.g. because the client is broken.  A new client will be built in its place when needed.
+func (pool *ClientPool[T]) Discard(client T) {
+	pool.evict(context.Background(), client, "discarded")
+}
+
+// Evicts an in-use client and releases its slot
+func (pool *ClientPool[T]) evict(ctx context.Context, client T, reason string) {
+	pool.mu.Lock()
+	_, fromPool := pool.checkIn(client)
+	pool.mu.Unlock()
+	if !fromPool {
+		pool.close(client)
+		return
+	}
+	pool.discard(ctx, client, reason)
+	pool.release()
+}
+
+// Closes a client that has been removed from the pool's idle or in-use clients
+func (pool *ClientPool[T]) discard(ctx context.Context, client T, reason string) {
+	atomic.AddInt64(&pool.size, -1)
+	atomic.AddInt64(&pool.evictions, 1)
+	pool.metrics.evicted(ctx, reason)
+	pool.close(client)
+}
+
+func (pool *ClientPool[T]) release() {
+	select {
+	case <-pool.slots:
+	default:
+	}
+}
+
+func (pool *ClientPool[T]) close(client T) {
+	if closer, isCloser := any(client).(io.Closer); isCloser {
+		closer.Close()
+	}
+}
+
+// Interval at which the background goroutine evicts expired idle clients; 0 if clients don't expire
+func (pool *ClientPool[T]) reapInterval() time.Duration {
+	interval := pool.idleTimeout
+	if interval == 0 || (pool.maxLifetime > 0 && pool.maxLifetime < interval) {
+		interval = pool.maxLifetime
+	}
+	if interval == 0 {
+		return 0
+	}
+	return max(interval/2, 10*time.Millisecond)
+}
+
+func (pool *ClientPool[T]) reap(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-pool.done:
+			return
+		case now := <-ticker.C:
+			pool.evictExpired(now)
+		}
+	}
+}
+
+// Evicts the idle clients that have exceeded the idle timeout or max lifetime
+func (pool *ClientPool[T]) evictExpired(now time.Time) {
+	pool.mu.Lock()
+	var expired []T
+	remaining := pool.idle[:0]
+	for _, pooled := range pool.idle {
+		if pool.expired(pooled, now) {
+			expired = append(expired, pooled.client)
+		} else {
+			remaining = append(remaining, pooled)
+		}
+	}
+	pool.idle = remaining
+	pool.mu.Unlock()
+	for _, client := range expired {
+		pool.discard(context.Background(), client, "expired")
+	}
+}
+
+// Closes the pool, evicting its idle clients and stopping its background goroutine.  Clients
+// that are in use are evicted when they are returned to the pool.
+func (pool *ClientPool[T]) Close() error {
+	pool.mu.Lock()
+	if pool.closed {
+		pool.mu.Unlock()
+		return nil
+	}
+	pool.closed = true
+	close(pool.done)
+	idle := pool.idle
+	pool.idle = nil
+	pool.mu.Unlock()
+	for _, pooled := range idle {
+		pool.discard(context.Background(), pooled.client, "closed")
+	}
+	return pool.metrics.close()
 }
 
 // Returns the capacity of the client pool
@@ -92,10 +456,26 @@ func (pool *ClientPool[T]) Capacity() int {
 
 // Returns the current size of the client pool
 func (pool *ClientPool[T]) Size() int {
-	return int(pool.size)
+	return int(atomic.LoadInt64(&pool.size))
 }
 
 // Returns the current number of available clients in the client pool
 func (pool *ClientPool[T]) Available() int {
-	return int(pool.available)
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	return len(pool.idle)
+}
+
+// Returns the current statistics of the client pool
+func (pool *ClientPool[T]) Stats() Stats {
+	return Stats{
+		Capacity:  pool.Capacity(),
+		Size:      pool.Size(),
+		Available: pool.Available(),
+		Waiting:   int(atomic.LoadInt64(&pool.waiting)),
+		Waits:     atomic.LoadInt64(&pool.waits),
+		WaitTime:  time.Duration(atomic.LoadInt64(&pool.waitTime)),
+		Builds:    atomic.LoadInt64(&pool.builds),
+		Evictions: atomic.LoadInt64(&pool.evictions),
+	}
 }
@@ -2,6 +2,8 @@ package clientpool_test
 
 import (
 	"context"
+	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -32,7 +34,8 @@ func TestClientPool(t *testing.T) {
 	}
 
 	cap := 5
-	pool := clientpool.NewClientPool[*element](cap, build)
+	pool, err := clientpool.NewClientPool[*element](cap, build)
+	require.NoError(t, err)
 
 	ctx, _ := context.WithTimeout(context.Background(), 1*time.Second)
 
@@ -117,3 +120,259 @@ func TestClientPool(t *testing.T) {
 		require.False(t, isDone(ctx), "iteration %v", i)
 	}
 }
+
+type closeableElement struct {
+	i       int
+	healthy bool
+	closed  atomic.Bool
+}
+
+func (e *closeableElement) HealthCheck(ctx context.Context) error {
+	if !e.healthy {
+		return errors.New("unhealthy")
+	}
+	return nil
+}
+
+func (e *closeableElement) Close() error {
+	e.closed.Store(true)
+	return nil
+}
+
+func newCloseablePool(t *testing.T, capacity int, opts ...string) *clientpool.ClientPool[*closeableElement] {
+	i := 0
+	build := func() (*closeableElement, error) {
+		i += 1
+		return &closeableElement{i: i, healthy: true}, nil
+	}
+	pool, err := clientpool.NewClientPool(capacity, build, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func TestHealthCheck(t *testing.T) {
+	ctx := context.Background()
+	pool := newCloseablePool(t, 2)
+
+	e, err := pool.Pop(ctx)
+	require.NoError(t, err)
+	e.healthy = false
+	pool.Push(e)
+
+	// The unhealthy client is evicted and closed, and a new client built in its place
+	e2, err := pool.Pop(ctx)
+	require.NoError(t, err)
+	require.NotSame(t, e, e2)
+	require.True(t, e.closed.Load())
+	require.Equal(t, 1, pool.Size())
+	pool.Push(e2)
+
+	// The health check can be overridden
+	pool.SetHealthCheck(func(ctx context.Context, client *closeableElement) error {
+		return errors.New("always unhealthy")
+	})
+	e3, err := pool.Pop(ctx)
+	require.NoError(t, err)
+	require.True(t, e2.closed.Load())
+	require.False(t, e3.closed.Load())
+
+	stats := pool.Stats()
+	require.Equal(t, int64(3), stats.Builds)
+	require.Equal(t, int64(2), stats.Evictions)
+}
+
+func TestHealthCheckKeepsCapacity(t *testing.T) {
+	ctx := context.Background()
+	pool := newCloseablePool(t, 1)
+
+	e, err := pool.Pop(ctx)
+	require.NoError(t, err)
+	e.healthy = false
+	pool.Push(e)
+
+	// Replacing the unhealthy client must not free the slot for another caller
+	e2, err := pool.Pop(ctx)
+	require.NoError(t, err)
+	require.True(t, e.closed.Load())
+
+	popped := make(chan *closeableElement)
+	go func() {
+		e3, err := pool.Pop(ctx)
+		require.NoError(t, err)
+		popped <- e3
+	}()
+	require.Eventually(t, func() bool { return pool.Stats().Waiting == 1 }, time.Second, time.Millisecond)
+	select {
+	case <-popped:
+		t.Fatal("Pop did not block on a full pool")
+	case <-time.After(20 * time.Millisecond):
+	}
+	require.Equal(t, 1, pool.Size())
+
+	pool.Push(e2)
+	require.Same(t, e2, <-popped)
+	require.Equal(t, 1, pool.Stats().Size)
+}
+
+func TestEvictOnError(t *testing.T) {
+	ctx := context.Background()
+	pool := newCloseablePool(t, 1, "evictonerror=true")
+
+	e, err := pool.Pop(ctx)
+	require.NoError(t, err)
+	pool.Release(e, nil)
+	require.Equal(t, 1, pool.Available())
+
+	e, err = pool.Pop(ctx)
+	require.NoError(t, err)
+	pool.Release(e, errors.New("connection reset"))
+	require.True(t, e.closed.Load())
+	require.Equal(t, 0, pool.Size())
+
+	// The evicted client's slot is freed
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	e2, err := pool.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, e2.i)
+
+	// Discard evicts regardless of configuration
+	pool.Discard(e2)
+	require.True(t, e2.closed.Load())
+	require.Equal(t, 0, pool.Size())
+}
+
+func TestIdleTimeoutAndMaxLifetime(t *testing.T) {
+	ctx := context.Background()
+
+	pool := newCloseablePool(t, 3, "idletimeout=20ms")
+	es := []*closeableElement{}
+	for i := 0; i < 3; i++ {
+		e, err := pool.Pop(ctx)
+		require.NoError(t, err)
+		es = append(es, e)
+	}
+	for _, e := range es {
+		pool.Push(e)
+	}
+	require.Equal(t, 3, pool.Available())
+
+	// Idle clients are evicted in the background, shrinking the pool
+	require.Eventually(t, func() bool { return pool.Size() == 0 }, time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool {
+		for _, e := range es {
+			if !e.closed.Load() {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 5*time.Millisecond)
+
+	pool = newCloseablePool(t, 1, "maxlifetime=20ms")
+	e, err := pool.Pop(ctx)
+	require.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+
+	// Clients that outlive their lifetime are evicted when returned
+	pool.Push(e)
+	require.True(t, e.closed.Load())
+	require.Equal(t, 0, pool.Size())
+}
+
+func TestPushDoesNotBlock(t *testing.T) {
+	pool := newCloseablePool(t, 1)
+
+	// Returning a client that didn't come from a full pool evicts it rather than blocking
+	pool.Push(&closeableElement{healthy: true})
+	require.Equal(t, 1, pool.Size())
+	extra := &closeableElement{healthy: true}
+	pool.Push(extra)
+	require.True(t, extra.closed.Load())
+	require.Equal(t, 1, pool.Size())
+	require.Equal(t, 1, pool.Available())
+}
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	pool := newCloseablePool(t, 1)
+
+	e, err := pool.Pop(ctx)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e2, err := pool.Pop(ctx)
+		require.NoError(t, err)
+		pool.Push(e2)
+	}()
+	require.Eventually(t, func() bool { return pool.Stats().Waiting == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	pool.Push(e)
+	<-done
+
+	stats := pool.Stats()
+	require.Equal(t, 0, stats.Waiting)
+	require.Equal(t, int64(1), stats.Waits)
+	require.GreaterOrEqual(t, stats.WaitTime, 10*time.Millisecond)
+	require.Equal(t, int64(1), stats.Builds)
+	require.Equal(t, 1, stats.Size)
+	require.Equal(t, 1, stats.Available)
+
+	// Closing the pool evicts its clients
+	require.NoError(t, pool.Close())
+	require.True(t, e.closed.Load())
+	_, err = pool.Pop(ctx)
+	require.Error(t, err)
+}
+
+func TestInvalidOptions(t *testing.T) {
+	build := func() (*element, error) { return &element{}, nil }
+	for _, opts := range [][]string{{"idletimeout"}, {"idletimeout=soon"}, {"maxlifetime=-1s"}, {"evictonerror=maybe"}, {"size=3"}} {
+		_, err := clientpool.NewClientPool(1, build, opts...)
+		require.Error(t, err, "options %v", opts)
+	}
+	_, err := clientpool.NewClientPool(0, build)
+	require.Error(t, err)
+}
+
+func TestValueClients(t *testing.T) {
+	ctx := context.Background()
+
+	// Clients needn't be comparable
+	slices, err := clientpool.NewClientPool(2, func() ([]int, error) { return []int{1}, nil }, "maxlifetime=1m")
+	require.NoError(t, err)
+	defer slices.Close()
+	s1, err := slices.Pop(ctx)
+	require.NoError(t, err)
+	s2, err := slices.Pop(ctx)
+	require.NoError(t, err)
+	slices.Push(s1)
+	slices.Push(s2)
+	require.Equal(t, 2, slices.Size())
+	require.Equal(t, 2, slices.Available())
+
+	// Clients that are equal are still pooled separately
+	values, err := clientpool.NewClientPool(2, func() (element, error) { return element{}, nil }, "evictonerror=true")
+	require.NoError(t, err)
+	defer values.Close()
+	v1, err := values.Pop(ctx)
+	require.NoError(t, err)
+	v2, err := values.Pop(ctx)
+	require.NoError(t, err)
+	values.Release(v1, errors.New("connection reset"))
+	require.Equal(t, 1, values.Size())
+	values.Push(v2)
+	require.Equal(t, 1, values.Size())
+	require.Equal(t, 1, values.Available())
+
+	// Both slots are free
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = values.Pop(ctx)
+	require.NoError(t, err)
+	_, err = values.Pop(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, values.Size())
+}
@@ -0,0 +1,86 @@
+package clientpool
+
+import (
+	"context"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Build, eviction and wait metrics for a [ClientPool], plus gauges reporting its current size
+// and number of waiters.  All measurements are attributed with the pool's name.
+//
+// The instruments are created on first use; see [backend.LazyMeter].
+type poolMetrics[T any] struct {
+	pool         *ClientPool[T]
+	meter        backend.LazyMeter
+	attrs        metric.MeasurementOption
+	builds       metric.Int64Counter
+	evictions    metric.Int64Counter
+	waitTime     metric.Float64Histogram
+	registration metric.Registration
+}
+
+func (m *poolMetrics[T]) init(ctx context.Context) {
+	m.meter.Init(ctx, "clientpool", m.register)
+}
+
+func (m *poolMetrics[T]) register(meter metric.Meter) (err error) {
+	m.attrs = metric.WithAttributes(attribute.String("pool", m.pool.name))
+	if m.builds, err = meter.Int64Counter("clientpool_builds", metric.WithDescription("Number of clients built")); err != nil {
+		return err
+	}
+	if m.evictions, err = meter.Int64Counter("clientpool_evictions", metric.WithDescription("Number of clients evicted from the pool")); err != nil {
+		return err
+	}
+	if m.waitTime, err = meter.Float64Histogram("clientpool_wait_time", metric.WithDescription("Time spent waiting for a client when the pool is at capacity"), metric.WithUnit("ms")); err != nil {
+		return err
+	}
+	size, err := meter.Int64ObservableGauge("clientpool_size", metric.WithDescription("Number of clients in the pool, whether in use or idle"))
+	if err != nil {
+		return err
+	}
+	available, err := meter.Int64ObservableGauge("clientpool_available", metric.WithDescription("Number of idle clients in the pool"))
+	if err != nil {
+		return err
+	}
+	waiters, err := meter.Int64ObservableGauge("clientpool_waiters", metric.WithDescription("Number of callers waiting for a client"))
+	if err != nil {
+		return err
+	}
+	m.registration, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		stats := m.pool.Stats()
+		attrs := metric.WithAttributes(attribute.String("pool", m.pool.name))
+		o.ObserveInt64(size, int64(stats.Size), attrs)
+		o.ObserveInt64(available, int64(stats.Available), attrs)
+		o.ObserveInt64(waiters, int64(stats.Waiting), attrs)
+		return nil
+	}, size, available, waiters)
+	return err
+}
+
+func (m *poolMetrics[T]) built(ctx context.Context) {
+	m.init(ctx)
+	m.builds.Add(ctx, 1, m.attrs)
+}
+
+func (m *poolMetrics[T]) evicted(ctx context.Context, reason string) {
+	m.init(ctx)
+	m.evictions.Add(ctx, 1, m.attrs, metric.WithAttributes(attribute.String("reason", reason)))
+}
+
+func (m *poolMetrics[T]) waited(ctx context.Context, waited time.Duration) {
+	m.init(ctx)
+	m.waitTime.Record(ctx, float64(waited)/float64(time.Millisecond), m.attrs)
+}
+
+// Unregisters the gauges of a closed pool
+func (m *poolMetrics[T]) close() error {
+	m.init(context.Background())
+	if m.registration != nil {
+		return m.registration.Unregister()
+	}
+	return nil
+}
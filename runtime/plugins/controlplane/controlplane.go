@@ -0,0 +1,46 @@
+// Package controlplane implements the runtime component of Blueprint's control plane plugin.
+//
+// The admin server does not need to be used directly by application workflow specs.  Instead, it is
+// added to a process by the control plane plugin, and serves [control.Handler] so that the parameters
+// of the process's modifiers can be inspected and changed while the process is running.
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/control"
+	"golang.org/x/exp/slog"
+)
+
+// An HTTP server that serves a process's control plane
+type AdminServer struct {
+	Address string
+}
+
+// Instantiates an [AdminServer] that will serve at addr once it is run
+func NewAdminServer(ctx context.Context, addr string) (*AdminServer, error) {
+	return &AdminServer{Address: addr}, nil
+}
+
+// Serves the control plane until ctx is done.
+// Run is called automatically in a separate goroutine by runtime/plugins/golang/namespace.go
+func (s *AdminServer) Run(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:    s.Address,
+		Handler: control.Handler(),
+	}
+
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
+	slog.Info(fmt.Sprintf("Serving control plane at %v", s.Address))
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package faults
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/exp/slog"
+)
+
+// The environment variable containing the address at which to serve the admin endpoint
+const AdminAddrEnvVar = "FAULTS_ADMIN_ADDR"
+
+var adminStarted bool
+
+// Starts the admin endpoint if it is configured and not yet started; requires injectors lock
+func startAdmin() error {
+	addr := os.Getenv(AdminAddrEnvVar)
+	if adminStarted || addr == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to serve fault injection admin endpoint at %v: %w", addr, err)
+	}
+	adminStarted = true
+	go func() {
+		if err := http.Serve(listener, Handler()); err != nil {
+			slog.Error(fmt.Sprintf("fault injection admin endpoint at %v failed: %v", addr, err))
+		}
+	}()
+	slog.Info(fmt.Sprintf("Serving fault injection admin endpoint at %v", addr))
+	return nil
+}
+
+// Returns an HTTP handler for reconfiguring the fault injectors in this process.  Configurations are
+// JSON-encoded [Config] values.
+//   - GET /faults returns the configuration of every injector, keyed by injector name
+//   - GET /faults/<name> returns the configuration of the named injector
+//   - PUT /faults/<name> replaces the configuration of the named injector
+//   - POST /faults/<name>/enable and POST /faults/<name>/disable enable and disable the named injector
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/faults", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		configs := make(map[string]Config)
+		for _, name := range Names() {
+			configs[name] = Get(name).Config()
+		}
+		writeJSON(w, configs)
+	})
+	mux.HandleFunc("/faults/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/faults/")
+		action := ""
+		if injector := Get(name); injector == nil {
+			if i := strings.LastIndex(name, "/"); i >= 0 {
+				name, action = name[:i], name[i+1:]
+			}
+		}
+		injector := Get(name)
+		if injector == nil {
+			http.Error(w, fmt.Sprintf("unknown fault injector %v", name), http.StatusNotFound)
+			return
+		}
+
+		switch {
+		case action == "" && r.Method == http.MethodGet:
+		case action == "" && r.Method == http.MethodPut:
+			var config Config
+			if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := injector.SetConfig(config); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case (action == "enable" || action == "disable") && r.Method == http.MethodPost:
+			injector.SetEnabled(action == "enable")
+		case action != "" && action != "enable" && action != "disable":
+			http.Error(w, fmt.Sprintf("unknown fault injector %v/%v", name, action), http.StatusNotFound)
+			return
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, injector.Config())
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
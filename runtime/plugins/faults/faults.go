@@ -0,0 +1,394 @@
+// Package faults implements the runtime components of Blueprint's fault injection plugin.
+//
+// Fault injectors do not need to be used directly by application workflow specs.  Instead, this
+// code is included in a compiled application by applying the faults modifiers to the wiring spec.
+//
+// An [Injector] injects faults into calls at configurable per-method rates.  The injected faults are:
+//   - [Error]: the call fails with an injected error, without the call being made
+//   - [Abort]: the call is made, but its response is replaced by an injected error
+//   - [Drop]: the call is made, but its response is dropped; the caller waits until its context is done, or
+//     until the injector's drop timeout expires
+//   - [Panic]: the call panics, without the call being made
+//
+// Injectors can be enabled, disabled and reconfigured while the process is running, either with environment
+// variables when the process starts (see [EnvVar]), with the admin HTTP endpoint served at the address
+// in the FAULTS_ADMIN_ADDR environment variable (see [Handler]), or with the process's control plane, with
+// which every injector is registered (see [control.Handler]).
+//
+// [control.Handler]: https://github.com/Blueprint-uServices/blueprint/tree/main/runtime/core/control
+package faults
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/control"
+	"github.com/blueprint-uservices/blueprint/runtime/core/options"
+)
+
+// A kind of fault
+type Fault string
+
+// The kinds of fault that can be injected
+const (
+	None  Fault = ""
+	Error Fault = "error"
+	Abort Fault = "abort"
+	Drop  Fault = "drop"
+	Panic Fault = "panic"
+)
+
+// All of the methods of a service; the rates for [AllMethods] apply to methods without rates of their own
+const AllMethods = "*"
+
+// How long callers of dropped calls wait, if their context isn't done first, unless the injector is
+// configured with a drop timeout of its own
+const DefaultDropTimeout = 30 * time.Second
+
+// The error returned by injected faults; use errors.Is to check whether a call failed due to an injected fault
+var ErrInjected = errors.New("injected fault")
+
+// The probability of each kind of fault being injected into a call.  The rates must sum to at most 1.
+type Rates struct {
+	Error float64 `json:"error,omitempty"`
+	Abort float64 `json:"abort,omitempty"`
+	Drop  float64 `json:"drop,omitempty"`
+	Panic float64 `json:"panic,omitempty"`
+}
+
+// The configuration of an [Injector]
+type Config struct {
+	Enabled     bool             `json:"enabled"`
+	Methods     map[string]Rates `json:"methods"`               // Keyed by method name, or [AllMethods]
+	DropTimeout time.Duration    `json:"droptimeout,omitempty"` // In nanoseconds; 0 means [DefaultDropTimeout]
+}
+
+// Injects faults into calls; safe for concurrent use, including reconfiguration.
+type Injector struct {
+	name   string
+	mu     sync.RWMutex
+	config Config
+	rng    *rand.Rand
+	rngMu  sync.Mutex
+}
+
+// Instantiates an [Injector] named name and registers it with the admin endpoint.  If an injector named
+// name already exists in this process, e.g. because a client is instantiated multiple times by a client
+// pool, that injector is returned instead, so that its configuration is shared.
+//
+// opts are "key=value" strings that configure the injector:
+//   - <fault>=<rate> injects the fault into the given fraction of calls to all methods, e.g. error=0.1
+//   - <method>[,<method>...].<fault>=<rate> injects the fault into calls to the named methods, e.g. GetCart.drop=0.5
+//   - enabled=<bool> enables or disables the injector; defaults to true
+//   - droptimeout=<duration> is the longest that callers of dropped calls wait; defaults to [DefaultDropTimeout]
+//
+// If the environment variable named by [EnvVar] is set, it contains comma-separated options that are applied
+// after opts, e.g. FAULTS_CART_SERVER_FAULTS="error=0.2,GetCart,AddItem.panic=0.01"; a comma only separates
+// options if it is followed by a key=value option, so method lists can be used.
+//
+// If the FAULTS_ADMIN_ADDR environment variable is set, the first injector that is instantiated starts the admin
+// HTTP endpoint at that address; see [Handler].
+func NewInjector(name string, opts ...string) (*Injector, error) {
+	injector := &Injector{
+		name:   name,
+		config: Config{Enabled: true, Methods: make(map[string]Rates)},
+		rng:    rand.New(rand.NewSource(rand.Int63())),
+	}
+	if env := os.Getenv(EnvVar(name)); env != "" {
+		opts = append(opts, splitOptions(env)...)
+	}
+	if err := injector.configure(opts); err != nil {
+		return nil, err
+	}
+	return register(injector)
+}
+
+// Splits a comma-separated list of options.  Commas also separate the methods of an option, so an element
+// without an '=' is part of the option that follows it, e.g. "error=0.1,Get,Put.drop=0.5" contains
+// "error=0.1" and "Get,Put.drop=0.5".
+func splitOptions(list string) []string {
+	var opts []string
+	start := 0
+	elems := strings.Split(list, ",")
+	for i, elem := range elems {
+		elems[i] = strings.TrimSpace(elem)
+		if strings.Contains(elem, "=") || i == len(elems)-1 {
+			opts = append(opts, strings.Join(elems[start:i+1], ","))
+			start = i + 1
+		}
+	}
+	return opts
+}
+
+// Returns the name of the environment variable that configures the injector named name: the name in upper case,
+// with non-alphanumeric characters replaced by underscores and prefixed with FAULTS_.
+func EnvVar(name string) string {
+	return "FAULTS_" + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(name))
+}
+
+func (injector *Injector) configure(opts []string) error {
+	config := injector.Config()
+	var trimmed []string
+	for _, opt := range opts {
+		if opt = strings.TrimSpace(opt); opt != "" {
+			trimmed = append(trimmed, opt)
+		}
+	}
+	parsed, err := options.Parse("faults", trimmed)
+	if err != nil {
+		return err
+	}
+	for _, opt := range parsed {
+		key, value := opt.Key, opt.Value
+		if key == "enabled" {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for faults option %v: %v", key, err)
+			}
+			config.Enabled = enabled
+			continue
+		}
+		if key == "droptimeout" {
+			timeout, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid value for faults option %v: %v", key, err)
+			}
+			config.DropTimeout = timeout
+			continue
+		}
+
+		methods, fault := AllMethods, key
+		if i := strings.LastIndex(key, "."); i >= 0 {
+			methods, fault = key[:i], key[i+1:]
+		}
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid value for faults option %v: %v", key, err)
+		}
+		for _, method := range strings.Split(methods, ",") {
+			rates := config.Methods[method]
+			switch Fault(fault) {
+			case Error:
+				rates.Error = rate
+			case Abort:
+				rates.Abort = rate
+			case Drop:
+				rates.Drop = rate
+			case Panic:
+				rates.Panic = rate
+			default:
+				return fmt.Errorf("unknown faults option %v", key)
+			}
+			config.Methods[method] = rates
+		}
+	}
+	return injector.SetConfig(config)
+}
+
+// Returns the name of the injector
+func (injector *Injector) Name() string {
+	return injector.name
+}
+
+// Returns a copy of the injector's current configuration
+func (injector *Injector) Config() Config {
+	injector.mu.RLock()
+	defer injector.mu.RUnlock()
+	config := Config{Enabled: injector.config.Enabled, Methods: make(map[string]Rates), DropTimeout: injector.config.DropTimeout}
+	for method, rates := range injector.config.Methods {
+		config.Methods[method] = rates
+	}
+	return config
+}
+
+// Replaces the injector's configuration.  Returns an error, leaving the configuration
+// unchanged, if any rates or the drop timeout are invalid.
+func (injector *Injector) SetConfig(config Config) error {
+	if config.DropTimeout < 0 {
+		return fmt.Errorf("the drop timeout must not be negative; got %v", config.DropTimeout)
+	}
+	methods := make(map[string]Rates)
+	for method, rates := range config.Methods {
+		for _, rate := range []float64{rates.Error, rates.Abort, rates.Drop, rates.Panic} {
+			if rate < 0 || rate > 1 {
+				return fmt.Errorf("fault rates must be between 0 and 1; got %v for %v", rate, method)
+			}
+		}
+		if total := rates.Error + rates.Abort + rates.Drop + rates.Panic; total > 1 {
+			return fmt.Errorf("fault rates for %v must sum to at most 1; got %v", method, total)
+		}
+		methods[method] = rates
+	}
+	injector.mu.Lock()
+	defer injector.mu.Unlock()
+	injector.config = Config{Enabled: config.Enabled, Methods: methods, DropTimeout: config.DropTimeout}
+	return nil
+}
+
+// Returns the injector's configuration as options in the format accepted by [NewInjector], e.g.
+// {"enabled": "true", "error": "0.1", "GetCart.drop": "0.5"}.  Implements control.Tunable.
+func (injector *Injector) Parameters() map[string]string {
+	config := injector.Config()
+	params := map[string]string{"enabled": strconv.FormatBool(config.Enabled)}
+	if config.DropTimeout > 0 {
+		params["droptimeout"] = config.DropTimeout.String()
+	}
+	for method, rates := range config.Methods {
+		prefix := method + "."
+		if method == AllMethods {
+			prefix = ""
+		}
+		for _, rate := range []struct {
+			fault Fault
+			rate  float64
+		}{{Error, rates.Error}, {Abort, rates.Abort}, {Drop, rates.Drop}, {Panic, rates.Panic}} {
+			if rate.rate > 0 {
+				params[prefix+string(rate.fault)] = strconv.FormatFloat(rate.rate, 'g', -1, 64)
+			}
+		}
+	}
+	return params
+}
+
+// Applies options in the format accepted by [NewInjector], e.g. {"GetCart.error": "0.2"}, leaving the
+// rates of faults that are not named unchanged.  Returns an error, leaving the configuration unchanged,
+// if any of the options are invalid.  Implements control.Tunable.
+func (injector *Injector) SetParameters(params map[string]string) error {
+	var keys []string
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var opts []string
+	for _, key := range keys {
+		opts = append(opts, key+"="+params[key])
+	}
+	return injector.configure(opts)
+}
+
+// Enables or disables the injector without changing its rates
+func (injector *Injector) SetEnabled(enabled bool) {
+	injector.mu.Lock()
+	defer injector.mu.Unlock()
+	injector.config.Enabled = enabled
+}
+
+// Chooses the fault to inject into a call to method, if any
+func (injector *Injector) Choose(method string) Fault {
+	injector.mu.RLock()
+	rates, exists := injector.config.Methods[method]
+	if !exists {
+		rates = injector.config.Methods[AllMethods]
+	}
+	enabled := injector.config.Enabled
+	injector.mu.RUnlock()
+	if !enabled {
+		return None
+	}
+
+	injector.rngMu.Lock()
+	p := injector.rng.Float64()
+	injector.rngMu.Unlock()
+	for _, candidate := range []struct {
+		fault Fault
+		rate  float64
+	}{{Error, rates.Error}, {Abort, rates.Abort}, {Drop, rates.Drop}, {Panic, rates.Panic}} {
+		if p < candidate.rate {
+			return candidate.fault
+		}
+		p -= candidate.rate
+	}
+	return None
+}
+
+// Called before a call to method is made.  Chooses the fault to inject into the call, and returns it
+// to be passed to [Injector.After].  If the chosen fault prevents the call from being made, returns an
+// error or panics.
+func (injector *Injector) Before(ctx context.Context, method string) (Fault, error) {
+	fault := injector.Choose(method)
+	switch fault {
+	case Error:
+		return fault, fmt.Errorf("%w: %v in %v.%v", ErrInjected, fault, injector.name, method)
+	case Panic:
+		panic(fmt.Sprintf("%v: %v in %v.%v", ErrInjected, fault, injector.name, method))
+	}
+	return fault, nil
+}
+
+// Called after a call to method has been made with the fault returned by [Injector.Before].  Returns
+// the error that the call should return, which is err if no fault was injected.  If the call's response
+// is dropped, waits until ctx is done or the drop timeout expires.
+func (injector *Injector) After(ctx context.Context, method string, fault Fault, err error) error {
+	switch fault {
+	case Abort:
+		return fmt.Errorf("%w: %v in %v.%v", ErrInjected, fault, injector.name, method)
+	case Drop:
+		injector.mu.RLock()
+		timeout := injector.config.DropTimeout
+		injector.mu.RUnlock()
+		if timeout == 0 {
+			timeout = DefaultDropTimeout
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %v in %v.%v: %w", ErrInjected, fault, injector.name, method, ctx.Err())
+		case <-timer.C:
+			return fmt.Errorf("%w: %v in %v.%v after %v", ErrInjected, fault, injector.name, method, timeout)
+		}
+	}
+	return err
+}
+
+var injectors = struct {
+	sync.RWMutex
+	byName map[string]*Injector
+}{byName: make(map[string]*Injector)}
+
+// Registers injector, or returns the existing injector with the same name
+func register(injector *Injector) (*Injector, error) {
+	injectors.Lock()
+	defer injectors.Unlock()
+	if existing, exists := injectors.byName[injector.name]; exists {
+		return existing, nil
+	}
+	if err := startAdmin(); err != nil {
+		return nil, err
+	}
+	injectors.byName[injector.name] = injector
+	control.Register(injector.name, "faults", injector)
+	return injector, nil
+}
+
+// Returns the names of the injectors in this process, in sorted order
+func Names() []string {
+	injectors.RLock()
+	defer injectors.RUnlock()
+	var names []string
+	for name := range injectors.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns the injector named name in this process, or nil if there is no such injector
+func Get(name string) *Injector {
+	injectors.RLock()
+	defer injectors.RUnlock()
+	return injectors.byName[name]
+}
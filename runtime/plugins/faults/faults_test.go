@@ -0,0 +1,217 @@
+package faults_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/control"
+	"github.com/blueprint-uservices/blueprint/runtime/plugins/faults"
+	"github.com/stretchr/testify/require"
+)
+
+func countFaults(injector *faults.Injector, method string, n int) map[faults.Fault]int {
+	counts := make(map[faults.Fault]int)
+	for i := 0; i < n; i++ {
+		counts[injector.Choose(method)]++
+	}
+	return counts
+}
+
+func TestRates(t *testing.T) {
+	injector, err := faults.NewInjector(t.Name(), "error=0.2", "abort=0.1", "GetCart,AddItem.drop=0.5")
+	require.NoError(t, err)
+
+	counts := countFaults(injector, "Checkout", 10000)
+	require.InDelta(t, 2000, counts[faults.Error], 250)
+	require.InDelta(t, 1000, counts[faults.Abort], 200)
+	require.InDelta(t, 7000, counts[faults.None], 300)
+	require.Zero(t, counts[faults.Drop])
+
+	// Methods with their own rates don't use the rates for all methods
+	counts = countFaults(injector, "AddItem", 10000)
+	require.InDelta(t, 5000, counts[faults.Drop], 300)
+	require.Zero(t, counts[faults.Error])
+
+	// Disabled injectors don't inject faults
+	injector.SetEnabled(false)
+	require.Equal(t, 1000, countFaults(injector, "AddItem", 1000)[faults.None])
+}
+
+func TestBeforeAfter(t *testing.T) {
+	ctx := context.Background()
+	callErr := errors.New("call failed")
+
+	failures, err := faults.NewInjector(t.Name()+".error", "error=1")
+	require.NoError(t, err)
+	fault, err := failures.Before(ctx, "Get")
+	require.Equal(t, faults.Error, fault)
+	require.ErrorIs(t, err, faults.ErrInjected)
+
+	aborts, err := faults.NewInjector(t.Name()+".abort", "abort=1")
+	require.NoError(t, err)
+	fault, err = aborts.Before(ctx, "Get")
+	require.NoError(t, err)
+	require.ErrorIs(t, aborts.After(ctx, "Get", fault, nil), faults.ErrInjected)
+
+	drops, err := faults.NewInjector(t.Name()+".drop", "drop=1")
+	require.NoError(t, err)
+	fault, err = drops.Before(ctx, "Get")
+	require.NoError(t, err)
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	err = drops.After(timeout, "Get", fault, nil)
+	require.ErrorIs(t, err, faults.ErrInjected)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Dropped calls without a deadline wait until the drop timeout expires
+	drops, err = faults.NewInjector(t.Name()+".droptimeout", "drop=1", "droptimeout=10ms")
+	require.NoError(t, err)
+	fault, err = drops.Before(ctx, "Get")
+	require.NoError(t, err)
+	start := time.Now()
+	require.ErrorIs(t, drops.After(ctx, "Get", fault, nil), faults.ErrInjected)
+	require.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	require.Equal(t, "10ms", drops.Parameters()["droptimeout"])
+
+	panics, err := faults.NewInjector(t.Name()+".panic", "panic=1")
+	require.NoError(t, err)
+	require.Panics(t, func() { panics.Before(ctx, "Get") })
+
+	// Without a fault, the call's error is returned
+	none, err := faults.NewInjector(t.Name() + ".none")
+	require.NoError(t, err)
+	fault, err = none.Before(ctx, "Get")
+	require.NoError(t, err)
+	require.Equal(t, callErr, none.After(ctx, "Get", fault, callErr))
+}
+
+func TestEnvironment(t *testing.T) {
+	name := t.Name() + ".server.faults"
+	require.Equal(t, "FAULTS_TESTENVIRONMENT_SERVER_FAULTS", faults.EnvVar(name))
+	t.Setenv(faults.EnvVar(name), "error=0.5, Get.panic=0.1")
+
+	injector, err := faults.NewInjector(name, "error=0.1", "enabled=false")
+	require.NoError(t, err)
+	require.Equal(t, faults.Config{
+		Enabled: false,
+		Methods: map[string]faults.Rates{"*": {Error: 0.5}, "Get": {Panic: 0.1}},
+	}, injector.Config())
+
+	// Injectors with the same name are shared
+	same, err := faults.NewInjector(name)
+	require.NoError(t, err)
+	require.Same(t, injector, same)
+	require.Equal(t, injector, faults.Get(name))
+	require.Contains(t, faults.Names(), name)
+}
+
+func TestEnvironmentMethodLists(t *testing.T) {
+	name := t.Name() + ".server.faults"
+	t.Setenv(faults.EnvVar(name), "GetCart,PlaceOrder.error=0.5,drop=0.1, Get , Put.abort=0.2")
+
+	injector, err := faults.NewInjector(name)
+	require.NoError(t, err)
+	require.Equal(t, faults.Config{
+		Enabled: true,
+		Methods: map[string]faults.Rates{
+			"*":          {Drop: 0.1},
+			"GetCart":    {Error: 0.5},
+			"PlaceOrder": {Error: 0.5},
+			"Get":        {Abort: 0.2},
+			"Put":        {Abort: 0.2},
+		},
+	}, injector.Config())
+
+	// A trailing method list without a rate is invalid
+	t.Setenv(faults.EnvVar(name+".invalid"), "error=0.5,Get")
+	_, err = faults.NewInjector(name + ".invalid")
+	require.Error(t, err)
+}
+
+func TestAdminHandler(t *testing.T) {
+	name := t.Name() + ".server.faults"
+	injector, err := faults.NewInjector(name, "error=0.1")
+	require.NoError(t, err)
+	server := httptest.NewServer(faults.Handler())
+	defer server.Close()
+
+	do := func(method, path, body string) (int, faults.Config) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var config faults.Config
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&config))
+		}
+		return resp.StatusCode, config
+	}
+
+	status, config := do("GET", "/faults/"+name, "")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, 0.1, config.Methods["*"].Error)
+
+	status, _ = do("PUT", "/faults/"+name, `{"enabled": true, "methods": {"Get": {"drop": 0.3}}}`)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, map[string]faults.Rates{"Get": {Drop: 0.3}}, injector.Config().Methods)
+
+	status, config = do("POST", "/faults/"+name+"/disable", "")
+	require.Equal(t, http.StatusOK, status)
+	require.False(t, config.Enabled)
+	require.False(t, injector.Config().Enabled)
+
+	// Invalid configurations are rejected
+	status, _ = do("PUT", "/faults/"+name, `{"enabled": true, "methods": {"Get": {"drop": 0.8, "error": 0.8}}}`)
+	require.Equal(t, http.StatusBadRequest, status)
+	status, _ = do("GET", "/faults/missing", "")
+	require.Equal(t, http.StatusNotFound, status)
+
+	resp, err := http.Get(server.URL + "/faults")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var configs map[string]faults.Config
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&configs))
+	require.Contains(t, configs, name)
+}
+
+func TestControlPlane(t *testing.T) {
+	injector, err := faults.NewInjector("test.control.faults", "error=0.1", "GetCart.drop=0.5")
+	require.NoError(t, err)
+
+	modifier, exists := control.Get("test.control.faults")
+	require.True(t, exists)
+	require.Equal(t, "faults", modifier.Kind)
+	require.Equal(t, map[string]string{"enabled": "true", "error": "0.1", "GetCart.drop": "0.5"}, modifier.Parameters)
+
+	require.NoError(t, control.Set("test.control.faults", map[string]string{"GetCart.drop": "0", "abort": "0.2", "enabled": "false"}))
+	config := injector.Config()
+	require.False(t, config.Enabled)
+	require.Equal(t, faults.Rates{Error: 0.1, Abort: 0.2}, config.Methods[faults.AllMethods])
+	require.Equal(t, faults.Rates{}, config.Methods["GetCart"])
+
+	require.Error(t, control.Set("test.control.faults", map[string]string{"error": "0.9", "enabled": "true"}))
+	require.False(t, injector.Config().Enabled)
+}
+
+func TestInvalidOptions(t *testing.T) {
+	for _, opts := range [][]string{
+		{"error"},
+		{"error=often"},
+		{"error=1.5"},
+		{"error=0.6", "abort=0.6"},
+		{"Get.crash=0.1"},
+		{"enabled=maybe"},
+		{"droptimeout=soon"},
+		{"droptimeout=-1s"},
+	} {
+		_, err := faults.NewInjector(t.Name(), opts...)
+		require.Error(t, err, "options %v", opts)
+	}
+}
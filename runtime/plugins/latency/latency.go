@@ -0,0 +1,323 @@
+// Package latency implements the runtime components of Blueprint's latency injection plugin.
+//
+// The latency injector does not need to be used directly by application workflow specs.  Instead, this
+// code is included in a compiled application by applying one of the latency modifiers to the wiring spec.
+//
+// An [Injector] samples latencies from a configurable distribution, and can be restricted to a random
+// fraction of requests and to a subset of a service's methods.
+package latency
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/options"
+	"golang.org/x/exp/rand"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// The distributions that latencies can be sampled from
+const (
+	Fixed       = "fixed"
+	Uniform     = "uniform"
+	Normal      = "normal"
+	Exponential = "exponential"
+	Pareto      = "pareto"
+	Empirical   = "empirical"
+)
+
+// Injects latency into requests; safe for concurrent use, including reconfiguration.
+type Injector struct {
+	mu       sync.Mutex
+	options  map[string]string
+	rng      *rand.Rand
+	sample   func() time.Duration
+	fraction float64
+	methods  map[string]bool // nil if latency is injected into all methods
+}
+
+// Instantiates an [Injector].
+//
+// opts are "key=value" strings that configure the injector.  The distribution option selects the distribution
+// that latencies are sampled from, which determines the remaining required options:
+//   - distribution=fixed (the default) requires latency=<duration>
+//   - distribution=uniform requires min=<duration> and max=<duration>
+//   - distribution=normal requires mean=<duration> and stddev=<duration>
+//   - distribution=exponential requires mean=<duration>
+//   - distribution=pareto requires scale=<duration>, the minimum latency, and shape=<float>
+//   - distribution=empirical requires file=<path> to a CSV file of latency samples, which are selected uniformly at
+//     random.  The first column of each row is a sample, either a duration such as 12.5ms, or a number of milliseconds.
+//     A header row is permitted.
+//
+// Sampled latencies that are negative are treated as zero.  The following options are optional:
+//   - fraction=<float> injects latency into only a random fraction of requests, between 0 and 1; defaults to 1
+//   - methods=<name,name,...> injects latency into only the named methods; defaults to all methods
+//   - seed=<int> seeds the random number generator; defaults to the current time
+//
+// The options can be changed while the process is running with [Injector.SetParameters].
+func NewInjector(opts ...string) (*Injector, error) {
+	values, err := options.Map("latency", opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := values["seed"]; !exists {
+		values["seed"] = strconv.FormatUint(uint64(time.Now().UnixNano()), 10)
+	}
+
+	injector := &Injector{}
+	if err := injector.configure(values); err != nil {
+		return nil, err
+	}
+	return injector, nil
+}
+
+// Replaces the injector's configuration with values.  Returns an error, leaving the configuration
+// unchanged, if any of the values are invalid.
+func (injector *Injector) configure(values map[string]string) error {
+	options := make(map[string]string)
+	for key, value := range values {
+		options[key] = value
+	}
+
+	seed, err := strconv.ParseUint(options["seed"], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid value for latency option seed: %v", err)
+	}
+	rng := rand.New(rand.NewSource(seed))
+	fraction := 1.0
+	if value, exists := options["fraction"]; exists {
+		if fraction, err = strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("invalid value for latency option fraction: %v", err)
+		}
+		if fraction < 0 || fraction > 1 {
+			return fmt.Errorf("latency fraction must be between 0 and 1; got %v", fraction)
+		}
+	}
+	var methods map[string]bool
+	if value, exists := options["methods"]; exists {
+		methods = make(map[string]bool)
+		for _, method := range strings.Split(value, ",") {
+			if method = strings.TrimSpace(method); method != "" {
+				methods[method] = true
+			}
+		}
+	}
+
+	distribution := options["distribution"]
+	if distribution == "" {
+		distribution = Fixed
+	}
+	remaining := make(map[string]string)
+	for key, value := range options {
+		remaining[key] = value
+	}
+	sample, err := distributionFunc(distribution, rng, remaining)
+	if err != nil {
+		return err
+	}
+
+	// Any remaining options are not recognized
+	for _, key := range []string{"seed", "fraction", "methods", "distribution"} {
+		delete(remaining, key)
+	}
+	for key := range remaining {
+		return fmt.Errorf("unknown option %v for %v latency distribution", key, distribution)
+	}
+
+	injector.mu.Lock()
+	defer injector.mu.Unlock()
+	injector.options = options
+	injector.rng = rng
+	injector.sample = sample
+	injector.fraction = fraction
+	injector.methods = methods
+	return nil
+}
+
+// Returns the injector's current options.  Implements control.Tunable.
+func (injector *Injector) Parameters() map[string]string {
+	injector.mu.Lock()
+	defer injector.mu.Unlock()
+	params := make(map[string]string)
+	for key, value := range injector.options {
+		params[key] = value
+	}
+	return params
+}
+
+// Changes the named options, leaving the others unchanged, e.g. {"latency": "20ms"}.  If the distribution is
+// changed, the options of the previous distribution are discarded.  An empty value removes an optional option,
+// e.g. {"methods": ""}.  Returns an error, leaving the configuration unchanged, if any of the options are invalid.
+// Implements control.Tunable.
+func (injector *Injector) SetParameters(params map[string]string) error {
+	values := injector.Parameters()
+	current := values["distribution"]
+	if current == "" {
+		current = Fixed
+	}
+	if distribution, exists := params["distribution"]; exists && distribution != current {
+		for key := range values {
+			if key != "seed" && key != "fraction" && key != "methods" {
+				delete(values, key)
+			}
+		}
+	}
+	for key, value := range params {
+		if value == "" {
+			delete(values, key)
+		} else {
+			values[key] = value
+		}
+	}
+	return injector.configure(values)
+}
+
+// Returns a func that samples from the named distribution using rng, removing the distribution's options from values
+func distributionFunc(name string, rng *rand.Rand, values map[string]string) (func() time.Duration, error) {
+	var err error
+	duration := func(key string) float64 {
+		value, exists := values[key]
+		delete(values, key)
+		if err != nil {
+			return 0
+		}
+		if !exists {
+			err = fmt.Errorf("%v latency distribution requires option %v", name, key)
+			return 0
+		}
+		var d time.Duration
+		if d, err = time.ParseDuration(value); err != nil {
+			err = fmt.Errorf("invalid value for latency option %v: %v", key, err)
+		}
+		return float64(d)
+	}
+
+	var sample func() time.Duration
+	switch name {
+	case Fixed:
+		latency := time.Duration(duration("latency"))
+		sample = func() time.Duration { return latency }
+	case Uniform:
+		dist := distuv.Uniform{Min: duration("min"), Max: duration("max"), Src: rng}
+		if err == nil && dist.Min > dist.Max {
+			err = fmt.Errorf("uniform latency distribution min must not exceed max")
+		}
+		sample = func() time.Duration { return time.Duration(dist.Rand()) }
+	case Normal:
+		dist := distuv.Normal{Mu: duration("mean"), Sigma: duration("stddev"), Src: rng}
+		if err == nil && dist.Sigma < 0 {
+			err = fmt.Errorf("normal latency distribution stddev must be non-negative")
+		}
+		sample = func() time.Duration { return time.Duration(dist.Rand()) }
+	case Exponential:
+		mean := duration("mean")
+		if err == nil && mean <= 0 {
+			err = fmt.Errorf("exponential latency distribution mean must be positive")
+		}
+		dist := distuv.Exponential{Rate: 1 / mean, Src: rng}
+		sample = func() time.Duration { return time.Duration(dist.Rand()) }
+	case Pareto:
+		scale := duration("scale")
+		shape, parseErr := strconv.ParseFloat(values["shape"], 64)
+		if _, exists := values["shape"]; err == nil && !exists {
+			err = fmt.Errorf("pareto latency distribution requires option shape")
+		} else if err == nil && parseErr != nil {
+			err = fmt.Errorf("invalid value for latency option shape: %v", parseErr)
+		} else if err == nil && (scale <= 0 || shape <= 0) {
+			err = fmt.Errorf("pareto latency distribution scale and shape must be positive")
+		}
+		delete(values, "shape")
+		dist := distuv.Pareto{Xm: scale, Alpha: shape, Src: rng}
+		sample = func() time.Duration { return time.Duration(min(dist.Rand(), math.MaxInt64)) }
+	case Empirical:
+		path, exists := values["file"]
+		delete(values, "file")
+		if !exists {
+			return nil, fmt.Errorf("empirical latency distribution requires option file")
+		}
+		var samples []time.Duration
+		if samples, err = LoadSamples(path); err == nil && len(samples) == 0 {
+			err = fmt.Errorf("empirical latency distribution file %v contains no samples", path)
+		}
+		sample = func() time.Duration { return samples[rng.Intn(len(samples))] }
+	default:
+		return nil, fmt.Errorf("unknown latency distribution %v", name)
+	}
+	return sample, err
+}
+
+// Loads latency samples from the CSV file at path.  The first column of each row is a sample, either a
+// duration such as 12.5ms, or a number of milliseconds.  If the first row is not a sample, it is treated
+// as a header and skipped.
+func LoadSamples(path string) ([]time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	var samples []time.Duration
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return samples, nil
+		} else if err != nil {
+			return nil, err
+		}
+		value := strings.TrimSpace(record[0])
+		sample, err := time.ParseDuration(value)
+		if err != nil {
+			ms, floatErr := strconv.ParseFloat(value, 64)
+			if floatErr != nil {
+				if row == 1 {
+					continue
+				}
+				return nil, fmt.Errorf("invalid latency sample %q on row %v of %v", value, row, path)
+			}
+			sample = time.Duration(ms * float64(time.Millisecond))
+		}
+		samples = append(samples, sample)
+	}
+}
+
+// Returns the latency to inject into a call to method, which is zero if the call is not selected
+// for latency injection.
+func (injector *Injector) Sample(method string) time.Duration {
+	injector.mu.Lock()
+	defer injector.mu.Unlock()
+	if injector.methods != nil && !injector.methods[method] {
+		return 0
+	}
+	if injector.fraction < 1 && injector.rng.Float64() >= injector.fraction {
+		return 0
+	}
+	return max(injector.sample(), 0)
+}
+
+// Injects latency into a call to method by sleeping for a sampled duration.  Returns early with
+// the context's error if ctx is done before the latency has elapsed.
+func (injector *Injector) Inject(ctx context.Context, method string) error {
+	latency := injector.Sample(method)
+	if latency <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,161 @@
+package latency_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/plugins/latency"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns the mean, minimum and maximum of n samples
+func sampleStats(injector *latency.Injector, n int) (mean, lowest, highest time.Duration) {
+	lowest = time.Duration(1<<63 - 1)
+	var total time.Duration
+	for i := 0; i < n; i++ {
+		d := injector.Sample("Method")
+		total += d
+		lowest = min(lowest, d)
+		highest = max(highest, d)
+	}
+	return total / time.Duration(n), lowest, highest
+}
+
+func TestDistributions(t *testing.T) {
+	n := 20000
+
+	fixed, err := latency.NewInjector("latency=5ms")
+	require.NoError(t, err)
+	mean, lowest, highest := sampleStats(fixed, 10)
+	require.Equal(t, 5*time.Millisecond, mean)
+	require.Equal(t, lowest, highest)
+
+	uniform, err := latency.NewInjector("distribution=uniform", "min=10ms", "max=20ms", "seed=1")
+	require.NoError(t, err)
+	mean, lowest, highest = sampleStats(uniform, n)
+	require.InDelta(t, 15*time.Millisecond, mean, float64(500*time.Microsecond))
+	require.GreaterOrEqual(t, lowest, 10*time.Millisecond)
+	require.LessOrEqual(t, highest, 20*time.Millisecond)
+
+	// Negative samples are treated as zero
+	normal, err := latency.NewInjector("distribution=normal", "mean=10ms", "stddev=2ms", "seed=1")
+	require.NoError(t, err)
+	mean, lowest, _ = sampleStats(normal, n)
+	require.InDelta(t, 10*time.Millisecond, mean, float64(500*time.Microsecond))
+	require.GreaterOrEqual(t, lowest, time.Duration(0))
+
+	exponential, err := latency.NewInjector("distribution=exponential", "mean=10ms", "seed=1")
+	require.NoError(t, err)
+	mean, _, _ = sampleStats(exponential, n)
+	require.InDelta(t, 10*time.Millisecond, mean, float64(time.Millisecond))
+
+	// The mean of a pareto distribution is shape * scale / (shape - 1)
+	pareto, err := latency.NewInjector("distribution=pareto", "scale=10ms", "shape=3", "seed=1")
+	require.NoError(t, err)
+	mean, lowest, _ = sampleStats(pareto, n)
+	require.InDelta(t, 15*time.Millisecond, mean, float64(time.Millisecond))
+	require.GreaterOrEqual(t, lowest, 10*time.Millisecond)
+}
+
+func TestEmpirical(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "samples.csv")
+	require.NoError(t, os.WriteFile(file, []byte("latency,source\n1ms,a\n2.5,b\n3ms\n"), 0644))
+
+	samples, err := latency.LoadSamples(file)
+	require.NoError(t, err)
+	require.Equal(t, []time.Duration{time.Millisecond, 2500 * time.Microsecond, 3 * time.Millisecond}, samples)
+
+	empirical, err := latency.NewInjector("distribution=empirical", "file="+file, "seed=1")
+	require.NoError(t, err)
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		seen[empirical.Sample("Method")] = true
+	}
+	require.Len(t, seen, 3)
+	for _, sample := range samples {
+		require.True(t, seen[sample])
+	}
+
+	// Invalid samples after the first row are errors
+	require.NoError(t, os.WriteFile(file, []byte("1ms\nslow\n"), 0644))
+	_, err = latency.NewInjector("distribution=empirical", "file="+file)
+	require.Error(t, err)
+}
+
+func TestFractionAndMethods(t *testing.T) {
+	injector, err := latency.NewInjector("latency=1ms", "fraction=0.25", "methods=Get, Put", "seed=1")
+	require.NoError(t, err)
+
+	injected := 0
+	for i := 0; i < 10000; i++ {
+		if injector.Sample("Get") > 0 {
+			injected++
+		}
+		require.Zero(t, injector.Sample("Delete"))
+	}
+	require.InDelta(t, 2500, injected, 200)
+
+	never, err := latency.NewInjector("latency=1ms", "fraction=0")
+	require.NoError(t, err)
+	require.Zero(t, never.Sample("Get"))
+}
+
+func TestInject(t *testing.T) {
+	injector, err := latency.NewInjector("latency=20ms")
+	require.NoError(t, err)
+
+	start := time.Now()
+	require.NoError(t, injector.Inject(context.Background(), "Method"))
+	require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	// Injection stops when the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, injector.Inject(ctx, "Method"), context.DeadlineExceeded)
+}
+
+func TestInvalidOptions(t *testing.T) {
+	for _, opts := range [][]string{
+		{},
+		{"latency"},
+		{"latency=fast"},
+		{"latency=1ms", "fraction=2"},
+		{"latency=1ms", "mean=1ms"},
+		{"distribution=gamma"},
+		{"distribution=uniform", "min=2ms", "max=1ms"},
+		{"distribution=normal", "mean=1ms"},
+		{"distribution=exponential", "mean=0s"},
+		{"distribution=pareto", "scale=1ms"},
+		{"distribution=pareto", "scale=1ms", "shape=-1"},
+		{"distribution=empirical"},
+		{"distribution=empirical", "file=missing.csv"},
+	} {
+		_, err := latency.NewInjector(opts...)
+		require.Error(t, err, "options %v", opts)
+	}
+}
+
+func TestSetParameters(t *testing.T) {
+	injector, err := latency.NewInjector("latency=5ms", "methods=Get", "seed=1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"latency": "5ms", "methods": "Get", "seed": "1"}, injector.Parameters())
+
+	require.NoError(t, injector.SetParameters(map[string]string{"latency": "7ms"}))
+	require.Equal(t, 7*time.Millisecond, injector.Sample("Get"))
+	require.Zero(t, injector.Sample("Put"))
+
+	// Changing the distribution discards the previous distribution's options
+	require.NoError(t, injector.SetParameters(map[string]string{"distribution": "uniform", "min": "1ms", "max": "2ms", "methods": ""}))
+	require.Equal(t, map[string]string{"distribution": "uniform", "min": "1ms", "max": "2ms", "seed": "1"}, injector.Parameters())
+	sample := injector.Sample("Put")
+	require.GreaterOrEqual(t, sample, time.Millisecond)
+	require.LessOrEqual(t, sample, 2*time.Millisecond)
+
+	// Invalid changes leave the configuration unchanged
+	require.Error(t, injector.SetParameters(map[string]string{"max": "0s"}))
+	require.Error(t, injector.SetParameters(map[string]string{"stddev": "1ms"}))
+	require.Equal(t, "2ms", injector.Parameters()["max"])
+}
@@ -2,9 +2,13 @@
 package memcached
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"math"
 	"sync"
+	"time"
 
 	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
 	"github.com/bradfitz/gomemcache/memcache"
@@ -27,13 +31,94 @@ func NewMemcachedClient(ctx context.Context, serverAddress string) (*Memcached,
 
 // Implements the backend.Cache interface
 func (m *Memcached) Put(ctx context.Context, key string, value interface{}) error {
+	return m.PutWithTTL(ctx, key, value, 0)
+}
+
+// Implements the backend.Cache interface
+func (m *Memcached) PutWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if ttl < 0 {
+		return fmt.Errorf("invalid ttl %v for key %v", ttl, key)
+	}
 	marshaled_val, err := json.Marshal(value)
 	if err != nil {
 		return err
 	}
-	return m.Client.Set(&memcache.Item{Key: key, Value: marshaled_val})
+	item := &memcache.Item{Key: key, Value: marshaled_val}
+	setExpiry(item, ttl)
+	return m.Client.Set(item)
+}
+
+// Implements the backend.Cache interface
+func (m *Memcached) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if ttl < 0 {
+		return false, fmt.Errorf("invalid ttl %v for key %v", ttl, key)
+	}
+	for {
+		it, err := m.Client.Get(key)
+		if err == memcache.ErrCacheMiss {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// Touch cannot update the flags, so rewrite the item to record the new expiry
+		setExpiry(it, ttl)
+		err = m.Client.CompareAndSwap(it)
+		if err == memcache.ErrCASConflict {
+			continue
+		}
+		if err == memcache.ErrNotStored {
+			// Deleted or expired since the Get
+			return false, nil
+		}
+		return err == nil, err
+	}
 }
 
+// Implements the backend.Cache interface
+func (m *Memcached) TTL(ctx context.Context, key string) (time.Duration, bool, error) {
+	it, err := m.Client.Get(key)
+	if err == memcache.ErrCacheMiss {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if it.Flags == 0 {
+		// Key exists but has no expiry
+		return 0, true, nil
+	}
+	ttl := time.Until(time.Unix(int64(it.Flags), 0))
+	if ttl <= 0 {
+		// memcached expiry has one-second granularity; the key is about to be evicted
+		return 0, false, nil
+	}
+	return ttl, true, nil
+}
+
+// memcached has no command for querying the remaining TTL of a key, so the
+// absolute expiry time (in unix seconds) is stored in the item's flags.  A flags
+// value of 0 means the item has no expiry.
+func setExpiry(item *memcache.Item, ttl time.Duration) {
+	if ttl == 0 {
+		item.Flags = 0
+		item.Expiration = 0
+		return
+	}
+	seconds := int64(math.Ceil(ttl.Seconds()))
+	expiresAt := time.Now().Unix() + seconds
+	item.Flags = uint32(expiresAt)
+	if seconds > maxRelativeExpiration {
+		// memcached interprets expirations longer than 30 days as absolute unix timestamps
+		item.Expiration = int32(expiresAt)
+	} else {
+		item.Expiration = int32(seconds)
+	}
+}
+
+// The longest expiration, in seconds, that memcached treats as relative to the current time
+const maxRelativeExpiration = 60 * 60 * 24 * 30
+
 // Implements the backend.Cache interface
 func (m *Memcached) Get(ctx context.Context, key string, value interface{}) (bool, error) {
 	it, err := m.Client.Get(key)
@@ -52,6 +137,64 @@ func (m *Memcached) Incr(ctx context.Context, key string) (int64, error) {
 	return int64(val), err
 }
 
+// Implements the backend.Cache interface
+//
+// memcached does not support negative values; decrementing a key below 0 will set it to 0.
+func (m *Memcached) Decr(ctx context.Context, key string) (int64, error) {
+	return m.IncrBy(ctx, key, -1)
+}
+
+// Implements the backend.Cache interface
+//
+// memcached does not support negative values; decrementing a key below 0 will set it to 0.
+func (m *Memcached) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	var val uint64
+	var err error
+	if delta >= 0 {
+		val, err = m.Client.Increment(key, uint64(delta))
+	} else {
+		val, err = m.Client.Decrement(key, uint64(-delta))
+	}
+	return int64(val), err
+}
+
+// Implements the backend.Cache interface
+func (m *Memcached) CompareAndSwap(ctx context.Context, key string, oldValue interface{}, newValue interface{}) (bool, error) {
+	old_val, err := json.Marshal(oldValue)
+	if err != nil {
+		return false, err
+	}
+	new_val, err := json.Marshal(newValue)
+	if err != nil {
+		return false, err
+	}
+	for {
+		it, err := m.Client.Get(key)
+		if err == memcache.ErrCacheMiss {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// memcached's decr can leave trailing spaces in the stored value, e.g. decrementing "10" yields "9 "
+		if !bytes.Equal(bytes.TrimSpace(it.Value), old_val) {
+			return false, nil
+		}
+		it.Value = new_val
+		setExpiry(it, 0)
+		err = m.Client.CompareAndSwap(it)
+		if err == memcache.ErrCASConflict {
+			// The value was modified concurrently; compare against the new value
+			continue
+		}
+		if err == memcache.ErrNotStored {
+			// Deleted or expired since the Get
+			return false, nil
+		}
+		return err == nil, err
+	}
+}
+
 // Implements the backend.Cache interface
 func (m *Memcached) Delete(ctx context.Context, key string) error {
 	return m.Client.Delete(key)
@@ -3,6 +3,7 @@ package memcached
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -159,3 +160,95 @@ func TestMemcachedMset(t *testing.T) {
 		t.Errorf("Incorrect value received from server. Expected: {7 NotVaastav}, Actual: %v", val1)
 	}
 }
+
+func TestMemcachedTTL(t *testing.T) {
+	ctx := context.Background()
+	memcached, err := NewMemcachedClient(ctx, "localhost:11211")
+	if err != nil {
+		t.Error(err)
+	}
+	err = memcached.PutWithTTL(ctx, "ttlKey", 6, 2*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	ttl, exists, err := memcached.TTL(ctx, "ttlKey")
+	assert.True(t, exists)
+	assert.NoError(t, err)
+	if ttl <= 0 || ttl > 2*time.Second {
+		t.Errorf("Incorrect TTL received from server. Expected: (0s, 2s], Actual: %v", ttl)
+	}
+
+	exists, err = memcached.Expire(ctx, "ttlKey", 0)
+	assert.True(t, exists)
+	assert.NoError(t, err)
+	ttl, exists, err = memcached.TTL(ctx, "ttlKey")
+	assert.True(t, exists)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	exists, err = memcached.Expire(ctx, "ttlKey", time.Second)
+	assert.True(t, exists)
+	assert.NoError(t, err)
+
+	time.Sleep(2500 * time.Millisecond)
+
+	var val int
+	exists, err = memcached.Get(ctx, "ttlKey", &val)
+	assert.False(t, exists)
+	assert.NoError(t, err)
+
+	exists, err = memcached.Expire(ctx, "ttlKey", time.Second)
+	assert.False(t, exists)
+	assert.NoError(t, err)
+}
+
+func TestMemcachedIncrBy(t *testing.T) {
+	ctx := context.Background()
+	memcached, err := NewMemcachedClient(ctx, "localhost:11211")
+	if err != nil {
+		t.Error(err)
+	}
+	err = memcached.Put(ctx, "counterKey", 5)
+	if err != nil {
+		t.Error(err)
+	}
+	val, err := memcached.IncrBy(ctx, "counterKey", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(15), val)
+	val, err = memcached.Decr(ctx, "counterKey")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(14), val)
+	val, err = memcached.IncrBy(ctx, "counterKey", -4)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), val)
+}
+
+func TestMemcachedCompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	memcached, err := NewMemcachedClient(ctx, "localhost:11211")
+	if err != nil {
+		t.Error(err)
+	}
+	data := someData{ID: 5, Name: "Vaastav"}
+	err = memcached.Put(ctx, "casKey", data)
+	if err != nil {
+		t.Error(err)
+	}
+	swapped, err := memcached.CompareAndSwap(ctx, "casKey", someData{ID: 6, Name: "Vaastav"}, someData{ID: 7, Name: "Vaastav"})
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+
+	swapped, err = memcached.CompareAndSwap(ctx, "casKey", data, someData{ID: 7, Name: "Vaastav"})
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+
+	var resultData someData
+	exists, err := memcached.Get(ctx, "casKey", &resultData)
+	assert.True(t, exists)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), resultData.ID)
+
+	swapped, err = memcached.CompareAndSwap(ctx, "nonexistentCasKey", data, data)
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+}
@@ -131,6 +131,35 @@ func (mc *MongoCollection) FindMany(ctx context.Context, filter bson.D, projecti
 	return &MongoCursor{underlyingResult: cursor}, nil
 }
 
+// Implements the [backend.NoSQLCollection] interface
+func (mc *MongoCollection) Find(ctx context.Context, filter bson.D, opts backend.FindOptions) (backend.NoSQLCursor, error) {
+	findOpts := options.Find()
+	if opts.Projection != nil {
+		findOpts.SetProjection(opts.Projection)
+	}
+	if opts.Sort != nil {
+		findOpts.SetSort(opts.Sort)
+	}
+	if opts.Skip > 0 {
+		findOpts.SetSkip(opts.Skip)
+	}
+	if opts.Limit > 0 {
+		findOpts.SetLimit(opts.Limit)
+	}
+	if opts.BatchSize > 0 {
+		findOpts.SetBatchSize(opts.BatchSize)
+	}
+
+	cursor, err := mc.collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.Err() != nil {
+		return nil, cursor.Err()
+	}
+	return &MongoCursor{underlyingResult: cursor}, nil
+}
+
 // Implements the [backend.NoSQLCollection] interface
 func (mc *MongoCollection) UpdateOne(ctx context.Context, filter bson.D, update bson.D) (int, error) {
 	result, err := mc.collection.UpdateOne(ctx, filter, update)
@@ -184,9 +213,28 @@ func (mc *MongoCollection) ReplaceMany(ctx context.Context, filter bson.D, repla
 	return 0, errors.New("ReplaceMany not implemented")
 }
 
+// Implements the [backend.NoSQLCollection] interface
+func (mc *MongoCollection) Aggregate(ctx context.Context, pipeline []bson.D) (backend.NoSQLCursor, error) {
+	cursor, err := mc.collection.Aggregate(ctx, mongo.Pipeline(pipeline))
+	if err != nil {
+		return nil, err
+	}
+	return &MongoCursor{underlyingResult: cursor}, nil
+}
+
+// Implements the [backend.NoSQLCollection] interface
+func (mc *MongoCollection) CreateIndex(ctx context.Context, keys bson.D, unique bool) (string, error) {
+	model := mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetUnique(unique),
+	}
+	return mc.collection.Indexes().CreateOne(ctx, model)
+}
+
 // Implements the [backend.NoSQLCursor] interface as a client-wrapper to the Cursor returned by a mongodb server
 type MongoCursor struct {
 	underlyingResult interface{}
+	consumed         bool // true once Next has returned a SingleResult
 }
 
 // Implements the [backend.NoSQLCursor] interface
@@ -216,3 +264,30 @@ func (mr *MongoCursor) All(ctx context.Context, objs interface{}) error {
 		return errors.New("result does not return a Cursor")
 	}
 }
+
+// Implements the [backend.NoSQLCursor] interface
+func (mr *MongoCursor) Next(ctx context.Context, obj interface{}) (bool, error) {
+	switch v := mr.underlyingResult.(type) {
+	case *mongo.Cursor:
+		if v.Next(ctx) {
+			return true, v.Decode(obj)
+		}
+		return false, v.Err()
+	case *mongo.SingleResult:
+		if mr.consumed {
+			return false, nil
+		}
+		mr.consumed = true
+		return mr.One(ctx, obj)
+	default:
+		return false, errors.New("result has no decode method")
+	}
+}
+
+// Implements the [backend.NoSQLCursor] interface
+func (mr *MongoCursor) Close(ctx context.Context) error {
+	if v, isCursor := mr.underlyingResult.(*mongo.Cursor); isCursor {
+		return v.Close(ctx)
+	}
+	return nil
+}
@@ -4,7 +4,11 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/blueprint-uservices/blueprint/runtime/core/migrations"
+	"github.com/blueprint-uservices/blueprint/runtime/core/options"
 	"github.com/jmoiron/sqlx"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -17,7 +21,26 @@ type MySqlDB struct {
 }
 
 // Instantiates a new [MySqlDB] instance that stores query data in a MySqlDB instance
-func NewMySqlDB(ctx context.Context, addr string, name string, username string, password string) (*MySqlDB, error) {
+//
+// opts are optional "key=value" strings that configure the client:
+//   - migrations=<path> applies the versioned migrations in the directory at path that have not yet been
+//     applied to the database; see [migrations.Migrate].  Returns an error if the migrations fail
+func NewMySqlDB(ctx context.Context, addr string, name string, username string, password string, opts ...string) (*MySqlDB, error) {
+	var migrationsDir string
+	parsed, err := options.Parse("mysql", opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range parsed {
+		key, value := opt.Key, opt.Value
+		switch key {
+		case "migrations":
+			migrationsDir = value
+		default:
+			return nil, fmt.Errorf("unknown mysql option %v", key)
+		}
+	}
+
 	db, err := sqlx.Open("mysql", username+":"+password+"@tcp("+addr+")/")
 	if err != nil {
 		return nil, err
@@ -39,7 +62,40 @@ func NewMySqlDB(ctx context.Context, addr string, name string, username string,
 		return nil, err
 	}
 
-	return &MySqlDB{name: name, db: db}, nil
+	s := &MySqlDB{name: name, db: db}
+	if migrationsDir != "" {
+		if err := s.migrate(ctx, migrationsDir); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Applies the migrations in dir while holding an advisory lock on the database, so that clients in different
+// processes that start at the same time don't race to apply the same migrations
+func (s *MySqlDB) migrate(ctx context.Context, dir string) error {
+	// Advisory locks belong to the session, so the lock is taken and released on a dedicated connection
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	lock := "blueprint_migrations." + s.name
+	if len(lock) > 64 {
+		lock = lock[:64] // MySQL's limit on the length of lock names
+	}
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, -1);`, lock).Scan(&acquired); err != nil {
+		return fmt.Errorf("unable to lock %v to apply migrations: %w", s.name, err)
+	}
+	if acquired.Int64 != 1 {
+		return fmt.Errorf("unable to lock %v to apply migrations", s.name)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK(?);`, lock)
+
+	return migrations.Migrate(ctx, s, dir)
 }
 
 // Exec implements backend.RelationalDB
@@ -66,3 +122,55 @@ func (s *MySqlDB) Select(ctx context.Context, dst interface{}, query string, arg
 func (s *MySqlDB) Get(ctx context.Context, dst interface{}, query string, args ...any) error {
 	return s.db.GetContext(ctx, dst, query, args...)
 }
+
+// BeginTx implements backend.RelationalDB
+//
+// opts are passed to the mysql server, which supports the read uncommitted, read committed, repeatable read
+// and serializable isolation levels, and read-only transactions.  The default isolation level is repeatable read.
+func (s *MySqlDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (backend.RelationalTx, error) {
+	tx, err := s.db.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &MySqlTx{tx: tx}, nil
+}
+
+// A transaction on a [MySqlDB], started by [MySqlDB.BeginTx]
+type MySqlTx struct {
+	tx *sqlx.Tx
+}
+
+// Exec implements backend.RelationalTx
+func (t *MySqlTx) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, query, args...)
+}
+
+// Query implements backend.RelationalTx
+func (t *MySqlTx) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
+// Prepare implements backend.RelationalTx
+func (t *MySqlTx) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.tx.PrepareContext(ctx, query)
+}
+
+// Select implements backend.RelationalTx
+func (t *MySqlTx) Select(ctx context.Context, dst interface{}, query string, args ...any) error {
+	return t.tx.SelectContext(ctx, dst, query, args...)
+}
+
+// Get implements backend.RelationalTx
+func (t *MySqlTx) Get(ctx context.Context, dst interface{}, query string, args ...any) error {
+	return t.tx.GetContext(ctx, dst, query, args...)
+}
+
+// Commit implements backend.RelationalTx
+func (t *MySqlTx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback implements backend.RelationalTx
+func (t *MySqlTx) Rollback() error {
+	return t.tx.Rollback()
+}
@@ -2,8 +2,12 @@ package mysql
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"testing"
 
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+
 	"github.com/stretchr/testify/require"
 )
 
@@ -61,3 +65,61 @@ func TestRelDB(t *testing.T) {
 
 	require.False(t, rows.Next())
 }
+
+// Test requires a functional mysql instance to be already running
+func TestTransactions(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := NewMySqlDB(ctx, "127.0.0.1:3306", "TestRelDB", "root", "pass")
+	require.NoError(t, err)
+
+	batch := []string{
+		`CREATE TABLE IF NOT EXISTS account (id INT PRIMARY KEY, balance INT);`,
+		`DELETE FROM account;`,
+		`INSERT INTO account (id, balance) VALUES (1, 100), (2, 0);`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(ctx, b)
+		require.NoError(t, err)
+	}
+
+	errInsufficientFunds := errors.New("insufficient funds")
+	transfer := func(tx backend.RelationalTx, amount int) error {
+		if _, err := tx.Exec(ctx, `UPDATE account SET balance = balance - ? WHERE id = 1;`, amount); err != nil {
+			return err
+		}
+		var balance int
+		if err := tx.Get(ctx, &balance, `SELECT balance FROM account WHERE id = 1;`); err != nil {
+			return err
+		}
+		if balance < 0 {
+			return errInsufficientFunds
+		}
+		_, err := tx.Exec(ctx, `UPDATE account SET balance = balance + ? WHERE id = 2;`, amount)
+		return err
+	}
+	balances := func() []int {
+		var balances []int
+		require.NoError(t, db.Select(ctx, &balances, `SELECT balance FROM account ORDER BY id;`))
+		return balances
+	}
+
+	err = backend.WithTx(ctx, db, &sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx backend.RelationalTx) error {
+		return transfer(tx, 30)
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{70, 30}, balances())
+
+	err = backend.WithTx(ctx, db, nil, func(tx backend.RelationalTx) error {
+		return transfer(tx, 100)
+	})
+	require.ErrorIs(t, err, errInsufficientFunds)
+	require.Equal(t, []int{70, 30}, balances())
+
+	// Writes are rejected by read-only transactions
+	err = backend.WithTx(ctx, db, &sql.TxOptions{ReadOnly: true}, func(tx backend.RelationalTx) error {
+		return transfer(tx, 10)
+	})
+	require.Error(t, err)
+	require.Equal(t, []int{70, 30}, balances())
+}
@@ -0,0 +1,259 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/blueprint-uservices/blueprint/runtime/core/options"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// The default time a received message stays unacknowledged before it is redelivered
+const defaultVisibilityTimeout = 30 * time.Second
+
+// How often Receive polls a subscriber group's queue while it is empty
+const pollInterval = 10 * time.Millisecond
+
+// Implements a [backend.PubSub] that uses the rabbitmq package.
+//
+// Each topic is a fanout exchange, and each subscriber group is a quorum queue bound to the exchange,
+// named "<topic>.<group>".  Dead-lettering uses rabbitmq's delivery limit and dead-letter exchange,
+// so the maximum number of deliveries is enforced by the rabbitmq server.
+//
+// Visibility timeouts are enforced by the client: a message that is not acknowledged in time is
+// rejected back to its queue.  Messages are fetched from the server one at a time by Receive, rather
+// than prefetched by a consumer, so that every message held by the client is timed, and messages that
+// have not been received remain available to other clients.
+type RabbitPubSub struct {
+	sync.Mutex
+	conn     *amqp.Connection
+	ch       *amqp.Channel // used for declarations and publishing
+	subs     map[string]*subscription
+	declared map[string]bool // topics whose exchanges have been declared
+
+	visibility    time.Duration
+	maxDeliveries int // 0 if messages can be redelivered indefinitely
+}
+
+// A subscriber group's queue.  Each subscription has its own channel, because delivery tags are
+// scoped to a channel.
+type subscription struct {
+	sync.Mutex
+	ch       *amqp.Channel
+	queue    string
+	inflight map[uint64]*time.Timer // received deliveries awaiting Ack or Nack, by delivery tag
+}
+
+// Instantiates a new [RabbitPubSub] that provides a pub/sub interface via a RabbitMQ instance
+//
+// opts are optional "key=value" strings that configure the backend:
+//   - visibility=<duration> is how long a received message may go unacknowledged before it is
+//     redelivered, e.g. "visibility=10s"; defaults to 30s
+//   - maxdeliveries=<int> is how many times a message is delivered before it is moved to the
+//     dead-letter topic; defaults to 0, meaning messages can be redelivered indefinitely
+func NewRabbitPubSub(ctx context.Context, addr string, opts ...string) (*RabbitPubSub, error) {
+	ps := &RabbitPubSub{
+		subs:       make(map[string]*subscription),
+		declared:   make(map[string]bool),
+		visibility: defaultVisibilityTimeout,
+	}
+	parsed, err := options.Parse("rabbitmq pubsub", opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range parsed {
+		key, value := opt.Key, opt.Value
+		var err error
+		switch key {
+		case "visibility":
+			ps.visibility, err = time.ParseDuration(value)
+		case "maxdeliveries":
+			ps.maxDeliveries, err = strconv.Atoi(value)
+		default:
+			return nil, fmt.Errorf("unknown rabbitmq pubsub option %v", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for rabbitmq pubsub option %v: %v", key, err)
+		}
+	}
+	if ps.visibility <= 0 || ps.maxDeliveries < 0 {
+		return nil, fmt.Errorf("rabbitmq pubsub visibility must be positive and maxdeliveries non-negative")
+	}
+
+	ps.conn, err = amqp.Dial("amqp://guest:guest@" + addr + "/")
+	if err != nil {
+		return nil, err
+	}
+	ps.ch, err = ps.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+// Publish implements backend.PubSub
+func (ps *RabbitPubSub) Publish(ctx context.Context, topic string, item interface{}) error {
+	raw_bytes, err := getBytes(item)
+	if err != nil {
+		return err
+	}
+	ps.Lock()
+	defer ps.Unlock()
+	if err := ps.declareTopic(topic); err != nil {
+		return err
+	}
+	publish_msg := amqp.Publishing{ContentType: "text/plain", DeliveryMode: amqp.Persistent, Body: raw_bytes}
+	return ps.ch.PublishWithContext(ctx, topic, "", false, false, publish_msg)
+}
+
+// Subscribe implements backend.PubSub
+func (ps *RabbitPubSub) Subscribe(ctx context.Context, topic string, group string) error {
+	_, err := ps.subscribe(topic, group)
+	return err
+}
+
+// Receive implements backend.PubSub
+func (ps *RabbitPubSub) Receive(ctx context.Context, topic string, group string, dst interface{}) (string, bool, error) {
+	sub, err := ps.subscribe(topic, group)
+	if err != nil {
+		return "", false, err
+	}
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	for {
+		v, ok, err := sub.ch.Get(sub.queue, false)
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			sub.receive(v.DeliveryTag, ps.visibility)
+			val, err := decodeBytes(v.Body)
+			if err != nil {
+				return "", true, err
+			}
+			return strconv.FormatUint(v.DeliveryTag, 10), true, backend.CopyResult(val, dst)
+		}
+		select {
+		case <-poll.C:
+		case <-ctx.Done():
+			return "", false, nil
+		}
+	}
+}
+
+// Ack implements backend.PubSub
+func (ps *RabbitPubSub) Ack(ctx context.Context, topic string, group string, id string) error {
+	sub, tag, err := ps.settle(topic, group, id)
+	if err != nil {
+		return err
+	}
+	return sub.ch.Ack(tag, false)
+}
+
+// Nack implements backend.PubSub
+func (ps *RabbitPubSub) Nack(ctx context.Context, topic string, group string, id string) error {
+	sub, tag, err := ps.settle(topic, group, id)
+	if err != nil {
+		return err
+	}
+	return sub.ch.Nack(tag, false, true)
+}
+
+// Removes an in-flight delivery, returning its subscription and delivery tag
+func (ps *RabbitPubSub) settle(topic string, group string, id string) (*subscription, uint64, error) {
+	ps.Lock()
+	sub, exists := ps.subs[queueName(topic, group)]
+	ps.Unlock()
+	tag, err := strconv.ParseUint(id, 10, 64)
+	if exists && err == nil {
+		sub.Lock()
+		defer sub.Unlock()
+		// If the timer already fired then the delivery was rejected on visibility timeout
+		if timer, inflight := sub.inflight[tag]; inflight && timer.Stop() {
+			delete(sub.inflight, tag)
+			return sub, tag, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("unknown or expired delivery %v for %v/%v", id, topic, group)
+}
+
+// Returns the subscription for the subscriber group, declaring its queue if necessary
+func (ps *RabbitPubSub) subscribe(topic string, group string) (*subscription, error) {
+	ps.Lock()
+	defer ps.Unlock()
+	name := queueName(topic, group)
+	if sub, exists := ps.subs[name]; exists {
+		return sub, nil
+	}
+
+	dlx := backend.DeadLetterTopic(topic)
+	if err := ps.declareTopic(topic); err != nil {
+		return nil, err
+	}
+	if err := ps.declareTopic(dlx); err != nil {
+		return nil, err
+	}
+	args := amqp.Table{"x-queue-type": "quorum", "x-dead-letter-exchange": dlx}
+	if ps.maxDeliveries > 0 {
+		// rabbitmq's delivery limit counts redeliveries, not deliveries
+		args["x-delivery-limit"] = ps.maxDeliveries - 1
+	} else if ps.serverVersion() >= 4 {
+		// Since rabbitmq 4.0, quorum queues have a default delivery limit, which -1 disables
+		args["x-delivery-limit"] = -1
+	}
+	q, err := ps.ch.QueueDeclare(name, true, false, false, false, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := ps.ch.QueueBind(q.Name, "", topic, false, nil); err != nil {
+		return nil, err
+	}
+
+	ch, err := ps.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	sub := &subscription{ch: ch, queue: q.Name, inflight: make(map[uint64]*time.Timer)}
+	ps.subs[name] = sub
+	return sub, nil
+}
+
+// Declares the fanout exchange for topic, if it hasn't already been declared.  The caller must hold the lock.
+func (ps *RabbitPubSub) declareTopic(topic string) error {
+	if ps.declared[topic] {
+		return nil
+	}
+	if err := ps.ch.ExchangeDeclare(topic, "fanout", true, false, false, false, nil); err != nil {
+		return err
+	}
+	ps.declared[topic] = true
+	return nil
+}
+
+// Returns the major version of the rabbitmq server, or 0 if the server didn't report its version
+func (ps *RabbitPubSub) serverVersion() int {
+	version, _ := ps.conn.Properties["version"].(string)
+	major, _ := strconv.Atoi(strings.Split(version, ".")[0])
+	return major
+}
+
+// Tracks a received delivery, rejecting it back to the queue if it isn't settled within the visibility timeout
+func (sub *subscription) receive(tag uint64, visibility time.Duration) {
+	sub.Lock()
+	defer sub.Unlock()
+	sub.inflight[tag] = time.AfterFunc(visibility, func() {
+		sub.Lock()
+		defer sub.Unlock()
+		delete(sub.inflight, tag)
+		sub.ch.Nack(tag, false, true)
+	})
+}
+
+func queueName(topic string, group string) string {
+	return topic + "." + group
+}
@@ -0,0 +1,75 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishReceive(t *testing.T) {
+	ctx := context.Background()
+
+	ps, err := NewRabbitPubSub(ctx, "localhost:5672")
+	require.NoError(t, err)
+	require.NoError(t, ps.Subscribe(ctx, "topic", "first"))
+	require.NoError(t, ps.Subscribe(ctx, "topic", "second"))
+
+	snd := "hello"
+	require.NoError(t, ps.Publish(ctx, "topic", snd))
+
+	// Every group receives a copy of the message
+	for _, group := range []string{"first", "second"} {
+		var rcv string
+		id, success, err := ps.Receive(ctx, "topic", group, &rcv)
+		require.NoError(t, err)
+		require.True(t, success)
+		require.Equal(t, snd, rcv)
+		require.NoError(t, ps.Ack(ctx, "topic", group, id))
+	}
+}
+
+func TestNackDeadLetter(t *testing.T) {
+	ctx := context.Background()
+
+	ps, err := NewRabbitPubSub(ctx, "localhost:5672", "maxdeliveries=2", "visibility=100ms")
+	require.NoError(t, err)
+	require.NoError(t, ps.Subscribe(ctx, "dltopic", "group"))
+	require.NoError(t, ps.Subscribe(ctx, backend.DeadLetterTopic("dltopic"), "group"))
+
+	snd := "hello"
+	require.NoError(t, ps.Publish(ctx, "dltopic", snd))
+
+	{
+		// A nacked message is redelivered
+		var rcv string
+		id, success, err := ps.Receive(ctx, "dltopic", "group", &rcv)
+		require.NoError(t, err)
+		require.True(t, success)
+		require.NoError(t, ps.Nack(ctx, "dltopic", "group", id))
+	}
+
+	{
+		// An unacknowledged message is redelivered after the visibility timeout; it has now
+		// exceeded its deliveries so is dead-lettered
+		var rcv string
+		id, success, err := ps.Receive(ctx, "dltopic", "group", &rcv)
+		require.NoError(t, err)
+		require.True(t, success)
+		time.Sleep(200 * time.Millisecond)
+		require.Error(t, ps.Ack(ctx, "dltopic", "group", id))
+	}
+
+	{
+		var rcv string
+		timeoutCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+		defer cancel()
+		id, success, err := ps.Receive(timeoutCtx, backend.DeadLetterTopic("dltopic"), "group", &rcv)
+		require.NoError(t, err)
+		require.True(t, success)
+		require.Equal(t, snd, rcv)
+		require.NoError(t, ps.Ack(ctx, backend.DeadLetterTopic("dltopic"), "group", id))
+	}
+}
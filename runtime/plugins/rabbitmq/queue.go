@@ -1,4 +1,5 @@
-// Package rabbitmq provides a client-wrapper implementation of the [backend.Queue] interface for a rabbitmq server.
+// Package rabbitmq provides client-wrapper implementations of the [backend.Queue] and [backend.PubSub]
+// interfaces for a rabbitmq server.
 package rabbitmq
 
 import (
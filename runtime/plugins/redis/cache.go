@@ -4,6 +4,8 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"time"
 
 	redis_impl "github.com/go-redis/redis/v8"
 )
@@ -26,12 +28,52 @@ func NewRedisCacheClient(ctx context.Context, addr string) (*RedisCache, error)
 
 // Implements the backend.Cache interface
 func (r *RedisCache) Put(ctx context.Context, key string, value interface{}) error {
+	return r.PutWithTTL(ctx, key, value, 0)
+}
+
+// Implements the backend.Cache interface
+func (r *RedisCache) PutWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if ttl < 0 {
+		return fmt.Errorf("invalid ttl %v for key %v", ttl, key)
+	}
 	val, err := json.Marshal(value)
 	if err != nil {
 		return err
 	}
 	val_str := string(val)
-	return r.client.Set(ctx, key, val_str, 0).Err()
+	return r.client.Set(ctx, key, val_str, ttl).Err()
+}
+
+// Implements the backend.Cache interface
+func (r *RedisCache) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if ttl < 0 {
+		return false, fmt.Errorf("invalid ttl %v for key %v", ttl, key)
+	} else if ttl == 0 {
+		// PERSIST reports false for keys that exist without an expiry, so check existence separately
+		n, err := r.client.Exists(ctx, key).Result()
+		if err != nil || n == 0 {
+			return false, err
+		}
+		return true, r.client.Persist(ctx, key).Err()
+	}
+	return r.client.PExpire(ctx, key, ttl).Result()
+}
+
+// Implements the backend.Cache interface
+func (r *RedisCache) TTL(ctx context.Context, key string) (time.Duration, bool, error) {
+	ttl, err := r.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, false, err
+	}
+	switch ttl {
+	case -2:
+		// Key doesn't exist
+		return 0, false, nil
+	case -1:
+		// Key exists but has no expiry
+		return 0, true, nil
+	}
+	return ttl, true, nil
 }
 
 // Implements the backend.Cache interface
@@ -52,6 +94,40 @@ func (r *RedisCache) Incr(ctx context.Context, key string) (int64, error) {
 	return r.client.Incr(ctx, key).Result()
 }
 
+// Implements the backend.Cache interface
+func (r *RedisCache) Decr(ctx context.Context, key string) (int64, error) {
+	return r.client.Decr(ctx, key).Result()
+}
+
+// Implements the backend.Cache interface
+func (r *RedisCache) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	return r.client.IncrBy(ctx, key, delta).Result()
+}
+
+// Compares the stored value with ARGV[1] and replaces it with ARGV[2] if they match.
+// Runs atomically on the redis server.
+var compareAndSwap = redis_impl.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// Implements the backend.Cache interface
+func (r *RedisCache) CompareAndSwap(ctx context.Context, key string, oldValue interface{}, newValue interface{}) (bool, error) {
+	old_val, err := json.Marshal(oldValue)
+	if err != nil {
+		return false, err
+	}
+	new_val, err := json.Marshal(newValue)
+	if err != nil {
+		return false, err
+	}
+	swapped, err := compareAndSwap.Run(ctx, r.client, []string{key}, string(old_val), string(new_val)).Int()
+	return swapped == 1, err
+}
+
 // Implements the backend.Cache interface
 func (r *RedisCache) Delete(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
@@ -205,3 +205,95 @@ func TestRedisPerformance(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestRedisTTL(t *testing.T) {
+	ctx := context.Background()
+	redis, err := NewRedisCacheClient(ctx, "localhost:6379")
+	if err != nil {
+		t.Error(err)
+	}
+	err = redis.PutWithTTL(ctx, "ttlKey", 6, 2*time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+	ttl, exists, err := redis.TTL(ctx, "ttlKey")
+	assert.True(t, exists)
+	assert.NoError(t, err)
+	if ttl <= 0 || ttl > 2*time.Second {
+		t.Errorf("Incorrect TTL received from server. Expected: (0s, 2s], Actual: %v", ttl)
+	}
+
+	exists, err = redis.Expire(ctx, "ttlKey", 0)
+	assert.True(t, exists)
+	assert.NoError(t, err)
+	ttl, exists, err = redis.TTL(ctx, "ttlKey")
+	assert.True(t, exists)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	exists, err = redis.Expire(ctx, "ttlKey", time.Second)
+	assert.True(t, exists)
+	assert.NoError(t, err)
+
+	time.Sleep(2500 * time.Millisecond)
+
+	var val int
+	exists, err = redis.Get(ctx, "ttlKey", &val)
+	assert.False(t, exists)
+	assert.NoError(t, err)
+
+	exists, err = redis.Expire(ctx, "ttlKey", time.Second)
+	assert.False(t, exists)
+	assert.NoError(t, err)
+}
+
+func TestRedisIncrBy(t *testing.T) {
+	ctx := context.Background()
+	redis, err := NewRedisCacheClient(ctx, "localhost:6379")
+	if err != nil {
+		t.Error(err)
+	}
+	err = redis.Put(ctx, "counterKey", 5)
+	if err != nil {
+		t.Error(err)
+	}
+	val, err := redis.IncrBy(ctx, "counterKey", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(15), val)
+	val, err = redis.Decr(ctx, "counterKey")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(14), val)
+	val, err = redis.IncrBy(ctx, "counterKey", -4)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), val)
+}
+
+func TestRedisCompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	redis, err := NewRedisCacheClient(ctx, "localhost:6379")
+	if err != nil {
+		t.Error(err)
+	}
+	data := someData{ID: 5, Name: "Vaastav"}
+	err = redis.Put(ctx, "casKey", data)
+	if err != nil {
+		t.Error(err)
+	}
+	swapped, err := redis.CompareAndSwap(ctx, "casKey", someData{ID: 6, Name: "Vaastav"}, someData{ID: 7, Name: "Vaastav"})
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+
+	swapped, err = redis.CompareAndSwap(ctx, "casKey", data, someData{ID: 7, Name: "Vaastav"})
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+
+	var resultData someData
+	exists, err := redis.Get(ctx, "casKey", &resultData)
+	assert.True(t, exists)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), resultData.ID)
+
+	swapped, err = redis.CompareAndSwap(ctx, "nonexistentCasKey", data, data)
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+}
@@ -0,0 +1,68 @@
+package retries
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/rpcerror"
+)
+
+// Decides whether a failed call should be retried
+type Predicate func(err error) bool
+
+var predicates = struct {
+	sync.RWMutex
+	byName map[string]Predicate
+}{byName: map[string]Predicate{
+	"all":     func(err error) bool { return true },
+	"timeout": IsTimeout,
+	"network": IsNetwork,
+}}
+
+// Registers a predicate that can be named in the retryon option of retriers, replacing any
+// existing predicate with the same name.  Applications can register predicates for their own
+// errors, e.g. in an init function of their workflow spec.
+//
+// The built-in predicates are:
+//   - all, which matches every error
+//   - timeout, which matches errors that are timeouts; see [IsTimeout]
+//   - network, which matches errors connecting to or communicating with a remote service; see [IsNetwork]
+func RegisterPredicate(name string, predicate Predicate) {
+	predicates.Lock()
+	defer predicates.Unlock()
+	predicates.byName[name] = predicate
+}
+
+func getPredicate(name string) Predicate {
+	predicates.RLock()
+	defer predicates.RUnlock()
+	return predicates.byName[name]
+}
+
+// Returns true if err is a timeout: context.DeadlineExceeded, a net.Error timeout, or an error returned
+// by a remote service with the [rpcerror.DeadlineExceeded] code.  Error messages are not inspected.
+func IsTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// Returns true if err is an error connecting to or communicating with a remote service, e.g. because
+// the connection was refused or reset: a net.Error, a connection error from the operating system, an
+// unexpected EOF, or an error returned by a generated client with the [rpcerror.Unavailable] code.
+// Error messages are not inspected.
+func IsNetwork(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, rpcerror.ErrUnavailable) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	for _, errno := range []syscall.Errno{syscall.ECONNREFUSED, syscall.ECONNRESET, syscall.ECONNABORTED, syscall.EPIPE} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
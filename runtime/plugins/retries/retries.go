@@ -0,0 +1,385 @@
+// Package retries implements the runtime components of Blueprint's retries plugin.
+//
+// The retrier does not need to be used directly by application workflow specs.  Instead, this
+// code is included in a compiled application by applying one of the retries modifiers to the wiring spec.
+//
+// A [Retrier] retries failed calls with a fixed or exponentially increasing delay between tries,
+// optionally with jitter.  Retries can be limited by a maximum number of tries, a maximum total
+// elapsed time, a retry budget, and predicates that decide which errors are retried.
+//
+// Methods that are not idempotent, e.g. placing an order, are not safe to retry.  A [Retrier] only
+// retries the methods that are safe to retry, and gives each logical call an idempotency key that
+// is reused by each of its tries, so that services can deduplicate retried calls; see the
+// [idempotency] package.
+package retries
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/idempotency"
+	"github.com/blueprint-uservices/blueprint/runtime/core/options"
+)
+
+// The ways that the delay between tries can grow
+const (
+	Fixed       = "fixed"
+	Exponential = "exponential"
+)
+
+// The kinds of jitter that can be applied to the delay between tries.  See
+// https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+const (
+	NoJitter           = "none"
+	FullJitter         = "full"
+	EqualJitter        = "equal"
+	DecorrelatedJitter = "decorrelated"
+)
+
+// The default number of retries that a retry budget allows before any calls have succeeded
+const DefaultBudgetBurst = 10
+
+// Retries failed calls; safe for concurrent use, including reconfiguration.
+type Retrier struct {
+	mu      sync.Mutex
+	options map[string]string
+	config  *config
+	rng     *rand.Rand
+	tokens  float64 // Remaining retry budget
+}
+
+type config struct {
+	backoff    string
+	maxTries   int64
+	delay      time.Duration
+	limit      time.Duration
+	jitter     string
+	budget     float64
+	burst      float64
+	maxElapsed time.Duration
+	retryOn    []Predicate
+	match      *regexp.Regexp
+
+	idempotent     map[string]bool
+	nonIdempotent  map[string]bool
+	idempotentOnly bool
+}
+
+// Instantiates a [Retrier].
+//
+// opts are "key=value" strings that configure the retrier:
+//   - backoff=fixed (the default) waits delay between tries.  backoff=exponential doubles the delay after every
+//     try, starting from delay; the delay is capped at limit, and if maxtries is not set, retries stop once the
+//     delay reaches limit.
+//   - maxtries=<int> is the maximum number of tries, including the first; 0, the default, means unlimited.  A
+//     fixed backoff requires maxtries or maxelapsed.
+//   - delay=<duration> is the delay between tries, or the initial delay; defaults to 0
+//   - limit=<duration> is the maximum delay between tries; required for backoff=exponential
+//   - jitter=none|full|equal|decorrelated randomizes the delay between tries; defaults to none.  Full jitter waits
+//     a random delay up to the backoff delay; equal jitter waits at least half of the backoff delay; decorrelated
+//     jitter waits a random delay between delay and three times the previous delay, capped at limit.
+//   - budget=<float> limits retries to a fraction of successful calls, e.g. 0.1; each successful call deposits
+//     budget tokens into a token bucket, and each retry withdraws one token.  Defaults to no budget.
+//   - budgetburst=<int> is the capacity of the token bucket, which starts full; defaults to [DefaultBudgetBurst]
+//   - maxelapsed=<duration> stops retrying if the next try would start more than maxelapsed after the first
+//   - retryon=<name,name,...> retries only errors that match one of the named predicates; see [RegisterPredicate]
+//     for the built-in predicates.  Defaults to all errors, unless retryonmatch is set.
+//   - retryonmatch=<regexp> retries errors whose message matches the regular expression
+//   - idempotent=<method,method,...> names methods that are safe to retry
+//   - nonidempotent=<method,method,...> names methods that are not safe to retry; they are tried only once
+//   - idempotentonly=true retries only the methods named by the idempotent option.  By default, methods
+//     that are not named by the nonidempotent option are retried.
+//
+// Calls are never retried once their context is done.  The options can be changed while the process is
+// running with [Retrier.SetParameters].
+func NewRetrier(opts ...string) (*Retrier, error) {
+	values, err := options.Map("retries", opts)
+	if err != nil {
+		return nil, err
+	}
+	retrier := &Retrier{rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	if err := retrier.configure(values); err != nil {
+		return nil, err
+	}
+	retrier.tokens = retrier.config.burst
+	return retrier, nil
+}
+
+// Replaces the retrier's configuration with options.  Returns an error, leaving the configuration
+// unchanged, if any of the options are invalid.
+func (retrier *Retrier) configure(options map[string]string) error {
+	c := &config{backoff: Fixed, jitter: NoJitter, burst: DefaultBudgetBurst}
+	var err error
+	for key, value := range options {
+		switch key {
+		case "backoff":
+			c.backoff = value
+			if value != Fixed && value != Exponential {
+				return fmt.Errorf("unknown retries backoff %v", value)
+			}
+		case "maxtries":
+			c.maxTries, err = strconv.ParseInt(value, 10, 64)
+			if err == nil && c.maxTries < 0 {
+				err = fmt.Errorf("%v is negative", value)
+			}
+		case "delay":
+			c.delay, err = parseDuration(value)
+		case "limit":
+			c.limit, err = parseDuration(value)
+		case "maxelapsed":
+			c.maxElapsed, err = parseDuration(value)
+		case "jitter":
+			c.jitter = value
+			if value != NoJitter && value != FullJitter && value != EqualJitter && value != DecorrelatedJitter {
+				return fmt.Errorf("unknown retries jitter %v", value)
+			}
+		case "budget":
+			c.budget, err = strconv.ParseFloat(value, 64)
+			if err == nil && c.budget < 0 {
+				err = fmt.Errorf("%v is negative", value)
+			}
+		case "budgetburst":
+			var burst int64
+			burst, err = strconv.ParseInt(value, 10, 64)
+			if err == nil && burst < 1 {
+				err = fmt.Errorf("%v is less than 1", value)
+			}
+			c.burst = float64(burst)
+		case "retryon":
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name == "" {
+					continue
+				}
+				predicate := getPredicate(name)
+				if predicate == nil {
+					return fmt.Errorf("unknown retries predicate %v", name)
+				}
+				c.retryOn = append(c.retryOn, predicate)
+			}
+		case "retryonmatch":
+			c.match, err = regexp.Compile(value)
+		case "idempotent":
+			c.idempotent = methodSet(value)
+		case "nonidempotent":
+			c.nonIdempotent = methodSet(value)
+		case "idempotentonly":
+			c.idempotentOnly, err = strconv.ParseBool(value)
+		default:
+			return fmt.Errorf("unknown retries option %v", key)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for retries option %v: %v", key, err)
+		}
+	}
+
+	if c.backoff == Exponential && c.limit <= 0 {
+		return fmt.Errorf("exponential retries backoff requires option limit")
+	}
+	if c.backoff == Exponential && c.maxTries == 0 && c.delay <= 0 {
+		return fmt.Errorf("exponential retries backoff requires a positive delay unless maxtries is set")
+	}
+	if c.backoff == Fixed && c.maxTries == 0 && c.maxElapsed == 0 {
+		return fmt.Errorf("fixed retries backoff requires option maxtries or maxelapsed")
+	}
+
+	copied := make(map[string]string)
+	for key, value := range options {
+		copied[key] = value
+	}
+	retrier.mu.Lock()
+	defer retrier.mu.Unlock()
+	retrier.options = copied
+	retrier.config = c
+	retrier.tokens = min(retrier.tokens, c.burst)
+	return nil
+}
+
+func methodSet(value string) map[string]bool {
+	methods := make(map[string]bool)
+	for _, method := range strings.Split(value, ",") {
+		if method = strings.TrimSpace(method); method != "" {
+			methods[method] = true
+		}
+	}
+	return methods
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err == nil && d < 0 {
+		err = fmt.Errorf("%v is negative", value)
+	}
+	return d, err
+}
+
+// Returns the retrier's current options.  Implements control.Tunable.
+func (retrier *Retrier) Parameters() map[string]string {
+	retrier.mu.Lock()
+	defer retrier.mu.Unlock()
+	params := make(map[string]string)
+	for key, value := range retrier.options {
+		params[key] = value
+	}
+	return params
+}
+
+// Changes the named options, leaving the others unchanged, e.g. {"maxtries": "5"}.  An empty value
+// removes an option, restoring its default.  Returns an error, leaving the configuration unchanged,
+// if any of the options are invalid.  Implements control.Tunable.
+func (retrier *Retrier) SetParameters(params map[string]string) error {
+	options := retrier.Parameters()
+	for key, value := range params {
+		if value == "" {
+			delete(options, key)
+		} else {
+			options[key] = value
+		}
+	}
+	return retrier.configure(options)
+}
+
+// Calls call, a call of method, retrying it according to the retrier's configuration until it
+// succeeds or no more retries are permitted.  Returns the error of the last try.  Methods that are
+// not safe to retry are only tried once.
+//
+// Unless ctx already has an outgoing idempotency key for method, e.g. because retriers are nested,
+// each try is called with a context that has a new idempotency key for method, which is the same
+// for every try.
+func (retrier *Retrier) Do(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	retrier.mu.Lock()
+	c := retrier.config
+	retrier.mu.Unlock()
+
+	if idempotency.OutgoingKey(ctx, method) == "" {
+		ctx = idempotency.WithKey(ctx, method, idempotency.NewKey())
+	}
+	if !c.safe(method) {
+		err := call(ctx)
+		if err == nil {
+			retrier.deposit(c)
+		}
+		return err
+	}
+
+	start := time.Now()
+	previous := c.delay
+	for try := int64(1); ; try++ {
+		err := call(ctx)
+		if err == nil {
+			retrier.deposit(c)
+			return nil
+		}
+		if ctx.Err() != nil || (c.maxTries > 0 && try >= c.maxTries) || !c.retryable(err) {
+			return err
+		}
+
+		delay := c.delay
+		if c.backoff == Exponential {
+			delay = c.delay << min(try-1, 62)
+			if delay < c.delay || delay >= c.limit {
+				if c.maxTries == 0 {
+					return err
+				}
+				delay = c.limit
+			}
+		}
+		delay = retrier.jitter(c, delay, previous)
+		previous = delay
+
+		if c.maxElapsed > 0 && time.Since(start)+delay > c.maxElapsed {
+			return err
+		}
+		if !retrier.withdraw(c) {
+			return err
+		}
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// Returns whether method is safe to retry
+func (c *config) safe(method string) bool {
+	if c.nonIdempotent[method] {
+		return false
+	}
+	return c.idempotent[method] || !c.idempotentOnly
+}
+
+// Returns whether err should be retried
+func (c *config) retryable(err error) bool {
+	if len(c.retryOn) == 0 && c.match == nil {
+		return true
+	}
+	for _, predicate := range c.retryOn {
+		if predicate(err) {
+			return true
+		}
+	}
+	return c.match != nil && c.match.MatchString(err.Error())
+}
+
+// Applies the configured jitter to delay
+func (retrier *Retrier) jitter(c *config, delay, previous time.Duration) time.Duration {
+	retrier.mu.Lock()
+	defer retrier.mu.Unlock()
+	switch c.jitter {
+	case FullJitter:
+		if delay > 0 {
+			return time.Duration(retrier.rng.Int63n(int64(delay) + 1))
+		}
+	case EqualJitter:
+		if half := delay / 2; half > 0 {
+			return delay - half + time.Duration(retrier.rng.Int63n(int64(half)+1))
+		}
+	case DecorrelatedJitter:
+		upper := max(previous*3, c.delay)
+		if upper < previous {
+			upper = c.limit
+		}
+		if c.limit > 0 {
+			upper = min(upper, c.limit)
+		}
+		if upper > c.delay {
+			return c.delay + time.Duration(retrier.rng.Int63n(int64(upper-c.delay)+1))
+		}
+		return upper
+	}
+	return delay
+}
+
+// Deposits a successful call's contribution to the retry budget
+func (retrier *Retrier) deposit(c *config) {
+	if c.budget == 0 {
+		return
+	}
+	retrier.mu.Lock()
+	defer retrier.mu.Unlock()
+	retrier.tokens = min(retrier.tokens+c.budget, c.burst)
+}
+
+// Withdraws a retry from the retry budget; returns false if the budget is exhausted
+func (retrier *Retrier) withdraw(c *config) bool {
+	if c.budget == 0 {
+		return true
+	}
+	retrier.mu.Lock()
+	defer retrier.mu.Unlock()
+	if retrier.tokens < 1 {
+		return false
+	}
+	retrier.tokens--
+	return true
+}
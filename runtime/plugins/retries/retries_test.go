@@ -0,0 +1,269 @@
+package retries_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/idempotency"
+	"github.com/blueprint-uservices/blueprint/runtime/core/rpcerror"
+	"github.com/blueprint-uservices/blueprint/runtime/plugins/retries"
+	"github.com/stretchr/testify/require"
+)
+
+var errBoom = errors.New("boom")
+
+// Returns a call that fails the first failures times, and the number of tries made so far
+func failing(failures int) (func(context.Context) error, *int) {
+	tries := 0
+	return func(ctx context.Context) error {
+		tries++
+		if tries <= failures {
+			return errBoom
+		}
+		return nil
+	}, &tries
+}
+
+func TestMaxTries(t *testing.T) {
+	retrier, err := retries.NewRetrier("maxtries=3")
+	require.NoError(t, err)
+
+	call, tries := failing(2)
+	require.NoError(t, retrier.Do(context.Background(), "Hello", call))
+	require.Equal(t, 3, *tries)
+
+	call, tries = failing(10)
+	require.ErrorIs(t, retrier.Do(context.Background(), "Hello", call), errBoom)
+	require.Equal(t, 3, *tries)
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	// Without maxtries, retries stop once the delay reaches the limit: delays of 1, 2, 4 and 8ms
+	retrier, err := retries.NewRetrier("backoff=exponential", "delay=1ms", "limit=16ms")
+	require.NoError(t, err)
+	call, tries := failing(100)
+	start := time.Now()
+	require.ErrorIs(t, retrier.Do(context.Background(), "Hello", call), errBoom)
+	require.Equal(t, 5, *tries)
+	require.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond)
+
+	// With maxtries, the delay is capped at the limit
+	retrier, err = retries.NewRetrier("backoff=exponential", "delay=1ms", "limit=2ms", "maxtries=6")
+	require.NoError(t, err)
+	call, tries = failing(100)
+	start = time.Now()
+	require.ErrorIs(t, retrier.Do(context.Background(), "Hello", call), errBoom)
+	require.Equal(t, 6, *tries)
+	require.GreaterOrEqual(t, time.Since(start), 9*time.Millisecond)
+}
+
+func TestJitter(t *testing.T) {
+	for _, jitter := range []string{retries.FullJitter, retries.EqualJitter, retries.DecorrelatedJitter} {
+		retrier, err := retries.NewRetrier("delay=5ms", "limit=10ms", "maxtries=5", "jitter="+jitter)
+		require.NoError(t, err)
+		call, tries := failing(100)
+		start := time.Now()
+		require.ErrorIs(t, retrier.Do(context.Background(), "Hello", call), errBoom)
+		require.Equal(t, 5, *tries)
+
+		// Four delays of at most the limit
+		require.Less(t, time.Since(start), 40*time.Millisecond+100*time.Millisecond, jitter)
+	}
+
+	// Equal jitter waits at least half of the delay
+	retrier, err := retries.NewRetrier("delay=10ms", "maxtries=3", "jitter=equal")
+	require.NoError(t, err)
+	call, _ := failing(100)
+	start := time.Now()
+	retrier.Do(context.Background(), "Hello", call)
+	require.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+}
+
+func TestBudget(t *testing.T) {
+	retrier, err := retries.NewRetrier("maxtries=10", "budget=0.5", "budgetburst=2")
+	require.NoError(t, err)
+
+	// The budget starts with two retries
+	call, tries := failing(100)
+	require.Error(t, retrier.Do(context.Background(), "Hello", call))
+	require.Equal(t, 3, *tries)
+
+	// The budget is exhausted, so there are no retries
+	call, tries = failing(100)
+	require.Error(t, retrier.Do(context.Background(), "Hello", call))
+	require.Equal(t, 1, *tries)
+
+	// Two successful calls earn another retry
+	for i := 0; i < 2; i++ {
+		require.NoError(t, retrier.Do(context.Background(), "Hello", func(context.Context) error { return nil }))
+	}
+	call, tries = failing(100)
+	require.Error(t, retrier.Do(context.Background(), "Hello", call))
+	require.Equal(t, 2, *tries)
+}
+
+func TestMaxElapsed(t *testing.T) {
+	retrier, err := retries.NewRetrier("delay=10ms", "maxelapsed=35ms")
+	require.NoError(t, err)
+	call, tries := failing(100)
+	start := time.Now()
+	require.Error(t, retrier.Do(context.Background(), "Hello", call))
+	require.LessOrEqual(t, *tries, 4)
+	require.GreaterOrEqual(t, *tries, 2)
+	require.Less(t, time.Since(start), 35*time.Millisecond+50*time.Millisecond)
+}
+
+func TestPredicates(t *testing.T) {
+	retrier, err := retries.NewRetrier("maxtries=3", "retryon=timeout")
+	require.NoError(t, err)
+
+	call, tries := failing(100)
+	require.Error(t, retrier.Do(context.Background(), "Hello", call))
+	require.Equal(t, 1, *tries)
+
+	tries2 := 0
+	require.Error(t, retrier.Do(context.Background(), "Hello", func(context.Context) error {
+		tries2++
+		return fmt.Errorf("calling leaf: %w", context.DeadlineExceeded)
+	}))
+	require.Equal(t, 3, tries2)
+
+	// Errors are matched by their codes when they cross process boundaries, never by their messages
+	require.True(t, retries.IsTimeout(rpcerror.New(rpcerror.DeadlineExceeded, "calling leaf")))
+	require.False(t, retries.IsTimeout(errors.New("Request was timed out")))
+	require.True(t, retries.IsNetwork(rpcerror.New(rpcerror.Unavailable, "leaf is down")))
+	require.True(t, retries.IsNetwork(fmt.Errorf("calling leaf: %w", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED})))
+	require.True(t, retries.IsNetwork(fmt.Errorf("reading response: %w", syscall.ECONNRESET)))
+	require.False(t, retries.IsNetwork(errors.New("dial tcp 10.0.0.1:80: connect: connection refused")))
+	require.False(t, retries.IsNetwork(rpcerror.New(rpcerror.NotFound, "service unavailable")))
+	require.False(t, retries.IsNetwork(errBoom))
+
+	retries.RegisterPredicate("boom", func(err error) bool { return errors.Is(err, errBoom) })
+	retrier, err = retries.NewRetrier("maxtries=3", "retryon=timeout,boom")
+	require.NoError(t, err)
+	call, tries = failing(100)
+	require.Error(t, retrier.Do(context.Background(), "Hello", call))
+	require.Equal(t, 3, *tries)
+
+	retrier, err = retries.NewRetrier("maxtries=3", "retryonmatch=^bo+m$")
+	require.NoError(t, err)
+	call, tries = failing(100)
+	require.Error(t, retrier.Do(context.Background(), "Hello", call))
+	require.Equal(t, 3, *tries)
+}
+
+func TestIdempotency(t *testing.T) {
+	retrier, err := retries.NewRetrier("maxtries=3", "nonidempotent=PlaceOrder")
+	require.NoError(t, err)
+
+	call, tries := failing(100)
+	require.Error(t, retrier.Do(context.Background(), "PlaceOrder", call))
+	require.Equal(t, 1, *tries)
+
+	call, tries = failing(100)
+	require.Error(t, retrier.Do(context.Background(), "GetOrder", call))
+	require.Equal(t, 3, *tries)
+
+	retrier, err = retries.NewRetrier("maxtries=3", "idempotent=GetOrder", "idempotentonly=true")
+	require.NoError(t, err)
+
+	call, tries = failing(100)
+	require.Error(t, retrier.Do(context.Background(), "GetOrder", call))
+	require.Equal(t, 3, *tries)
+
+	call, tries = failing(100)
+	require.Error(t, retrier.Do(context.Background(), "Hello", call))
+	require.Equal(t, 1, *tries)
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	retrier, err := retries.NewRetrier("maxtries=3")
+	require.NoError(t, err)
+
+	// Every try of a call has the same key
+	var keys []string
+	retrier.Do(context.Background(), "Hello", func(ctx context.Context) error {
+		keys = append(keys, idempotency.OutgoingKey(ctx, "Hello"))
+		return errBoom
+	})
+	require.Len(t, keys, 3)
+	require.NotEmpty(t, keys[0])
+	require.Equal(t, keys[0], keys[1])
+	require.Equal(t, keys[0], keys[2])
+
+	// Distinct calls have distinct keys
+	var key string
+	retrier.Do(context.Background(), "Hello", func(ctx context.Context) error {
+		key = idempotency.OutgoingKey(ctx, "Hello")
+		return nil
+	})
+	require.NotEqual(t, keys[0], key)
+
+	// Existing keys are kept, e.g. when retriers are nested
+	ctx := idempotency.WithKey(context.Background(), "Hello", "order-1")
+	retrier.Do(ctx, "Hello", func(ctx context.Context) error {
+		key = idempotency.OutgoingKey(ctx, "Hello")
+		return nil
+	})
+	require.Equal(t, "order-1", key)
+
+	// Calls of other methods, e.g. by an in-process callee, get their own keys
+	retrier.Do(ctx, "Goodbye", func(ctx context.Context) error {
+		key = idempotency.OutgoingKey(ctx, "Goodbye")
+		return nil
+	})
+	require.NotEmpty(t, key)
+	require.NotEqual(t, "order-1", key)
+}
+
+func TestContextDone(t *testing.T) {
+	retrier, err := retries.NewRetrier("delay=1h", "maxtries=3")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	call, tries := failing(100)
+	require.ErrorIs(t, retrier.Do(ctx, "Hello", call), errBoom)
+	require.Equal(t, 1, *tries)
+}
+
+func TestSetParameters(t *testing.T) {
+	retrier, err := retries.NewRetrier("maxtries=2", "delay=1ms")
+	require.NoError(t, err)
+	require.NoError(t, retrier.SetParameters(map[string]string{"maxtries": "4", "jitter": "full"}))
+	require.Equal(t, map[string]string{"maxtries": "4", "delay": "1ms", "jitter": "full"}, retrier.Parameters())
+
+	call, tries := failing(100)
+	require.Error(t, retrier.Do(context.Background(), "Hello", call))
+	require.Equal(t, 4, *tries)
+
+	require.Error(t, retrier.SetParameters(map[string]string{"maxtries": "", "jitter": "lots"}))
+	require.Error(t, retrier.SetParameters(map[string]string{"maxtries": ""}))
+	require.Equal(t, "4", retrier.Parameters()["maxtries"])
+}
+
+func TestInvalidOptions(t *testing.T) {
+	for _, opts := range [][]string{
+		{},
+		{"maxtries"},
+		{"maxtries=-1"},
+		{"maxtries=3", "delay=soon"},
+		{"maxtries=3", "backoff=linear"},
+		{"maxtries=3", "jitter=lots"},
+		{"maxtries=3", "budget=-0.1"},
+		{"maxtries=3", "budgetburst=0"},
+		{"maxtries=3", "retryon=flaky"},
+		{"maxtries=3", "retryonmatch=("},
+		{"maxtries=3", "tries=3"},
+		{"maxtries=3", "idempotentonly=sometimes"},
+		{"backoff=exponential", "delay=1ms"},
+		{"backoff=exponential", "limit=1s"},
+	} {
+		_, err := retries.NewRetrier(opts...)
+		require.Error(t, err, "options %v", opts)
+	}
+}
@@ -1,63 +1,146 @@
 // Package simplecache implements a key-value [backend.Cache] using a golang map.
+//
+// By default the cache is unbounded.  It can optionally be bounded by number of keys and/or
+// approximate memory usage, in which case keys are evicted according to an [EvictionPolicy].
+// Cache hits, misses and evictions are reported as metrics through [backend.Meter], labelled with the
+// cache's name.
 package simplecache
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/blueprint-uservices/blueprint/runtime/core/options"
 )
 
+// How often the background sweeper removes expired keys from the cache.
+//
+// Expired keys are never visible to callers, regardless of whether the sweeper has run yet;
+// the sweeper only exists to reclaim memory.
+const sweepInterval = 1 * time.Second
+
 // A simple map-based cache that implements the [backend.Cache] interface
 type SimpleCache struct {
 	backend.Cache
 	sync.RWMutex
-	values map[string]any
+	name     string
+	values   map[string]any
+	expiries map[string]time.Time
+	sizes    map[string]int64
+	bytes    int64
+
+	capacity int
+	maxBytes int64
+	evictor  evictor // nil if the cache is unbounded
+
+	metrics cacheMetrics
 }
 
-// Instantiates a map-based [SimpleCache]
-func NewSimpleCache(ctx context.Context) (*SimpleCache, error) {
-	cache := &SimpleCache{}
+// Instantiates a map-based [SimpleCache].
+//
+// Keys stored with a TTL are lazily expired when accessed, and periodically removed by a
+// background sweeper that runs until ctx is cancelled.
+//
+// opts are optional "key=value" strings that configure the cache:
+//   - name=<name> identifies the cache in its metrics; defaults to simplecache
+//   - capacity=<int> bounds the number of keys in the cache
+//   - maxbytes=<int> bounds the approximate size of the cache contents, in bytes
+//   - policy=<lru|lfu|random> selects the [EvictionPolicy] of a bounded cache; defaults to lru
+//
+// Sizes are approximated by the length of the key plus the length of the JSON-encoded value,
+// mirroring how the value would be stored by memcached or redis.
+func NewSimpleCache(ctx context.Context, opts ...string) (*SimpleCache, error) {
+	cache := &SimpleCache{name: "simplecache"}
 	cache.values = make(map[string]any)
+	cache.expiries = make(map[string]time.Time)
+	cache.sizes = make(map[string]int64)
+	cache.metrics.cache = cache
+	if err := cache.configure(opts); err != nil {
+		return nil, err
+	}
+	go cache.sweep(ctx)
 	return cache, nil
 }
 
-func (cache *SimpleCache) Put(ctx context.Context, key string, value interface{}) error {
-	cache.Lock()
-	defer cache.Unlock()
-	cache.values[key] = value
+func (cache *SimpleCache) configure(opts []string) error {
+	policy := LRU
+	parsed, err := options.Parse("simplecache", opts)
+	if err != nil {
+		return err
+	}
+	for _, opt := range parsed {
+		key, value := opt.Key, opt.Value
+		var err error
+		switch key {
+		case "name":
+			cache.name = value
+		case "capacity":
+			cache.capacity, err = strconv.Atoi(value)
+		case "maxbytes":
+			cache.maxBytes, err = strconv.ParseInt(value, 10, 64)
+		case "policy":
+			policy = EvictionPolicy(value)
+		default:
+			return fmt.Errorf("unknown simplecache option %v", key)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for simplecache option %v: %v", key, err)
+		}
+	}
+	if cache.capacity < 0 || cache.maxBytes < 0 {
+		return fmt.Errorf("simplecache capacity and maxbytes must be non-negative")
+	}
+	if cache.capacity > 0 || cache.maxBytes > 0 {
+		var err error
+		cache.evictor, err = newEvictor(policy)
+		return err
+	}
 	return nil
 }
 
+func (cache *SimpleCache) Put(ctx context.Context, key string, value interface{}) error {
+	return cache.PutWithTTL(ctx, key, value, 0)
+}
+
 func (cache *SimpleCache) Get(ctx context.Context, key string, val interface{}) (bool, error) {
-	if v, exists := cache.values[key]; exists {
-		return true, backend.CopyResult(v, val)
-	}
-	return false, nil
+	unlock := cache.lockForRead()
+	defer unlock()
+	return cache.get(ctx, key, val, time.Now())
 }
 
+// Stores all keys atomically; concurrent readers observe either none or all of the keys
 func (cache *SimpleCache) Mset(ctx context.Context, keys []string, values []interface{}) error {
 	if len(keys) != len(values) {
 		return fmt.Errorf("mset received %v keys but %v values", len(keys), len(values))
 	}
 
+	cache.Lock()
+	defer cache.Unlock()
 	for i, key := range keys {
-		err := cache.Put(ctx, key, values[i])
-		if err != nil {
-			return err
-		}
+		delete(cache.expiries, key)
+		cache.store(ctx, key, values[i])
 	}
 
 	return nil
 }
+
+// Reads all keys atomically, from a single snapshot of the cache
 func (cache *SimpleCache) Mget(ctx context.Context, keys []string, values []interface{}) error {
 	if len(keys) != len(values) {
 		return fmt.Errorf("mget received %v keys but %v values", len(keys), len(values))
 	}
 
+	unlock := cache.lockForRead()
+	defer unlock()
+	now := time.Now()
 	for i, key := range keys {
-		_, err := cache.Get(ctx, key, values[i])
+		_, err := cache.get(ctx, key, values[i], now)
 		if err != nil {
 			return err
 		}
@@ -68,16 +151,227 @@ func (cache *SimpleCache) Mget(ctx context.Context, keys []string, values []inte
 func (cache *SimpleCache) Delete(ctx context.Context, key string) error {
 	cache.Lock()
 	defer cache.Unlock()
-	delete(cache.values, key)
+	cache.remove(key)
 	return nil
 }
 
+// Increments the value mapped to key.  Like redis, incrementing a key retains its expiry.
 func (cache *SimpleCache) Incr(ctx context.Context, key string) (int64, error) {
+	return cache.IncrBy(ctx, key, 1)
+}
+
+// Decrements the value mapped to key.  Like redis, decrementing a key retains its expiry.
+func (cache *SimpleCache) Decr(ctx context.Context, key string) (int64, error) {
+	return cache.IncrBy(ctx, key, -1)
+}
+
+// Adds delta to the value mapped to key.  Like redis, incrementing a key retains its expiry.
+func (cache *SimpleCache) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	cache.Lock()
+	defer cache.Unlock()
 	cur := int64(0)
-	_, err := cache.Get(ctx, key, &cur)
+	if v, exists := cache.lookup(key, time.Now()); exists {
+		if err := backend.CopyResult(v, &cur); err != nil {
+			return cur, err
+		}
+	} else {
+		delete(cache.expiries, key)
+	}
+	cur += delta
+	cache.store(ctx, key, cur)
+	return cur, nil
+}
+
+func (cache *SimpleCache) CompareAndSwap(ctx context.Context, key string, oldValue interface{}, newValue interface{}) (bool, error) {
+	expected, err := json.Marshal(oldValue)
 	if err != nil {
-		return cur, err
+		return false, err
+	}
+	cache.Lock()
+	defer cache.Unlock()
+	cur, exists := cache.lookup(key, time.Now())
+	if !exists {
+		return false, nil
+	}
+	actual, err := json.Marshal(cur)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(expected, actual) {
+		return false, nil
+	}
+	delete(cache.expiries, key)
+	cache.store(ctx, key, newValue)
+	return true, nil
+}
+
+func (cache *SimpleCache) PutWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if ttl < 0 {
+		return fmt.Errorf("invalid ttl %v for key %v", ttl, key)
+	}
+	cache.Lock()
+	defer cache.Unlock()
+	if ttl == 0 {
+		delete(cache.expiries, key)
+	} else {
+		cache.expiries[key] = time.Now().Add(ttl)
+	}
+	cache.store(ctx, key, value)
+	return nil
+}
+
+func (cache *SimpleCache) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if ttl < 0 {
+		return false, fmt.Errorf("invalid ttl %v for key %v", ttl, key)
+	}
+	cache.Lock()
+	defer cache.Unlock()
+	now := time.Now()
+	if _, exists := cache.lookup(key, now); !exists {
+		return false, nil
+	}
+	if ttl == 0 {
+		delete(cache.expiries, key)
+	} else {
+		cache.expiries[key] = now.Add(ttl)
+	}
+	return true, nil
+}
+
+func (cache *SimpleCache) TTL(ctx context.Context, key string) (time.Duration, bool, error) {
+	cache.RLock()
+	defer cache.RUnlock()
+	now := time.Now()
+	if _, exists := cache.lookup(key, now); !exists {
+		return 0, false, nil
+	}
+	if expiry, hasExpiry := cache.expiries[key]; hasExpiry {
+		return expiry.Sub(now), true, nil
+	}
+	return 0, true, nil
+}
+
+// Acquires the lock needed to read from the cache, returning the corresponding unlock func.
+// Reads from a bounded cache update the eviction policy's bookkeeping, so need the write lock.
+func (cache *SimpleCache) lockForRead() (unlock func()) {
+	if cache.evictor != nil {
+		cache.Lock()
+		return cache.Unlock
+	}
+	cache.RLock()
+	return cache.RUnlock
+}
+
+// Copies the value mapped to key into val.  The caller must hold the lock returned by lockForRead.
+func (cache *SimpleCache) get(ctx context.Context, key string, val interface{}, now time.Time) (bool, error) {
+	if v, exists := cache.lookup(key, now); exists {
+		cache.metrics.hit(ctx)
+		if cache.evictor != nil {
+			cache.evictor.touch(key)
+		}
+		return true, backend.CopyResult(v, val)
+	}
+	cache.metrics.miss(ctx)
+	return false, nil
+}
+
+// Returns the value mapped to key, treating expired keys as if they don't exist.
+// The caller must hold the lock.
+func (cache *SimpleCache) lookup(key string, now time.Time) (any, bool) {
+	if expiry, hasExpiry := cache.expiries[key]; hasExpiry && !now.Before(expiry) {
+		return nil, false
+	}
+	v, exists := cache.values[key]
+	return v, exists
+}
+
+// Maps key to value, then evicts keys if the cache is over capacity.
+// The caller must hold the write lock.
+func (cache *SimpleCache) store(ctx context.Context, key string, value any) {
+	_, existed := cache.values[key]
+	cache.values[key] = value
+
+	// Sizes are only needed, and only computed, when the cache is bounded by bytes
+	if cache.maxBytes > 0 {
+		size := sizeOf(key, value)
+		cache.bytes += size - cache.sizes[key]
+		cache.sizes[key] = size
+	}
+
+	if cache.evictor == nil {
+		return
+	}
+	if existed {
+		cache.evictor.touch(key)
+	}
+
+	// A new key is only added to the evictor after making room for it; otherwise a policy
+	// such as LFU would always choose the new key as the victim
+	evicted := 0
+	for cache.overCapacity() {
+		// Never evict the key that was just stored, even if it alone exceeds maxbytes
+		victim, ok := cache.evictor.victim(key)
+		if !ok {
+			break
+		}
+		cache.remove(victim)
+		evicted++
+	}
+	if !existed {
+		cache.evictor.add(key)
+	}
+	if evicted > 0 {
+		cache.metrics.evicted(ctx, evicted)
+	}
+}
+
+func (cache *SimpleCache) overCapacity() bool {
+	return (cache.capacity > 0 && len(cache.values) > cache.capacity) ||
+		(cache.maxBytes > 0 && cache.bytes > cache.maxBytes)
+}
+
+// Removes key from the cache.  The caller must hold the write lock.
+func (cache *SimpleCache) remove(key string) {
+	delete(cache.values, key)
+	delete(cache.expiries, key)
+	cache.bytes -= cache.sizes[key]
+	delete(cache.sizes, key)
+	if cache.evictor != nil {
+		cache.evictor.remove(key)
+	}
+}
+
+// Approximates the memory used by a key-value pair
+func sizeOf(key string, value any) int64 {
+	switch v := value.(type) {
+	case string:
+		return int64(len(key) + len(v))
+	case []byte:
+		return int64(len(key) + len(v))
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return int64(len(key))
+	}
+	return int64(len(key) + len(encoded))
+}
+
+// Periodically removes expired keys until ctx is cancelled
+func (cache *SimpleCache) sweep(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			cache.Lock()
+			for key, expiry := range cache.expiries {
+				if !now.Before(expiry) {
+					cache.remove(key)
+				}
+			}
+			cache.Unlock()
+		}
 	}
-	cur += 1
-	return cur, cache.Put(ctx, key, cur)
 }
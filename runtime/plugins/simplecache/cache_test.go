@@ -2,7 +2,11 @@ package simplecache
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -73,3 +77,373 @@ func TestMget(t *testing.T) {
 	err = cache.Mget(ctx, []string{}, getvalues)
 	assert.Error(t, err)
 }
+
+func TestTTL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache, _ := NewSimpleCache(ctx)
+
+	err := cache.PutWithTTL(ctx, "short", "lived", 50*time.Millisecond)
+	assert.NoError(t, err)
+	err = cache.Put(ctx, "forever", "lived")
+	assert.NoError(t, err)
+
+	ttl, exists, err := cache.TTL(ctx, "short")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.True(t, ttl > 0 && ttl <= 50*time.Millisecond)
+
+	ttl, exists, err = cache.TTL(ctx, "forever")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	time.Sleep(100 * time.Millisecond)
+
+	var v string
+	exists, err = cache.Get(ctx, "short", &v)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	_, exists, err = cache.TTL(ctx, "short")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	exists, err = cache.Get(ctx, "forever", &v)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "lived", v)
+
+	err = cache.PutWithTTL(ctx, "short", "lived", -1)
+	assert.Error(t, err)
+}
+
+func TestExpire(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache, _ := NewSimpleCache(ctx)
+
+	exists, err := cache.Expire(ctx, "nonexistent", time.Second)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	err = cache.Put(ctx, "a", int64(5))
+	assert.NoError(t, err)
+	exists, err = cache.Expire(ctx, "a", 50*time.Millisecond)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	// Incr retains the expiry
+	v, err := cache.Incr(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), v)
+	ttl, exists, err := cache.TTL(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.True(t, ttl > 0)
+
+	// A ttl of 0 persists the key
+	err = cache.PutWithTTL(ctx, "b", "hello", 50*time.Millisecond)
+	assert.NoError(t, err)
+	exists, err = cache.Expire(ctx, "b", 0)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	time.Sleep(100 * time.Millisecond)
+
+	var j int64
+	exists, err = cache.Get(ctx, "a", &j)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	var s string
+	exists, err = cache.Get(ctx, "b", &s)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "hello", s)
+}
+
+func TestSweep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache, _ := NewSimpleCache(ctx)
+
+	err := cache.PutWithTTL(ctx, "a", "hello", time.Millisecond)
+	assert.NoError(t, err)
+
+	time.Sleep(sweepInterval + 100*time.Millisecond)
+
+	cache.RLock()
+	defer cache.RUnlock()
+	assert.Empty(t, cache.values)
+	assert.Empty(t, cache.expiries)
+}
+
+func TestName(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache, err := NewSimpleCache(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "simplecache", cache.name)
+
+	cache, err = NewSimpleCache(ctx, "name=my_cache", "capacity=10")
+	assert.NoError(t, err)
+	assert.Equal(t, "my_cache", cache.name)
+}
+
+func TestCapacity(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := NewSimpleCache(ctx, "capacity=-1")
+	assert.Error(t, err)
+	_, err = NewSimpleCache(ctx, "capacity=2", "policy=fifo")
+	assert.Error(t, err)
+	_, err = NewSimpleCache(ctx, "size")
+	assert.Error(t, err)
+
+	for _, policy := range []EvictionPolicy{LRU, LFU, Random} {
+		cache, err := NewSimpleCache(ctx, "capacity=10", "policy="+string(policy))
+		assert.NoError(t, err)
+
+		for i := 0; i < 100; i++ {
+			err := cache.Put(ctx, fmt.Sprintf("key%d", i), i)
+			assert.NoError(t, err)
+		}
+		assert.Len(t, cache.values, 10)
+		assert.Empty(t, cache.sizes, "sizes are only computed for caches bounded by bytes")
+
+		// The most recently stored key is never evicted
+		var v int
+		exists, err := cache.Get(ctx, "key99", &v)
+		assert.NoError(t, err)
+		assert.True(t, exists)
+		assert.Equal(t, 99, v)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache, err := NewSimpleCache(ctx, "capacity=2", "policy=lru")
+	assert.NoError(t, err)
+
+	assert.NoError(t, cache.Put(ctx, "a", 1))
+	assert.NoError(t, cache.Put(ctx, "b", 2))
+
+	// Reading a makes b the least recently used
+	var v int
+	exists, _ := cache.Get(ctx, "a", &v)
+	assert.True(t, exists)
+
+	assert.NoError(t, cache.Put(ctx, "c", 3))
+	exists, _ = cache.Get(ctx, "a", &v)
+	assert.True(t, exists)
+	exists, _ = cache.Get(ctx, "b", &v)
+	assert.False(t, exists)
+	exists, _ = cache.Get(ctx, "c", &v)
+	assert.True(t, exists)
+}
+
+func TestLFU(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache, err := NewSimpleCache(ctx, "capacity=2", "policy=lfu")
+	assert.NoError(t, err)
+
+	assert.NoError(t, cache.Put(ctx, "a", 1))
+	assert.NoError(t, cache.Put(ctx, "b", 2))
+
+	// a is used more frequently than b, even though b is used most recently
+	var v int
+	for i := 0; i < 3; i++ {
+		cache.Get(ctx, "a", &v)
+	}
+	cache.Get(ctx, "b", &v)
+
+	assert.NoError(t, cache.Put(ctx, "c", 3))
+	exists, _ := cache.Get(ctx, "a", &v)
+	assert.True(t, exists)
+	exists, _ = cache.Get(ctx, "b", &v)
+	assert.False(t, exists)
+}
+
+func TestMaxBytes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache, err := NewSimpleCache(ctx, "maxbytes=100")
+	assert.NoError(t, err)
+
+	value := strings.Repeat("x", 30)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, cache.Put(ctx, fmt.Sprintf("key%d", i), value))
+	}
+	// Each entry is 34 bytes, so only 2 fit
+	assert.Len(t, cache.values, 2)
+	assert.Equal(t, int64(68), cache.bytes)
+
+	assert.NoError(t, cache.Delete(ctx, "key9"))
+	assert.Equal(t, int64(34), cache.bytes)
+}
+
+func TestMaxBytesUpdate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, policy := range []EvictionPolicy{LRU, LFU, Random} {
+		cache, err := NewSimpleCache(ctx, "maxbytes=100", "policy="+string(policy))
+		assert.NoError(t, err)
+
+		assert.NoError(t, cache.Put(ctx, "key0", strings.Repeat("x", 30)))
+		assert.NoError(t, cache.Put(ctx, "key1", strings.Repeat("x", 30)))
+		var v string
+		for i := 0; i < 3; i++ {
+			cache.Get(ctx, "key1", &v)
+		}
+
+		// Growing the least-used key evicts the other keys rather than the key itself
+		assert.NoError(t, cache.Put(ctx, "key0", strings.Repeat("x", 70)), policy)
+		assert.Equal(t, int64(74), cache.bytes, policy)
+		exists, _ := cache.Get(ctx, "key0", &v)
+		assert.True(t, exists, policy)
+	}
+}
+
+func TestIncrBy(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := NewSimpleCache(ctx)
+
+	v, err := cache.IncrBy(ctx, "counter", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), v)
+
+	v, err = cache.Decr(ctx, "counter")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), v)
+
+	v, err = cache.IncrBy(ctx, "counter", -20)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-11), v)
+
+	assert.NoError(t, cache.Put(ctx, "hello", "world"))
+	_, err = cache.Incr(ctx, "hello")
+	assert.Error(t, err)
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := NewSimpleCache(ctx)
+
+	swapped, err := cache.CompareAndSwap(ctx, "a", "x", "y")
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+
+	assert.NoError(t, cache.PutWithTTL(ctx, "a", "x", time.Hour))
+	swapped, err = cache.CompareAndSwap(ctx, "a", "z", "y")
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+
+	swapped, err = cache.CompareAndSwap(ctx, "a", "x", "y")
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+
+	var v string
+	exists, err := cache.Get(ctx, "a", &v)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "y", v)
+
+	// Like Put, swapping removes the expiry
+	ttl, _, _ := cache.TTL(ctx, "a")
+	assert.Equal(t, time.Duration(0), ttl)
+
+	// Values are compared by their JSON encodings
+	assert.NoError(t, cache.Put(ctx, "b", int64(5)))
+	swapped, err = cache.CompareAndSwap(ctx, "b", 5, 6)
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+}
+
+func TestConcurrentIncr(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := NewSimpleCache(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := cache.Incr(ctx, "counter")
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var v int64
+	_, err := cache.Get(ctx, "counter", &v)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5000), v)
+}
+
+func TestConcurrentCompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := NewSimpleCache(ctx, "capacity=10")
+	assert.NoError(t, cache.Put(ctx, "counter", int64(0)))
+
+	// Optimistic increments via CompareAndSwap never lose updates
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				for {
+					var cur int64
+					_, err := cache.Get(ctx, "counter", &cur)
+					assert.NoError(t, err)
+					swapped, err := cache.CompareAndSwap(ctx, "counter", cur, cur+1)
+					assert.NoError(t, err)
+					if swapped {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var v int64
+	_, err := cache.Get(ctx, "counter", &v)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), v)
+}
+
+func TestConcurrentMset(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := NewSimpleCache(ctx)
+
+	// Mget never observes a partially-applied Mset
+	keys := []string{"a", "b", "c"}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int64) {
+			defer wg.Done()
+			for j := int64(0); j < 100; j++ {
+				v := i*1000 + j
+				assert.NoError(t, cache.Mset(ctx, keys, []interface{}{v, v, v}))
+			}
+		}(int64(i))
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				var a, b, c int64
+				assert.NoError(t, cache.Mget(ctx, keys, []interface{}{&a, &b, &c}))
+				assert.True(t, a == b && b == c)
+			}
+		}()
+	}
+	wg.Wait()
+}
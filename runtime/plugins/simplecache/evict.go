@@ -0,0 +1,224 @@
+package simplecache
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+	"math/rand"
+)
+
+// The policy used by a bounded [SimpleCache] to choose which key to evict when the cache is full.
+type EvictionPolicy string
+
+const (
+	// Evict the least-recently-used key
+	LRU EvictionPolicy = "lru"
+
+	// Evict the least-frequently-used key, breaking ties by least-recent use
+	LFU EvictionPolicy = "lfu"
+
+	// Evict a key chosen uniformly at random
+	Random EvictionPolicy = "random"
+)
+
+// Tracks the keys of a bounded cache and chooses victims for eviction.
+//
+// Implementations are not thread-safe; the cache lock must be held when calling them.
+type evictor interface {
+	// Called when key is inserted into the cache
+	add(key string)
+
+	// Called when an existing key is read or updated
+	touch(key string)
+
+	// Called when key is removed from the cache
+	remove(key string)
+
+	// Returns the next key to evict other than except, if any
+	victim(except string) (string, bool)
+}
+
+func newEvictor(policy EvictionPolicy) (evictor, error) {
+	switch policy {
+	case LRU:
+		return newLRU(), nil
+	case LFU:
+		return newLFU(), nil
+	case Random:
+		return newRandom(), nil
+	}
+	return nil, fmt.Errorf("unknown eviction policy %v; expected one of %v, %v, %v", policy, LRU, LFU, Random)
+}
+
+// Recency list; the front of the list is the most recently used key
+type lruEvictor struct {
+	order *list.List
+	elems map[string]*list.Element
+}
+
+func newLRU() *lruEvictor {
+	return &lruEvictor{order: list.New(), elems: make(map[string]*list.Element)}
+}
+
+func (e *lruEvictor) add(key string) {
+	e.elems[key] = e.order.PushFront(key)
+}
+
+func (e *lruEvictor) touch(key string) {
+	if elem, exists := e.elems[key]; exists {
+		e.order.MoveToFront(elem)
+	}
+}
+
+func (e *lruEvictor) remove(key string) {
+	if elem, exists := e.elems[key]; exists {
+		e.order.Remove(elem)
+		delete(e.elems, key)
+	}
+}
+
+func (e *lruEvictor) victim(except string) (string, bool) {
+	for elem := e.order.Back(); elem != nil; elem = elem.Prev() {
+		if key := elem.Value.(string); key != except {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// Min-heap of keys ordered by access count then by last access
+type lfuEvictor struct {
+	entries *lfuHeap
+	index   map[string]*lfuEntry
+	clock   uint64
+}
+
+type lfuEntry struct {
+	key      string
+	count    uint64
+	lastUsed uint64
+	pos      int
+}
+
+// Implements heap.Interface.  This is a struct rather than a slice type because Blueprint's
+// goparser only supports methods on struct types.
+type lfuHeap struct {
+	entries []*lfuEntry
+}
+
+func (h *lfuHeap) Len() int { return len(h.entries) }
+func (h *lfuHeap) Less(i, j int) bool {
+	if h.entries[i].count != h.entries[j].count {
+		return h.entries[i].count < h.entries[j].count
+	}
+	return h.entries[i].lastUsed < h.entries[j].lastUsed
+}
+func (h *lfuHeap) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	h.entries[i].pos = i
+	h.entries[j].pos = j
+}
+func (h *lfuHeap) Push(x any) {
+	entry := x.(*lfuEntry)
+	entry.pos = len(h.entries)
+	h.entries = append(h.entries, entry)
+}
+func (h *lfuHeap) Pop() any {
+	last := len(h.entries) - 1
+	entry := h.entries[last]
+	h.entries[last] = nil
+	h.entries = h.entries[:last]
+	return entry
+}
+
+func newLFU() *lfuEvictor {
+	return &lfuEvictor{entries: &lfuHeap{}, index: make(map[string]*lfuEntry)}
+}
+
+func (e *lfuEvictor) add(key string) {
+	e.clock++
+	entry := &lfuEntry{key: key, count: 1, lastUsed: e.clock}
+	e.index[key] = entry
+	heap.Push(e.entries, entry)
+}
+
+func (e *lfuEvictor) touch(key string) {
+	if entry, exists := e.index[key]; exists {
+		e.clock++
+		entry.count++
+		entry.lastUsed = e.clock
+		heap.Fix(e.entries, entry.pos)
+	}
+}
+
+func (e *lfuEvictor) remove(key string) {
+	if entry, exists := e.index[key]; exists {
+		heap.Remove(e.entries, entry.pos)
+		delete(e.index, key)
+	}
+}
+
+func (e *lfuEvictor) victim(except string) (string, bool) {
+	entries := e.entries.entries
+	if len(entries) == 0 {
+		return "", false
+	}
+	if entries[0].key != except {
+		return entries[0].key, true
+	}
+	// The next least-frequently-used key is one of the children of the root
+	switch {
+	case len(entries) == 1:
+		return "", false
+	case len(entries) == 2 || e.entries.Less(1, 2):
+		return entries[1].key, true
+	default:
+		return entries[2].key, true
+	}
+}
+
+// Keys are stored in a slice so that a random victim can be chosen in constant time
+type randomEvictor struct {
+	keys  []string
+	index map[string]int
+}
+
+func newRandom() *randomEvictor {
+	return &randomEvictor{index: make(map[string]int)}
+}
+
+func (e *randomEvictor) add(key string) {
+	e.index[key] = len(e.keys)
+	e.keys = append(e.keys, key)
+}
+
+func (e *randomEvictor) touch(key string) {}
+
+func (e *randomEvictor) remove(key string) {
+	i, exists := e.index[key]
+	if !exists {
+		return
+	}
+	last := len(e.keys) - 1
+	e.keys[i] = e.keys[last]
+	e.index[e.keys[i]] = i
+	e.keys = e.keys[:last]
+	delete(e.index, key)
+}
+
+func (e *randomEvictor) victim(except string) (string, bool) {
+	candidates := len(e.keys)
+	i, skip := e.index[except]
+	if skip {
+		candidates--
+	}
+	if candidates <= 0 {
+		return "", false
+	}
+	// Choose among the keys other than except, by skipping over its index
+	j := rand.Intn(candidates)
+	if skip && j >= i {
+		j++
+	}
+	return e.keys[j], true
+}
@@ -0,0 +1,72 @@
+package simplecache
+
+import (
+	"context"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Hit, miss and eviction counters for a [SimpleCache], plus gauges reporting its current size.  Every
+// measurement has a cache attribute with the cache's name.
+//
+// The instruments are created on first use; see [backend.LazyMeter].
+type cacheMetrics struct {
+	cache     *SimpleCache
+	meter     backend.LazyMeter
+	hits      metric.Int64Counter
+	misses    metric.Int64Counter
+	evictions metric.Int64Counter
+}
+
+func (m *cacheMetrics) init(ctx context.Context) {
+	m.meter.Init(ctx, "simplecache", m.register)
+}
+
+func (m *cacheMetrics) register(meter metric.Meter) (err error) {
+	if m.hits, err = meter.Int64Counter("cache_hits", metric.WithDescription("Number of lookups that found the key")); err != nil {
+		return err
+	}
+	if m.misses, err = meter.Int64Counter("cache_misses", metric.WithDescription("Number of lookups that did not find the key")); err != nil {
+		return err
+	}
+	if m.evictions, err = meter.Int64Counter("cache_evictions", metric.WithDescription("Number of keys evicted to stay within capacity")); err != nil {
+		return err
+	}
+	keys, err := meter.Int64ObservableGauge("cache_keys", metric.WithDescription("Number of keys in the cache"))
+	if err != nil {
+		return err
+	}
+	bytes, err := meter.Int64ObservableGauge("cache_bytes", metric.WithDescription("Approximate size of the cache contents, if the cache is bounded by maxbytes"), metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		m.cache.RLock()
+		defer m.cache.RUnlock()
+		o.ObserveInt64(keys, int64(len(m.cache.values)), m.attrs())
+		o.ObserveInt64(bytes, m.cache.bytes, m.attrs())
+		return nil
+	}, keys, bytes)
+	return err
+}
+
+func (m *cacheMetrics) attrs() metric.MeasurementOption {
+	return metric.WithAttributes(attribute.String("cache", m.cache.name))
+}
+
+func (m *cacheMetrics) hit(ctx context.Context) {
+	m.init(ctx)
+	m.hits.Add(ctx, 1, m.attrs())
+}
+
+func (m *cacheMetrics) miss(ctx context.Context) {
+	m.init(ctx)
+	m.misses.Add(ctx, 1, m.attrs())
+}
+
+func (m *cacheMetrics) evicted(ctx context.Context, count int) {
+	m.init(ctx)
+	m.evictions.Add(ctx, int64(count), m.attrs())
+}
@@ -0,0 +1,261 @@
+package simplenosqldb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type VendorCount struct {
+	ID      string   `bson:"_id"`
+	Count   int      `bson:"count"`
+	Average float64  `bson:"average"`
+	Best    int      `bson:"best"`
+	Teas    []string `bson:"teas"`
+}
+
+func aggregate[T any](t *testing.T, ctx context.Context, db backend.NoSQLCollection, pipeline ...bson.D) []T {
+	cursor, err := db.Aggregate(ctx, pipeline)
+	require.NoError(t, err)
+	var results []T
+	require.NoError(t, cursor.All(ctx, &results))
+	return results
+}
+
+func TestAggregateMatchSortLimit(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+
+	results := aggregate[Tea](t, ctx, db,
+		bson.D{{"$match", bson.D{{"rating", bson.D{{"$gte", 6}}}}}},
+		bson.D{{"$sort", bson.D{{"rating", 1}}}},
+		bson.D{{"$skip", 1}},
+		bson.D{{"$limit", 2}},
+	)
+	require.Len(t, results, 2)
+	require.Equal(t, "Oolong", results[0].Type)
+	require.Equal(t, "Earl Grey", results[1].Type)
+
+	// The collection is unaffected by the pipeline
+	require.Equal(t, []string{"Masala", "English Breakfast", "Oolong", "Assam", "Earl Grey"},
+		findTypes(t, ctx, db, bson.D{}, backend.FindOptions{}))
+}
+
+func TestAggregateUnwindGroup(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+
+	results := aggregate[VendorCount](t, ctx, db,
+		bson.D{{"$unwind", "$vendor"}},
+		bson.D{{"$group", bson.D{
+			{"_id", "$vendor"},
+			{"count", bson.D{{"$sum", 1}}},
+			{"average", bson.D{{"$avg", "$rating"}}},
+			{"best", bson.D{{"$max", "$rating"}}},
+			{"teas", bson.D{{"$push", "$type"}}},
+		}}},
+		bson.D{{"$sort", bson.D{{"_id", 1}}}},
+	)
+	require.Equal(t, []VendorCount{
+		{ID: "A", Count: 2, Average: 9, Best: 10, Teas: []string{"Masala", "Earl Grey"}},
+		{ID: "B", Count: 1, Average: 8, Best: 8, Teas: []string{"Earl Grey"}},
+		{ID: "C", Count: 2, Average: 8.5, Best: 10, Teas: []string{"Masala", "Oolong"}},
+	}, results)
+}
+
+func TestAggregateUnwindOptions(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+
+	// Documents without vendors are preserved, and the array index is recorded
+	results := aggregate[bson.D](t, ctx, db,
+		bson.D{{"$unwind", bson.D{{"path", "$vendor"}, {"includeArrayIndex", "i"}, {"preserveNullAndEmptyArrays", true}}}},
+		bson.D{{"$project", bson.D{{"_id", 0}, {"type", 1}, {"vendor", 1}, {"i", 1}}}},
+	)
+	require.Len(t, results, 7)
+	require.Equal(t, bson.D{{"type", "Masala"}, {"vendor", "C"}, {"i", int64(1)}}, results[1])
+	require.Equal(t, bson.D{{"type", "English Breakfast"}, {"i", nil}}, results[2])
+}
+
+func TestAggregateGroupAccumulators(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+
+	results := aggregate[bson.D](t, ctx, db,
+		bson.D{{"$sort", bson.D{{"rating", 1}}}},
+		bson.D{{"$group", bson.D{
+			{"_id", nil},
+			{"count", bson.D{{"$count", bson.D{}}}},
+			{"total", bson.D{{"$sum", "$rating"}}},
+			{"lowest", bson.D{{"$min", "$rating"}}},
+			{"first", bson.D{{"$first", "$type"}}},
+			{"last", bson.D{{"$last", "$type"}}},
+			{"kinds", bson.D{{"$addToSet", "$packaging.kind"}}},
+		}}},
+	)
+	require.Equal(t, []bson.D{{
+		{"_id", nil},
+		{"count", int32(5)},
+		{"total", int32(36)},
+		{"lowest", int32(5)},
+		{"first", "Assam"},
+		{"last", "Masala"},
+		{"kinds", bson.A{"Cardboard", "", "Paper"}},
+	}}, results)
+
+	// Groups can be keyed by documents
+	results = aggregate[bson.D](t, ctx, db,
+		bson.D{{"$group", bson.D{{"_id", bson.D{{"kind", "$packaging.kind"}, {"sized", bson.D{{"$size", "$sizes"}}}}}, {"n", bson.D{{"$sum", 1}}}}}},
+		bson.D{{"$sort", bson.D{{"_id", 1}}}},
+	)
+	require.Equal(t, []bson.D{
+		{{"_id", bson.D{{"kind", ""}, {"sized", int32(1)}}}, {"n", int32(1)}},
+		{{"_id", bson.D{{"kind", ""}, {"sized", int32(2)}}}, {"n", int32(1)}},
+		{{"_id", bson.D{{"kind", ""}, {"sized", int32(3)}}}, {"n", int32(1)}},
+		{{"_id", bson.D{{"kind", "Cardboard"}, {"sized", int32(1)}}}, {"n", int32(1)}},
+		{{"_id", bson.D{{"kind", "Paper"}, {"sized", int32(1)}}}, {"n", int32(1)}},
+	}, results)
+}
+
+func TestAggregateProject(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+	match := bson.D{{"$match", bson.D{{"type", "Assam"}}}}
+
+	// Inclusion projections keep _id unless it is excluded, and can compute fields
+	results := aggregate[bson.D](t, ctx, db, match,
+		bson.D{{"$project", bson.D{
+			{"_id", 0},
+			{"packaging.kind", 1},
+			{"type", true},
+			{"area", bson.D{{"$multiply", bson.A{"$packaging.length", "$packaging.width"}}}},
+			{"label", bson.D{{"$concat", bson.A{"$type", " tea"}}}},
+			{"constant", bson.D{{"$literal", "$type"}}},
+		}}},
+	)
+	require.Equal(t, []bson.D{{
+		{"type", "Assam"},
+		{"packaging", bson.D{{"kind", "Cardboard"}}},
+		{"area", int64(40)},
+		{"label", "Assam tea"},
+		{"constant", "$type"},
+	}}, results)
+
+	// Exclusion projections remove fields
+	results = aggregate[bson.D](t, ctx, db, match,
+		bson.D{{"$project", bson.D{{"_id", 0}, {"sizes", 0}, {"packaging", bson.D{{"length", 0}, {"width", 0}}}}}},
+	)
+	require.Equal(t, []bson.D{{
+		{"type", "Assam"},
+		{"rating", int32(5)},
+		{"packaging", bson.D{{"kind", "Cardboard"}}},
+	}}, results)
+}
+
+func TestAggregateCount(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+
+	results := aggregate[bson.D](t, ctx, db,
+		bson.D{{"$match", bson.D{{"vendor", "A"}}}},
+		bson.D{{"$count", "teas"}},
+	)
+	require.Equal(t, []bson.D{{{"teas", int32(2)}}}, results)
+
+	results = aggregate[bson.D](t, ctx, db,
+		bson.D{{"$match", bson.D{{"vendor", "Z"}}}},
+		bson.D{{"$count", "teas"}},
+	)
+	require.Empty(t, results)
+}
+
+type Vendor struct {
+	Name    string `bson:"name"`
+	Country string `bson:"country"`
+}
+
+type TeaWithVendors struct {
+	Type    string   `bson:"type"`
+	Vendors []Vendor `bson:"vendors"`
+}
+
+func TestAggregateLookup(t *testing.T) {
+	ctx, db := getDB(t)
+	teaColl, err := db.GetCollection(ctx, "lookupdb", "teas")
+	require.NoError(t, err)
+	for _, tea := range teas {
+		require.NoError(t, teaColl.InsertOne(ctx, tea))
+	}
+	vendors, err := db.GetCollection(ctx, "lookupdb", "vendors")
+	require.NoError(t, err)
+	require.NoError(t, vendors.InsertMany(ctx, []interface{}{
+		Vendor{Name: "A", Country: "India"},
+		Vendor{Name: "B", Country: "China"},
+		Vendor{Name: "C", Country: "Sri Lanka"},
+	}))
+
+	// Array fields match any of their elements
+	results := aggregate[TeaWithVendors](t, ctx, teaColl,
+		bson.D{{"$match", bson.D{{"type", bson.D{{"$in", bson.A{"Earl Grey", "Assam"}}}}}}},
+		bson.D{{"$lookup", bson.D{{"from", "vendors"}, {"localField", "vendor"}, {"foreignField", "name"}, {"as", "vendors"}}}},
+	)
+	require.Equal(t, []TeaWithVendors{
+		{Type: "Assam", Vendors: []Vendor{}},
+		{Type: "Earl Grey", Vendors: []Vendor{{Name: "A", Country: "India"}, {Name: "B", Country: "China"}}},
+	}, results)
+
+	// Lookups can be followed by other stages
+	counts := aggregate[bson.D](t, ctx, teaColl,
+		bson.D{{"$lookup", bson.D{{"from", "vendors"}, {"localField", "vendor"}, {"foreignField", "name"}, {"as", "vendors"}}}},
+		bson.D{{"$unwind", "$vendors"}},
+		bson.D{{"$group", bson.D{{"_id", "$vendors.country"}, {"n", bson.D{{"$sum", 1}}}}}},
+		bson.D{{"$sort", bson.D{{"_id", 1}}}},
+	)
+	require.Equal(t, []bson.D{
+		{{"_id", "China"}, {"n", int32(1)}},
+		{{"_id", "India"}, {"n", int32(2)}},
+		{{"_id", "Sri Lanka"}, {"n", int32(2)}},
+	}, counts)
+
+	// Looking up a nonexistent collection matches nothing
+	results = aggregate[TeaWithVendors](t, ctx, teaColl,
+		bson.D{{"$match", bson.D{{"type", "Oolong"}}}},
+		bson.D{{"$lookup", bson.D{{"from", "missing"}, {"localField", "vendor"}, {"foreignField", "name"}, {"as", "vendors"}}}},
+	)
+	require.Equal(t, []TeaWithVendors{{Type: "Oolong", Vendors: []Vendor{}}}, results)
+}
+
+func TestAggregateIndexedMatch(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+	_, err := db.CreateIndex(ctx, bson.D{{"type", 1}}, false)
+	require.NoError(t, err)
+
+	results := aggregate[Tea](t, ctx, db,
+		bson.D{{"$match", bson.D{{"type", "Oolong"}}}},
+		bson.D{{"$match", bson.D{{"rating", 7}}}},
+	)
+	require.Len(t, results, 1)
+	require.Equal(t, "Oolong", results[0].Type)
+}
+
+func TestAggregateInvalidPipelines(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+
+	for _, pipeline := range [][]bson.D{
+		{{{"$bogus", bson.D{}}}},
+		{{{"$match", bson.D{}}, {"$limit", 1}}},
+		{{{"$limit", 0}}},
+		{{{"$skip", -1}}},
+		{{{"$sort", bson.D{{"rating", 0}}}}},
+		{{{"$group", bson.D{{"count", bson.D{{"$sum", 1}}}}}}},
+		{{{"$group", bson.D{{"_id", nil}, {"count", bson.D{{"$median", 1}}}}}}},
+		{{{"$project", bson.D{{"type", 1}, {"rating", 0}}}}},
+		{{{"$project", bson.D{{"packaging", 1}, {"packaging.kind", 1}}}}},
+		{{{"$unwind", "vendor"}}},
+		{{{"$lookup", bson.D{{"from", "other"}, {"pipeline", bson.A{}}, {"as", "x"}}}}},
+	} {
+		_, err := db.Aggregate(ctx, pipeline)
+		require.Error(t, err, "pipeline %v", pipeline)
+	}
+
+	// Expressions are checked as they are evaluated
+	_, err := db.Aggregate(ctx, []bson.D{{{"$project", bson.D{{"x", bson.D{{"$add", bson.A{"$type", 1}}}}}}}})
+	require.Error(t, err)
+}
@@ -0,0 +1,189 @@
+package simplenosqldb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Imports every collection file found in dir.
+//
+// Files must be laid out as dir/<db_name>/<collection_name>.<json|bson>, as produced by [SimpleNoSQLDB.Export].
+// The documents of each file are inserted into the corresponding collection; see [SimpleCollection.Import].
+func (impl *SimpleNoSQLDB) Import(ctx context.Context, dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		ext := filepath.Ext(path)
+		if ext != ".json" && ext != ".bson" {
+			continue
+		}
+		dbName := filepath.Base(filepath.Dir(path))
+		collectionName := strings.TrimSuffix(filepath.Base(path), ext)
+		collection, err := impl.GetCollection(ctx, dbName, collectionName)
+		if err != nil {
+			return err
+		}
+		if err := collection.(*SimpleCollection).Import(ctx, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Exports every collection to dir, laid out as dir/<db_name>/<collection_name>.<format>.
+//
+// format must be "json" or "bson"; see [SimpleCollection.Export].
+func (impl *SimpleNoSQLDB) Export(ctx context.Context, dir string, format string) error {
+	if format != "json" && format != "bson" {
+		return fmt.Errorf("unknown export format %v; expected json or bson", format)
+	}
+	impl.mu.RLock()
+	defer impl.mu.RUnlock()
+	for dbName, collections := range impl.collections {
+		if err := os.MkdirAll(filepath.Join(dir, dbName), 0755); err != nil {
+			return err
+		}
+		for collectionName, collection := range collections {
+			path := filepath.Join(dir, dbName, collectionName+"."+format)
+			if err := collection.Export(ctx, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Inserts the documents from the file at path into the collection.
+//
+// The file format is chosen by the file extension:
+//   - .json files contain MongoDB Extended JSON documents, either as a JSON array or one document
+//     after another, as produced by mongoexport
+//   - .bson files contain concatenated BSON documents, as produced by mongodump
+func (db *SimpleCollection) Import(ctx context.Context, path string) error {
+	var docs []interface{}
+	switch filepath.Ext(path) {
+	case ".json":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		isArray := false
+		if tok, err := dec.Token(); err == nil && tok == json.Delim('[') {
+			isArray = true
+		} else {
+			dec = json.NewDecoder(bytes.NewReader(data))
+		}
+		for dec.More() {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return fmt.Errorf("unable to import %v: %v", path, err)
+			}
+			var d bson.D
+			if err := bson.UnmarshalExtJSON(raw, false, &d); err != nil {
+				return fmt.Errorf("unable to import %v: %v", path, err)
+			}
+			docs = append(docs, d)
+		}
+		if isArray {
+			if _, err := dec.Token(); err != nil {
+				return fmt.Errorf("unable to import %v: %v", path, err)
+			}
+		}
+	case ".bson":
+		_, err := readDocuments(path, false, func(raw bson.Raw) error {
+			var d bson.D
+			if err := bson.Unmarshal(raw, &d); err != nil {
+				return err
+			}
+			docs = append(docs, d)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("unable to import %v: %v", path, err)
+		}
+	default:
+		return fmt.Errorf("unable to import %v; expected a .json or .bson file", path)
+	}
+	return db.InsertMany(ctx, docs)
+}
+
+// Writes all documents in the collection to the file at path, replacing it if it exists.
+//
+// The file format is chosen by the file extension:
+//   - .json files contain a JSON array of documents in MongoDB's relaxed Extended JSON format
+//   - .bson files contain concatenated BSON documents, as produced by mongodump.  Unlike JSON, BSON
+//     exactly preserves the types of all values
+func (db *SimpleCollection) Export(ctx context.Context, path string) error {
+	ext := filepath.Ext(path)
+	if ext != ".json" && ext != ".bson" {
+		return fmt.Errorf("unable to export to %v; expected a .json or .bson file", path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	w := bufio.NewWriter(f)
+	if ext == ".json" {
+		err = exportJSON(w, db.items)
+	} else {
+		for _, d := range db.items {
+			if err = writeBson(w, d); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func exportJSON(w io.Writer, docs []bson.D) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i, d := range docs {
+		data, err := bson.MarshalExtJSON(d, false, false)
+		if err != nil {
+			return err
+		}
+		sep := ",\n"
+		if i == 0 {
+			sep = "\n"
+		}
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\n]\n")
+	return err
+}
+
+func writeBson(w io.Writer, v any) error {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,117 @@
+package simplenosqldb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func findTypes(t *testing.T, ctx context.Context, db backend.NoSQLCollection, filter bson.D, opts backend.FindOptions) []string {
+	cursor, err := db.Find(ctx, filter, opts)
+	require.NoError(t, err)
+	var results []Tea
+	require.NoError(t, cursor.All(ctx, &results))
+	var types []string
+	for _, tea := range results {
+		types = append(types, tea.Type)
+	}
+	return types
+}
+
+func TestFindSort(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+
+	require.Equal(t, []string{"Masala", "Earl Grey", "Oolong", "English Breakfast", "Assam"},
+		findTypes(t, ctx, db, bson.D{}, backend.FindOptions{Sort: bson.D{{"rating", -1}}}))
+
+	require.Equal(t, []string{"Assam", "Earl Grey", "English Breakfast", "Masala", "Oolong"},
+		findTypes(t, ctx, db, bson.D{}, backend.FindOptions{Sort: bson.D{{"type", 1}}}))
+
+	// Sorting is combined with filters
+	require.Equal(t, []string{"Oolong", "Earl Grey", "Masala"},
+		findTypes(t, ctx, db, bson.D{{"rating", bson.D{{"$gte", 7}}}}, backend.FindOptions{Sort: bson.D{{"rating", 1}}}))
+}
+
+func TestFindSortMultipleKeys(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+	require.NoError(t, db.InsertOne(ctx, Tea{Type: "Darjeeling", Rating: 8}))
+
+	require.Equal(t, []string{"Masala", "Darjeeling", "Earl Grey", "Oolong", "English Breakfast", "Assam"},
+		findTypes(t, ctx, db, bson.D{}, backend.FindOptions{Sort: bson.D{{"rating", -1}, {"type", 1}}}))
+}
+
+func TestFindSortArraysAndMissingFields(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+
+	// Ascending sorts use the smallest array element and descending sorts the largest
+	require.Equal(t, []string{"Masala", "English Breakfast", "Oolong", "Assam", "Earl Grey"},
+		findTypes(t, ctx, db, bson.D{}, backend.FindOptions{Sort: bson.D{{"sizes", 1}}}))
+	require.Equal(t, []string{"Earl Grey", "English Breakfast", "Oolong", "Assam", "Masala"},
+		findTypes(t, ctx, db, bson.D{}, backend.FindOptions{Sort: bson.D{{"sizes", -1}}}))
+
+	// Missing fields sort as null, before all other values
+	require.Equal(t, []string{"English Breakfast", "Assam", "Masala", "Earl Grey", "Oolong"},
+		findTypes(t, ctx, db, bson.D{}, backend.FindOptions{Sort: bson.D{{"vendor", 1}}}))
+}
+
+func TestFindSkipLimit(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+	sort := bson.D{{"rating", 1}}
+
+	require.Equal(t, []string{"Assam", "English Breakfast"},
+		findTypes(t, ctx, db, bson.D{}, backend.FindOptions{Sort: sort, Limit: 2}))
+	require.Equal(t, []string{"Oolong", "Earl Grey"},
+		findTypes(t, ctx, db, bson.D{}, backend.FindOptions{Sort: sort, Skip: 2, Limit: 2}))
+	require.Equal(t, []string{"Masala"},
+		findTypes(t, ctx, db, bson.D{}, backend.FindOptions{Sort: sort, Skip: 4, Limit: 2}))
+	require.Empty(t, findTypes(t, ctx, db, bson.D{}, backend.FindOptions{Sort: sort, Skip: 10}))
+
+	// Without a sort, documents are returned in insertion order
+	require.Equal(t, []string{"English Breakfast", "Oolong"},
+		findTypes(t, ctx, db, bson.D{}, backend.FindOptions{Skip: 1, Limit: 2, BatchSize: 1}))
+}
+
+func TestCursorNext(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+
+	cursor, err := db.Find(ctx, bson.D{}, backend.FindOptions{Sort: bson.D{{"rating", -1}}, Limit: 3})
+	require.NoError(t, err)
+	var types []string
+	for {
+		var tea Tea
+		ok, err := cursor.Next(ctx, &tea)
+		require.NoError(t, err)
+		if !ok {
+			break
+		}
+		types = append(types, tea.Type)
+	}
+	require.Equal(t, []string{"Masala", "Earl Grey", "Oolong"}, types)
+	require.NoError(t, cursor.Close(ctx))
+
+	// Cursors are unaffected by subsequent writes
+	cursor, err = db.FindMany(ctx, bson.D{{"type", "Assam"}})
+	require.NoError(t, err)
+	_, err = db.UpdateOne(ctx, bson.D{{"type", "Assam"}}, bson.D{{"$set", bson.D{{"rating", 1}}}})
+	require.NoError(t, err)
+	var tea Tea
+	ok, err := cursor.Next(ctx, &tea)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 5, tea.Rating)
+	ok, err = cursor.Next(ctx, &tea)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestFindInvalidOptions(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+
+	_, err := db.Find(ctx, bson.D{}, backend.FindOptions{Sort: bson.D{{"rating", 2}}})
+	require.Error(t, err)
+	_, err = db.Find(ctx, bson.D{}, backend.FindOptions{Sort: bson.D{{"rating", "up"}}})
+	require.Error(t, err)
+}
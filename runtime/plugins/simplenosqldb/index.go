@@ -0,0 +1,338 @@
+package simplenosqldb
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blueprint-uservices/blueprint/runtime/plugins/simplenosqldb/query"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The error code used by MongoDB for unique index violations
+const duplicateKeyErrorCode = 11000
+
+// A secondary index over one or more fields of a [SimpleCollection]'s documents.
+//
+// The index maps the encoded values of the indexed fields to the positions of the documents that
+// have those values.  To support queries on a prefix of a compound index, there is a map for each
+// prefix of the indexed fields.  As with MongoDB, array fields are indexed by each of their
+// elements, and a missing field is indexed as null.
+type index struct {
+	name    string
+	keys    bson.D // The index specification, e.g. {{"user", 1}, {"created", -1}}
+	unique  bool
+	entries []map[string][]int // entries[i] indexes the first i+1 fields; positions are ascending
+}
+
+// Implements the [backend.NoSQLCollection] interface.
+//
+// Indexes are used by the Find, Update, Delete and Replace methods whenever the filter constrains
+// the indexed fields (or a prefix of them) to equal a value or one of a list of $in values.
+// Index directions are accepted for compatibility with MongoDB but are otherwise ignored.
+func (db *SimpleCollection) CreateIndex(ctx context.Context, keys bson.D, unique bool) (string, error) {
+	var name string
+	err := db.write(func() error {
+		var created bool
+		var err error
+		name, created, err = db.createIndex(keys, unique)
+		if err != nil || !created {
+			return err
+		}
+		return db.log(walRecord{Op: opIndex, Doc: keys, Unique: unique})
+	})
+	return name, err
+}
+
+// Creates and populates an index, without logging it.  Returns false if the index already exists.
+// The caller must hold the write lock.
+func (db *SimpleCollection) createIndex(keys bson.D, unique bool) (string, bool, error) {
+	if len(keys) == 0 {
+		return "", false, fmt.Errorf("index keys must not be empty")
+	}
+	var names []string
+	for _, e := range keys {
+		if _, isNumber := numberKey(e.Value); !isNumber {
+			return "", false, fmt.Errorf("unsupported index type %v for field %v", e.Value, e.Key)
+		}
+		names = append(names, fmt.Sprintf("%v_%v", e.Key, e.Value))
+	}
+	name := strings.Join(names, "_")
+
+	for _, idx := range db.indexes {
+		if idx.name == name {
+			if idx.unique != unique {
+				return "", false, fmt.Errorf("index with name %v already exists with different options", name)
+			}
+			return name, false, nil
+		}
+	}
+
+	idx := newIndex(name, keys, unique)
+	for i, doc := range db.items {
+		if err := db.checkIndexUnique(idx, doc, -1); err != nil {
+			return "", false, err
+		}
+		idx.add(i, doc)
+	}
+	db.indexes = append(db.indexes, idx)
+	return name, true, nil
+}
+
+func newIndex(name string, keys bson.D, unique bool) *index {
+	idx := &index{name: name, keys: keys, unique: unique}
+	for range keys {
+		idx.entries = append(idx.entries, make(map[string][]int))
+	}
+	return idx
+}
+
+// Returns the encoded keys of doc for each prefix of the indexed fields
+func (idx *index) documentKeys(doc bson.D) [][]string {
+	var prefixKeys [][]string
+	keys := []string{""}
+	for i, e := range idx.keys {
+		var fieldKeys []string
+		for _, v := range query.Values(doc, e.Key) {
+			fieldKeys = append(fieldKeys, encodeKey(v))
+		}
+		if len(fieldKeys) == 0 {
+			fieldKeys = append(fieldKeys, encodeKey(nil))
+		}
+		keys = combineKeys(keys, fieldKeys, i == 0)
+		prefixKeys = append(prefixKeys, keys)
+	}
+	return prefixKeys
+}
+
+// Returns the deduplicated cartesian product of prefixes and fieldKeys
+func combineKeys(prefixes []string, fieldKeys []string, first bool) []string {
+	seen := make(map[string]struct{})
+	var combined []string
+	for _, prefix := range prefixes {
+		for _, fieldKey := range fieldKeys {
+			key := fieldKey
+			if !first {
+				key = prefix + "\x00" + fieldKey
+			}
+			if _, exists := seen[key]; !exists {
+				seen[key] = struct{}{}
+				combined = append(combined, key)
+			}
+		}
+	}
+	return combined
+}
+
+// Adds the document at position i to the index
+func (idx *index) add(i int, doc bson.D) {
+	for level, keys := range idx.documentKeys(doc) {
+		for _, key := range keys {
+			positions := idx.entries[level][key]
+			j := sort.SearchInts(positions, i)
+			positions = append(positions, 0)
+			copy(positions[j+1:], positions[j:])
+			positions[j] = i
+			idx.entries[level][key] = positions
+		}
+	}
+}
+
+// Removes the document at position i from the index
+func (idx *index) remove(i int, doc bson.D) {
+	for level, keys := range idx.documentKeys(doc) {
+		for _, key := range keys {
+			positions := idx.entries[level][key]
+			j := sort.SearchInts(positions, i)
+			if j < len(positions) && positions[j] == i {
+				positions = append(positions[:j], positions[j+1:]...)
+			}
+			if len(positions) == 0 {
+				delete(idx.entries[level], key)
+			} else {
+				idx.entries[level][key] = positions
+			}
+		}
+	}
+}
+
+// Returns the ascending positions of the documents that might match predicates, using the longest
+// prefix of the indexed fields that predicates constrain.  The returned slice is newly allocated.
+// Returns false if predicates do not constrain the first indexed field.
+func (idx *index) lookup(predicates map[string][]any) ([]int, bool) {
+	keys := []string{""}
+	level := -1
+	for i, e := range idx.keys {
+		values, constrained := predicates[e.Key]
+		if !constrained {
+			break
+		}
+		var fieldKeys []string
+		for _, v := range values {
+			fieldKeys = append(fieldKeys, encodeKey(v))
+		}
+		keys = combineKeys(keys, fieldKeys, i == 0)
+		level = i
+	}
+	if level < 0 {
+		return nil, false
+	}
+
+	var positions []int
+	for _, key := range keys {
+		positions = append(positions, idx.entries[level][key]...)
+	}
+	if len(keys) > 1 {
+		// Documents can appear under more than one key, e.g. for array fields
+		sort.Ints(positions)
+		deduplicated := positions[:0]
+		for _, p := range positions {
+			if len(deduplicated) == 0 || p != deduplicated[len(deduplicated)-1] {
+				deduplicated = append(deduplicated, p)
+			}
+		}
+		positions = deduplicated
+	}
+	return positions, true
+}
+
+// Returns the positions of the documents that might match filter, in ascending order.
+//
+// If the filter constrains indexed fields then the most selective index is used; otherwise
+// all positions are returned.  The returned slice is not modified by subsequent changes to
+// the collection.
+func (db *SimpleCollection) candidates(filter bson.D) []int {
+	var best []int
+	found := false
+	if len(db.indexes) > 0 {
+		predicates := query.EqualityPredicates(filter)
+		for _, idx := range db.indexes {
+			if positions, ok := idx.lookup(predicates); ok && (!found || len(positions) < len(best)) {
+				best, found = positions, true
+			}
+		}
+	}
+	if found {
+		return best
+	}
+	all := make([]int, len(db.items))
+	for i := range all {
+		all[i] = i
+	}
+	return all
+}
+
+// Returns a duplicate key error if doc violates any of the collection's unique indexes.
+// If doc is replacing an existing document, then position is that document's position,
+// otherwise it is -1.
+func (db *SimpleCollection) checkUnique(doc bson.D, position int) error {
+	for _, idx := range db.indexes {
+		if err := db.checkIndexUnique(idx, doc, position); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *SimpleCollection) checkIndexUnique(idx *index, doc bson.D, position int) error {
+	if !idx.unique {
+		return nil
+	}
+	prefixKeys := idx.documentKeys(doc)
+	for _, key := range prefixKeys[len(prefixKeys)-1] {
+		for _, p := range idx.entries[len(idx.entries)-1][key] {
+			if p != position {
+				return db.duplicateKeyError(idx, doc)
+			}
+		}
+	}
+	return nil
+}
+
+// Returns an error equivalent to the one MongoDB returns for unique index violations,
+// so that mongo.IsDuplicateKeyError can be used to check for it
+func (db *SimpleCollection) duplicateKeyError(idx *index, doc bson.D) error {
+	var fields []string
+	for _, e := range idx.keys {
+		var value any = "null"
+		if values := query.Values(doc, e.Key); len(values) > 0 && values[0] != nil {
+			value = values[0]
+		}
+		fields = append(fields, fmt.Sprintf("%v: %v", e.Key, value))
+	}
+	return mongo.WriteException{
+		WriteErrors: []mongo.WriteError{{
+			Code:    duplicateKeyErrorCode,
+			Message: fmt.Sprintf("E11000 duplicate key error collection: %v.%v index: %v dup key: { %v }", db.dbName, db.name, idx.name, strings.Join(fields, ", ")),
+		}},
+	}
+}
+
+// Encodes a value as an index key.
+//
+// Values that filters consider equal have the same encoding; in particular, numbers of different
+// types are encoded by their numeric value.  Documents and arrays are encoded structurally, so
+// that they can be checked for uniqueness.
+func encodeKey(value any) string {
+	if key, isNumber := numberKey(value); isNumber {
+		return key
+	}
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "s" + strconv.Quote(v)
+	case bool:
+		return "b" + strconv.FormatBool(v)
+	case primitive.ObjectID:
+		return "o" + v.Hex()
+	case primitive.DateTime:
+		return "d" + strconv.FormatInt(int64(v), 10)
+	}
+	if data, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: value}}, true, false); err == nil {
+		return "x" + string(data)
+	}
+	return fmt.Sprintf("x%#v", value)
+}
+
+// Integers with magnitude beyond this cannot all be represented exactly as a float64
+const maxExactFloatInt = 1 << 53
+
+func numberKey(value any) (string, bool) {
+	var i int64
+	switch v := value.(type) {
+	case int:
+		i = int64(v)
+	case int8:
+		i = int64(v)
+	case int16:
+		i = int64(v)
+	case int32:
+		i = int64(v)
+	case int64:
+		i = v
+	case float32:
+		return floatKey(float64(v)), true
+	case float64:
+		return floatKey(v), true
+	default:
+		return "", false
+	}
+	if i > maxExactFloatInt || i < -maxExactFloatInt {
+		// Filters compare integers with floats by converting them to floats
+		return floatKey(float64(i)), true
+	}
+	return "n" + strconv.FormatInt(i, 10), true
+}
+
+func floatKey(f float64) string {
+	if f == math.Trunc(f) && math.Abs(f) < math.MaxInt64 {
+		return "n" + strconv.FormatInt(int64(f), 10)
+	}
+	return "n" + strconv.FormatFloat(f, 'g', -1, 64)
+}
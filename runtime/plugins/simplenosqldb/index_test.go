@@ -0,0 +1,196 @@
+package simplenosqldb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestCreateIndex(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+
+	name, err := db.CreateIndex(ctx, bson.D{{"type", 1}}, false)
+	require.NoError(t, err)
+	require.Equal(t, "type_1", name)
+
+	name, err = db.CreateIndex(ctx, bson.D{{"rating", 1}, {"packaging.kind", -1}}, false)
+	require.NoError(t, err)
+	require.Equal(t, "rating_1_packaging.kind_-1", name)
+
+	// Creating an existing index is a no-op
+	name, err = db.CreateIndex(ctx, bson.D{{"type", 1}}, false)
+	require.NoError(t, err)
+	require.Equal(t, "type_1", name)
+}
+
+func TestIndexedFind(t *testing.T) {
+	ctx, scanned := MakeTestDB(t)
+	_, indexed := MakeTestDB(t)
+
+	for _, keys := range []bson.D{
+		{{"type", 1}},
+		{{"rating", 1}, {"type", 1}},
+		{{"vendor", 1}},
+		{{"sizes", 1}},
+		{{"packaging.kind", 1}},
+	} {
+		_, err := indexed.CreateIndex(ctx, keys, false)
+		require.NoError(t, err)
+	}
+
+	filters := []bson.D{
+		{{"type", "Oolong"}},
+		{{"type", "Lapsang"}},
+		{{"rating", 7}},
+		{{"rating", 7.0}},
+		{{"rating", bson.D{{"$eq", int64(8)}}}},
+		{{"rating", bson.D{{"$in", bson.A{5, 6, 10}}}}},
+		{{"rating", 6}, {"type", "English Breakfast"}},
+		{{"rating", 6}, {"type", "Masala"}},
+		{{"rating", bson.D{{"$in", bson.A{5, 10}}}}, {"type", bson.D{{"$in", bson.A{"Assam", "Masala", "Oolong"}}}}},
+		{{"vendor", "A"}},
+		{{"sizes", 16}},
+		{{"sizes", bson.D{{"$in", bson.A{4, 32}}}}},
+		{{"packaging.kind", "Paper"}},
+		{{"$and", bson.A{bson.D{{"type", "Assam"}}, bson.D{{"rating", bson.D{{"$gt", 4}}}}}}},
+		{{"type", "Masala"}, {"rating", bson.D{{"$lt", 5}}}},
+		{{"$or", bson.A{bson.D{{"type", "Assam"}}, bson.D{{"type", "Oolong"}}}}},
+	}
+	for _, filter := range filters {
+		var expected, actual []Tea
+		cursor, err := scanned.FindMany(ctx, filter)
+		require.NoError(t, err)
+		require.NoError(t, cursor.All(ctx, &expected))
+
+		cursor, err = indexed.FindMany(ctx, filter)
+		require.NoError(t, err)
+		require.NoError(t, cursor.All(ctx, &actual))
+		require.Equal(t, expected, actual, "filter %v", filter)
+	}
+}
+
+func TestIndexedWrites(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+	_, err := db.CreateIndex(ctx, bson.D{{"rating", 1}}, false)
+	require.NoError(t, err)
+
+	// The index is kept up to date by updates, replacements and deletes
+	updated, err := db.UpdateOne(ctx, bson.D{{"type", "Oolong"}}, bson.D{{"$set", bson.D{{"rating", 9}}}})
+	require.NoError(t, err)
+	require.Equal(t, 1, updated)
+	_, err = db.ReplaceOne(ctx, bson.D{{"rating", 10}}, Tea{Type: "Lapsang", Rating: 9})
+	require.NoError(t, err)
+	require.NoError(t, db.DeleteOne(ctx, bson.D{{"type", "English Breakfast"}}))
+	require.NoError(t, db.InsertOne(ctx, Tea{Type: "Sencha", Rating: 7}))
+
+	cursor, err := db.FindMany(ctx, bson.D{{"rating", 9}})
+	require.NoError(t, err)
+	var results []Tea
+	require.NoError(t, cursor.All(ctx, &results))
+	require.Len(t, results, 2)
+	require.Equal(t, "Lapsang", results[0].Type)
+	require.Equal(t, "Oolong", results[1].Type)
+
+	cursor, err = db.FindMany(ctx, bson.D{{"rating", bson.D{{"$in", bson.A{6, 7, 10}}}}})
+	require.NoError(t, err)
+	require.NoError(t, cursor.All(ctx, &results))
+	require.Len(t, results, 1)
+	require.Equal(t, "Sencha", results[0].Type)
+
+	updated, err = db.UpdateMany(ctx, bson.D{{"rating", 9}}, bson.D{{"$inc", bson.D{{"rating", 1}}}})
+	require.NoError(t, err)
+	require.Equal(t, 2, updated)
+	require.NoError(t, db.DeleteMany(ctx, bson.D{{"rating", 10}}))
+
+	cursor, err = db.FindMany(ctx, bson.D{})
+	require.NoError(t, err)
+	require.NoError(t, cursor.All(ctx, &results))
+	require.Len(t, results, 3)
+}
+
+func TestUniqueIndex(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+	_, err := db.CreateIndex(ctx, bson.D{{"type", 1}}, true)
+	require.NoError(t, err)
+
+	err = db.InsertOne(ctx, Tea{Type: "Masala", Rating: 1})
+	require.Error(t, err)
+	require.True(t, mongo.IsDuplicateKeyError(err))
+
+	_, err = db.UpdateOne(ctx, bson.D{{"type", "Assam"}}, bson.D{{"$set", bson.D{{"type", "Oolong"}}}})
+	require.True(t, mongo.IsDuplicateKeyError(err))
+
+	_, err = db.ReplaceOne(ctx, bson.D{{"type", "Assam"}}, Tea{Type: "Earl Grey"})
+	require.True(t, mongo.IsDuplicateKeyError(err))
+
+	// Replacing a document with itself is not a violation
+	_, err = db.ReplaceOne(ctx, bson.D{{"type", "Assam"}}, Tea{Type: "Assam", Rating: 6})
+	require.NoError(t, err)
+
+	// Multi-document writes are all or nothing, including when the documents duplicate each other
+	_, err = db.UpdateMany(ctx, bson.D{}, bson.D{{"$set", bson.D{{"type", "Pu-erh"}}}})
+	require.True(t, mongo.IsDuplicateKeyError(err))
+	_, err = db.ReplaceMany(ctx, bson.D{}, Tea{Type: "Pu-erh"}, Tea{Type: "Oolong"})
+	require.True(t, mongo.IsDuplicateKeyError(err))
+
+	// The failed writes had no effect
+	cursor, err := db.FindMany(ctx, bson.D{})
+	require.NoError(t, err)
+	var results []Tea
+	require.NoError(t, cursor.All(ctx, &results))
+	require.Len(t, results, len(teas))
+	for i := range teas {
+		require.Equal(t, teas[i].Type, results[i].Type)
+	}
+
+	// Deleting a document frees its key
+	require.NoError(t, db.DeleteOne(ctx, bson.D{{"type", "Masala"}}))
+	require.NoError(t, db.InsertOne(ctx, Tea{Type: "Masala", Rating: 1}))
+}
+
+func TestUniqueCompoundIndex(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+	_, err := db.CreateIndex(ctx, bson.D{{"type", 1}, {"rating", 1}}, true)
+	require.NoError(t, err)
+
+	require.NoError(t, db.InsertOne(ctx, Tea{Type: "Masala", Rating: 9}))
+	err = db.InsertOne(ctx, Tea{Type: "Masala", Rating: 10})
+	require.True(t, mongo.IsDuplicateKeyError(err))
+}
+
+func TestUniqueIndexOnDuplicates(t *testing.T) {
+	ctx, db := MakeTestDB(t)
+	require.NoError(t, db.InsertOne(ctx, Tea{Type: "Masala", Rating: 1}))
+
+	_, err := db.CreateIndex(ctx, bson.D{{"type", 1}}, true)
+	require.True(t, mongo.IsDuplicateKeyError(err))
+
+	// The failed index is not created, so a non-unique index can be created instead
+	_, err = db.CreateIndex(ctx, bson.D{{"type", 1}}, false)
+	require.NoError(t, err)
+}
+
+func TestIndexPersistence(t *testing.T) {
+	dir := t.TempDir()
+
+	ctx, db := openPersistentDB(t, "dir="+dir, "snapshot=0")
+	coll, err := db.GetCollection(ctx, "testdb", "teas")
+	require.NoError(t, err)
+	_, err = coll.CreateIndex(ctx, bson.D{{"type", 1}}, true)
+	require.NoError(t, err)
+	insertTeas(t, ctx, db)
+
+	// Indexes are restored from both the write-ahead log and snapshots
+	for _, snapshot := range []bool{false, true} {
+		if snapshot {
+			require.NoError(t, db.Snapshot(ctx))
+		}
+		ctx, db = openPersistentDB(t, "dir="+dir, "snapshot=0")
+		coll, err = db.GetCollection(ctx, "testdb", "teas")
+		require.NoError(t, err)
+		err = coll.InsertOne(ctx, Tea{Type: "Masala"})
+		require.True(t, mongo.IsDuplicateKeyError(err), "snapshot %v", snapshot)
+	}
+}
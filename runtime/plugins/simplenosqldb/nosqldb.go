@@ -2,6 +2,25 @@
 //
 // Only a small set of common basic filter and update operators are supported, but typically this is sufficient
 // for most applications and enables writing service-level unit tests.
+//
+// Aggregation pipelines support the $match, $group, $sort, $project, $unwind, $skip, $limit, $count and $lookup stages.
+//
+// Collections support single-field, compound and unique secondary indexes.  Queries that constrain indexed fields
+// to particular values are evaluated using the index rather than by scanning the collection, and writes that
+// violate a unique index fail with the same duplicate key error as MongoDB.
+//
+// By default the database is purely in-memory.  It can optionally persist its contents to a directory, using a
+// write-ahead log plus periodic BSON snapshots, so that data survives process restarts.  Collections can also be
+// imported from and exported to JSON or BSON files, e.g. to seed a database with fixtures.
+//
+// The database is safe for concurrent use.  Each operation on a collection is atomic and isolated: reads observe
+// either all or none of the changes made by a concurrent write, including multi-document writes such as UpdateMany
+// (a stronger guarantee than MongoDB, whose multi-document writes are not isolated), and a multi-document write
+// that fails, e.g. with a duplicate key error, leaves the collection unchanged.  The exception is InsertMany, which
+// like MongoDB's ordered inserts keeps the documents that were inserted before the failure.  Writes to the same collection
+// are serialized, whereas reads of a collection and operations on different collections proceed concurrently.
+// Cursors are snapshots of their results and are unaffected by subsequent writes.  There are no transactions
+// spanning multiple operations.
 package simplenosqldb
 
 import (
@@ -9,8 +28,11 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/blueprint-uservices/blueprint/runtime/core/options"
 	"github.com/blueprint-uservices/blueprint/runtime/plugins/simplenosqldb/query"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -24,27 +46,104 @@ type (
 	//
 	// Only a small set of common basic filter and update operators are supported, but typically this is sufficient
 	// for most applications and enables writing service-level unit tests.
+	//
+	// The database is safe for concurrent use; see the package documentation for its isolation guarantees.
 	SimpleNoSQLDB struct {
+		// Guards collections.  Collection writes hold a read lock for their duration, so that
+		// snapshots, which hold the write lock, observe no partially-logged writes.
+		mu          sync.RWMutex
 		collections map[string]map[string]*SimpleCollection
+		persistence *persistence // nil if the database is in-memory only
 	}
 
 	SimpleCollection struct {
-		items []bson.D
+		mu      sync.RWMutex // Guards items and indexes
+		items   []bson.D
+		indexes []*index
+
+		impl   *SimpleNoSQLDB
+		dbName string
+		name   string
 	}
 
+	// Cursors are snapshots of their results and are unaffected by subsequent writes
 	SimpleCursor struct {
 		results []bson.D
+		next    int // The position of the next result returned by Next
 	}
 )
 
 // Instantiate a new in-memory NoSQLDB
-func NewSimpleNoSQLDB(ctx context.Context) (*SimpleNoSQLDB, error) {
+//
+// opts are optional "key=value" strings that configure the database:
+//   - dir=<path> persists the database to the directory at path.  On startup, the database is restored from
+//     the most recent snapshot and write-ahead log in the directory
+//   - snapshot=<duration> is the minimum interval between snapshots of a persistent database, e.g. "snapshot=30s";
+//     defaults to 1m.  Snapshots are taken when the database is modified after the interval has elapsed.
+//     An interval of 0 disables automatic snapshots; see [SimpleNoSQLDB.Snapshot]
+//   - seed=<path> imports the collection files in the directory at path; see [SimpleNoSQLDB.Import].
+//     A persistent database is only seeded if it is empty when restored
+func NewSimpleNoSQLDB(ctx context.Context, opts ...string) (*SimpleNoSQLDB, error) {
 	db := &SimpleNoSQLDB{}
 	db.collections = make(map[string]map[string]*SimpleCollection)
+
+	var seed string
+	var p persistence
+	p.interval = defaultSnapshotInterval
+	parsed, err := options.Parse("simplenosqldb", opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range parsed {
+		key, value := opt.Key, opt.Value
+		var err error
+		switch key {
+		case "dir":
+			p.dir = value
+		case "snapshot":
+			p.interval, err = time.ParseDuration(value)
+		case "seed":
+			seed = value
+		default:
+			return nil, fmt.Errorf("unknown simplenosqldb option %v", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for simplenosqldb option %v: %v", key, err)
+		}
+	}
+	if p.interval < 0 {
+		return nil, fmt.Errorf("simplenosqldb snapshot interval must be non-negative")
+	}
+
+	if p.dir != "" {
+		db.persistence = &p
+		if err := p.open(db); err != nil {
+			return nil, err
+		}
+	}
+	if seed != "" && db.empty() {
+		if err := db.Import(ctx, seed); err != nil {
+			return nil, err
+		}
+	}
 	return db, nil
 }
 
 func (impl *SimpleNoSQLDB) GetCollection(ctx context.Context, db_name string, collection_name string) (backend.NoSQLCollection, error) {
+	impl.mu.RLock()
+	collection, exists := impl.collections[db_name][collection_name]
+	impl.mu.RUnlock()
+	if exists {
+		return collection, nil
+	}
+
+	impl.mu.Lock()
+	defer impl.mu.Unlock()
+	return impl.getCollection(db_name, collection_name), nil
+}
+
+// Returns the named collection, creating it if it does not exist.  The caller must hold the write lock.
+func (impl *SimpleNoSQLDB) getCollection(db_name string, collection_name string) *SimpleCollection {
 	db, dbExists := impl.collections[db_name]
 	if !dbExists {
 		db = make(map[string]*SimpleCollection)
@@ -53,11 +152,176 @@ func (impl *SimpleNoSQLDB) GetCollection(ctx context.Context, db_name string, co
 
 	collection, collectionExists := db[collection_name]
 	if !collectionExists {
-		collection = &SimpleCollection{}
+		collection = &SimpleCollection{impl: impl, dbName: db_name, name: collection_name}
 		db[collection_name] = collection
 	}
 
-	return collection, nil
+	return collection
+}
+
+// Writes a snapshot of a persistent database and truncates its write-ahead log.
+//
+// Snapshots are taken automatically according to the configured snapshot interval, but can also be
+// taken explicitly, e.g. before shutting down.  Does nothing if the database is in-memory only.
+func (impl *SimpleNoSQLDB) Snapshot(ctx context.Context) error {
+	if impl.persistence == nil {
+		return nil
+	}
+	impl.mu.Lock()
+	defer impl.mu.Unlock()
+	return impl.persistence.snapshot(impl)
+}
+
+// Takes a snapshot of a persistent database if the snapshot interval has elapsed
+func (impl *SimpleNoSQLDB) snapshotIfDue() error {
+	if impl.persistence == nil || !impl.persistence.snapshotDue() {
+		return nil
+	}
+	impl.mu.Lock()
+	defer impl.mu.Unlock()
+	// Another write might have taken the snapshot while we waited for the lock
+	if !impl.persistence.snapshotDue() {
+		return nil
+	}
+	return impl.persistence.snapshot(impl)
+}
+
+// Reports whether the database contains no documents.  Only used during construction, so does not lock.
+func (impl *SimpleNoSQLDB) empty() bool {
+	for _, collections := range impl.collections {
+		for _, collection := range collections {
+			if len(collection.items) > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Applies a write-ahead log record to the database, without logging it.  Only used during construction,
+// so does not lock.
+func (impl *SimpleNoSQLDB) apply(r *walRecord) error {
+	collection := impl.getCollection(r.DB, r.Collection)
+	switch r.Op {
+	case opInsert:
+		collection.appendDocument(r.Doc)
+	case opReplace:
+		if len(r.Index) != 1 || r.Index[0] < 0 || r.Index[0] >= len(collection.items) {
+			return fmt.Errorf("invalid %v record for %v.%v: index %v", r.Op, r.DB, r.Collection, r.Index)
+		}
+		collection.setDocument(r.Index[0], r.Doc)
+	case opDelete:
+		for i, j := range r.Index {
+			if j < 0 || j >= len(collection.items) || (i > 0 && j <= r.Index[i-1]) {
+				return fmt.Errorf("invalid %v record for %v.%v: index %v", r.Op, r.DB, r.Collection, r.Index)
+			}
+		}
+		collection.deleteDocuments(r.Index)
+	case opIndex:
+		if _, _, err := collection.createIndex(r.Doc, r.Unique); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown write-ahead log operation %v", r.Op)
+	}
+	return nil
+}
+
+// Appends a document to the collection and adds it to the collection's indexes
+func (db *SimpleCollection) appendDocument(doc bson.D) {
+	db.items = append(db.items, doc)
+	for _, idx := range db.indexes {
+		idx.add(len(db.items)-1, doc)
+	}
+}
+
+// Replaces the document at position i and updates the collection's indexes
+func (db *SimpleCollection) setDocument(i int, doc bson.D) {
+	for _, idx := range db.indexes {
+		idx.remove(i, db.items[i])
+		idx.add(i, doc)
+	}
+	db.items[i] = doc
+}
+
+// Deletes the documents at the specified ascending positions and rebuilds the collection's indexes,
+// since deletion changes the positions of subsequent documents
+func (db *SimpleCollection) deleteDocuments(positions []int) {
+	remaining := make([]bson.D, 0, len(db.items)-len(positions))
+	next := 0
+	for i, doc := range db.items {
+		if next < len(positions) && positions[next] == i {
+			next++
+		} else {
+			remaining = append(remaining, doc)
+		}
+	}
+	db.items = remaining
+	for j, idx := range db.indexes {
+		db.indexes[j] = newIndex(idx.name, idx.keys, idx.unique)
+		for i, doc := range db.items {
+			db.indexes[j].add(i, doc)
+		}
+	}
+}
+
+// Replaces the document at position i with doc, checking unique indexes and logging the change
+func (db *SimpleCollection) replace(i int, doc bson.D) error {
+	if err := db.checkUnique(doc, i); err != nil {
+		return err
+	}
+	db.setDocument(i, doc)
+	return db.log(walRecord{Op: opReplace, Index: []int{i}, Doc: doc})
+}
+
+// Replaces the documents at positions with docs, all or none: if any of the replacements violate a unique
+// index, including by duplicating each other, then the collection is left unchanged.
+func (db *SimpleCollection) replaceAll(positions []int, docs []bson.D) error {
+	previous := make([]bson.D, len(positions))
+	for j, i := range positions {
+		previous[j] = db.items[i]
+		db.setDocument(i, docs[j])
+	}
+	for j, i := range positions {
+		if err := db.checkUnique(docs[j], i); err != nil {
+			for k := len(positions) - 1; k >= 0; k-- {
+				db.setDocument(positions[k], previous[k])
+			}
+			return err
+		}
+	}
+	for j, i := range positions {
+		if err := db.log(walRecord{Op: opReplace, Index: []int{i}, Doc: docs[j]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Runs f, which modifies the collection, while holding the collection's write lock.  Afterwards,
+// takes a snapshot of a persistent database if one is due.
+func (db *SimpleCollection) write(f func() error) error {
+	db.impl.mu.RLock()
+	db.mu.Lock()
+	err := f()
+	db.mu.Unlock()
+	db.impl.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return db.impl.snapshotIfDue()
+}
+
+// Records a change to the collection in the write-ahead log of a persistent database.
+// The caller must hold the write lock.
+func (db *SimpleCollection) log(r walRecord) error {
+	p := db.impl.persistence
+	if p == nil {
+		return nil
+	}
+	r.DB = db.dbName
+	r.Collection = db.name
+	return p.append(&r)
 }
 
 func (c *SimpleCursor) One(ctx context.Context, obj interface{}) (bool, error) {
@@ -95,13 +359,59 @@ func (c *SimpleCursor) All(ctx context.Context, obj interface{}) error {
 	return copyResult(c.results, obj)
 }
 
+func (c *SimpleCursor) Next(ctx context.Context, obj interface{}) (bool, error) {
+	if c.next >= len(c.results) {
+		return false, nil
+	}
+	c.next++
+	return true, fromBson(c.results[c.next-1], obj)
+}
+
+func (c *SimpleCursor) Close(ctx context.Context) error {
+	c.results = nil
+	c.next = 0
+	return nil
+}
+
 func (db *SimpleCollection) InsertOne(ctx context.Context, document interface{}) error {
+	d, err := newDocument(document)
+	if err != nil {
+		return err
+	}
+	return db.write(func() error {
+		return db.insert(d)
+	})
+}
+
+// Inserts the documents in order.  The documents are inserted atomically with respect to concurrent
+// operations, but if a document cannot be inserted then the preceding documents remain inserted.
+func (db *SimpleCollection) InsertMany(ctx context.Context, documents []interface{}) error {
+	var docs []bson.D
+	for _, document := range documents {
+		d, err := newDocument(document)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, d)
+	}
+	return db.write(func() error {
+		for _, d := range docs {
+			if err := db.insert(d); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Converts document to bson, adding an _id field if it does not have one
+func newDocument(document interface{}) (bson.D, error) {
 	d, isD := document.(bson.D)
 	if !isD {
 		var err error
 		d, err = toBson(document)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 	hasId := false
@@ -114,19 +424,16 @@ func (db *SimpleCollection) InsertOne(ctx context.Context, document interface{})
 	if !hasId {
 		d = append(bson.D{{"_id", primitive.NewObjectID()}}, d...)
 	}
-
-	db.items = append(db.items, d)
-	return nil
+	return d, nil
 }
 
-func (db *SimpleCollection) InsertMany(ctx context.Context, documents []interface{}) error {
-	for _, d := range documents {
-		err := db.InsertOne(ctx, d)
-		if err != nil {
-			return err
-		}
+// Inserts d into the collection.  The caller must hold the write lock.
+func (db *SimpleCollection) insert(d bson.D) error {
+	if err := db.checkUnique(d, -1); err != nil {
+		return err
 	}
-	return nil
+	db.appendDocument(d)
+	return db.log(walRecord{Op: opInsert, Doc: d})
 }
 
 func (db *SimpleCollection) FindOne(ctx context.Context, filter bson.D, projection ...bson.D) (backend.NoSQLCursor, error) {
@@ -134,11 +441,14 @@ func (db *SimpleCollection) FindOne(ctx context.Context, filter bson.D, projecti
 	if err != nil {
 		return nil, err
 	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
 	if verbose {
 		fmt.Printf("---- FindOne\n%v\n", query)
 	}
 	cursor := &SimpleCursor{}
-	for _, item := range db.items {
+	for _, i := range db.candidates(filter) {
+		item := db.items[i]
 		if query.Apply(item) {
 			cursor.results = append(cursor.results, item)
 			if verbose {
@@ -164,11 +474,14 @@ func (db *SimpleCollection) FindMany(ctx context.Context, filter bson.D, project
 	if err != nil {
 		return nil, err
 	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
 	if verbose {
 		fmt.Printf("---- FindMany\n%v\n", query)
 	}
 	cursor := &SimpleCursor{}
-	for _, item := range db.items {
+	for _, i := range db.candidates(filter) {
+		item := db.items[i]
 		if query.Apply(item) {
 			cursor.results = append(cursor.results, item)
 			if verbose {
@@ -183,18 +496,108 @@ func (db *SimpleCollection) FindMany(ctx context.Context, filter bson.D, project
 	return cursor, nil
 }
 
+// Projections are not currently supported and are ignored.  Results are sorted in memory; the batch size is
+// ignored since the cursor's results are held in memory regardless.
+func (db *SimpleCollection) Find(ctx context.Context, filter bson.D, opts backend.FindOptions) (backend.NoSQLCursor, error) {
+	filterOp, err := query.ParseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	var sortOp query.Sort
+	if len(opts.Sort) > 0 {
+		if sortOp, err = query.ParseSort(opts.Sort); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Skip < 0 || opts.Limit < 0 {
+		return nil, fmt.Errorf("invalid find options: skip and limit must be non-negative")
+	}
+
+	db.mu.RLock()
+	var results []bson.D
+	for _, i := range db.candidates(filter) {
+		if filterOp.Apply(db.items[i]) {
+			results = append(results, db.items[i])
+		}
+	}
+	db.mu.RUnlock()
+	if verbose {
+		fmt.Printf("---- Find\n%v\n%v skip %v limit %v\n", filterOp, sortOp, opts.Skip, opts.Limit)
+	}
+
+	if sortOp != nil {
+		query.SortDocuments(results, sortOp)
+	}
+	if opts.Skip >= int64(len(results)) {
+		results = nil
+	} else {
+		results = results[opts.Skip:]
+	}
+	if opts.Limit > 0 && opts.Limit < int64(len(results)) {
+		results = results[:opts.Limit]
+	}
+	return &SimpleCursor{results: results}, nil
+}
+
+// Implements the [backend.NoSQLCollection] interface.
+//
+// Pipelines are evaluated in memory; see [query.ParsePipeline] for the supported stages.  A leading $match stage
+// uses the collection's indexes.  $lookup stages join with other collections of the same database; each collection
+// is read atomically, but the pipeline is not isolated from writes to other collections made while it runs.
+func (db *SimpleCollection) Aggregate(ctx context.Context, pipeline []bson.D) (backend.NoSQLCursor, error) {
+	p, err := query.ParsePipeline(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var filter bson.D
+	if len(pipeline) > 0 && pipeline[0][0].Key == "$match" {
+		filter = pipeline[0][0].Value.(bson.D)
+	}
+
+	db.mu.RLock()
+	var docs []bson.D
+	for _, i := range db.candidates(filter) {
+		docs = append(docs, db.items[i])
+	}
+	db.mu.RUnlock()
+	if verbose {
+		fmt.Printf("---- Aggregate\n%v\n", p)
+	}
+
+	results, err := p.Run(docs, db.siblingDocuments)
+	if err != nil {
+		return nil, err
+	}
+	return &SimpleCursor{results: results}, nil
+}
+
+// Returns the documents of the named collection in the same database as db, for $lookup stages
+func (db *SimpleCollection) siblingDocuments(name string) ([]bson.D, error) {
+	db.impl.mu.RLock()
+	sibling, exists := db.impl.collections[db.dbName][name]
+	db.impl.mu.RUnlock()
+	if !exists {
+		return nil, nil
+	}
+	sibling.mu.RLock()
+	defer sibling.mu.RUnlock()
+	return append([]bson.D(nil), sibling.items...), nil
+}
+
 func (db *SimpleCollection) DeleteOne(ctx context.Context, filter bson.D) error {
 	query, err := query.ParseFilter(filter)
 	if err != nil {
 		return err
 	}
-	for i, item := range db.items {
-		if query.Apply(item) {
-			db.items = append(db.items[:i], db.items[i+1:]...)
-			return nil
+	return db.write(func() error {
+		for _, i := range db.candidates(filter) {
+			if query.Apply(db.items[i]) {
+				db.deleteDocuments([]int{i})
+				return db.log(walRecord{Op: opDelete, Index: []int{i}})
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 func (db *SimpleCollection) DeleteMany(ctx context.Context, filter bson.D) error {
@@ -202,22 +605,19 @@ func (db *SimpleCollection) DeleteMany(ctx context.Context, filter bson.D) error
 	if err != nil {
 		return err
 	}
-	copyrangebegin := 0
-	newitems := make([]bson.D, 0, len(db.items))
-	for i, item := range db.items {
-		if query.Apply(item) {
-			if i > copyrangebegin {
-				newitems = append(newitems, db.items[copyrangebegin:i]...)
+	return db.write(func() error {
+		var deleted []int
+		for _, i := range db.candidates(filter) {
+			if query.Apply(db.items[i]) {
+				deleted = append(deleted, i)
 			}
-			copyrangebegin = i + 1
 		}
-	}
-	if copyrangebegin < len(db.items) {
-		newitems = append(newitems, db.items[copyrangebegin:len(db.items)]...)
-	}
-	db.items = newitems
-	return nil
-
+		if len(deleted) == 0 {
+			return nil
+		}
+		db.deleteDocuments(deleted)
+		return db.log(walRecord{Op: opDelete, Index: deleted})
+	})
 }
 
 func (db *SimpleCollection) UpdateOne(ctx context.Context, filter bson.D, update bson.D) (int, error) {
@@ -230,23 +630,32 @@ func (db *SimpleCollection) UpdateOne(ctx context.Context, filter bson.D, update
 		return 0, err
 	}
 
-	if verbose {
-		fmt.Printf("---- UpdateOne\n%v\n%v\n", filter, update)
-	}
+	updatedCount := 0
+	err = db.write(func() error {
+		if verbose {
+			fmt.Printf("---- UpdateOne\n%v\n%v\n", filter, update)
+		}
 
-	for i := range db.items {
-		if filterOp.Apply(db.items[i]) {
-			if verbose {
-				fmt.Printf("MATCH: %v\n", db.items[i])
-			}
-			return 1, updateOp.Apply(&db.items[i])
-		} else {
-			if verbose {
-				fmt.Printf("      %v\n", db.items[i])
+		for _, i := range db.candidates(filter) {
+			if filterOp.Apply(db.items[i]) {
+				if verbose {
+					fmt.Printf("MATCH: %v\n", db.items[i])
+				}
+				updatedCount = 1
+				updated := copyDocument(db.items[i])
+				if err := updateOp.Apply(&updated); err != nil {
+					return err
+				}
+				return db.replace(i, updated)
+			} else {
+				if verbose {
+					fmt.Printf("      %v\n", db.items[i])
+				}
 			}
 		}
-	}
-	return 0, nil
+		return nil
+	})
+	return updatedCount, err
 }
 
 func (db *SimpleCollection) UpdateMany(ctx context.Context, filter bson.D, update bson.D) (int, error) {
@@ -258,41 +667,73 @@ func (db *SimpleCollection) UpdateMany(ctx context.Context, filter bson.D, updat
 	if err != nil {
 		return 0, err
 	}
-	if verbose {
-		fmt.Printf("---- UpdateMany\n")
-		fmt.Printf(" MATCH:  %v\n", filterOp)
-		fmt.Printf(" UPDATE: %v\n", updateOp)
-	}
 
 	updated := 0
-	for i := range db.items {
-		if filterOp.Apply(db.items[i]) {
-			if verbose {
-				fmt.Printf("UPDATING: %v\n", db.items[i])
-			}
-			err := updateOp.Apply(&db.items[i])
-			if err != nil {
-				return updated, err
-			}
-			if verbose {
-				fmt.Printf("      --> %v\n", db.items[i])
-			}
-			updated += 1
-		} else {
-			if verbose {
-				fmt.Printf("          %v\n", db.items[i])
+	err = db.write(func() error {
+		if verbose {
+			fmt.Printf("---- UpdateMany\n")
+			fmt.Printf(" MATCH:  %v\n", filterOp)
+			fmt.Printf(" UPDATE: %v\n", updateOp)
+		}
+
+		// Every update is applied before any document is replaced, so that a failed update changes nothing
+		var positions []int
+		var docs []bson.D
+		for _, i := range db.candidates(filter) {
+			if filterOp.Apply(db.items[i]) {
+				doc := copyDocument(db.items[i])
+				err := updateOp.Apply(&doc)
+				if err != nil {
+					return err
+				}
+				if verbose {
+					fmt.Printf("UPDATING: %v\n", db.items[i])
+					fmt.Printf("      --> %v\n", doc)
+				}
+				positions = append(positions, i)
+				docs = append(docs, doc)
+			} else {
+				if verbose {
+					fmt.Printf("          %v\n", db.items[i])
+				}
 			}
 		}
-	}
-	return updated, nil
+		if err := db.replaceAll(positions, docs); err != nil {
+			return err
+		}
+		updated = len(positions)
+		return nil
+	})
+	return updated, err
 }
 
+// The replacement and insertion happen atomically, so concurrent upserts with the same
+// filter do not insert duplicate documents.
 func (db *SimpleCollection) Upsert(ctx context.Context, filter bson.D, document interface{}) (bool, error) {
-	updatedCount, err := db.ReplaceOne(ctx, filter, document)
-	if updatedCount == 1 || err != nil {
-		return true, err
+	query, err := query.ParseFilter(filter)
+	if err != nil {
+		return false, err
+	}
+	replacement, err := toBson(document)
+	if err != nil {
+		return false, err
+	}
+	d, err := newDocument(replacement)
+	if err != nil {
+		return false, err
 	}
-	return false, db.InsertOne(ctx, document)
+
+	updated := false
+	err = db.write(func() error {
+		for _, i := range db.candidates(filter) {
+			if query.Apply(db.items[i]) {
+				updated = true
+				return db.replace(i, replacement)
+			}
+		}
+		return db.insert(d)
+	})
+	return updated, err
 }
 
 func (db *SimpleCollection) UpsertID(ctx context.Context, id primitive.ObjectID, document interface{}) (bool, error) {
@@ -309,13 +750,22 @@ func (db *SimpleCollection) ReplaceOne(ctx context.Context, filter bson.D, repla
 	if err != nil {
 		return 0, err
 	}
-	for i, item := range db.items {
-		if query.Apply(item) {
-			db.items[i], err = toBson(replacement)
-			return 1, err
-		}
+	doc, err := toBson(replacement)
+	if err != nil {
+		return 0, err
 	}
-	return 0, nil
+
+	replaced := 0
+	err = db.write(func() error {
+		for _, i := range db.candidates(filter) {
+			if query.Apply(db.items[i]) {
+				replaced = 1
+				return db.replace(i, doc)
+			}
+		}
+		return nil
+	})
+	return replaced, err
 }
 
 func (db *SimpleCollection) ReplaceMany(ctx context.Context, filter bson.D, replacements ...interface{}) (int, error) {
@@ -323,17 +773,33 @@ func (db *SimpleCollection) ReplaceMany(ctx context.Context, filter bson.D, repl
 	if err != nil {
 		return 0, nil
 	}
+	var docs []bson.D
+	for _, replacement := range replacements {
+		doc, err := toBson(replacement)
+		if err != nil {
+			return 0, err
+		}
+		docs = append(docs, doc)
+	}
+
 	updateCount := 0
-	for i := 0; updateCount < len(replacements) && i < len(db.items); i++ {
-		if query.Apply(db.items[i]) {
-			db.items[i], err = toBson(replacements[updateCount])
-			if err != nil {
-				return updateCount, err
+	err = db.write(func() error {
+		var positions []int
+		for _, i := range db.candidates(filter) {
+			if len(positions) == len(docs) {
+				break
+			}
+			if query.Apply(db.items[i]) {
+				positions = append(positions, i)
 			}
-			updateCount++
 		}
-	}
-	return updateCount, nil
+		if err := db.replaceAll(positions, docs[:len(positions)]); err != nil {
+			return err
+		}
+		updateCount = len(positions)
+		return nil
+	})
+	return updateCount, err
 }
 
 func toBson(document any) (bson.D, error) {
@@ -346,6 +812,31 @@ func toBson(document any) (bson.D, error) {
 	return d, err
 }
 
+// Returns a copy of doc that shares no documents or arrays with doc, so that updates can be
+// applied to the copy without modifying doc
+func copyDocument(doc bson.D) bson.D {
+	return copyValue(doc).(bson.D)
+}
+
+func copyValue(value any) any {
+	switch v := value.(type) {
+	case bson.D:
+		d := make(bson.D, len(v))
+		for i, e := range v {
+			d[i] = bson.E{Key: e.Key, Value: copyValue(e.Value)}
+		}
+		return d
+	case bson.A:
+		a := make(bson.A, len(v))
+		for i, e := range v {
+			a[i] = copyValue(e)
+		}
+		return a
+	default:
+		return v
+	}
+}
+
 func fromBson(d bson.D, dst any) error {
 	bytes, err := bson.Marshal(d)
 	if err != nil {
@@ -355,6 +846,8 @@ func fromBson(d bson.D, dst any) error {
 }
 
 func (db *SimpleCollection) String() string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
 	var strs []string
 	for i := range db.items {
 		strs = append(strs, fmt.Sprintf("%v", db.items[i]))
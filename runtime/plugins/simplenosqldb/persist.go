@@ -0,0 +1,233 @@
+package simplenosqldb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The default interval between snapshots of a persistent [SimpleNoSQLDB]
+const defaultSnapshotInterval = 1 * time.Minute
+
+// Operations recorded in the write-ahead log
+const (
+	opSnapshot = "snapshot" // The header of a snapshot file
+	opInsert   = "insert"   // A document was appended to a collection
+	opReplace  = "replace"  // The document at Index[0] was replaced
+	opDelete   = "delete"   // The documents at Index were deleted; indices are ascending
+	opIndex    = "index"    // An index on the fields in Doc was created
+)
+
+// A record in the write-ahead log or a snapshot.
+//
+// Records describe the physical change made to a collection's documents, rather than the
+// operation that caused it, so that replaying them is deterministic.
+type walRecord struct {
+	Op         string `bson:"op"`
+	Gen        uint64 `bson:"gen,omitempty"`
+	DB         string `bson:"db,omitempty"`
+	Collection string `bson:"collection,omitempty"`
+	Index      []int  `bson:"index,omitempty"`
+	Doc        bson.D `bson:"doc,omitempty"`
+	Unique     bool   `bson:"unique,omitempty"`
+}
+
+// The on-disk state of a persistent [SimpleNoSQLDB].
+//
+// The directory contains a snapshot file and a write-ahead log.  The snapshot file starts with a
+// header recording its generation, followed by an index record for every index and an insert
+// record for every document.  The write-ahead log of the same generation records every change made since the snapshot was taken.
+//
+// A new snapshot is written to a temporary file and then renamed, which atomically switches to
+// the next generation's (empty) write-ahead log.  A crash at any point therefore leaves a snapshot
+// and write-ahead log that are consistent with each other.
+//
+// Appends from different collections may happen concurrently, so the write-ahead log is guarded by mu.
+// Snapshots are only taken while the database is locked against writes.
+type persistence struct {
+	mu           sync.Mutex
+	dir          string
+	gen          uint64
+	wal          *os.File
+	interval     time.Duration // 0 if snapshots are only taken explicitly
+	lastSnapshot time.Time
+}
+
+func (p *persistence) snapshotPath() string {
+	return filepath.Join(p.dir, "snapshot.bson")
+}
+
+func (p *persistence) walPath(gen uint64) string {
+	return filepath.Join(p.dir, fmt.Sprintf("wal-%d.log", gen))
+}
+
+// Loads the snapshot and replays the write-ahead log, then opens the write-ahead log for appending
+func (p *persistence) open(impl *SimpleNoSQLDB) error {
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return err
+	}
+
+	_, err := readRecords(p.snapshotPath(), func(r *walRecord) error {
+		if r.Op == opSnapshot {
+			p.gen = r.Gen
+			return nil
+		}
+		return impl.apply(r)
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to load snapshot from %v: %v", p.snapshotPath(), err)
+	}
+
+	valid, err := readRecords(p.walPath(p.gen), impl.apply)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to replay write-ahead log %v: %v", p.walPath(p.gen), err)
+	}
+
+	p.wal, err = os.OpenFile(p.walPath(p.gen), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	// Discard any truncated record at the end of the log before appending to it
+	if err := p.wal.Truncate(valid); err != nil {
+		return err
+	}
+	if _, err := p.wal.Seek(valid, io.SeekStart); err != nil {
+		return err
+	}
+	p.lastSnapshot = time.Now()
+	return nil
+}
+
+// Appends a record to the write-ahead log
+func (p *persistence) append(r *walRecord) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return writeBson(p.wal, r)
+}
+
+// Reports whether the snapshot interval has elapsed since the last snapshot
+func (p *persistence) snapshotDue() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.interval > 0 && time.Since(p.lastSnapshot) >= p.interval
+}
+
+// Writes a snapshot of all collections and starts a new, empty write-ahead log.  The caller must
+// hold the database's write lock.
+func (p *persistence) snapshot(impl *SimpleNoSQLDB) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	nextGen := p.gen + 1
+
+	tmpPath := p.snapshotPath() + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = writeBson(w, &walRecord{Op: opSnapshot, Gen: nextGen})
+	for dbName, collections := range impl.collections {
+		for collectionName, collection := range collections {
+			for _, idx := range collection.indexes {
+				if err == nil {
+					err = writeBson(w, &walRecord{Op: opIndex, DB: dbName, Collection: collectionName, Doc: idx.keys, Unique: idx.unique})
+				}
+			}
+			for _, doc := range collection.items {
+				if err == nil {
+					err = writeBson(w, &walRecord{Op: opInsert, DB: dbName, Collection: collectionName, Doc: doc})
+				}
+			}
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	wal, err := os.OpenFile(p.walPath(nextGen), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, p.snapshotPath()); err != nil {
+		wal.Close()
+		return err
+	}
+
+	p.wal.Close()
+	os.Remove(p.walPath(p.gen))
+	p.wal = wal
+	p.gen = nextGen
+	p.lastSnapshot = time.Now()
+	return nil
+}
+
+// Reads a file of concatenated BSON records, calling apply for each.
+//
+// A truncated final record, e.g. from a crash partway through a write, is ignored.  Returns the
+// length of the file up to the end of the last complete record.
+func readRecords(path string, apply func(*walRecord) error) (int64, error) {
+	return readDocuments(path, true, func(raw bson.Raw) error {
+		var r walRecord
+		if err := bson.Unmarshal(raw, &r); err != nil {
+			return err
+		}
+		return apply(&r)
+	})
+}
+
+// Reads a file of concatenated BSON documents, calling f for each.  If allowTruncated is true
+// then a truncated final document is ignored; otherwise it is an error.
+//
+// Returns the number of bytes read up to the end of the last complete document.
+func readDocuments(path string, allowTruncated bool, f func(bson.Raw) error) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	r := bufio.NewReader(file)
+	var offset int64
+	for {
+		var length [4]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			if err == io.EOF || (allowTruncated && err == io.ErrUnexpectedEOF) {
+				return offset, nil
+			}
+			return offset, err
+		}
+		size := binary.LittleEndian.Uint32(length[:])
+		if size < 5 {
+			return offset, fmt.Errorf("invalid bson document length %v in %v", size, path)
+		}
+		raw := make([]byte, size)
+		copy(raw, length[:])
+		if _, err := io.ReadFull(r, raw[4:]); err != nil {
+			if allowTruncated && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+				return offset, nil
+			}
+			return offset, err
+		}
+		if err := f(bson.Raw(raw)); err != nil {
+			return offset, err
+		}
+		offset += int64(size)
+	}
+}
@@ -0,0 +1,195 @@
+package simplenosqldb_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blueprint-uservices/blueprint/runtime/plugins/simplenosqldb"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func openPersistentDB(t *testing.T, opts ...string) (context.Context, *simplenosqldb.SimpleNoSQLDB) {
+	ctx := context.Background()
+	db, err := simplenosqldb.NewSimpleNoSQLDB(ctx, opts...)
+	require.NoError(t, err)
+	return ctx, db
+}
+
+func insertTeas(t *testing.T, ctx context.Context, db *simplenosqldb.SimpleNoSQLDB) {
+	coll, err := db.GetCollection(ctx, "testdb", "teas")
+	require.NoError(t, err)
+	var docs []interface{}
+	for _, t := range teas {
+		docs = append(docs, t)
+	}
+	require.NoError(t, coll.InsertMany(ctx, docs))
+}
+
+func findTeas(t *testing.T, ctx context.Context, db *simplenosqldb.SimpleNoSQLDB) []Tea {
+	coll, err := db.GetCollection(ctx, "testdb", "teas")
+	require.NoError(t, err)
+	cursor, err := coll.FindMany(ctx, bson.D{})
+	require.NoError(t, err)
+	var results []Tea
+	require.NoError(t, cursor.All(ctx, &results))
+	return results
+}
+
+// Applies an insert, update, replace and delete to the teas collection
+func modifyTeas(t *testing.T, ctx context.Context, db *simplenosqldb.SimpleNoSQLDB) {
+	coll, err := db.GetCollection(ctx, "testdb", "teas")
+	require.NoError(t, err)
+	require.NoError(t, coll.InsertOne(ctx, newtea))
+	_, err = coll.UpdateMany(ctx, bson.D{{"rating", bson.D{{"$gt", 7}}}}, bson.D{{"$inc", bson.D{{"rating", 1}}}})
+	require.NoError(t, err)
+	_, err = coll.ReplaceOne(ctx, bson.D{{"type", "Assam"}}, Tea{Type: "Darjeeling", Rating: 9})
+	require.NoError(t, err)
+	require.NoError(t, coll.DeleteMany(ctx, bson.D{{"rating", bson.D{{"$lt", 7}}}}))
+}
+
+func TestPersistence(t *testing.T) {
+	dir := t.TempDir()
+
+	ctx, db := openPersistentDB(t, "dir="+dir)
+	insertTeas(t, ctx, db)
+	modifyTeas(t, ctx, db)
+	expected := findTeas(t, ctx, db)
+	require.Len(t, expected, 5)
+
+	// Reopening the database replays the write-ahead log
+	ctx, db = openPersistentDB(t, "dir="+dir)
+	require.Equal(t, expected, findTeas(t, ctx, db))
+}
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	ctx, db := openPersistentDB(t, "dir="+dir, "snapshot=0")
+	insertTeas(t, ctx, db)
+	require.NoError(t, db.Snapshot(ctx))
+	modifyTeas(t, ctx, db)
+	expected := findTeas(t, ctx, db)
+
+	// The snapshot replaced the initial write-ahead log
+	require.FileExists(t, filepath.Join(dir, "snapshot.bson"))
+	require.NoFileExists(t, filepath.Join(dir, "wal-0.log"))
+	require.FileExists(t, filepath.Join(dir, "wal-1.log"))
+
+	// Reopening the database loads the snapshot then replays the write-ahead log
+	ctx, db = openPersistentDB(t, "dir="+dir, "snapshot=0")
+	require.Equal(t, expected, findTeas(t, ctx, db))
+}
+
+func TestAutomaticSnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	// With a 1ns interval, every write takes a snapshot.  InsertMany is a single write.
+	ctx, db := openPersistentDB(t, "dir="+dir, "snapshot=1ns")
+	insertTeas(t, ctx, db)
+	expected := findTeas(t, ctx, db)
+
+	info, err := os.Stat(filepath.Join(dir, "wal-1.log"))
+	require.NoError(t, err)
+	require.Zero(t, info.Size())
+
+	ctx, db = openPersistentDB(t, "dir="+dir)
+	require.Equal(t, expected, findTeas(t, ctx, db))
+}
+
+func TestTruncatedLog(t *testing.T) {
+	dir := t.TempDir()
+
+	ctx, db := openPersistentDB(t, "dir="+dir)
+	insertTeas(t, ctx, db)
+	expected := findTeas(t, ctx, db)
+
+	// Simulate a crash partway through writing a record
+	f, err := os.OpenFile(filepath.Join(dir, "wal-0.log"), os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{100, 0, 0, 0, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// The truncated record is discarded, and subsequent writes are not lost
+	ctx, db = openPersistentDB(t, "dir="+dir)
+	require.Equal(t, expected, findTeas(t, ctx, db))
+	modifyTeas(t, ctx, db)
+	expected = findTeas(t, ctx, db)
+
+	ctx, db = openPersistentDB(t, "dir="+dir)
+	require.Equal(t, expected, findTeas(t, ctx, db))
+}
+
+func TestExportImport(t *testing.T) {
+	for _, format := range []string{"json", "bson"} {
+		dir := t.TempDir()
+
+		ctx, db := openPersistentDB(t)
+		insertTeas(t, ctx, db)
+		expected := findTeas(t, ctx, db)
+		require.NoError(t, db.Export(ctx, dir, format))
+		require.FileExists(t, filepath.Join(dir, "testdb", "teas."+format))
+
+		ctx, db = openPersistentDB(t)
+		require.NoError(t, db.Import(ctx, dir))
+		require.Equal(t, expected, findTeas(t, ctx, db), "format %v", format)
+	}
+}
+
+func TestImportJSON(t *testing.T) {
+	dir := t.TempDir()
+
+	// Both a JSON array and a sequence of documents are accepted
+	array := `[{"type": "Masala", "rating": 10}, {"type": "Oolong", "rating": 7}]`
+	sequence := `{"type": "Masala", "rating": 10}
+	{"type": "Oolong", "rating": 7}`
+	for _, contents := range []string{array, sequence} {
+		path := filepath.Join(dir, "teas.json")
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+
+		ctx, db := openPersistentDB(t)
+		coll, err := db.GetCollection(ctx, "testdb", "teas")
+		require.NoError(t, err)
+		require.NoError(t, coll.(*simplenosqldb.SimpleCollection).Import(ctx, path))
+
+		results := findTeas(t, ctx, db)
+		require.Len(t, results, 2)
+		require.Equal(t, "Oolong", results[1].Type)
+		require.Equal(t, 7, results[1].Rating)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	seed := t.TempDir()
+	{
+		ctx, db := openPersistentDB(t)
+		insertTeas(t, ctx, db)
+		require.NoError(t, db.Export(ctx, seed, "json"))
+	}
+
+	ctx, db := openPersistentDB(t, "seed="+seed)
+	require.Len(t, findTeas(t, ctx, db), len(teas))
+
+	// A persistent database is only seeded when it is empty
+	dir := t.TempDir()
+	ctx, db = openPersistentDB(t, "dir="+dir, "seed="+seed)
+	modifyTeas(t, ctx, db)
+	expected := findTeas(t, ctx, db)
+
+	ctx, db = openPersistentDB(t, "dir="+dir, "seed="+seed)
+	require.Equal(t, expected, findTeas(t, ctx, db))
+}
+
+func TestInvalidOptions(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := simplenosqldb.NewSimpleNoSQLDB(ctx, "dir")
+	require.Error(t, err)
+	_, err = simplenosqldb.NewSimpleNoSQLDB(ctx, "snapshot=-1s")
+	require.Error(t, err)
+	_, err = simplenosqldb.NewSimpleNoSQLDB(ctx, "capacity=10")
+	require.Error(t, err)
+}
@@ -0,0 +1,816 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+Evaluation of aggregation pipelines, following mongodb's aggregation semantics.
+
+The supported stages are $match, $group, $sort, $project, $unwind, $skip, $limit, $count and $lookup.
+$lookup only supports equality matches on a local and foreign field, not the let/pipeline form.
+
+https://www.mongodb.com/docs/manual/core/aggregation-pipeline/
+*/
+
+// An aggregation pipeline, which transforms a sequence of input documents into a sequence of results
+type Pipeline interface {
+	// Runs the pipeline over docs.  docs are not modified.
+	//
+	// collections returns the documents of the collections named by $lookup stages; it can be nil if
+	// the pipeline has no $lookup stages.
+	Run(docs []bson.D, collections Collections) ([]bson.D, error)
+
+	String() string
+}
+
+// Returns the documents of the named collection, or no documents if the collection does not exist
+type Collections func(name string) ([]bson.D, error)
+
+type stage interface {
+	apply(docs []bson.D, collections Collections) ([]bson.D, error)
+	String() string
+}
+
+type (
+	pipeline struct {
+		stages []stage
+	}
+
+	matchStage struct {
+		filter Filter
+	}
+
+	sortStage struct {
+		sort Sort
+	}
+
+	skipStage struct {
+		n int64
+	}
+
+	limitStage struct {
+		n int64
+	}
+
+	countStage struct {
+		field string
+	}
+
+	projectStage struct {
+		projection *projection
+		exclude    bool // true for exclusion projections, false for inclusion projections
+	}
+
+	unwindStage struct {
+		path       []string
+		indexField []string // optional
+		preserve   bool     // preserveNullAndEmptyArrays
+	}
+
+	groupStage struct {
+		id     any // expression
+		fields []accumulatorField
+	}
+
+	accumulatorField struct {
+		name string
+		op   string
+		expr any
+	}
+
+	lookupStage struct {
+		from         string
+		localField   string
+		foreignField string
+		as           []string
+	}
+)
+
+// Parses a mongodb aggregation pipeline, e.g.
+//
+//	[]bson.D{
+//		{{"$match", bson.D{{"rating", bson.D{{"$gte", 5}}}}}},
+//		{{"$group", bson.D{{"_id", "$vendor"}, {"count", bson.D{{"$sum", 1}}}}}},
+//	}
+func ParsePipeline(stages []bson.D) (Pipeline, error) {
+	p := &pipeline{}
+	for _, d := range stages {
+		if len(d) != 1 {
+			return nil, fmt.Errorf("a pipeline stage must have exactly one field; found %v", d)
+		}
+		s, err := parseStage(d[0].Key, d[0].Value)
+		if err != nil {
+			return nil, err
+		}
+		p.stages = append(p.stages, s)
+	}
+	return p, nil
+}
+
+func parseStage(op string, value any) (stage, error) {
+	switch op {
+	case "$match":
+		d, isD := value.(bson.D)
+		if !isD {
+			return nil, fmt.Errorf("the argument to $match must be a bson.D; found %v", value)
+		}
+		filter, err := ParseFilter(d)
+		return &matchStage{filter: filter}, err
+	case "$sort":
+		d, isD := value.(bson.D)
+		if !isD || len(d) == 0 {
+			return nil, fmt.Errorf("the argument to $sort must be a non-empty bson.D; found %v", value)
+		}
+		sort, err := ParseSort(d)
+		return &sortStage{sort: sort}, err
+	case "$skip":
+		n, isInt := intValue(value)
+		if !isInt || n < 0 {
+			return nil, fmt.Errorf("the argument to $skip must be a non-negative integer; found %v", value)
+		}
+		return &skipStage{n: n}, nil
+	case "$limit":
+		n, isInt := intValue(value)
+		if !isInt || n <= 0 {
+			return nil, fmt.Errorf("the argument to $limit must be a positive integer; found %v", value)
+		}
+		return &limitStage{n: n}, nil
+	case "$count":
+		field, isString := value.(string)
+		if !isString || field == "" || strings.HasPrefix(field, "$") || strings.Contains(field, ".") {
+			return nil, fmt.Errorf("the argument to $count must be a field name; found %v", value)
+		}
+		return &countStage{field: field}, nil
+	case "$project":
+		return parseProject(value)
+	case "$unwind":
+		return parseUnwind(value)
+	case "$group":
+		return parseGroup(value)
+	case "$lookup":
+		return parseLookup(value)
+	}
+	return nil, fmt.Errorf("unsupported aggregation stage %v", op)
+}
+
+func (p *pipeline) Run(docs []bson.D, collections Collections) ([]bson.D, error) {
+	// Stages return new slices and documents rather than modifying their inputs
+	var err error
+	for _, s := range p.stages {
+		if docs, err = s.apply(docs, collections); err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+func (p *pipeline) String() string {
+	var strs []string
+	for _, s := range p.stages {
+		strs = append(strs, s.String())
+	}
+	return strings.Join(strs, "\n")
+}
+
+func (s *matchStage) apply(docs []bson.D, collections Collections) ([]bson.D, error) {
+	var results []bson.D
+	for _, doc := range docs {
+		if s.filter.Apply(doc) {
+			results = append(results, doc)
+		}
+	}
+	return results, nil
+}
+
+func (s *matchStage) String() string {
+	return "$match " + s.filter.String()
+}
+
+func (s *sortStage) apply(docs []bson.D, collections Collections) ([]bson.D, error) {
+	results := append([]bson.D(nil), docs...)
+	SortDocuments(results, s.sort)
+	return results, nil
+}
+
+func (s *sortStage) String() string {
+	return "$" + s.sort.String()
+}
+
+func (s *skipStage) apply(docs []bson.D, collections Collections) ([]bson.D, error) {
+	if s.n >= int64(len(docs)) {
+		return nil, nil
+	}
+	return docs[s.n:], nil
+}
+
+func (s *skipStage) String() string {
+	return fmt.Sprintf("$skip %v", s.n)
+}
+
+func (s *limitStage) apply(docs []bson.D, collections Collections) ([]bson.D, error) {
+	if s.n < int64(len(docs)) {
+		return docs[:s.n], nil
+	}
+	return docs, nil
+}
+
+func (s *limitStage) String() string {
+	return fmt.Sprintf("$limit %v", s.n)
+}
+
+func (s *countStage) apply(docs []bson.D, collections Collections) ([]bson.D, error) {
+	// As with mongodb, there is no result if there are no input documents
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	return []bson.D{{{Key: s.field, Value: intResult(int64(len(docs)), true)}}}, nil
+}
+
+func (s *countStage) String() string {
+	return "$count " + s.field
+}
+
+/*
+$unwind
+*/
+
+func parseUnwind(value any) (stage, error) {
+	s := &unwindStage{}
+	var path string
+	switch v := value.(type) {
+	case string:
+		path = v
+	case bson.D:
+		for _, e := range v {
+			switch e.Key {
+			case "path":
+				path, _ = e.Value.(string)
+			case "includeArrayIndex":
+				field, isString := e.Value.(string)
+				if !isString || field == "" || strings.HasPrefix(field, "$") {
+					return nil, fmt.Errorf("includeArrayIndex for $unwind must be a field name; found %v", e.Value)
+				}
+				s.indexField = strings.Split(field, ".")
+			case "preserveNullAndEmptyArrays":
+				preserve, isBool := e.Value.(bool)
+				if !isBool {
+					return nil, fmt.Errorf("preserveNullAndEmptyArrays for $unwind must be a bool; found %v", e.Value)
+				}
+				s.preserve = preserve
+			default:
+				return nil, fmt.Errorf("unknown $unwind option %v", e.Key)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("the argument to $unwind must be a field path or a bson.D; found %v", value)
+	}
+	if !strings.HasPrefix(path, "$") || len(path) == 1 {
+		return nil, fmt.Errorf("the path for $unwind must be a field path prefixed with $; found %q", path)
+	}
+	s.path = strings.Split(path[1:], ".")
+	return s, nil
+}
+
+func (s *unwindStage) apply(docs []bson.D, collections Collections) ([]bson.D, error) {
+	var results []bson.D
+	for _, doc := range docs {
+		value, _ := getPath(doc, s.path)
+		a, isA := value.(bson.A)
+		switch {
+		case isA && len(a) > 0:
+			for i, elem := range a {
+				result := setPath(doc, s.path, elem)
+				if s.indexField != nil {
+					result = setPath(result, s.indexField, int64(i))
+				}
+				results = append(results, result)
+			}
+		case isA || value == nil:
+			// Missing, null and empty arrays produce no results unless they are preserved
+			if s.preserve {
+				if s.indexField != nil {
+					doc = setPath(doc, s.indexField, nil)
+				}
+				results = append(results, doc)
+			}
+		default:
+			// As with mongodb, a non-array value is treated as a single-element array
+			if s.indexField != nil {
+				doc = setPath(doc, s.indexField, nil)
+			}
+			results = append(results, doc)
+		}
+	}
+	return results, nil
+}
+
+func (s *unwindStage) String() string {
+	return "$unwind " + strings.Join(s.path, ".")
+}
+
+/*
+$group
+*/
+
+var accumulators = map[string]struct{}{}
+
+func init() {
+	for _, op := range []string{"$sum", "$avg", "$min", "$max", "$first", "$last", "$push", "$addToSet", "$count"} {
+		accumulators[op] = struct{}{}
+	}
+}
+
+func parseGroup(value any) (stage, error) {
+	d, isD := value.(bson.D)
+	if !isD {
+		return nil, fmt.Errorf("the argument to $group must be a bson.D; found %v", value)
+	}
+	s := &groupStage{}
+	hasID := false
+	for _, e := range d {
+		if e.Key == "_id" {
+			s.id, hasID = e.Value, true
+			continue
+		}
+		acc, isD := e.Value.(bson.D)
+		if !isD || len(acc) != 1 {
+			return nil, fmt.Errorf("the $group field %v must specify exactly one accumulator; found %v", e.Key, e.Value)
+		}
+		if _, exists := accumulators[acc[0].Key]; !exists {
+			return nil, fmt.Errorf("unsupported $group accumulator %v", acc[0].Key)
+		}
+		if acc[0].Key == "$count" {
+			if arg, isD := acc[0].Value.(bson.D); !isD || len(arg) != 0 {
+				return nil, fmt.Errorf("the argument to $count must be an empty bson.D; found %v", acc[0].Value)
+			}
+		}
+		s.fields = append(s.fields, accumulatorField{name: e.Key, op: acc[0].Key, expr: acc[0].Value})
+	}
+	if !hasID {
+		return nil, fmt.Errorf("$group must specify an _id")
+	}
+	return s, nil
+}
+
+func (s *groupStage) apply(docs []bson.D, collections Collections) ([]bson.D, error) {
+	type group struct {
+		id   any
+		docs []bson.D
+	}
+	// Groups are returned in the order they are first seen
+	var groups []*group
+	for _, doc := range docs {
+		id, found, err := evaluate(doc, s.id)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			id = nil
+		}
+		var g *group
+		for _, existing := range groups {
+			if typeRank(existing.id) == typeRank(id) && Compare(existing.id, id) == 0 {
+				g = existing
+				break
+			}
+		}
+		if g == nil {
+			g = &group{id: id}
+			groups = append(groups, g)
+		}
+		g.docs = append(g.docs, doc)
+	}
+
+	var results []bson.D
+	for _, g := range groups {
+		result := bson.D{{Key: "_id", Value: g.id}}
+		for _, f := range s.fields {
+			value, err := f.accumulate(g.docs)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, bson.E{Key: f.name, Value: value})
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (f *accumulatorField) accumulate(docs []bson.D) (any, error) {
+	if f.op == "$count" {
+		return intResult(int64(len(docs)), true), nil
+	}
+	var values bson.A // The values of the expression, excluding missing fields
+	for _, doc := range docs {
+		value, found, err := evaluate(doc, f.expr)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			values = append(values, value)
+		} else if f.op == "$first" || f.op == "$last" {
+			values = append(values, nil)
+		}
+	}
+
+	switch f.op {
+	case "$sum", "$avg":
+		// As with mongodb, non-numeric values are ignored
+		var intSum int64
+		var floatSum float64
+		count, isFloat, narrow := 0, false, true
+		for _, v := range values {
+			if i, isInt := intValue(v); isInt {
+				narrow = narrow && isInt32(v)
+				intSum += i
+				floatSum += float64(i)
+				count++
+			} else if fl, isNumber := floatValue(v); isNumber {
+				floatSum += fl
+				count++
+				isFloat = true
+			}
+		}
+		if f.op == "$avg" {
+			if count == 0 {
+				return nil, nil
+			}
+			return floatSum / float64(count), nil
+		}
+		if isFloat {
+			return floatSum, nil
+		}
+		return intResult(intSum, narrow), nil
+	case "$min", "$max":
+		var result any
+		for _, v := range values {
+			if v == nil {
+				continue
+			}
+			if result == nil || (f.op == "$min" && Compare(v, result) < 0) || (f.op == "$max" && Compare(v, result) > 0) {
+				result = v
+			}
+		}
+		return result, nil
+	case "$first":
+		return values[0], nil
+	case "$last":
+		return values[len(values)-1], nil
+	case "$push":
+		return append(bson.A{}, values...), nil
+	case "$addToSet":
+		set := bson.A{}
+		for _, v := range values {
+			if !containsValue(set, v) {
+				set = append(set, v)
+			}
+		}
+		return set, nil
+	}
+	return nil, fmt.Errorf("unsupported $group accumulator %v", f.op)
+}
+
+func containsValue(a bson.A, value any) bool {
+	for _, v := range a {
+		if typeRank(v) == typeRank(value) && Compare(v, value) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *groupStage) String() string {
+	var strs []string
+	for _, f := range s.fields {
+		strs = append(strs, fmt.Sprintf("%v: %v(%v)", f.name, f.op, f.expr))
+	}
+	return fmt.Sprintf("$group by %v {%v}", s.id, strings.Join(strs, ", "))
+}
+
+/*
+$lookup
+*/
+
+func parseLookup(value any) (stage, error) {
+	d, isD := value.(bson.D)
+	if !isD {
+		return nil, fmt.Errorf("the argument to $lookup must be a bson.D; found %v", value)
+	}
+	s := &lookupStage{}
+	var as string
+	for _, e := range d {
+		str, isString := e.Value.(string)
+		if !isString {
+			return nil, fmt.Errorf("the $lookup option %v must be a string; found %v", e.Key, e.Value)
+		}
+		switch e.Key {
+		case "from":
+			s.from = str
+		case "localField":
+			s.localField = str
+		case "foreignField":
+			s.foreignField = str
+		case "as":
+			as = str
+		default:
+			return nil, fmt.Errorf("unsupported $lookup option %v; only from, localField, foreignField and as are supported", e.Key)
+		}
+	}
+	if s.from == "" || s.localField == "" || s.foreignField == "" || as == "" {
+		return nil, fmt.Errorf("$lookup requires from, localField, foreignField and as; found %v", d)
+	}
+	s.as = strings.Split(as, ".")
+	return s, nil
+}
+
+func (s *lookupStage) apply(docs []bson.D, collections Collections) ([]bson.D, error) {
+	if collections == nil {
+		return nil, fmt.Errorf("$lookup is not supported by this collection")
+	}
+	foreign, err := collections(s.from)
+	if err != nil {
+		return nil, err
+	}
+	var results []bson.D
+	for _, doc := range docs {
+		// As with mongodb, a missing field matches null and missing fields, and an
+		// array field matches any of its elements
+		local := Values(doc, s.localField)
+		if len(local) == 0 {
+			local = []any{nil}
+		}
+		matches := bson.A{}
+		for _, f := range foreign {
+			if s.matches(local, f) {
+				matches = append(matches, f)
+			}
+		}
+		results = append(results, setPath(doc, s.as, matches))
+	}
+	return results, nil
+}
+
+func (s *lookupStage) matches(local []any, foreign bson.D) bool {
+	values := Values(foreign, s.foreignField)
+	if len(values) == 0 {
+		values = []any{nil}
+	}
+	for _, l := range local {
+		for _, v := range values {
+			if typeRank(l) == typeRank(v) && Compare(l, v) == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *lookupStage) String() string {
+	return fmt.Sprintf("$lookup %v on %v = %v.%v as %v", s.from, s.localField, s.from, s.foreignField, strings.Join(s.as, "."))
+}
+
+/*
+$project
+*/
+
+// A projection of a document's fields.  Dotted and nested fields are represented by nested projections.
+type projection struct {
+	fields []*projectionField
+}
+
+type projectionField struct {
+	name     string
+	include  bool
+	exclude  bool
+	computed bool
+	expr     any         // The expression of a computed field
+	nested   *projection // The projection of an embedded document
+}
+
+func parseProject(value any) (stage, error) {
+	d, isD := value.(bson.D)
+	if !isD || len(d) == 0 {
+		return nil, fmt.Errorf("the argument to $project must be a non-empty bson.D; found %v", value)
+	}
+	p := &projection{}
+	for _, e := range d {
+		if err := p.add(strings.Split(e.Key, "."), e.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	// The _id field can be excluded from inclusion projections, but otherwise
+	// inclusions and exclusions cannot be mixed
+	var includes, excludes int
+	for _, f := range p.fields {
+		if f.name == "_id" && (f.include || f.exclude) {
+			continue
+		}
+		f.count(&includes, &excludes)
+	}
+	if includes > 0 && excludes > 0 {
+		return nil, fmt.Errorf("cannot mix inclusion and exclusion in $project %v", d)
+	}
+	id := p.lookup("_id")
+	return &projectStage{projection: p, exclude: includes == 0 && (excludes > 0 || (id != nil && id.exclude))}, nil
+}
+
+func (p *projection) add(path []string, value any) error {
+	f := p.lookup(path[0])
+	if f == nil {
+		f = &projectionField{name: path[0]}
+		p.fields = append(p.fields, f)
+	}
+	if len(path) > 1 {
+		if f.include || f.exclude || f.computed {
+			return fmt.Errorf("$project specifies both %v and its subfields", path[0])
+		}
+		if f.nested == nil {
+			f.nested = &projection{}
+		}
+		return f.nested.add(path[1:], value)
+	}
+	if f.include || f.exclude || f.computed || f.nested != nil {
+		return fmt.Errorf("$project specifies %v more than once", path[0])
+	}
+
+	if b, isBool := value.(bool); isBool {
+		f.include, f.exclude = b, !b
+	} else if n, isNumber := floatValue(value); isNumber {
+		f.include, f.exclude = n != 0, n == 0
+	} else if d, isD := value.(bson.D); isD && len(d) > 0 && !strings.HasPrefix(d[0].Key, "$") {
+		f.nested = &projection{}
+		for _, e := range d {
+			if err := f.nested.add(strings.Split(e.Key, "."), e.Value); err != nil {
+				return err
+			}
+		}
+	} else {
+		f.computed, f.expr = true, value
+	}
+	return nil
+}
+
+func (f *projectionField) count(includes, excludes *int) {
+	switch {
+	case f.include, f.computed:
+		*includes++
+	case f.exclude:
+		*excludes++
+	case f.nested != nil:
+		for _, nested := range f.nested.fields {
+			nested.count(includes, excludes)
+		}
+	}
+}
+
+func (s *projectStage) apply(docs []bson.D, collections Collections) ([]bson.D, error) {
+	results := make([]bson.D, 0, len(docs))
+	for _, doc := range docs {
+		var result bson.D
+		if s.exclude {
+			result = s.projection.excludeFields(doc)
+		} else {
+			var err error
+			if result, err = s.projection.includeFields(doc, doc, true); err != nil {
+				return nil, err
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (p *projection) lookup(name string) *projectionField {
+	for _, f := range p.fields {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (p *projection) excludeFields(doc bson.D) bson.D {
+	result := bson.D{}
+	for _, e := range doc {
+		f := p.lookup(e.Key)
+		switch {
+		case f == nil || f.include:
+			result = append(result, e)
+		case f.nested != nil:
+			result = append(result, bson.E{Key: e.Key, Value: f.nested.excludeValue(e.Value)})
+		}
+	}
+	return result
+}
+
+func (p *projection) excludeValue(value any) any {
+	switch v := value.(type) {
+	case bson.D:
+		return p.excludeFields(v)
+	case bson.A:
+		result := bson.A{}
+		for _, elem := range v {
+			result = append(result, p.excludeValue(elem))
+		}
+		return result
+	}
+	return value
+}
+
+// Projects the included and computed fields of doc, which is either root or embedded within root.
+// Included fields retain their order within doc, and are followed by computed fields.
+func (p *projection) includeFields(root bson.D, doc bson.D, top bool) (bson.D, error) {
+	result := bson.D{}
+	seen := make(map[string]struct{})
+	for _, e := range doc {
+		f := p.lookup(e.Key)
+		if f == nil {
+			// As with mongodb, _id is included unless it is explicitly excluded
+			if top && e.Key == "_id" {
+				result = append(result, e)
+			}
+			continue
+		}
+		seen[e.Key] = struct{}{}
+		if f.include {
+			result = append(result, e)
+		} else if f.nested != nil {
+			value, include, err := f.nested.includeValue(root, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			if include {
+				result = append(result, bson.E{Key: e.Key, Value: value})
+			}
+		}
+	}
+	for _, f := range p.fields {
+		if f.computed {
+			value, found, err := evaluate(root, f.expr)
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				result = setPath(result, []string{f.name}, value)
+			}
+		} else if _, isSeen := seen[f.name]; !isSeen && f.nested != nil {
+			// Computed fields within embedded documents that doc lacks
+			value, err := f.nested.includeFields(root, bson.D{}, false)
+			if err != nil {
+				return nil, err
+			}
+			if len(value) > 0 {
+				result = append(result, bson.E{Key: f.name, Value: value})
+			}
+		}
+	}
+	return result, nil
+}
+
+// Projects an embedded value.  Returns false if the value should be omitted.
+func (p *projection) includeValue(root bson.D, value any) (any, bool, error) {
+	switch v := value.(type) {
+	case bson.D:
+		d, err := p.includeFields(root, v, false)
+		return d, true, err
+	case bson.A:
+		result := bson.A{}
+		for _, elem := range v {
+			projected, include, err := p.includeValue(root, elem)
+			if err != nil {
+				return nil, false, err
+			}
+			if include {
+				result = append(result, projected)
+			}
+		}
+		return result, true, nil
+	}
+	return nil, false, nil
+}
+
+func (s *projectStage) String() string {
+	return "$project " + s.projection.String()
+}
+
+func (p *projection) String() string {
+	var strs []string
+	for _, f := range p.fields {
+		switch {
+		case f.include:
+			strs = append(strs, f.name+": 1")
+		case f.exclude:
+			strs = append(strs, f.name+": 0")
+		case f.computed:
+			strs = append(strs, fmt.Sprintf("%v: %v", f.name, f.expr))
+		case f.nested != nil:
+			strs = append(strs, f.name+": "+f.nested.String())
+		}
+	}
+	return "{" + strings.Join(strs, ", ") + "}"
+}
@@ -0,0 +1,278 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+Evaluation of aggregation expressions, following mongodb's expression semantics.
+
+An expression is one of
+  - a field path, e.g. "$packaging.kind", or the variable "$$ROOT"
+  - an operator expression, e.g. { $add: [ "$price", "$tax" ] }
+  - a document or array whose fields or elements are themselves expressions
+  - any other value, which evaluates to itself
+
+https://www.mongodb.com/docs/manual/meta/aggregation-quick-reference/#expressions
+*/
+
+// Evaluates expr against doc.  Returns false if expr is a path to a field that doc does not have.
+func evaluate(doc bson.D, expr any) (any, bool, error) {
+	switch v := expr.(type) {
+	case string:
+		if v == "$$ROOT" || v == "$$CURRENT" {
+			return doc, true, nil
+		}
+		if strings.HasPrefix(v, "$$") {
+			return nil, false, fmt.Errorf("unsupported aggregation variable %v", v)
+		}
+		if strings.HasPrefix(v, "$") {
+			value, found := fieldPath(doc, strings.Split(v[1:], "."))
+			return value, found, nil
+		}
+		return v, true, nil
+	case bson.D:
+		if len(v) > 0 && strings.HasPrefix(v[0].Key, "$") {
+			if len(v) != 1 {
+				return nil, false, fmt.Errorf("an operator expression must have exactly one field; found %v", v)
+			}
+			value, err := evaluateOperator(doc, v[0].Key, v[0].Value)
+			return value, true, err
+		}
+		result := bson.D{}
+		for _, e := range v {
+			value, found, err := evaluate(doc, e.Value)
+			if err != nil {
+				return nil, false, err
+			}
+			// As with mongodb, fields whose expressions refer to missing fields are omitted
+			if found {
+				result = append(result, bson.E{Key: e.Key, Value: value})
+			}
+		}
+		return result, true, nil
+	case bson.A:
+		result := bson.A{}
+		for _, elem := range v {
+			value, found, err := evaluate(doc, elem)
+			if err != nil {
+				return nil, false, err
+			}
+			if !found {
+				value = nil
+			}
+			result = append(result, value)
+		}
+		return result, true, nil
+	case bson.M:
+		return nil, false, fmt.Errorf("expressions must be composed of bson.D, bson.A, or value literals; not bson.M found in %v", v)
+	}
+	return expr, true, nil
+}
+
+// Returns the value at path within value.  As with mongodb, selecting a field of an array
+// selects the field from each of the array's documents, returning an array of the results.
+func fieldPath(value any, path []string) (any, bool) {
+	if len(path) == 0 {
+		return value, true
+	}
+	switch v := value.(type) {
+	case bson.D:
+		for _, e := range v {
+			if e.Key == path[0] {
+				return fieldPath(e.Value, path[1:])
+			}
+		}
+	case bson.A:
+		results := bson.A{}
+		for _, elem := range v {
+			if _, isD := elem.(bson.D); isD {
+				if result, found := fieldPath(elem, path); found {
+					results = append(results, result)
+				}
+			}
+		}
+		return results, true
+	}
+	return nil, false
+}
+
+func evaluateOperator(doc bson.D, op string, arg any) (any, error) {
+	if op == "$literal" {
+		return arg, nil
+	}
+	args, err := evaluateArgs(doc, arg)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "$add", "$multiply":
+		return arithmetic(op, args)
+	case "$subtract", "$divide":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%v requires exactly 2 arguments; found %v", op, len(args))
+		}
+		return arithmetic(op, args)
+	case "$concat":
+		var sb strings.Builder
+		for _, a := range args {
+			if a == nil {
+				return nil, nil
+			}
+			s, isString := a.(string)
+			if !isString {
+				return nil, fmt.Errorf("$concat only supports strings; found %v", a)
+			}
+			sb.WriteString(s)
+		}
+		return sb.String(), nil
+	case "$size":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("$size requires exactly 1 argument; found %v", len(args))
+		}
+		a, isA := args[0].(bson.A)
+		if !isA {
+			return nil, fmt.Errorf("the argument to $size must be an array; found %v", args[0])
+		}
+		return int32(len(a)), nil
+	}
+	return nil, fmt.Errorf("unsupported aggregation expression operator %v", op)
+}
+
+// Evaluates the arguments of an operator, which are either an array of expressions or a single expression.
+// Arguments that refer to missing fields evaluate to null.
+func evaluateArgs(doc bson.D, arg any) (bson.A, error) {
+	if a, isA := arg.(bson.A); isA {
+		value, _, err := evaluate(doc, a)
+		if err != nil {
+			return nil, err
+		}
+		return value.(bson.A), nil
+	}
+	value, found, err := evaluate(doc, arg)
+	if !found {
+		value = nil
+	}
+	return bson.A{value}, err
+}
+
+// Applies an arithmetic operator.  As with mongodb, the result is null if any argument is null, an
+// integer if all arguments are integers (except for $divide), and a float otherwise.
+func arithmetic(op string, args bson.A) (any, error) {
+	allInts := op != "$divide"
+	for _, a := range args {
+		if a == nil {
+			return nil, nil
+		}
+		if _, isNumber := floatValue(a); !isNumber {
+			return nil, fmt.Errorf("%v only supports numeric types; found %v", op, a)
+		}
+		if _, isInt := intValue(a); !isInt {
+			allInts = false
+		}
+	}
+	if allInts {
+		var result int64
+		narrow := true
+		for i, a := range args {
+			v, _ := intValue(a)
+			narrow = narrow && isInt32(a)
+			switch {
+			case i == 0:
+				result = v
+			case op == "$add":
+				result += v
+			case op == "$subtract":
+				result -= v
+			case op == "$multiply":
+				result *= v
+			}
+		}
+		if op == "$multiply" && len(args) == 0 {
+			result = 1
+		}
+		return intResult(result, narrow), nil
+	}
+	var result float64
+	for i, a := range args {
+		v, _ := floatValue(a)
+		switch {
+		case i == 0:
+			result = v
+		case op == "$add":
+			result += v
+		case op == "$subtract":
+			result -= v
+		case op == "$multiply":
+			result *= v
+		case op == "$divide":
+			if v == 0 {
+				return nil, fmt.Errorf("can't $divide by zero")
+			}
+			result /= v
+		}
+	}
+	return result, nil
+}
+
+// Returns the value of the field at path within doc, without traversing arrays
+func getPath(doc bson.D, path []string) (any, bool) {
+	for _, e := range doc {
+		if e.Key == path[0] {
+			if len(path) == 1 {
+				return e.Value, true
+			}
+			if d, isD := e.Value.(bson.D); isD {
+				return getPath(d, path[1:])
+			}
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// Returns a copy of doc with the field at path set to value, creating any missing embedded documents.
+// doc itself is not modified.
+func setPath(doc bson.D, path []string, value any) bson.D {
+	result := make(bson.D, len(doc), len(doc)+1)
+	copy(result, doc)
+	for i, e := range result {
+		if e.Key == path[0] {
+			if len(path) > 1 {
+				d, _ := e.Value.(bson.D)
+				value = setPath(d, path[1:], value)
+			}
+			result[i].Value = value
+			return result
+		}
+	}
+	if len(path) > 1 {
+		value = setPath(bson.D{}, path[1:], value)
+	}
+	return append(result, bson.E{Key: path[0], Value: value})
+}
+
+// Returns whether value is an integer that mongodb would store as a 32-bit integer
+func isInt32(value any) bool {
+	switch value.(type) {
+	case int8, int16, int32:
+		return true
+	case int:
+		i, _ := intValue(value)
+		return i >= math.MinInt32 && i <= math.MaxInt32
+	}
+	return false
+}
+
+// As with mongodb, the result of integer arithmetic on 32-bit integers is a 32-bit
+// integer unless it overflows, in which case it is a 64-bit integer
+func intResult(result int64, narrow bool) any {
+	if narrow && result >= math.MinInt32 && result <= math.MaxInt32 {
+		return int32(result)
+	}
+	return result
+}
@@ -0,0 +1,141 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+/*
+Helpers for evaluating filters using indexes
+*/
+
+// Returns the equality predicates of filter that an index can be used to evaluate.
+//
+// The result maps a field selector (possibly dotted, as in filter) to the values that field
+// is constrained to by a { <field>: <value> }, { <field>: { $eq: <value> } } or
+// { <field>: { $in: [<values>] } } condition at the root of filter or nested within $and.
+// Only literal values are returned; conditions on documents or arrays are ignored.
+//
+// A document can only match filter if, for every returned selector, one of the document's
+// [Values] for that selector equals one of the returned values.  The converse is not true, so
+// the filter must still be applied to any document found via an index.
+func EqualityPredicates(filter bson.D) map[string][]any {
+	predicates := make(map[string][]any)
+	addEqualityPredicates(filter, predicates)
+	return predicates
+}
+
+func addEqualityPredicates(filter bson.D, predicates map[string][]any) {
+	for _, e := range filter {
+		if e.Key == "$and" {
+			if a, isA := e.Value.(bson.A); isA {
+				for _, v := range a {
+					if d, isD := v.(bson.D); isD {
+						addEqualityPredicates(d, predicates)
+					}
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(e.Key, "$") {
+			continue
+		}
+		if _, exists := predicates[e.Key]; exists {
+			continue
+		}
+		if values, ok := equalityValues(e.Value); ok {
+			predicates[e.Key] = values
+		}
+	}
+}
+
+func equalityValues(value any) ([]any, bool) {
+	d, isD := value.(bson.D)
+	if !isD {
+		if isLiteral(value) {
+			return []any{value}, true
+		}
+		return nil, false
+	}
+	for _, e := range d {
+		if !strings.HasPrefix(e.Key, "$") {
+			// An exact document match
+			return nil, false
+		}
+	}
+	for _, e := range d {
+		if e.Key == "$eq" && isLiteral(e.Value) {
+			return []any{e.Value}, true
+		}
+	}
+	for _, e := range d {
+		if e.Key == "$in" {
+			a, isA := e.Value.(bson.A)
+			if !isA || len(a) == 0 {
+				return nil, false
+			}
+			for _, v := range a {
+				if !isLiteral(v) {
+					return nil, false
+				}
+			}
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// Literal values are those that filters compare directly, rather than structurally
+func isLiteral(value any) bool {
+	switch value.(type) {
+	case bson.D, bson.A, bson.E, bson.M:
+		return false
+	}
+	return true
+}
+
+// Returns the values of item selected by selector, with the same semantics as [Lookup].
+//
+// If a selected field is an array, then the array's elements are returned rather than the array
+// itself, since filters on the field match against any of the elements.  Arrays are also traversed
+// when selecting fields of embedded documents within arrays.  Returns no values if the selected
+// field does not exist.
+func Values(item any, selector string) []any {
+	var values []any
+	collectValues(item, strings.Split(selector, "."), false, &values)
+	return values
+}
+
+// If broadcast is true and item is an array, then the path is selected from each of its elements
+func collectValues(item any, path []string, broadcast bool, values *[]any) {
+	if len(path) == 0 {
+		if a, isA := item.(bson.A); isA {
+			*values = append(*values, a...)
+		} else {
+			*values = append(*values, item)
+		}
+		return
+	}
+	if j, err := strconv.Atoi(path[0]); err == nil {
+		if a, isA := item.(bson.A); isA && j >= 0 && j < len(a) {
+			collectValues(a[j], path[1:], true, values)
+		}
+		return
+	}
+	if a, isA := item.(bson.A); isA && broadcast {
+		for _, e := range a {
+			collectValues(e, path, false, values)
+		}
+		return
+	}
+	if d, isD := item.(bson.D); isD {
+		for _, e := range d {
+			if e.Key == path[0] {
+				collectValues(e.Value, path[1:], true, values)
+				return
+			}
+		}
+	}
+}
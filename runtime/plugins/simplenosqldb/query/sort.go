@@ -0,0 +1,226 @@
+package query
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+/*
+Sorting of documents, following mongodb's sort semantics
+*/
+
+// Orders documents according to a mongodb sort specification
+type Sort interface {
+	// Returns a negative number if a sorts before b, a positive number if b sorts before a, or 0
+	Compare(a, b bson.D) int
+
+	String() string
+}
+
+type sortKey struct {
+	field      string
+	descending bool
+}
+
+type sortSpec struct {
+	keys []sortKey
+}
+
+// Parses a mongodb sort specification, e.g. bson.D{{"timestamp", -1}, {"id", 1}}
+//
+// https://www.mongodb.com/docs/manual/reference/method/cursor.sort/
+func ParseSort(spec bson.D) (Sort, error) {
+	s := &sortSpec{}
+	for _, e := range spec {
+		direction, isInt := intValue(e.Value)
+		if !isInt || (direction != 1 && direction != -1) {
+			return nil, fmt.Errorf("invalid sort direction %v for field %v; expected 1 or -1", e.Value, e.Key)
+		}
+		s.keys = append(s.keys, sortKey{field: e.Key, descending: direction == -1})
+	}
+	return s, nil
+}
+
+// Sorts docs in place.  Documents that compare equal retain their relative order.
+func SortDocuments(docs []bson.D, s Sort) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		return s.Compare(docs[i], docs[j]) < 0
+	})
+}
+
+func (s *sortSpec) Compare(a, b bson.D) int {
+	for _, key := range s.keys {
+		// As with mongodb, an array field sorts by its smallest element in ascending
+		// order and by its largest element in descending order
+		va := sortValue(a, key)
+		vb := sortValue(b, key)
+		if c := Compare(va, vb); c != 0 {
+			if key.descending {
+				return -c
+			}
+			return c
+		}
+	}
+	return 0
+}
+
+func sortValue(doc bson.D, key sortKey) any {
+	values := Values(doc, key.field)
+	if len(values) == 0 {
+		return nil
+	}
+	v := values[0]
+	for _, other := range values[1:] {
+		c := Compare(other, v)
+		if (key.descending && c > 0) || (!key.descending && c < 0) {
+			v = other
+		}
+	}
+	return v
+}
+
+func (s *sortSpec) String() string {
+	var strs []string
+	for _, key := range s.keys {
+		if key.descending {
+			strs = append(strs, key.field+" desc")
+		} else {
+			strs = append(strs, key.field+" asc")
+		}
+	}
+	return "sort " + strings.Join(strs, ", ")
+}
+
+// Compares two values using mongodb's comparison order for BSON types.
+//
+// Values of different types are ordered by type: null, numbers, strings, documents, arrays,
+// binary data, ObjectIds, booleans, dates, timestamps and regular expressions.  Values of the
+// same type are compared by value.  Returns a negative number, 0, or a positive number if a is
+// less than, equal to, or greater than b respectively.
+//
+// https://www.mongodb.com/docs/manual/reference/bson-type-comparison-order/
+func Compare(a, b any) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	switch va := a.(type) {
+	case string, primitive.Symbol:
+		return strings.Compare(stringValue(va), stringValue(b))
+	case bson.D:
+		vb := b.(bson.D)
+		for i := 0; i < len(va) && i < len(vb); i++ {
+			if c := strings.Compare(va[i].Key, vb[i].Key); c != 0 {
+				return c
+			}
+			if c := Compare(va[i].Value, vb[i].Value); c != 0 {
+				return c
+			}
+		}
+		return len(va) - len(vb)
+	case bson.A:
+		vb := b.(bson.A)
+		for i := 0; i < len(va) && i < len(vb); i++ {
+			if c := Compare(va[i], vb[i]); c != 0 {
+				return c
+			}
+		}
+		return len(va) - len(vb)
+	case primitive.Binary:
+		return bytes.Compare(va.Data, b.(primitive.Binary).Data)
+	case primitive.ObjectID:
+		vb := b.(primitive.ObjectID)
+		return bytes.Compare(va[:], vb[:])
+	case bool:
+		return boolRank(va) - boolRank(b.(bool))
+	case primitive.Timestamp:
+		vb := b.(primitive.Timestamp)
+		return primitive.CompareTimestamp(va, vb)
+	}
+	if ta, isTime := timeValue(a); isTime {
+		tb, _ := timeValue(b)
+		return ta.Compare(tb)
+	}
+	if ia, isInt := intValue(a); isInt {
+		if ib, isInt := intValue(b); isInt {
+			return cmp3(ia < ib, ia > ib)
+		}
+	}
+	if fa, isFloat := floatValue(a); isFloat {
+		fb, _ := floatValue(b)
+		return cmp3(fa < fb, fa > fb)
+	}
+	return 0
+}
+
+func typeRank(v any) int {
+	switch v.(type) {
+	case primitive.MinKey:
+		return 0
+	case nil, primitive.Null, primitive.Undefined:
+		return 1
+	case int, int8, int16, int32, int64, float32, float64, primitive.Decimal128:
+		return 2
+	case string, primitive.Symbol:
+		return 3
+	case bson.D:
+		return 4
+	case bson.A:
+		return 5
+	case primitive.Binary:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime, time.Time:
+		return 9
+	case primitive.Timestamp:
+		return 10
+	case primitive.Regex:
+		return 11
+	case primitive.MaxKey:
+		return 13
+	default:
+		return 12
+	}
+}
+
+func timeValue(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case primitive.DateTime:
+		return t.Time(), true
+	case time.Time:
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+func stringValue(v any) string {
+	if sym, isSymbol := v.(primitive.Symbol); isSymbol {
+		return string(sym)
+	}
+	return v.(string)
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func cmp3(less, greater bool) int {
+	if less {
+		return -1
+	} else if greater {
+		return 1
+	}
+	return 0
+}
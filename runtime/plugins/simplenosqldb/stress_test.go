@@ -0,0 +1,158 @@
+package simplenosqldb_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/blueprint-uservices/blueprint/runtime/plugins/simplenosqldb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type Counter struct {
+	Worker int
+	Seq    int
+	Count  int
+}
+
+type Pair struct {
+	ID string `bson:"_id"`
+	A  int
+	B  int
+}
+
+const (
+	stressWorkers    = 8
+	stressReaders    = 4
+	stressIterations = 200
+)
+
+// Runs concurrent inserts, updates, upserts and finds against db, then checks that no writes were
+// lost and that no read observed a partially-applied write
+func stress(t *testing.T, ctx context.Context, db *simplenosqldb.SimpleNoSQLDB) {
+	before := simplenosqldb.SetVerbose(false)
+	defer simplenosqldb.SetVerbose(before)
+
+	counters, err := db.GetCollection(ctx, "stressdb", "counters")
+	require.NoError(t, err)
+	_, err = counters.CreateIndex(ctx, bson.D{{"worker", 1}, {"seq", 1}}, true)
+	require.NoError(t, err)
+	pairs, err := db.GetCollection(ctx, "stressdb", "pairs")
+	require.NoError(t, err)
+	require.NoError(t, pairs.InsertOne(ctx, Pair{ID: "shared"}))
+
+	var writers, readers sync.WaitGroup
+	done := make(chan struct{})
+	for w := 0; w < stressWorkers; w++ {
+		writers.Add(1)
+		go func(w int) {
+			defer writers.Done()
+			for i := 0; i < stressIterations; i++ {
+				assert.NoError(t, counters.InsertOne(ctx, Counter{Worker: w, Seq: i}))
+
+				// Inserting a duplicate key must fail
+				err := counters.InsertOne(ctx, Counter{Worker: w, Seq: i})
+				assert.Error(t, err)
+
+				updated, err := counters.UpdateOne(ctx, bson.D{{"worker", w}, {"seq", i}}, bson.D{{"$inc", bson.D{{"count", 1}}}})
+				assert.NoError(t, err)
+				assert.Equal(t, 1, updated)
+
+				_, err = pairs.UpdateMany(ctx, bson.D{{"_id", "shared"}}, bson.D{{"$inc", bson.D{{"a", 1}, {"b", 1}}}})
+				assert.NoError(t, err)
+
+				_, err = pairs.Upsert(ctx, bson.D{{"_id", fmt.Sprintf("worker%v", w)}}, Pair{ID: fmt.Sprintf("worker%v", w), A: i, B: i})
+				assert.NoError(t, err)
+
+				cursor, err := counters.FindMany(ctx, bson.D{{"worker", w}})
+				assert.NoError(t, err)
+				var mine []Counter
+				assert.NoError(t, cursor.All(ctx, &mine))
+				assert.Len(t, mine, i+1)
+
+				// Concurrently create and use other collections
+				other, err := db.GetCollection(ctx, "stressdb", fmt.Sprintf("other%v", i%10))
+				assert.NoError(t, err)
+				assert.NoError(t, other.InsertOne(ctx, Counter{Worker: w, Seq: i}))
+			}
+		}(w)
+	}
+	for r := 0; r < stressReaders; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				cursor, err := pairs.FindMany(ctx, bson.D{})
+				assert.NoError(t, err)
+				var all []Pair
+				assert.NoError(t, cursor.All(ctx, &all))
+				for _, p := range all {
+					assert.Equal(t, p.A, p.B, "observed a partial update of %v", p.ID)
+				}
+			}
+		}()
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+
+	cursor, err := counters.FindMany(ctx, bson.D{{"count", 1}})
+	require.NoError(t, err)
+	var all []Counter
+	require.NoError(t, cursor.All(ctx, &all))
+	require.Len(t, all, stressWorkers*stressIterations)
+
+	cursor, err = pairs.FindMany(ctx, bson.D{})
+	require.NoError(t, err)
+	var pairResults []Pair
+	require.NoError(t, cursor.All(ctx, &pairResults))
+	require.Len(t, pairResults, stressWorkers+1)
+	require.Equal(t, Pair{ID: "shared", A: stressWorkers * stressIterations, B: stressWorkers * stressIterations}, pairResults[0])
+
+	total := 0
+	for i := 0; i < 10; i++ {
+		other, err := db.GetCollection(ctx, "stressdb", fmt.Sprintf("other%v", i))
+		require.NoError(t, err)
+		cursor, err := other.FindMany(ctx, bson.D{})
+		require.NoError(t, err)
+		var results []Counter
+		require.NoError(t, cursor.All(ctx, &results))
+		total += len(results)
+	}
+	require.Equal(t, stressWorkers*stressIterations, total)
+}
+
+func TestStress(t *testing.T) {
+	ctx, db := openPersistentDB(t)
+	stress(t, ctx, db)
+}
+
+func TestStressPersistent(t *testing.T) {
+	dir := t.TempDir()
+
+	// Snapshots are taken frequently, concurrently with writes
+	ctx, db := openPersistentDB(t, "dir="+dir, "snapshot=50ms")
+	stress(t, ctx, db)
+	expected := findAll(t, ctx, db, "counters")
+
+	ctx, db = openPersistentDB(t, "dir="+dir)
+	require.Equal(t, expected, findAll(t, ctx, db, "counters"))
+}
+
+func findAll(t *testing.T, ctx context.Context, db *simplenosqldb.SimpleNoSQLDB, collection string) []Counter {
+	coll, err := db.GetCollection(ctx, "stressdb", collection)
+	require.NoError(t, err)
+	cursor, err := coll.FindMany(ctx, bson.D{})
+	require.NoError(t, err)
+	var results []Counter
+	require.NoError(t, cursor.All(ctx, &results))
+	return results
+}
@@ -0,0 +1,61 @@
+package simplequeue
+
+import (
+	"context"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Push, pop and drop counters for a [SimpleQueue], plus a gauge reporting its current depth.
+//
+// The instruments are created on first use; see [backend.LazyMeter].
+type queueMetrics struct {
+	queue   *SimpleQueue
+	meter   backend.LazyMeter
+	pushes  metric.Int64Counter
+	pops    metric.Int64Counter
+	dropped metric.Int64Counter
+}
+
+func (m *queueMetrics) init(ctx context.Context) {
+	m.meter.Init(ctx, "simplequeue", m.register)
+}
+
+func (m *queueMetrics) register(meter metric.Meter) (err error) {
+	if m.pushes, err = meter.Int64Counter("queue_pushes", metric.WithDescription("Number of items pushed to the queue")); err != nil {
+		return err
+	}
+	if m.pops, err = meter.Int64Counter("queue_pops", metric.WithDescription("Number of items popped from the queue")); err != nil {
+		return err
+	}
+	if m.dropped, err = meter.Int64Counter("queue_dropped", metric.WithDescription("Number of items dropped or rejected because the queue was full")); err != nil {
+		return err
+	}
+	depth, err := meter.Int64ObservableGauge("queue_depth", metric.WithDescription("Number of items in the queue"))
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		m.queue.Lock()
+		defer m.queue.Unlock()
+		o.ObserveInt64(depth, int64(len(m.queue.items)))
+		return nil
+	}, depth)
+	return err
+}
+
+func (m *queueMetrics) pushed(ctx context.Context) {
+	m.init(ctx)
+	m.pushes.Add(ctx, 1)
+}
+
+func (m *queueMetrics) popped(ctx context.Context) {
+	m.init(ctx)
+	m.pops.Add(ctx, 1)
+}
+
+func (m *queueMetrics) drop(ctx context.Context) {
+	m.init(ctx)
+	m.dropped.Add(ctx, 1)
+}
@@ -0,0 +1,236 @@
+package simplequeue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/blueprint-uservices/blueprint/runtime/core/options"
+)
+
+// The default time a received message stays invisible to other consumers before it is redelivered
+const defaultVisibilityTimeout = 30 * time.Second
+
+// A simple in-memory implementation of the [backend.PubSub] interface.
+//
+// Each subscriber group has its own unbounded FIFO of messages.  Published messages are copied
+// to the FIFO of every group that has subscribed to the topic.
+type SimplePubSub struct {
+	backend.PubSub
+	sync.Mutex
+	topics map[string]map[string]*subscriberGroup
+
+	visibility    time.Duration
+	maxDeliveries int // 0 if messages can be redelivered indefinitely
+	nextID        uint64
+}
+
+type subscriberGroup struct {
+	ready    []*message           // messages awaiting delivery, in FIFO order
+	inflight map[string]*delivery // received messages awaiting Ack or Nack, by delivery id
+	signal   chan struct{}        // closed and replaced when messages are added to ready
+}
+
+type message struct {
+	item       any
+	deliveries int
+}
+
+type delivery struct {
+	msg      *message
+	deadline time.Time
+}
+
+// Instantiates an in-memory [SimplePubSub].
+//
+// opts are optional "key=value" strings that configure the backend:
+//   - visibility=<duration> is how long a received message may go unacknowledged before it is
+//     redelivered, e.g. "visibility=10s"; defaults to 30s
+//   - maxdeliveries=<int> is how many times a message is delivered before it is moved to the
+//     dead-letter topic; defaults to 0, meaning messages are redelivered indefinitely
+func NewSimplePubSub(ctx context.Context, opts ...string) (*SimplePubSub, error) {
+	ps := &SimplePubSub{
+		topics:     make(map[string]map[string]*subscriberGroup),
+		visibility: defaultVisibilityTimeout,
+	}
+	parsed, err := options.Parse("simplepubsub", opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range parsed {
+		key, value := opt.Key, opt.Value
+		var err error
+		switch key {
+		case "visibility":
+			ps.visibility, err = time.ParseDuration(value)
+		case "maxdeliveries":
+			ps.maxDeliveries, err = strconv.Atoi(value)
+		default:
+			return nil, fmt.Errorf("unknown simplepubsub option %v", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for simplepubsub option %v: %v", key, err)
+		}
+	}
+	if ps.visibility <= 0 || ps.maxDeliveries < 0 {
+		return nil, fmt.Errorf("simplepubsub visibility must be positive and maxdeliveries non-negative")
+	}
+	return ps, nil
+}
+
+// Publish implements backend.PubSub
+func (ps *SimplePubSub) Publish(ctx context.Context, topic string, item interface{}) error {
+	ps.Lock()
+	defer ps.Unlock()
+	ps.publish(topic, item)
+	return nil
+}
+
+// Subscribe implements backend.PubSub
+func (ps *SimplePubSub) Subscribe(ctx context.Context, topic string, group string) error {
+	ps.Lock()
+	defer ps.Unlock()
+	ps.subscribe(topic, group)
+	return nil
+}
+
+// Receive implements backend.PubSub
+func (ps *SimplePubSub) Receive(ctx context.Context, topic string, group string, dst interface{}) (string, bool, error) {
+	ps.Lock()
+	g := ps.subscribe(topic, group)
+	for {
+		now := time.Now()
+		ps.expire(topic, g, now)
+
+		if len(g.ready) > 0 {
+			msg := g.ready[0]
+			g.ready[0] = nil
+			g.ready = g.ready[1:]
+			msg.deliveries++
+
+			ps.nextID++
+			id := strconv.FormatUint(ps.nextID, 10)
+			g.inflight[id] = &delivery{msg: msg, deadline: now.Add(ps.visibility)}
+			ps.Unlock()
+			return id, true, backend.CopyResult(msg.item, dst)
+		}
+
+		// Wait for a message to be published, or for an in-flight message to become visible again
+		signal := g.signal
+		var timer *time.Timer
+		var expiry <-chan time.Time
+		if next, ok := nextDeadline(g); ok {
+			timer = time.NewTimer(next.Sub(now))
+			expiry = timer.C
+		}
+		ps.Unlock()
+
+		select {
+		case <-signal:
+		case <-expiry:
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return "", false, nil
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		ps.Lock()
+	}
+}
+
+// Ack implements backend.PubSub
+func (ps *SimplePubSub) Ack(ctx context.Context, topic string, group string, id string) error {
+	ps.Lock()
+	defer ps.Unlock()
+	_, err := ps.settle(topic, group, id)
+	return err
+}
+
+// Nack implements backend.PubSub
+func (ps *SimplePubSub) Nack(ctx context.Context, topic string, group string, id string) error {
+	ps.Lock()
+	defer ps.Unlock()
+	d, err := ps.settle(topic, group, id)
+	if err != nil {
+		return err
+	}
+	ps.redeliver(topic, ps.topics[topic][group], d.msg)
+	return nil
+}
+
+// Removes an in-flight delivery.  The caller must hold the lock.
+func (ps *SimplePubSub) settle(topic string, group string, id string) (*delivery, error) {
+	if g, exists := ps.topics[topic][group]; exists {
+		ps.expire(topic, g, time.Now())
+		if d, exists := g.inflight[id]; exists {
+			delete(g.inflight, id)
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown or expired delivery %v for %v/%v", id, topic, group)
+}
+
+// Returns the subscriber group, creating it if necessary.  The caller must hold the lock.
+func (ps *SimplePubSub) subscribe(topic string, group string) *subscriberGroup {
+	groups, exists := ps.topics[topic]
+	if !exists {
+		groups = make(map[string]*subscriberGroup)
+		ps.topics[topic] = groups
+	}
+	g, exists := groups[group]
+	if !exists {
+		g = &subscriberGroup{inflight: make(map[string]*delivery), signal: make(chan struct{})}
+		groups[group] = g
+	}
+	return g
+}
+
+// Copies item to every subscriber group of topic.  The caller must hold the lock.
+func (ps *SimplePubSub) publish(topic string, item any) {
+	for _, g := range ps.topics[topic] {
+		g.push(&message{item: item})
+	}
+}
+
+// Returns msg to the group, or dead-letters it if it has been delivered too many times.
+// The caller must hold the lock.
+func (ps *SimplePubSub) redeliver(topic string, g *subscriberGroup, msg *message) {
+	if ps.maxDeliveries > 0 && msg.deliveries >= ps.maxDeliveries {
+		ps.publish(backend.DeadLetterTopic(topic), msg.item)
+		return
+	}
+	g.push(msg)
+}
+
+// Redelivers in-flight messages whose visibility timeout has expired.  The caller must hold the lock.
+func (ps *SimplePubSub) expire(topic string, g *subscriberGroup, now time.Time) {
+	for id, d := range g.inflight {
+		if !now.Before(d.deadline) {
+			delete(g.inflight, id)
+			ps.redeliver(topic, g, d.msg)
+		}
+	}
+}
+
+func (g *subscriberGroup) push(msg *message) {
+	g.ready = append(g.ready, msg)
+	close(g.signal)
+	g.signal = make(chan struct{})
+}
+
+// Returns the earliest visibility deadline of the group's in-flight messages
+func nextDeadline(g *subscriberGroup) (time.Time, bool) {
+	var next time.Time
+	for _, d := range g.inflight {
+		if next.IsZero() || d.deadline.Before(next) {
+			next = d.deadline
+		}
+	}
+	return next, !next.IsZero()
+}
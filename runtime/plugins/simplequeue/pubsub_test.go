@@ -0,0 +1,198 @@
+package simplequeue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanOut(t *testing.T) {
+	ctx := context.Background()
+
+	ps, err := NewSimplePubSub(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ps.Subscribe(ctx, "orders", "billing"))
+	require.NoError(t, ps.Subscribe(ctx, "orders", "shipping"))
+
+	require.NoError(t, ps.Publish(ctx, "orders", "hello"))
+
+	// Every group receives a copy of the message
+	for _, group := range []string{"billing", "shipping"} {
+		var rcv string
+		id, success, err := ps.Receive(ctx, "orders", group, &rcv)
+		require.NoError(t, err)
+		require.True(t, success)
+		require.Equal(t, "hello", rcv)
+		require.NoError(t, ps.Ack(ctx, "orders", group, id))
+	}
+
+	{
+		// Messages published before a group subscribes are not delivered to it
+		var rcv string
+		timeoutCtx, cancel := context.WithTimeout(ctx, 0*time.Second)
+		defer cancel()
+		_, success, err := ps.Receive(timeoutCtx, "orders", "audit", &rcv)
+		require.NoError(t, err)
+		require.False(t, success)
+	}
+}
+
+func TestCompetingConsumers(t *testing.T) {
+	ctx := context.Background()
+
+	ps, err := NewSimplePubSub(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ps.Subscribe(ctx, "orders", "billing"))
+
+	items := []string{"hello", "world"}
+	for _, item := range items {
+		require.NoError(t, ps.Publish(ctx, "orders", item))
+	}
+
+	// Consumers of the same group each receive different messages, in order
+	for _, item := range items {
+		var rcv string
+		_, success, err := ps.Receive(ctx, "orders", "billing", &rcv)
+		require.NoError(t, err)
+		require.True(t, success)
+		require.Equal(t, item, rcv)
+	}
+
+	{
+		// In-flight messages are not visible to other consumers
+		var rcv string
+		timeoutCtx, cancel := context.WithTimeout(ctx, 0*time.Second)
+		defer cancel()
+		_, success, err := ps.Receive(timeoutCtx, "orders", "billing", &rcv)
+		require.NoError(t, err)
+		require.False(t, success)
+	}
+}
+
+func TestReceiveBlocks(t *testing.T) {
+	ctx := context.Background()
+
+	ps, err := NewSimplePubSub(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ps.Subscribe(ctx, "orders", "billing"))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		require.NoError(t, ps.Publish(ctx, "orders", "hello"))
+	}()
+
+	var rcv string
+	timeoutCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	_, success, err := ps.Receive(timeoutCtx, "orders", "billing", &rcv)
+	require.NoError(t, err)
+	require.True(t, success)
+	require.Equal(t, "hello", rcv)
+}
+
+func TestAckNack(t *testing.T) {
+	ctx := context.Background()
+
+	ps, err := NewSimplePubSub(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ps.Subscribe(ctx, "orders", "billing"))
+	require.NoError(t, ps.Publish(ctx, "orders", "hello"))
+
+	var rcv string
+	id, success, err := ps.Receive(ctx, "orders", "billing", &rcv)
+	require.NoError(t, err)
+	require.True(t, success)
+
+	// A nacked message is redelivered under a new delivery id
+	require.NoError(t, ps.Nack(ctx, "orders", "billing", id))
+	require.Error(t, ps.Ack(ctx, "orders", "billing", id))
+
+	id, success, err = ps.Receive(ctx, "orders", "billing", &rcv)
+	require.NoError(t, err)
+	require.True(t, success)
+	require.Equal(t, "hello", rcv)
+
+	// An acked message is not redelivered
+	require.NoError(t, ps.Ack(ctx, "orders", "billing", id))
+	require.Error(t, ps.Ack(ctx, "orders", "billing", id))
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 0*time.Second)
+	defer cancel()
+	_, success, err = ps.Receive(timeoutCtx, "orders", "billing", &rcv)
+	require.NoError(t, err)
+	require.False(t, success)
+}
+
+func TestVisibilityTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	ps, err := NewSimplePubSub(ctx, "visibility=20ms")
+	require.NoError(t, err)
+	require.NoError(t, ps.Subscribe(ctx, "orders", "billing"))
+	require.NoError(t, ps.Publish(ctx, "orders", "hello"))
+
+	var rcv string
+	id, success, err := ps.Receive(ctx, "orders", "billing", &rcv)
+	require.NoError(t, err)
+	require.True(t, success)
+
+	// A blocked Receive gets the message once its visibility timeout expires
+	timeoutCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	_, success, err = ps.Receive(timeoutCtx, "orders", "billing", &rcv)
+	require.NoError(t, err)
+	require.True(t, success)
+	require.Equal(t, "hello", rcv)
+
+	// The original delivery can no longer be acked
+	require.Error(t, ps.Ack(ctx, "orders", "billing", id))
+}
+
+func TestDeadLetter(t *testing.T) {
+	ctx := context.Background()
+
+	ps, err := NewSimplePubSub(ctx, "maxdeliveries=2")
+	require.NoError(t, err)
+	require.NoError(t, ps.Subscribe(ctx, "orders", "billing"))
+	require.NoError(t, ps.Subscribe(ctx, backend.DeadLetterTopic("orders"), "ops"))
+	require.NoError(t, ps.Publish(ctx, "orders", "hello"))
+
+	var rcv string
+	for i := 0; i < 2; i++ {
+		id, success, err := ps.Receive(ctx, "orders", "billing", &rcv)
+		require.NoError(t, err)
+		require.True(t, success)
+		require.NoError(t, ps.Nack(ctx, "orders", "billing", id))
+	}
+
+	{
+		// The message exceeded its deliveries so is no longer redelivered
+		timeoutCtx, cancel := context.WithTimeout(ctx, 0*time.Second)
+		defer cancel()
+		_, success, err := ps.Receive(timeoutCtx, "orders", "billing", &rcv)
+		require.NoError(t, err)
+		require.False(t, success)
+	}
+
+	{
+		// Instead it was moved to the dead-letter topic
+		_, success, err := ps.Receive(ctx, backend.DeadLetterTopic("orders"), "ops", &rcv)
+		require.NoError(t, err)
+		require.True(t, success)
+		require.Equal(t, "hello", rcv)
+	}
+}
+
+func TestInvalidPubSubOptions(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := NewSimplePubSub(ctx, "visibility")
+	require.Error(t, err)
+	_, err = NewSimplePubSub(ctx, "visibility=-1s")
+	require.Error(t, err)
+	_, err = NewSimplePubSub(ctx, "capacity=10")
+	require.Error(t, err)
+}
@@ -1,65 +1,177 @@
-// Package simplequeue implements an simple in-memory [backend.Queue] that internally
-// uses a golang channel of capacity 10 for passing items from producer to consumer.
+// Package simplequeue implements an simple in-memory [backend.Queue].
 //
-// Calls to [backend.Queue.Push] will block once the queue capacity reaches 10.
+// By default the queue has capacity 10, and calls to [backend.Queue.Push] will block once the
+// queue capacity is reached.  The capacity can be configured, or the queue can be unbounded, and
+// an [OverflowPolicy] can be chosen to drop items or return an error instead of blocking.
+// Queue depth, pushes, pops and dropped items are reported as metrics through [backend.Meter].
+//
+// The package also implements an in-memory [backend.PubSub], [SimplePubSub], that supports topics
+// with multiple subscriber groups, acknowledgements, visibility timeouts and dead-letter topics.
 package simplequeue
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
 
 	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/blueprint-uservices/blueprint/runtime/core/options"
+)
+
+// The capacity of a [SimpleQueue] if none is specified
+const defaultCapacity = 10
+
+// What a bounded [SimpleQueue] does when an item is pushed while the queue is full.
+type OverflowPolicy string
+
+const (
+	// Push blocks until there is room in the queue, or until the context is cancelled
+	Block OverflowPolicy = "block"
+
+	// Push discards the new item and reports that it was not pushed
+	DropNewest OverflowPolicy = "dropnewest"
+
+	// Push discards the item at the front of the queue to make room for the new item
+	DropOldest OverflowPolicy = "dropoldest"
+
+	// Push returns [ErrQueueFull]
+	ReturnError OverflowPolicy = "error"
 )
 
-// A simple chan-based queue that implements the [backend.Queue] interface
+// Returned by Push when the queue is full and its [OverflowPolicy] is [ReturnError]
+var ErrQueueFull = errors.New("queue is full")
+
+// A simple in-memory queue that implements the [backend.Queue] interface
 type SimpleQueue struct {
 	backend.Queue
-	q chan any
+	sync.Mutex
+	items    []any // FIFO of queued items
+	capacity int   // 0 if the queue is unbounded
+	overflow OverflowPolicy
+
+	notEmpty chan struct{} // closed and replaced when an item is pushed
+	notFull  chan struct{} // closed and replaced when an item is popped
+
+	metrics queueMetrics
 }
 
-// Instantiates a [backend.Queue] that internally uses a golang channel of capacity 10.
+// Instantiates an in-memory [SimpleQueue].
 //
-// Calls to [q.Push] will block once the queue capacity reaches 10.
-func NewSimpleQueue(ctx context.Context) (q *SimpleQueue, err error) {
-	return newSimpleQueueWithCapacity(10), nil
+// opts are optional "key=value" strings that configure the queue:
+//   - capacity=<int> is the maximum number of items in the queue; defaults to 10.  A capacity of 0
+//     means the queue is unbounded and Push never blocks
+//   - overflow=<block|dropnewest|dropoldest|error> selects the [OverflowPolicy] used when the
+//     queue is full; defaults to block
+func NewSimpleQueue(ctx context.Context, opts ...string) (q *SimpleQueue, err error) {
+	q = newSimpleQueueWithCapacity(defaultCapacity)
+	parsed, err := options.Parse("simplequeue", opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range parsed {
+		key, value := opt.Key, opt.Value
+		switch key {
+		case "capacity":
+			q.capacity, err = strconv.Atoi(value)
+		case "overflow":
+			q.overflow = OverflowPolicy(value)
+		default:
+			return nil, fmt.Errorf("unknown simplequeue option %v", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for simplequeue option %v: %v", key, err)
+		}
+	}
+	if q.capacity < 0 {
+		return nil, fmt.Errorf("simplequeue capacity must be non-negative")
+	}
+	switch q.overflow {
+	case Block, DropNewest, DropOldest, ReturnError:
+	default:
+		return nil, fmt.Errorf("unknown overflow policy %v; expected one of %v, %v, %v, %v", q.overflow, Block, DropNewest, DropOldest, ReturnError)
+	}
+	return q, nil
 }
 
-// Instantiates a [simpleQueue] with the specified capacity.
+// Instantiates a [SimpleQueue] with the specified capacity that blocks when full.
 func newSimpleQueueWithCapacity(capacity int) *SimpleQueue {
-	return &SimpleQueue{
-		q: make(chan any, capacity),
+	q := &SimpleQueue{
+		capacity: capacity,
+		overflow: Block,
+		notEmpty: make(chan struct{}),
+		notFull:  make(chan struct{}),
 	}
+	q.metrics.queue = q
+	return q
 }
 
 // Pop implements backend.Queue.
 func (q *SimpleQueue) Pop(ctx context.Context, dst interface{}) (bool, error) {
-	select {
-	case v := <-q.q:
-		return true, backend.CopyResult(v, dst)
-	default:
-		{
-			select {
-			case v := <-q.q:
-				return true, backend.CopyResult(v, dst)
-			case <-ctx.Done():
-				return false, nil
-			}
+	q.Lock()
+	for len(q.items) == 0 {
+		wait := q.notEmpty
+		q.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return false, nil
 		}
+		q.Lock()
 	}
+	v := q.pop()
+	q.Unlock()
+
+	q.metrics.popped(ctx)
+	return true, backend.CopyResult(v, dst)
 }
 
 // Push implements backend.Queue.
+//
+// When the queue is full, the behavior of Push depends on the queue's [OverflowPolicy].
 func (q *SimpleQueue) Push(ctx context.Context, item interface{}) (bool, error) {
-	select {
-	case q.q <- item:
-		return true, nil
-	default:
-		{
-			select {
-			case q.q <- item:
-				return true, nil
-			case <-ctx.Done():
-				return false, nil
-			}
+	q.Lock()
+	for q.capacity > 0 && len(q.items) >= q.capacity {
+		switch q.overflow {
+		case DropNewest:
+			q.Unlock()
+			q.metrics.drop(ctx)
+			return false, nil
+		case DropOldest:
+			q.pop()
+			q.metrics.drop(ctx)
+			continue
+		case ReturnError:
+			q.Unlock()
+			q.metrics.drop(ctx)
+			return false, ErrQueueFull
+		}
+
+		wait := q.notFull
+		q.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return false, nil
 		}
+		q.Lock()
 	}
+	q.items = append(q.items, item)
+	close(q.notEmpty)
+	q.notEmpty = make(chan struct{})
+	q.Unlock()
+
+	q.metrics.pushed(ctx)
+	return true, nil
+}
+
+// Removes and returns the item at the front of the queue.  The caller must hold the lock.
+func (q *SimpleQueue) pop() any {
+	v := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+	close(q.notFull)
+	q.notFull = make(chan struct{})
+	return v
 }
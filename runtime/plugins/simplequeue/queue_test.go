@@ -248,3 +248,79 @@ func TestTryTimeout(t *testing.T) {
 		require.Equal(t, second, rcv)
 	}
 }
+
+func TestUnbounded(t *testing.T) {
+	ctx := context.Background()
+
+	q, err := NewSimpleQueue(ctx, "capacity=0")
+	require.NoError(t, err)
+
+	// Push never blocks on an unbounded queue
+	for i := 0; i < 100; i++ {
+		timeoutCtx, cancel := context.WithTimeout(ctx, 0*time.Second)
+		success, err := q.Push(timeoutCtx, i)
+		cancel()
+		require.NoError(t, err)
+		require.True(t, success)
+	}
+
+	for i := 0; i < 100; i++ {
+		var rcv int
+		success, err := q.Pop(ctx, &rcv)
+		require.NoError(t, err)
+		require.True(t, success)
+		require.Equal(t, i, rcv)
+	}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	// The items remaining in a queue of capacity 2 after pushing 1, 2, 3
+	expected := map[OverflowPolicy][]int{
+		DropNewest:  {1, 2},
+		DropOldest:  {2, 3},
+		ReturnError: {1, 2},
+	}
+
+	for policy, remaining := range expected {
+		q, err := NewSimpleQueue(ctx, "capacity=2", "overflow="+string(policy))
+		require.NoError(t, err)
+
+		for i := 1; i <= 3; i++ {
+			success, err := q.Push(ctx, i)
+			switch {
+			case i < 3 || policy == DropOldest:
+				require.NoError(t, err)
+				require.True(t, success)
+			case policy == DropNewest:
+				require.NoError(t, err)
+				require.False(t, success)
+			case policy == ReturnError:
+				require.ErrorIs(t, err, ErrQueueFull)
+				require.False(t, success)
+			}
+		}
+
+		for _, item := range remaining {
+			var rcv int
+			success, err := q.Pop(ctx, &rcv)
+			require.NoError(t, err)
+			require.True(t, success)
+			require.Equal(t, item, rcv, "unexpected item for policy %v", policy)
+		}
+	}
+}
+
+func TestInvalidQueueOptions(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := NewSimpleQueue(ctx, "capacity")
+	require.Error(t, err)
+	_, err = NewSimpleQueue(ctx, "capacity=-1")
+	require.Error(t, err)
+	_, err = NewSimpleQueue(ctx, "overflow=spill")
+	require.Error(t, err)
+	_, err = NewSimpleQueue(ctx, "policy=block")
+	require.Error(t, err)
+}
@@ -1,6 +1,10 @@
 // Package sqlitereldb implements a [backend.RelationalDB] using the in-memory Golang
 // SQLite package [github.com/mattn/go-sqlite3].
 //
+// By default each [SqliteRelDB] instance has its own in-memory database.  A database can instead be
+// stored in a file, so that its contents survive process restarts.  Schema and seed SQL files can be
+// provided to initialize the database when it starts.
+//
 // If you are directly running go code (e.g. not from a docker container), the go-sqlite3
 // package requires CGO_ENABLED=1 and you must have gcc installed.  See [https://github.com/mattn/go-sqlite3]
 // for more details about installation instructions.
@@ -9,24 +13,140 @@ package sqlitereldb
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
 
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/blueprint-uservices/blueprint/runtime/core/migrations"
+	"github.com/blueprint-uservices/blueprint/runtime/core/options"
 	"github.com/jmoiron/sqlx"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// An in-memory relational DB that uses the go-sqlite3 package
+// A relational DB that uses the go-sqlite3 package
 type SqliteRelDB struct {
 	db *sqlx.DB
+
+	// In-memory databases are discarded when their last connection closes, so a
+	// connection is held open for the lifetime of the SqliteRelDB.  nil for file-backed databases.
+	keepalive *sql.Conn
 }
 
-// Instantiates a new [SqliteRelDB] instance that stores query data in-memory
-func NewSqliteRelDB(ctx context.Context) (*SqliteRelDB, error) {
-	db, err := sqlx.Open("sqlite3", "file:foobar?mode=memory&cache=shared")
+// Used to give each unnamed in-memory database a unique name
+var instances atomic.Int64
+
+// Instantiates a new [SqliteRelDB] instance.  By default, query data is stored in a new in-memory database.
+//
+// opts are optional "key=value" strings that configure the database:
+//   - name=<name> names the in-memory database.  Instances with the same name in the same process share a
+//     database; by default each instance has its own database
+//   - file=<path> stores the database in the file at path rather than in memory, creating the file if
+//     it does not exist.  The name option is ignored
+//   - schema=<path> executes the SQL statements in the file at path whenever the database is opened.
+//     The statements should be idempotent, e.g. CREATE TABLE IF NOT EXISTS
+//   - migrations=<path> applies the versioned migrations in the directory at path that have not yet been
+//     applied to the database, after executing any schema statements; see [migrations.Migrate]
+//   - seed=<path> executes the SQL statements in the file at path when the database is created, i.e.
+//     always for in-memory databases, and for file-backed databases only if the file did not exist.
+//     Seed statements are executed after the schema statements and migrations
+func NewSqliteRelDB(ctx context.Context, opts ...string) (*SqliteRelDB, error) {
+	var name, file, schema, migrationsDir, seed string
+	parsed, err := options.Parse("sqlitereldb", opts)
 	if err != nil {
 		return nil, err
 	}
-	return &SqliteRelDB{db: db}, nil
+	for _, opt := range parsed {
+		key, value := opt.Key, opt.Value
+		switch key {
+		case "name":
+			name = value
+		case "file":
+			file = value
+		case "schema":
+			schema = value
+		case "migrations":
+			migrationsDir = value
+		case "seed":
+			seed = value
+		default:
+			return nil, fmt.Errorf("unknown sqlitereldb option %v", key)
+		}
+	}
+
+	s := &SqliteRelDB{}
+	created := true
+	if file != "" {
+		if _, statErr := os.Stat(file); statErr == nil {
+			created = false
+		} else if !errors.Is(statErr, os.ErrNotExist) {
+			return nil, statErr
+		}
+		// Concurrent writers wait for each other rather than failing immediately
+		s.db, err = sqlx.Open("sqlite3", "file:"+escape(file)+"?_busy_timeout=5000")
+	} else {
+		if name == "" {
+			name = fmt.Sprintf("sqlitereldb%d", instances.Add(1))
+		}
+		s.db, err = sqlx.Open("sqlite3", "file:"+escape(name)+"?mode=memory&cache=shared")
+		if err == nil {
+			s.keepalive, err = s.db.Conn(ctx)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.initialize(ctx, schema, migrationsDir, seed, created); err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SqliteRelDB) initialize(ctx context.Context, schema string, migrationsDir string, seed string, created bool) error {
+	if schema != "" {
+		if err := s.execFile(ctx, schema); err != nil {
+			return err
+		}
+	}
+	if migrationsDir != "" {
+		if err := migrations.Migrate(ctx, s, migrationsDir); err != nil {
+			return err
+		}
+	}
+	if seed != "" && created {
+		return s.execFile(ctx, seed)
+	}
+	return nil
+}
+
+func (s *SqliteRelDB) close() {
+	if s.keepalive != nil {
+		s.keepalive.Close()
+	}
+	s.db.Close()
+}
+
+// Escapes characters that have a special meaning in SQLite URI filenames
+func escape(path string) string {
+	return strings.NewReplacer("%", url.QueryEscape("%"), "?", url.QueryEscape("?"), "#", url.QueryEscape("#")).Replace(path)
+}
+
+// Executes the SQL statements in the file at path
+func (s *SqliteRelDB) execFile(ctx context.Context, path string) error {
+	statements, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, string(statements)); err != nil {
+		return fmt.Errorf("unable to execute %v: %w", path, err)
+	}
+	return nil
 }
 
 // Exec implements backend.RelationalDB.
@@ -53,3 +173,55 @@ func (s *SqliteRelDB) Prepare(ctx context.Context, query string) (*sql.Stmt, err
 func (s *SqliteRelDB) Select(ctx context.Context, dst interface{}, query string, args ...any) error {
 	return s.db.SelectContext(ctx, dst, query, args...)
 }
+
+// BeginTx implements backend.RelationalDB.
+//
+// SQLite transactions are always serializable, so transactions run at the serializable isolation level
+// regardless of opts, and read-only transactions are not enforced.
+func (s *SqliteRelDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (backend.RelationalTx, error) {
+	tx, err := s.db.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SqliteRelTx{tx: tx}, nil
+}
+
+// A transaction on a [SqliteRelDB], started by [SqliteRelDB.BeginTx]
+type SqliteRelTx struct {
+	tx *sqlx.Tx
+}
+
+// Exec implements backend.RelationalTx.
+func (t *SqliteRelTx) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, query, args...)
+}
+
+// Query implements backend.RelationalTx.
+func (t *SqliteRelTx) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
+// Prepare implements backend.RelationalTx.
+func (t *SqliteRelTx) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.tx.PrepareContext(ctx, query)
+}
+
+// Select implements backend.RelationalTx.
+func (t *SqliteRelTx) Select(ctx context.Context, dst interface{}, query string, args ...any) error {
+	return t.tx.SelectContext(ctx, dst, query, args...)
+}
+
+// Get implements backend.RelationalTx.
+func (t *SqliteRelTx) Get(ctx context.Context, dst interface{}, query string, args ...any) error {
+	return t.tx.GetContext(ctx, dst, query, args...)
+}
+
+// Commit implements backend.RelationalTx.
+func (t *SqliteRelTx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback implements backend.RelationalTx.
+func (t *SqliteRelTx) Rollback() error {
+	return t.tx.Rollback()
+}
@@ -2,8 +2,13 @@ package sqlitereldb_test
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
 	"github.com/blueprint-uservices/blueprint/runtime/plugins/sqlitereldb"
 	"github.com/stretchr/testify/require"
 )
@@ -109,6 +114,198 @@ func TestRelDB(t *testing.T) {
 	}
 }
 
+var errInsufficientFunds = errors.New("insufficient funds")
+
+func TestTransactions(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sqlitereldb.NewSqliteRelDB(ctx)
+	require.NoError(t, err)
+
+	batch := []string{
+		`CREATE TABLE IF NOT EXISTS account (id INT PRIMARY KEY, balance INT);`,
+		`DELETE FROM account;`,
+		`INSERT INTO account (id, balance) VALUES (1, 100), (2, 0);`,
+	}
+	for _, b := range batch {
+		_, err = db.Exec(ctx, b)
+		require.NoError(t, err)
+	}
+
+	transfer := func(tx backend.RelationalTx, amount int) error {
+		if _, err := tx.Exec(ctx, `UPDATE account SET balance = balance - ? WHERE id = 1;`, amount); err != nil {
+			return err
+		}
+		var balance int
+		if err := tx.Get(ctx, &balance, `SELECT balance FROM account WHERE id = 1;`); err != nil {
+			return err
+		}
+		if balance < 0 {
+			return errInsufficientFunds
+		}
+		_, err := tx.Exec(ctx, `UPDATE account SET balance = balance + ? WHERE id = 2;`, amount)
+		return err
+	}
+	balances := func() []int {
+		var balances []int
+		require.NoError(t, db.Select(ctx, &balances, `SELECT balance FROM account ORDER BY id;`))
+		return balances
+	}
+
+	// Successful transactions are committed
+	err = backend.WithTx(ctx, db, nil, func(tx backend.RelationalTx) error {
+		return transfer(tx, 30)
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{70, 30}, balances())
+
+	// Transactions are rolled back on error
+	err = backend.WithTx(ctx, db, nil, func(tx backend.RelationalTx) error {
+		return transfer(tx, 100)
+	})
+	require.ErrorIs(t, err, errInsufficientFunds)
+	require.Equal(t, []int{70, 30}, balances())
+
+	// Transactions are rolled back on panic
+	require.Panics(t, func() {
+		backend.WithTx(ctx, db, &sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx backend.RelationalTx) error {
+			require.NoError(t, transfer(tx, 10))
+			panic("failed after transfer")
+		})
+	})
+	require.Equal(t, []int{70, 30}, balances())
+
+	// Transactions can be ended explicitly
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	require.NoError(t, err)
+	require.NoError(t, transfer(tx, 20))
+	require.NoError(t, tx.Rollback())
+	require.Equal(t, []int{70, 30}, balances())
+	_, err = tx.Exec(ctx, `DELETE FROM account;`)
+	require.ErrorIs(t, err, sql.ErrTxDone)
+}
+
+func TestIsolatedDatabases(t *testing.T) {
+	ctx := context.Background()
+
+	db1, err := sqlitereldb.NewSqliteRelDB(ctx)
+	require.NoError(t, err)
+	db2, err := sqlitereldb.NewSqliteRelDB(ctx)
+	require.NoError(t, err)
+
+	// Each instance has its own tables
+	_, err = db1.Exec(ctx, `CREATE TABLE item (id INT PRIMARY KEY);`)
+	require.NoError(t, err)
+	_, err = db2.Exec(ctx, `CREATE TABLE item (id INT PRIMARY KEY);`)
+	require.NoError(t, err)
+	_, err = db1.Exec(ctx, `INSERT INTO item (id) VALUES (1);`)
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, db2.Get(ctx, &count, `SELECT COUNT(*) FROM item;`))
+	require.Equal(t, 0, count)
+
+	// Instances with the same name share a database
+	named1, err := sqlitereldb.NewSqliteRelDB(ctx, "name=TestIsolatedDatabases")
+	require.NoError(t, err)
+	named2, err := sqlitereldb.NewSqliteRelDB(ctx, "name=TestIsolatedDatabases")
+	require.NoError(t, err)
+	_, err = named1.Exec(ctx, `CREATE TABLE item (id INT PRIMARY KEY);`)
+	require.NoError(t, err)
+	_, err = named1.Exec(ctx, `INSERT INTO item (id) VALUES (1), (2);`)
+	require.NoError(t, err)
+	require.NoError(t, named2.Get(ctx, &count, `SELECT COUNT(*) FROM item;`))
+	require.Equal(t, 2, count)
+}
+
+func TestSchemaAndSeed(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "schema.sql")
+	seed := filepath.Join(dir, "seed.sql")
+	require.NoError(t, os.WriteFile(schema, []byte(`
+		CREATE TABLE IF NOT EXISTS address (id INT PRIMARY KEY, street TEXT, street_number INT);
+		CREATE TABLE IF NOT EXISTS user_addresses (address_id INT, user_id INT);
+	`), 0644))
+	require.NoError(t, os.WriteFile(seed, []byte(`
+		INSERT INTO address (id, street, street_number) VALUES (1, 'rue Victor Hugo', 32);
+		INSERT INTO address (id, street, street_number) VALUES (2, 'boulevard de la République', 23);
+	`), 0644))
+
+	db, err := sqlitereldb.NewSqliteRelDB(ctx, "schema="+schema, "seed="+seed)
+	require.NoError(t, err)
+	var addresses []Address
+	require.NoError(t, db.Select(ctx, &addresses, `SELECT * FROM address ORDER BY id;`))
+	require.Equal(t, []Address{{"1", "rue Victor Hugo", 32}, {"2", "boulevard de la République", 23}}, addresses)
+
+	// Invalid SQL is reported when the database is created
+	invalid := filepath.Join(dir, "invalid.sql")
+	require.NoError(t, os.WriteFile(invalid, []byte(`CREATE TABLE;`), 0644))
+	_, err = sqlitereldb.NewSqliteRelDB(ctx, "schema="+invalid)
+	require.Error(t, err)
+	_, err = sqlitereldb.NewSqliteRelDB(ctx, "seed="+filepath.Join(dir, "missing.sql"))
+	require.Error(t, err)
+}
+
+func TestFileDatabase(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "test.db")
+	schema := filepath.Join(dir, "schema.sql")
+	seed := filepath.Join(dir, "seed.sql")
+	require.NoError(t, os.WriteFile(schema, []byte(`CREATE TABLE IF NOT EXISTS item (id INT PRIMARY KEY);`), 0644))
+	require.NoError(t, os.WriteFile(seed, []byte(`INSERT INTO item (id) VALUES (1);`), 0644))
+
+	db, err := sqlitereldb.NewSqliteRelDB(ctx, "file="+file, "schema="+schema, "seed="+seed)
+	require.NoError(t, err)
+	_, err = db.Exec(ctx, `INSERT INTO item (id) VALUES (2);`)
+	require.NoError(t, err)
+
+	// Reopening the file retains its contents, and does not reseed the database
+	db, err = sqlitereldb.NewSqliteRelDB(ctx, "file="+file, "schema="+schema, "seed="+seed)
+	require.NoError(t, err)
+	var ids []int
+	require.NoError(t, db.Select(ctx, &ids, `SELECT id FROM item ORDER BY id;`))
+	require.Equal(t, []int{1, 2}, ids)
+}
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "test.db")
+	migrationsDir := filepath.Join(dir, "migrations")
+	seed := filepath.Join(dir, "seed.sql")
+	require.NoError(t, os.Mkdir(migrationsDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(migrationsDir, "1_create_item.sql"), []byte(`CREATE TABLE item (id INT PRIMARY KEY);`), 0644))
+	require.NoError(t, os.WriteFile(seed, []byte(`INSERT INTO item (id) VALUES (1);`), 0644))
+
+	// Migrations are applied before the database is seeded
+	_, err := sqlitereldb.NewSqliteRelDB(ctx, "file="+file, "migrations="+migrationsDir, "seed="+seed)
+	require.NoError(t, err)
+
+	// Reopening the database only applies new migrations
+	require.NoError(t, os.WriteFile(filepath.Join(migrationsDir, "2_add_name.sql"), []byte(`ALTER TABLE item ADD COLUMN name TEXT;`), 0644))
+	db, err := sqlitereldb.NewSqliteRelDB(ctx, "file="+file, "migrations="+migrationsDir, "seed="+seed)
+	require.NoError(t, err)
+	var ids []int
+	require.NoError(t, db.Select(ctx, &ids, `SELECT id FROM item WHERE name IS NULL;`))
+	require.Equal(t, []int{1}, ids)
+
+	// Failed migrations prevent the database from starting
+	require.NoError(t, os.WriteFile(filepath.Join(migrationsDir, "3_broken.sql"), []byte(`ALTER TABLE missing ADD COLUMN x INT;`), 0644))
+	_, err = sqlitereldb.NewSqliteRelDB(ctx, "file="+file, "migrations="+migrationsDir)
+	require.Error(t, err)
+}
+
+func TestInvalidOptions(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := sqlitereldb.NewSqliteRelDB(ctx, "name")
+	require.Error(t, err)
+	_, err = sqlitereldb.NewSqliteRelDB(ctx, "size=10")
+	require.Error(t, err)
+}
+
 type Address struct {
 	ID     string `db:"id"`
 	Street string `db:"street"`
@@ -0,0 +1,76 @@
+package wiring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/plugins/circuitbreaker"
+	"github.com/blueprint-uservices/blueprint/plugins/goproc"
+	"github.com/blueprint-uservices/blueprint/plugins/grpc"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow"
+	wf "github.com/blueprint-uservices/blueprint/test/workflow/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreakerWithFallbacks(t *testing.T) {
+	spec := newWiringSpec("TestCircuitBreakerWithFallbacks")
+
+	leaf := workflow.Service[*wf.TestLeafServiceImpl](spec, "leaf")
+	nonleaf := workflow.Service[wf.TestNonLeafService](spec, "nonleaf", leaf)
+
+	circuitbreaker.AddCircuitBreaker(spec, leaf, 100, 0.5, "10s",
+		circuitbreaker.Cooldown(30*time.Second),
+		circuitbreaker.HalfOpenProbes(5),
+		circuitbreaker.Fallback("HelloInt", 0),
+		circuitbreaker.Fallback("HelloNothing"),
+	)
+
+	grpc.Deploy(spec, leaf)
+	grpc.Deploy(spec, nonleaf)
+
+	leafproc := goproc.CreateProcess(spec, "leafproc", leaf)
+	nonleafproc := goproc.CreateProcess(spec, "nonleafproc", nonleaf)
+
+	app := assertBuildSuccess(t, spec, leafproc, nonleafproc)
+
+	assertIR(t, app,
+		`TestCircuitBreakerWithFallbacks = BlueprintApplication() {
+			leaf.grpc.addr
+			leaf.grpc.bind_addr = AddressConfig()
+			leaf.grpc.dial_addr = AddressConfig()
+			leaf.handler.visibility
+			leafproc = GolangProcessNode(leaf.grpc.bind_addr) {
+			  leaf = TestLeafService()
+			  leaf.grpc_server = GRPCServer(leaf, leaf.grpc.bind_addr)
+			  leafproc.logger = SLogger()
+			  leafproc.stdoutmetriccollector = StdoutMetricCollector()
+			}
+			nonleaf.grpc.addr
+			nonleaf.grpc.bind_addr = AddressConfig()
+			nonleaf.handler.visibility
+			nonleafproc = GolangProcessNode(leaf.grpc.dial_addr, nonleaf.grpc.bind_addr) {
+			  leaf.client = leaf.client.cb
+			  leaf.client.cb = CircuitBreaker(leaf.grpc_client, "cooldown=30s", "halfopenprobes=5", "fallback.HelloInt=[0]", "fallback.HelloNothing=[]")
+			  leaf.grpc_client = GRPCClient(leaf.grpc.dial_addr)
+			  nonleaf = TestNonLeafService(leaf.client)
+			  nonleaf.grpc_server = GRPCServer(nonleaf, nonleaf.grpc.bind_addr)
+			  nonleafproc.logger = SLogger()
+			  nonleafproc.stdoutmetriccollector = StdoutMetricCollector()
+			}
+		  }`)
+}
+
+func TestCircuitBreakerWithInvalidFallback(t *testing.T) {
+	spec := newWiringSpec("TestCircuitBreakerWithInvalidFallback")
+
+	leaf := workflow.Service[*wf.TestLeafServiceImpl](spec, "leaf")
+	nonleaf := workflow.Service[wf.TestNonLeafService](spec, "nonleaf", leaf)
+
+	// Fallback values that cannot be encoded as JSON fail the build
+	circuitbreaker.AddCircuitBreaker(spec, leaf, 100, 0.5, "10s", circuitbreaker.Fallback("HelloInt", make(chan int)))
+
+	nonleafproc := goproc.CreateProcess(spec, "nonleafproc", nonleaf)
+
+	err := assertBuildFailure(t, spec, nonleafproc)
+	require.ErrorContains(t, err, "unable to encode fallback values of HelloInt")
+}
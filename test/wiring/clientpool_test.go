@@ -2,6 +2,7 @@ package wiring
 
 import (
 	"testing"
+	"time"
 
 	"github.com/blueprint-uservices/blueprint/plugins/clientpool"
 	"github.com/blueprint-uservices/blueprint/plugins/goproc"
@@ -56,6 +57,50 @@ func TestBasicClientPool(t *testing.T) {
 
 }
 
+func TestClientPoolOptions(t *testing.T) {
+	spec := newWiringSpec("TestClientPoolOptions")
+
+	leaf := workflow.Service[*wf.TestLeafServiceImpl](spec, "leaf")
+	nonleaf := workflow.Service[wf.TestNonLeafService](spec, "nonleaf", leaf)
+
+	clientpool.Create(spec, leaf, 7, clientpool.IdleTimeout(time.Minute), clientpool.MaxLifetime(time.Hour), clientpool.EvictOnError())
+
+	grpc.Deploy(spec, leaf)
+	grpc.Deploy(spec, nonleaf)
+
+	leafproc := goproc.CreateProcess(spec, "leafproc", leaf)
+	nonleafproc := goproc.CreateProcess(spec, "nonleafproc", nonleaf)
+
+	app := assertBuildSuccess(t, spec, leafproc, nonleafproc)
+
+	assertIR(t, app,
+		`TestClientPoolOptions = BlueprintApplication() {
+			leaf.grpc.addr
+			leaf.grpc.bind_addr = AddressConfig()
+			leaf.grpc.dial_addr = AddressConfig()
+			leaf.handler.visibility
+			leafproc = GolangProcessNode(leaf.grpc.bind_addr) {
+			  leaf = TestLeafService()
+			  leaf.grpc_server = GRPCServer(leaf, leaf.grpc.bind_addr)
+			  leafproc.logger = SLogger()
+			  leafproc.stdoutmetriccollector = StdoutMetricCollector()
+			}
+			nonleaf.grpc.addr
+			nonleaf.grpc.bind_addr = AddressConfig()
+			nonleaf.handler.visibility
+			nonleafproc = GolangProcessNode(leaf.grpc.dial_addr, nonleaf.grpc.bind_addr) {
+			  leaf.client = leaf.clientpool
+			  leaf.clientpool = ClientPool(leaf.grpc_client, 7, "idletimeout=1m0s", "maxlifetime=1h0m0s", "evictonerror=true") {
+				leaf.grpc_client = GRPCClient(leaf.grpc.dial_addr)
+			  }
+			  nonleaf = TestNonLeafService(leaf.client)
+			  nonleaf.grpc_server = GRPCServer(nonleaf, nonleaf.grpc.bind_addr)
+			  nonleafproc.logger = SLogger()
+			  nonleafproc.stdoutmetriccollector = StdoutMetricCollector()
+			}
+		  }`)
+}
+
 func TestBasicClientPoolInnerModifier(t *testing.T) {
 	spec := newWiringSpec("TestBasicClientPoolInnerModifier")
 
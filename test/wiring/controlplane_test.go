@@ -0,0 +1,62 @@
+package wiring
+
+import (
+	"testing"
+
+	"github.com/blueprint-uservices/blueprint/plugins/controlplane"
+	"github.com/blueprint-uservices/blueprint/plugins/goproc"
+	"github.com/blueprint-uservices/blueprint/plugins/grpc"
+	"github.com/blueprint-uservices/blueprint/plugins/retries"
+	"github.com/blueprint-uservices/blueprint/plugins/timeouts"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow"
+	wf "github.com/blueprint-uservices/blueprint/test/workflow/workflow"
+)
+
+func TestAdminServer(t *testing.T) {
+	spec := newWiringSpec("TestAdminServer")
+
+	leaf := workflow.Service[*wf.TestLeafServiceImpl](spec, "leaf")
+	nonleaf := workflow.Service[wf.TestNonLeafService](spec, "nonleaf", leaf)
+
+	retries.AddRetries(spec, leaf, 3)
+	timeouts.Add(spec, leaf, "1s")
+
+	grpc.Deploy(spec, leaf)
+	grpc.Deploy(spec, nonleaf)
+
+	leafproc := goproc.CreateProcess(spec, "leafproc", leaf)
+	nonleafproc := goproc.CreateProcess(spec, "nonleafproc", nonleaf)
+	controlplane.AddAdminServer(spec, nonleafproc)
+
+	app := assertBuildSuccess(t, spec, leafproc, nonleafproc)
+
+	assertIR(t, app,
+		`TestAdminServer = BlueprintApplication() {
+			leaf.grpc.addr
+			leaf.grpc.bind_addr = AddressConfig()
+			leaf.grpc.dial_addr = AddressConfig()
+			leaf.handler.visibility
+			leafproc = GolangProcessNode(leaf.grpc.bind_addr) {
+			  leaf = TestLeafService()
+			  leaf.grpc_server = GRPCServer(leaf, leaf.grpc.bind_addr)
+			  leafproc.logger = SLogger()
+			  leafproc.stdoutmetriccollector = StdoutMetricCollector()
+			}
+			nonleaf.grpc.addr
+			nonleaf.grpc.bind_addr = AddressConfig()
+			nonleaf.handler.visibility
+			nonleafproc = GolangProcessNode(leaf.grpc.dial_addr, nonleaf.grpc.bind_addr, nonleafproc.admin.bind_addr) {
+			  leaf.client = leaf.client.retrier
+			  leaf.client.retrier = Retrier(leaf.client.timeout)
+			  leaf.client.timeout = TimeoutClient(leaf.grpc_client)
+			  leaf.grpc_client = GRPCClient(leaf.grpc.dial_addr)
+			  nonleaf = TestNonLeafService(leaf.client)
+			  nonleaf.grpc_server = GRPCServer(nonleaf, nonleaf.grpc.bind_addr)
+			  nonleafproc.admin = AdminServer(nonleafproc.admin.bind_addr)
+			  nonleafproc.logger = SLogger()
+			  nonleafproc.stdoutmetriccollector = StdoutMetricCollector()
+			}
+			nonleafproc.admin.addr
+			nonleafproc.admin.bind_addr = AddressConfig()
+		  }`)
+}
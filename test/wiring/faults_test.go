@@ -0,0 +1,56 @@
+package wiring
+
+import (
+	"testing"
+
+	"github.com/blueprint-uservices/blueprint/plugins/faults"
+	"github.com/blueprint-uservices/blueprint/plugins/goproc"
+	"github.com/blueprint-uservices/blueprint/plugins/grpc"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow"
+	wf "github.com/blueprint-uservices/blueprint/test/workflow/workflow"
+)
+
+func TestServerAndClientFaults(t *testing.T) {
+	spec := newWiringSpec("TestServerAndClientFaults")
+
+	leaf := workflow.Service[*wf.TestLeafServiceImpl](spec, "leaf")
+	nonleaf := workflow.Service[wf.TestNonLeafService](spec, "nonleaf", leaf)
+
+	faults.AddServerFaults(spec, leaf, faults.ErrorRate(0.1), faults.PanicRate(0.01, "HelloInt", "HelloObject"))
+	faults.AddClientFaults(spec, leaf, faults.DropRate(0.05), faults.Disabled())
+
+	grpc.Deploy(spec, leaf)
+	grpc.Deploy(spec, nonleaf)
+
+	leafproc := goproc.CreateProcess(spec, "leafproc", leaf)
+	nonleafproc := goproc.CreateProcess(spec, "nonleafproc", nonleaf)
+
+	app := assertBuildSuccess(t, spec, leafproc, nonleafproc)
+
+	assertIR(t, app,
+		`TestServerAndClientFaults = BlueprintApplication() {
+			leaf.grpc.addr
+			leaf.grpc.bind_addr = AddressConfig()
+			leaf.grpc.dial_addr = AddressConfig()
+			leaf.handler.visibility
+			leafproc = GolangProcessNode(leaf.grpc.bind_addr) {
+			  leaf = TestLeafService()
+			  leaf.grpc_server = GRPCServer(leaf.server.faults, leaf.grpc.bind_addr)
+			  leaf.server.faults = FaultInjector(leaf, "error=0.1", "HelloInt,HelloObject.panic=0.01")
+			  leafproc.logger = SLogger()
+			  leafproc.stdoutmetriccollector = StdoutMetricCollector()
+			}
+			nonleaf.grpc.addr
+			nonleaf.grpc.bind_addr = AddressConfig()
+			nonleaf.handler.visibility
+			nonleafproc = GolangProcessNode(leaf.grpc.dial_addr, nonleaf.grpc.bind_addr) {
+			  leaf.client = leaf.client.faults
+			  leaf.client.faults = FaultInjector(leaf.grpc_client, "drop=0.05", "enabled=false")
+			  leaf.grpc_client = GRPCClient(leaf.grpc.dial_addr)
+			  nonleaf = TestNonLeafService(leaf.client)
+			  nonleaf.grpc_server = GRPCServer(nonleaf, nonleaf.grpc.bind_addr)
+			  nonleafproc.logger = SLogger()
+			  nonleafproc.stdoutmetriccollector = StdoutMetricCollector()
+			}
+		  }`)
+}
@@ -0,0 +1,41 @@
+package wiring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
+	"github.com/blueprint-uservices/blueprint/plugins/golang/gogen"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+Tests for the packaging of files that are read by generated processes when they run
+*/
+
+func TestPackageFileOptions(t *testing.T) {
+	workspace, err := gogen.NewWorkspaceBuilder(t.TempDir())
+	require.NoError(t, err)
+	module, err := gogen.NewModuleBuilder(workspace, "blueprint/testpackaged")
+	require.NoError(t, err)
+
+	fixtures := filepath.Join(t.TempDir(), "fixtures")
+	require.NoError(t, os.MkdirAll(filepath.Join(fixtures, "leaf_db"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(fixtures, "leaf_db", "users.json"), []byte(`[]`), 0644))
+
+	opts := []ir.IRNode{
+		&ir.IRValue{Value: "seed=" + fixtures},
+		&ir.IRValue{Value: "dir=/var/lib/leaf_db"},
+		&ir.IRValue{Value: "snapshot=1m"},
+	}
+	packaged, err := gogen.PackageFileOptions(module, "leaf_db", opts, "seed", "dir")
+	require.NoError(t, err)
+	require.Len(t, packaged, 3)
+
+	// Files that exist are copied into the module, and paths that don't exist are left to the process
+	require.Equal(t, "seed=files/leaf_db_seed/fixtures", packaged[0].(*ir.IRValue).Value)
+	require.FileExists(t, filepath.Join(module.Info().Path, "files", "leaf_db_seed", "fixtures", "leaf_db", "users.json"))
+	require.Equal(t, "dir=/var/lib/leaf_db", packaged[1].(*ir.IRValue).Value)
+	require.Equal(t, "snapshot=1m", packaged[2].(*ir.IRValue).Value)
+}
@@ -1,15 +1,19 @@
 package wiring
 
 import (
+	"os/exec"
 	"testing"
 
 	"github.com/blueprint-uservices/blueprint/plugins/goproc"
 	"github.com/blueprint-uservices/blueprint/plugins/grpc"
+	"github.com/blueprint-uservices/blueprint/plugins/grpc/grpccodegen"
 	"github.com/blueprint-uservices/blueprint/plugins/simple"
 	"github.com/blueprint-uservices/blueprint/plugins/workflow"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow/workflowspec"
 	"github.com/blueprint-uservices/blueprint/test/workflow/cache"
 	wf "github.com/blueprint-uservices/blueprint/test/workflow/workflow"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 /*
@@ -318,7 +322,7 @@ func TestImplicitCacheInSameProc(t *testing.T) {
 			leafproc = GolangProcessNode(leaf.grpc.bind_addr) {
 			  leaf = TestLeafService(leaf_cache)
 			  leaf.grpc_server = GRPCServer(leaf, leaf.grpc.bind_addr)
-			  leaf_cache = SimpleCache()
+			  leaf_cache = SimpleCache("name=leaf_cache")
 			  leafproc.logger = SLogger()
 			  leafproc.stdoutmetriccollector = StdoutMetricCollector()
 			}
@@ -337,3 +341,209 @@ func TestImplicitCacheInSameProc(t *testing.T) {
 		  }`)
 
 }
+
+/*
+Generates the gRPC server and client of TestStreamingService, then runs a test within the generated
+package that calls the server with the client.  Requires protoc and its Go plugins.
+*/
+func TestGRPCStreamingRoundTrip(t *testing.T) {
+	for _, tool := range []string{"protoc", "protoc-gen-go", "protoc-gen-go-grpc"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("skipping test that requires %v", tool)
+		}
+	}
+	newWiringSpec(t.Name())
+	svc, err := workflowspec.GetService[*wf.TestStreamingServiceImpl]()
+	require.NoError(t, err)
+	service := svc.Iface.ServiceInterface(nil)
+
+	workspace, module := newGeneratedModule(t)
+	require.NoError(t, grpccodegen.GenerateGRPCProto(module, service, "grpc"))
+	require.NoError(t, grpccodegen.GenerateServerHandler(module, service, "grpc"))
+	require.NoError(t, grpccodegen.GenerateClient(module, service, "grpc"))
+	runGeneratedTest(t, workspace, module, "grpc", "streaming_test.go", grpcStreamingTest)
+}
+
+var grpcStreamingTest = `package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/rpcerror"
+	wf "github.com/blueprint-uservices/blueprint/test/workflow/workflow"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// A server whose Watch streams fail after sending one value
+type failingServer struct {
+	UnimplementedTestStreamingServiceServer
+}
+
+func (failingServer) Watch(req *TestStreamingService_Watch_Request, stream TestStreamingService_WatchServer) error {
+	stream.SendHeader(metadata.MD{})
+	stream.Send(new(TestStreamingService_Watch_ResponseItem).marshall(wf.TestNestedLeafObject{Key: "1"}))
+	return status.Error(codes.Unavailable, "connection lost")
+}
+
+func listen(t *testing.T) net.Listener {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lis
+}
+
+func keys(objs <-chan wf.TestNestedLeafObject) []string {
+	var keys []string
+	for obj := range objs {
+		keys = append(keys, obj.Key)
+	}
+	return keys
+}
+
+func waitForServer(t *testing.T, addr string) {
+	for i := 0; ; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return
+		} else if i == 100 {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func send(objs chan<- wf.TestNestedLeafObject, keys ...string) {
+	for _, key := range keys {
+		objs <- wf.TestNestedLeafObject{Key: key}
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	lis := listen(t)
+	addr := lis.Addr().String()
+	lis.Close()
+	service := &wf.TestStreamingServiceImpl{Counts: make(chan int64, 16), Received: make(chan string, 16)}
+	server, _ := New_TestStreamingService_GRPCServerHandler(ctx, service, addr)
+	go server.Run(ctx)
+	waitForServer(t, addr)
+	client, err := New_TestStreamingService_GRPCClient(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Server streaming
+	watched, err := client.Watch(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(watched); len(got) != 3 || got[2] != "3" {
+		t.Errorf("Watch received %v", got)
+	}
+	if err := rpcerror.StreamError(watched); err != nil {
+		t.Errorf("Watch failed with %v", err)
+	}
+
+	// A nil channel is an empty stream
+	watched, err = client.Watch(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(watched); len(got) != 0 {
+		t.Errorf("Watch received %v", got)
+	}
+	if err := rpcerror.StreamError(watched); err != nil {
+		t.Errorf("empty Watch failed with %v", err)
+	}
+
+	var listed []string
+	err = client.List(ctx, 2, func(obj wf.TestNestedLeafObject) error {
+		listed = append(listed, obj.Key)
+		return nil
+	})
+	if err != nil || len(listed) != 2 {
+		t.Errorf("List received %v, %v", listed, err)
+	}
+
+	// Client streaming
+	objs := make(chan wf.TestNestedLeafObject)
+	go func() {
+		defer close(objs)
+		send(objs, "a", "b", "c")
+	}()
+	count, err := client.Count(ctx, objs)
+	if err != nil || count != 3 || <-service.Counts != 3 {
+		t.Errorf("Count returned %v, %v", count, err)
+	}
+	for i := 0; i < 3; i++ {
+		<-service.Received
+	}
+
+	// An aborted upload cancels the call, rather than ending the service's input
+	uploadCtx, abort := context.WithCancel(ctx)
+	objs = make(chan wf.TestNestedLeafObject)
+	go func() {
+		send(objs, "a", "b")
+		// Wait for the service to receive the values, since the call never reaches it if aborted earlier
+		<-service.Received
+		<-service.Received
+		abort()
+	}()
+	if _, err := client.Count(uploadCtx, objs); !errors.Is(err, context.Canceled) {
+		t.Errorf("aborted Count returned %v", err)
+	}
+	select {
+	case count := <-service.Counts:
+		if count != -1 {
+			t.Errorf("aborted upload was counted as complete with %v values", count)
+		}
+	case <-ctx.Done():
+		t.Fatal("service did not see the aborted upload")
+	}
+
+	// Bidirectional streaming
+	objs = make(chan wf.TestNestedLeafObject)
+	go func() {
+		defer close(objs)
+		send(objs, "a", "b")
+	}()
+	echoes, err := client.Echo(ctx, "echo-", objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(echoes); len(got) != 2 || got[0] != "echo-a" {
+		t.Errorf("Echo received %v", got)
+	}
+
+	// Streams that fail part-way are distinguished from complete streams
+	lis = listen(t)
+	failing := grpc.NewServer()
+	RegisterTestStreamingServiceServer(failing, failingServer{})
+	go failing.Serve(lis)
+	defer failing.Stop()
+	failingClient, err := New_TestStreamingService_GRPCClient(ctx, lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	watched, err = failingClient.Watch(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keys(watched); len(got) != 1 {
+		t.Errorf("failing Watch received %v", got)
+	}
+	if err := rpcerror.StreamError(watched); !errors.Is(err, rpcerror.ErrUnavailable) {
+		t.Errorf("failing Watch ended with %v", err)
+	}
+}
+`
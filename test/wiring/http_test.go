@@ -0,0 +1,257 @@
+package wiring
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blueprint-uservices/blueprint/plugins/golang/gocode"
+	"github.com/blueprint-uservices/blueprint/plugins/golang/gogen"
+	"github.com/blueprint-uservices/blueprint/plugins/goproc"
+	"github.com/blueprint-uservices/blueprint/plugins/http"
+	"github.com/blueprint-uservices/blueprint/plugins/http/httpcodegen"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow/workflowspec"
+	wf "github.com/blueprint-uservices/blueprint/test/workflow/workflow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "Rewrites the golden files in testdata")
+
+func TestHTTPRoutes(t *testing.T) {
+	spec := newWiringSpec("TestHTTPRoutes")
+
+	leaf := workflow.Service[*wf.TestLeafServiceImpl](spec, "leaf")
+	nonleaf := workflow.Service[wf.TestNonLeafService](spec, "nonleaf", leaf)
+
+	http.Deploy(spec, leaf, http.Route("HelloObject", "POST /objects"), http.Route("HelloInt", "GET /ints/{a}"))
+	http.Deploy(spec, nonleaf)
+
+	leafproc := goproc.CreateProcess(spec, "leafproc", leaf)
+	nonleafproc := goproc.CreateProcess(spec, "nonleafproc", nonleaf)
+
+	app := assertBuildSuccess(t, spec, leafproc, nonleafproc)
+
+	assertIR(t, app,
+		`TestHTTPRoutes = BlueprintApplication() {
+			leaf.handler.visibility
+			leaf.http.addr
+			leaf.http.bind_addr = AddressConfig()
+			leaf.http.dial_addr = AddressConfig()
+			leafproc = GolangProcessNode(leaf.http.bind_addr) {
+			  leaf = TestLeafService()
+			  leaf.http_server = HTTPServer(leaf, leaf.http.bind_addr, "route.HelloInt=GET /ints/{a}", "route.HelloObject=POST /objects")
+			  leafproc.logger = SLogger()
+			  leafproc.stdoutmetriccollector = StdoutMetricCollector()
+			}
+			nonleaf.handler.visibility
+			nonleaf.http.addr
+			nonleaf.http.bind_addr = AddressConfig()
+			nonleafproc = GolangProcessNode(leaf.http.dial_addr, nonleaf.http.bind_addr) {
+			  leaf.client = leaf.http_client
+			  leaf.http_client = HTTPClient(leaf.http.dial_addr)
+			  nonleaf = TestNonLeafService(leaf.client)
+			  nonleaf.http_server = HTTPServer(nonleaf, nonleaf.http.bind_addr)
+			  nonleafproc.logger = SLogger()
+			  nonleafproc.stdoutmetriccollector = StdoutMetricCollector()
+			}
+		  }`)
+}
+
+func getRoutedService(t *testing.T) *gocode.ServiceInterface {
+	newWiringSpec(t.Name())
+	svc, err := workflowspec.GetService[*wf.TestRoutedServiceImpl]()
+	require.NoError(t, err)
+	return svc.Iface.ServiceInterface(nil)
+}
+
+func TestHTTPParseRoute(t *testing.T) {
+	f := getRoutedService(t).Methods["PutObject"]
+
+	route, err := httpcodegen.ParseRoute(f, "PUT /names/{name}/objects/{id}")
+	require.NoError(t, err)
+	assert.Equal(t, "PUT", route.Verb)
+	assert.Equal(t, "/names/{name}/objects/{id}", route.Path)
+	require.Len(t, route.Params, 3)
+	assert.Equal(t, "path", route.Params[0].In)
+	assert.Equal(t, "path", route.Params[1].In)
+	assert.Equal(t, "body", route.Params[2].In)
+	assert.Equal(t, "Arg0", route.Params[2].Field)
+	assert.Equal(t, `"/names/" + url.PathEscape(param_name) + "/objects/" + url.PathEscape(param_id)`, route.PathExpr())
+
+	// Arguments that aren't bound explicitly are query params for verbs without a body
+	route, err = httpcodegen.ParseRoute(f, "DELETE /objects/{id}?name")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"query", "path", "query"}, []string{route.Params[0].In, route.Params[1].In, route.Params[2].In})
+	assert.Empty(t, route.BodyParams())
+
+	for _, invalid := range []string{
+		"/names/{name}",             // missing verb
+		"FETCH /names/{name}",       // unknown verb
+		"PUT names/{name}",          // relative path
+		"PUT /names/{name",          // unbalanced braces
+		"PUT /names/{name}/{name}",  // bound twice
+		"PUT /names/{name}?name",    // bound twice
+		"PUT /names/{nickname}",     // not an argument
+		"PUT /names/{name}?id&size", // not an argument
+	} {
+		_, err := httpcodegen.ParseRoute(f, invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestHTTPRouteDirectives(t *testing.T) {
+	service := getRoutedService(t)
+
+	// Routes are declared by directives on the interface's methods
+	routes, err := httpcodegen.Routes(service, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "GET", routes["Greet"].Verb)
+	assert.Equal(t, "/names/{name}", routes["Greet"].Path)
+	assert.Equal(t, "PUT", routes["PutObject"].Verb)
+
+	// Routes given to the plugin override directives; methods without routes get the default route
+	routes, err = httpcodegen.Routes(service, map[string]string{"Greet": "POST /greetings"})
+	require.NoError(t, err)
+	assert.Equal(t, "POST", routes["Greet"].Verb)
+	assert.Equal(t, "body", routes["Greet"].Params[0].In)
+
+	_, err = httpcodegen.Routes(service, map[string]string{"Farewell": "POST /farewells"})
+	assert.Error(t, err)
+
+	// Paths that differ only in the names of params conflict, as does the default route with any other route
+	_, err = httpcodegen.Routes(service, map[string]string{"Greet": "PUT /names/{greeting}/objects/{times}"})
+	assert.Error(t, err)
+	_, err = httpcodegen.Routes(service, map[string]string{"Greet": "GET /names/{name}/objects/{greeting}"})
+	assert.NoError(t, err)
+	undirected := service.Methods["Greet"]
+	undirected.Directives = nil
+	_, err = httpcodegen.Routes(&gocode.ServiceInterface{
+		UserType: service.UserType,
+		BaseName: service.BaseName,
+		Methods:  map[string]gocode.Func{"Greet": undirected, "PutObject": service.Methods["PutObject"]},
+	}, map[string]string{"PutObject": "POST /Greet"})
+	assert.Error(t, err)
+}
+
+func TestHTTPBindRawParams(t *testing.T) {
+	routes, err := httpcodegen.Routes(getRoutedService(t), nil)
+	require.NoError(t, err)
+	require.NoError(t, httpcodegen.BindRawParams(workflowspec.Get().Modules, routes))
+
+	// Strings and named string types are bound as is; other types are JSON-encoded
+	greet := routes["Greet"].Params
+	assert.Equal(t, []bool{true, true, false}, []bool{greet[0].Raw, greet[1].Raw, greet[2].Raw})
+	put := routes["PutObject"].Params
+	assert.Equal(t, []bool{true, false, false}, []bool{put[0].Raw, put[1].Raw, put[2].Raw})
+}
+
+/*
+Generates the OpenAPI document of TestCatalogService and compares it with the golden document in
+testdata.  Run with -update to rewrite the golden document.
+*/
+func TestHTTPOpenAPI(t *testing.T) {
+	newWiringSpec(t.Name())
+	svc, err := workflowspec.GetService[*wf.TestCatalogServiceImpl]()
+	require.NoError(t, err)
+
+	workspace, err := gogen.NewWorkspaceBuilder(t.TempDir())
+	require.NoError(t, err)
+	module, err := gogen.NewModuleBuilder(workspace, "blueprint/testopenapi")
+	require.NoError(t, err)
+	require.NoError(t, httpcodegen.GenerateOpenAPI(module, svc.Iface.ServiceInterface(nil), "http", nil))
+	actual, err := os.ReadFile(filepath.Join(module.Info().Path, "http", "TestCatalogService_openapi.json"))
+	require.NoError(t, err)
+
+	golden := filepath.Join("testdata", "TestCatalogService_openapi.json")
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll("testdata", 0755))
+		require.NoError(t, os.WriteFile(golden, actual, 0644))
+	}
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), string(actual))
+}
+
+/*
+Generates the HTTP server and client of TestRoutedService, then runs a test within the generated
+package that calls the server with the client
+*/
+func TestHTTPRoundTrip(t *testing.T) {
+	service := getRoutedService(t)
+	workspace, module := newGeneratedModule(t)
+	require.NoError(t, httpcodegen.GenerateServerHandler(module, service, "http", nil))
+	require.NoError(t, httpcodegen.GenerateClient(module, service, "http", nil))
+	runGeneratedTest(t, workspace, module, "http", "roundtrip_test.go", httpRoundTripTest)
+}
+
+var httpRoundTripTest = `package http
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	wf "github.com/blueprint-uservices/blueprint/test/workflow/workflow"
+)
+
+func TestRoundTrip(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	server, _ := New_TestRoutedService_HTTPServerHandler(ctx, &wf.TestRoutedServiceImpl{}, addr)
+	go server.Run(ctx)
+	client, _ := New_TestRoutedService_HTTPClient(ctx, addr)
+
+	// Wait for the server to start
+	for i := 0; ; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			break
+		} else if i == 100 {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Named string types are bound as is, and path params can contain slashes
+	greeting, err := client.Greet(ctx, "bob/alice", "hi there", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if greeting != "hi there bob/alice!hi there bob/alice!" {
+		t.Errorf("unexpected greeting %q", greeting)
+	}
+
+	obj, err := client.PutObject(ctx, "50% of bob", 7, wf.TestLeafObject{Count: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.ID != 7 || obj.Name != "50% of bob" || obj.Count != 3 {
+		t.Errorf("unexpected object %+v", obj)
+	}
+
+	// Other HTTP clients can pass strings without encoding them as JSON
+	resp, err := http.Get("http://" + addr + "/names/bob?greeting=hello&times=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "{\"Ret0\":\"hello bob!\"}\n" {
+		t.Errorf("unexpected response %v %s", resp.StatusCode, body)
+	}
+}
+`
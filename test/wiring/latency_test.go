@@ -0,0 +1,60 @@
+package wiring
+
+import (
+	"testing"
+
+	"github.com/blueprint-uservices/blueprint/plugins/goproc"
+	"github.com/blueprint-uservices/blueprint/plugins/latency"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow"
+	wf "github.com/blueprint-uservices/blueprint/test/workflow/workflow"
+)
+
+func TestFixedLatency(t *testing.T) {
+	spec := newWiringSpec("TestFixedLatency")
+
+	leaf := workflow.Service[*wf.TestLeafServiceImpl](spec, "leaf")
+	latency.AddFixed(spec, leaf, "100ms")
+
+	leafproc := goproc.CreateProcess(spec, "leafproc", leaf)
+
+	app := assertBuildSuccess(t, spec, leafproc)
+
+	assertIR(t, app,
+		`TestFixedLatency = BlueprintApplication() {
+			leaf.handler.visibility
+			leafproc = GolangProcessNode() {
+			  leaf = TestLeafService()
+			  leaf.server.latency = LatencyInjector(leaf, "distribution=fixed", "latency=100ms")
+			  leafproc.logger = SLogger()
+			  leafproc.stdoutmetriccollector = StdoutMetricCollector()
+			}
+		  }`)
+}
+
+func TestLatencyDistributions(t *testing.T) {
+	spec := newWiringSpec("TestLatencyDistributions")
+
+	leaf := workflow.Service[*wf.TestLeafServiceImpl](spec, "leaf")
+	nonleaf := workflow.Service[wf.TestNonLeafService](spec, "nonleaf", leaf)
+	latency.AddPareto(spec, leaf, "10ms", 2.5, latency.Fraction(0.1), latency.Methods("HelloInt", "HelloObject"))
+	latency.AddEmpirical(spec, nonleaf, "latencies.csv")
+
+	proc := goproc.CreateProcess(spec, "proc", leaf, nonleaf)
+
+	app := assertBuildSuccess(t, spec, proc)
+
+	assertIR(t, app,
+		`TestLatencyDistributions = BlueprintApplication() {
+			leaf.handler.visibility
+			nonleaf.handler.visibility
+			proc = GolangProcessNode() {
+			  leaf = TestLeafService()
+			  leaf.client = leaf.server.latency
+			  leaf.server.latency = LatencyInjector(leaf, "distribution=pareto", "scale=10ms", "shape=2.5", "fraction=0.1", "methods=HelloInt,HelloObject")
+			  nonleaf = TestNonLeafService(leaf.client)
+			  nonleaf.server.latency = LatencyInjector(nonleaf, "distribution=empirical", "file=latencies.csv")
+			  proc.logger = SLogger()
+			  proc.stdoutmetriccollector = StdoutMetricCollector()
+			}
+		  }`)
+}
@@ -0,0 +1,68 @@
+package wiring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blueprint-uservices/blueprint/plugins/rabbitmq"
+	"github.com/blueprint-uservices/blueprint/plugins/simple"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow"
+	"github.com/blueprint-uservices/blueprint/test/workflow/pubsub"
+)
+
+func TestSimplePubSub(t *testing.T) {
+	spec := newWiringSpec("TestSimplePubSub")
+
+	leaf_pubsub := simple.PubSub(spec, "leaf_pubsub")
+	leaf := workflow.Service[*pubsub.TestLeafServiceImplWithPubSub](spec, "leaf", leaf_pubsub)
+
+	app := assertBuildSuccess(t, spec, leaf, leaf_pubsub)
+
+	assertIR(t, app,
+		`TestSimplePubSub = BlueprintApplication() {
+			leaf = TestLeafService(leaf_pubsub)
+			leaf.client = leaf
+			leaf.handler.visibility
+			leaf_pubsub = SimplePubSub()
+			leaf_pubsub.backend.visibility
+          }`)
+}
+
+func TestSimplePubSubWithOptions(t *testing.T) {
+	spec := newWiringSpec("TestSimplePubSubWithOptions")
+
+	leaf_pubsub := simple.PubSub(spec, "leaf_pubsub", simple.VisibilityTimeout(10*time.Second), simple.MaxDeliveries(5))
+	leaf := workflow.Service[*pubsub.TestLeafServiceImplWithPubSub](spec, "leaf", leaf_pubsub)
+
+	app := assertBuildSuccess(t, spec, leaf, leaf_pubsub)
+
+	assertIR(t, app,
+		`TestSimplePubSubWithOptions = BlueprintApplication() {
+			leaf = TestLeafService(leaf_pubsub)
+			leaf.client = leaf
+			leaf.handler.visibility
+			leaf_pubsub = SimplePubSub("visibility=10s", "maxdeliveries=5")
+			leaf_pubsub.backend.visibility
+          }`)
+}
+
+func TestRabbitmqPubSub(t *testing.T) {
+	spec := newWiringSpec("TestRabbitmqPubSub")
+
+	leaf_pubsub := rabbitmq.PubSubContainer(spec, "leaf_pubsub", rabbitmq.MaxDeliveries(5))
+	leaf := workflow.Service[*pubsub.TestLeafServiceImplWithPubSub](spec, "leaf", leaf_pubsub)
+
+	app := assertBuildSuccess(t, spec, leaf, leaf_pubsub)
+
+	assertIR(t, app,
+		`TestRabbitmqPubSub = BlueprintApplication() {
+			leaf = TestLeafService(leaf_pubsub.client)
+			leaf.client = leaf
+			leaf.handler.visibility
+			leaf_pubsub.addr
+			leaf_pubsub.bind_addr = AddressConfig()
+			leaf_pubsub.client = RabbitmqPubSubClient(leaf_pubsub.dial_addr, "maxdeliveries=5")
+			leaf_pubsub.ctr = RabbitmqContainer(leaf_pubsub.bind_addr)
+			leaf_pubsub.dial_addr = AddressConfig()
+          }`)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/blueprint-uservices/blueprint/plugins/simple"
 	"github.com/blueprint-uservices/blueprint/plugins/workflow"
+	"github.com/blueprint-uservices/blueprint/runtime/plugins/simplecache"
 	"github.com/blueprint-uservices/blueprint/test/workflow/cache"
 	wf "github.com/blueprint-uservices/blueprint/test/workflow/workflow"
 )
@@ -22,7 +23,7 @@ func TestSimpleCache(t *testing.T) {
 			leaf = TestLeafService(leaf_cache)
 			leaf.client = leaf
 			leaf.handler.visibility
-			leaf_cache = SimpleCache()
+			leaf_cache = SimpleCache("name=leaf_cache")
 			leaf_cache.backend.visibility
           }`)
 }
@@ -40,10 +41,28 @@ func TestSimpleCacheAndServices(t *testing.T) {
 			leaf = TestLeafService(leaf_cache)
 			leaf.client = leaf
 			leaf.handler.visibility
-			leaf_cache = SimpleCache()
+			leaf_cache = SimpleCache("name=leaf_cache")
 			leaf_cache.backend.visibility
 			nonleaf = TestNonLeafService(leaf.client)
 			nonleaf.client = nonleaf
 			nonleaf.handler.visibility
           }`)
 }
+
+func TestSimpleCacheWithOptions(t *testing.T) {
+	spec := newWiringSpec("TestSimpleCacheWithOptions")
+
+	leaf_cache := simple.Cache(spec, "leaf_cache", simple.CacheCapacity(100), simple.CacheEvictionPolicy(simplecache.LFU))
+	leaf := workflow.Service[*cache.TestLeafServiceImplWithCache](spec, "leaf", leaf_cache)
+
+	app := assertBuildSuccess(t, spec, leaf, leaf_cache)
+
+	assertIR(t, app,
+		`TestSimpleCacheWithOptions = BlueprintApplication() {
+			leaf = TestLeafService(leaf_cache)
+			leaf.client = leaf
+			leaf.handler.visibility
+			leaf_cache = SimpleCache("name=leaf_cache", "capacity=100", "policy=lfu")
+			leaf_cache.backend.visibility
+          }`)
+}
@@ -2,6 +2,7 @@ package wiring
 
 import (
 	"testing"
+	"time"
 
 	"github.com/blueprint-uservices/blueprint/plugins/simple"
 	"github.com/blueprint-uservices/blueprint/plugins/workflow"
@@ -24,7 +25,7 @@ func TestSimpleNoSQLDB(t *testing.T) {
 			leaf = TestLeafService(leaf_cache, leaf_db)
 			leaf.client = leaf
 			leaf.handler.visibility
-			leaf_cache = SimpleCache()
+			leaf_cache = SimpleCache("name=leaf_cache")
 			leaf_cache.backend.visibility
 			leaf_db = SimpleNoSQLDB()
 			leaf_db.backend.visibility
@@ -33,3 +34,24 @@ func TestSimpleNoSQLDB(t *testing.T) {
 			nonleaf.handler.visibility
 		  }`)
 }
+
+func TestSimpleNoSQLDBWithOptions(t *testing.T) {
+	spec := newWiringSpec("TestSimpleNoSQLDBWithOptions")
+
+	leaf_cache := simple.Cache(spec, "leaf_cache")
+	leaf_db := simple.NoSQLDB(spec, "leaf_db", simple.NoSQLDBDataDir("data/leaf_db"), simple.NoSQLDBSnapshotInterval(30*time.Second), simple.NoSQLDBSeed("fixtures"))
+	leaf := workflow.Service[*nosqldb.TestLeafServiceImplWithDB](spec, "leaf", leaf_cache, leaf_db)
+
+	app := assertBuildSuccess(t, spec, leaf, leaf_db)
+
+	assertIR(t, app,
+		`TestSimpleNoSQLDBWithOptions = BlueprintApplication() {
+			leaf = TestLeafService(leaf_cache, leaf_db)
+			leaf.client = leaf
+			leaf.handler.visibility
+			leaf_cache = SimpleCache("name=leaf_cache")
+			leaf_cache.backend.visibility
+			leaf_db = SimpleNoSQLDB("dir=data/leaf_db", "snapshot=30s", "seed=fixtures")
+			leaf_db.backend.visibility
+		  }`)
+}
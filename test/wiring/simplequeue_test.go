@@ -0,0 +1,46 @@
+package wiring
+
+import (
+	"testing"
+
+	"github.com/blueprint-uservices/blueprint/plugins/simple"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow"
+	"github.com/blueprint-uservices/blueprint/runtime/plugins/simplequeue"
+	"github.com/blueprint-uservices/blueprint/test/workflow/queue"
+)
+
+func TestSimpleQueue(t *testing.T) {
+	spec := newWiringSpec("TestSimpleQueue")
+
+	leaf_queue := simple.Queue(spec, "leaf_queue")
+	leaf := workflow.Service[*queue.TestLeafServiceImplWithQueue](spec, "leaf", leaf_queue)
+
+	app := assertBuildSuccess(t, spec, leaf, leaf_queue)
+
+	assertIR(t, app,
+		`TestSimpleQueue = BlueprintApplication() {
+			leaf = TestLeafService(leaf_queue)
+			leaf.client = leaf
+			leaf.handler.visibility
+			leaf_queue = SimpleQueue()
+			leaf_queue.backend.visibility
+          }`)
+}
+
+func TestSimpleQueueWithOptions(t *testing.T) {
+	spec := newWiringSpec("TestSimpleQueueWithOptions")
+
+	leaf_queue := simple.Queue(spec, "leaf_queue", simple.QueueUnbounded(), simple.QueueOverflowPolicy(simplequeue.DropOldest))
+	leaf := workflow.Service[*queue.TestLeafServiceImplWithQueue](spec, "leaf", leaf_queue)
+
+	app := assertBuildSuccess(t, spec, leaf, leaf_queue)
+
+	assertIR(t, app,
+		`TestSimpleQueueWithOptions = BlueprintApplication() {
+			leaf = TestLeafService(leaf_queue)
+			leaf.client = leaf
+			leaf.handler.visibility
+			leaf_queue = SimpleQueue("capacity=0", "overflow=dropoldest")
+			leaf_queue.backend.visibility
+          }`)
+}
@@ -2,12 +2,17 @@ package wiring
 
 import (
 	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/blueprint/logging"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/ir"
 	"github.com/blueprint-uservices/blueprint/blueprint/pkg/wiring"
+	"github.com/blueprint-uservices/blueprint/plugins/golang"
+	"github.com/blueprint-uservices/blueprint/plugins/golang/gogen"
 	"github.com/blueprint-uservices/blueprint/plugins/workflow/workflowspec"
 	"github.com/stretchr/testify/require"
 )
@@ -64,3 +69,41 @@ func assertIR(t *testing.T, app *ir.ApplicationNode, expected string) bool {
 	require.Equal(t, b, a, "Got unexpected application\n%v", app.String())
 	return true
 }
+
+/*
+Creates a workspace in a temporary directory, containing a module that depends on the test workflow
+and the Blueprint runtime, to which tests can add generated code.  Tests that compile generated code
+are skipped in short mode.
+*/
+func newGeneratedModule(t *testing.T) (*gogen.WorkspaceBuilderImpl, *gogen.ModuleBuilderImpl) {
+	if testing.Short() {
+		t.Skip("skipping test that compiles generated code in short mode")
+	}
+	if !*compilerLogging {
+		logging.DisableCompilerLogging()
+		t.Cleanup(logging.EnableCompilerLogging)
+	}
+	workspace, err := gogen.NewWorkspaceBuilder(t.TempDir())
+	require.NoError(t, err)
+	module, err := gogen.NewModuleBuilder(workspace, "blueprint/testgenerated")
+	require.NoError(t, err)
+	for _, mod := range []string{"github.com/blueprint-uservices/blueprint/test/workflow", "github.com/blueprint-uservices/blueprint/runtime"} {
+		require.NoError(t, golang.AddModule(module, mod))
+	}
+	return workspace, module
+}
+
+/*
+Adds the test file to pkg within module, then finishes the workspace and runs go test on pkg
+*/
+func runGeneratedTest(t *testing.T, workspace *gogen.WorkspaceBuilderImpl, module *gogen.ModuleBuilderImpl, pkg string, filename string, contents string) {
+	pkgDir := filepath.Join(module.Info().Path, pkg)
+	require.NoError(t, os.WriteFile(filepath.Join(pkgDir, filename), []byte(contents), 0644))
+	require.NoError(t, workspace.Finish())
+
+	cmd := exec.Command("go", "test", "./"+pkg)
+	cmd.Dir = module.Info().Path
+	cmd.Env = append(os.Environ(), "GOWORK="+filepath.Join(workspace.Info().Path, "go.work"))
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "Generated code failed its test:\n%v", string(out))
+}
@@ -0,0 +1,49 @@
+package wiring
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blueprint-uservices/blueprint/plugins/thrift/thriftcodegen"
+	"github.com/blueprint-uservices/blueprint/plugins/workflow/workflowspec"
+	wf "github.com/blueprint-uservices/blueprint/test/workflow/workflow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+Tests for the code generated by the Thrift plugin
+*/
+
+func TestThriftCodegen(t *testing.T) {
+	newWiringSpec("TestThriftCodegen")
+	svc, err := workflowspec.GetService[*wf.TestLeafServiceImpl]()
+	require.NoError(t, err)
+
+	b := thriftcodegen.NewThriftBuilder(workflowspec.Get().Modules)
+	b.Package = "thrift"
+	b.PackageName = "blueprint/testproc/thrift"
+	b.ImportName = "testleafservice"
+	b.InternalPkg = b.PackageName + "/" + b.ImportName
+	require.NoError(t, b.AddService(svc.Iface.ServiceInterface(nil)))
+
+	dir := t.TempDir()
+	thriftFile := filepath.Join(dir, "TestLeafService.thrift")
+	require.NoError(t, b.WriteThriftFile(thriftFile))
+	require.NoError(t, b.GenerateMarshallingCode(filepath.Join(dir, "TestLeafService_conversions.go")))
+
+	data, err := os.ReadFile(thriftFile)
+	require.NoError(t, err)
+	thrift := string(data)
+	for _, method := range []string{"HelloNothing", "HelloInt", "HelloObject"} {
+		assert.Contains(t, thrift, "TestLeafService_"+method+"_Response "+method+" (1:TestLeafService_"+method+"_Request req)")
+	}
+	assert.Contains(t, thrift, "struct TestLeafObject {")
+
+	// Requests carry the client's timeout and idempotency key, and responses carry the service's error
+	assert.Equal(t, 3, strings.Count(thrift, "32767: i64 blueprint_timeout,"))
+	assert.Equal(t, 3, strings.Count(thrift, "32766: string blueprint_idempotency_key,"))
+	assert.Equal(t, 3, strings.Count(thrift, "32767: binary blueprint_error,"))
+}
@@ -87,3 +87,12 @@ func TestBadServiceConstructor2(t *testing.T) {
 		slog.Info("Application: \n" + app.String())
 	}
 }
+
+func TestEmbeddingServiceInterface(t *testing.T) {
+	spec := newWiringSpec("TestEmbeddingServiceInterface")
+
+	svc := workflow.Service[wf.TestEmbeddingService](spec, "svc") // the methods of embedded interfaces are not parsed
+
+	err := assertBuildFailure(t, spec, svc)
+	assert.Contains(t, err.Error(), "embeds")
+}
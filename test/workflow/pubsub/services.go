@@ -0,0 +1,59 @@
+package pubsub
+
+import (
+	ctxx "context"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/blueprint-uservices/blueprint/test/workflow/workflow"
+)
+
+/*
+Implements the services from ../workflow using a pub/sub backend
+*/
+
+/*
+Service implementation structs
+*/
+type (
+	TestLeafServiceImplWithPubSub struct {
+		workflow.TestLeafService
+		PubSub backend.PubSub
+	}
+)
+
+/*
+Constructors
+*/
+
+func NewTestLeafServiceImplWithPubSub(ctx ctxx.Context, pubsub backend.PubSub) (*TestLeafServiceImplWithPubSub, error) {
+	return &TestLeafServiceImplWithPubSub{PubSub: pubsub}, nil
+}
+
+/*
+Interface method bodies
+*/
+
+func (l *TestLeafServiceImplWithPubSub) HelloNothing(ctx ctxx.Context) error {
+	return nil
+}
+
+func (l *TestLeafServiceImplWithPubSub) HelloInt(ctx ctxx.Context, a int16) (int32, error) {
+	err := l.PubSub.Subscribe(ctx, "ints", "leaf")
+	if err != nil {
+		return 0, err
+	}
+	err = l.PubSub.Publish(ctx, "ints", int32(a))
+	if err != nil {
+		return 0, err
+	}
+	var myint int32
+	id, _, err := l.PubSub.Receive(ctx, "ints", "leaf", &myint)
+	if err != nil {
+		return 0, err
+	}
+	return myint, l.PubSub.Ack(ctx, "ints", "leaf", id)
+}
+
+func (l *TestLeafServiceImplWithPubSub) HelloObject(ctx ctxx.Context, obj workflow.TestLeafObject) (*workflow.TestLeafObject, error) {
+	return &obj, l.PubSub.Publish(ctx, "objects", obj)
+}
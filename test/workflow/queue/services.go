@@ -0,0 +1,53 @@
+package queue
+
+import (
+	ctxx "context"
+
+	"github.com/blueprint-uservices/blueprint/runtime/core/backend"
+	"github.com/blueprint-uservices/blueprint/test/workflow/workflow"
+)
+
+/*
+Implements the services from ../workflow using a queue
+*/
+
+/*
+Service implementation structs
+*/
+type (
+	TestLeafServiceImplWithQueue struct {
+		workflow.TestLeafService
+		Queue backend.Queue
+	}
+)
+
+/*
+Constructors
+*/
+
+func NewTestLeafServiceImplWithQueue(ctx ctxx.Context, queue backend.Queue) (*TestLeafServiceImplWithQueue, error) {
+	return &TestLeafServiceImplWithQueue{Queue: queue}, nil
+}
+
+/*
+Interface method bodies
+*/
+
+func (l *TestLeafServiceImplWithQueue) HelloNothing(ctx ctxx.Context) error {
+	return nil
+}
+
+func (l *TestLeafServiceImplWithQueue) HelloInt(ctx ctxx.Context, a int16) (int32, error) {
+	_, err := l.Queue.Push(ctx, int32(a))
+	if err != nil {
+		return 0, err
+	}
+	var myint int32
+	_, err = l.Queue.Pop(ctx, &myint)
+	return myint, err
+}
+
+func (l *TestLeafServiceImplWithQueue) HelloObject(ctx ctxx.Context, obj workflow.TestLeafObject) (*workflow.TestLeafObject, error) {
+	_, err := l.Queue.Push(ctx, obj)
+	return &obj, err
+}
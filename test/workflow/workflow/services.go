@@ -3,6 +3,9 @@ package workflow
 import (
 	"context"
 	ctxx "context"
+	"strconv"
+	"strings"
+	"time"
 )
 
 /*
@@ -10,6 +13,14 @@ Some simple services used for testing.
 
 TestNonLeafService calls TestLeafService
 
+TestRoutedService has RESTful HTTP routes
+
+TestEmbeddingService embeds TestLeafService, so is not a valid service
+
+TestStreamingService streams values to and from callers
+
+TestCatalogService has arguments and return values of nested, embedded and tagged structs
+
 No backend components are used.
 */
 
@@ -19,13 +30,45 @@ Workflow services
 type (
 	TestLeafService interface {
 		HelloNothing(ctx ctxx.Context) error
+
+		//blueprint:idempotent
 		HelloInt(ctx context.Context, a int16) (int32, error)
+
+		//blueprint:nonidempotent
 		HelloObject(ctxt context.Context, obj TestLeafObject) (*TestLeafObject, error)
 	}
 
 	TestNonLeafService interface {
 		Hello(ctx context.Context, a TestMyInt) (int64, error)
 	}
+
+	TestRoutedService interface {
+		//blueprint:http=GET /names/{name}?greeting
+		Greet(ctx context.Context, name TestName, greeting string, times int) (string, error)
+
+		//blueprint:http=PUT /names/{name}/objects/{id}
+		PutObject(ctx context.Context, name TestName, id int64, obj TestLeafObject) (*TestLeafObject, error)
+	}
+
+	TestEmbeddingService interface {
+		TestLeafService
+		Goodbye(ctx context.Context) error
+	}
+
+	TestStreamingService interface {
+		Watch(ctx context.Context, n int64) (<-chan TestNestedLeafObject, error)
+		List(ctx context.Context, n int64, yield func(TestNestedLeafObject) error) error
+		Count(ctx context.Context, objs <-chan TestNestedLeafObject) (int64, error)
+		Echo(ctx context.Context, prefix string, objs <-chan TestNestedLeafObject) (<-chan TestNestedLeafObject, error)
+	}
+
+	TestCatalogService interface {
+		//blueprint:http=GET /items/{id}
+		GetItem(ctx context.Context, id int64) (*TestCatalogItem, error)
+
+		//blueprint:http=PUT /items/{id}
+		PutItem(ctx context.Context, id int64, item TestCatalogItem) (TestItemStatus, error)
+	}
 )
 
 /*
@@ -34,6 +77,8 @@ Types used by services
 type (
 	TestMyInt int64
 
+	TestName string
+
 	TestNestedLeafObject struct {
 		Key   string
 		Value string
@@ -46,6 +91,28 @@ type (
 		Count int
 		Props map[string]TestNestedLeafObject
 	}
+
+	TestItemStatus string
+
+	TestItemTags []TestName
+
+	TestAudit struct {
+		CreatedBy string    `json:"created_by"`
+		Created   time.Time `json:"created,omitempty"`
+	}
+
+	TestCatalogItem struct {
+		TestAudit
+		ID       int64                  `json:"id"`
+		Name     string                 `json:"name,omitempty"`
+		Status   TestItemStatus         `json:"status"`
+		Tags     TestItemTags           `json:"tags,omitempty"`
+		Parts    []TestNestedLeafObject `json:"parts"`
+		Parent   *TestCatalogItem       `json:"parent,omitempty"`
+		Price    uint32
+		Secret   string `json:"-"`
+		internal int
+	}
 )
 
 /*
@@ -61,6 +128,24 @@ type (
 		leaf  TestLeafService
 		count int
 	}
+
+	TestRoutedServiceImpl struct {
+		TestRoutedService
+	}
+
+	TestEmbeddingServiceImpl struct {
+		TestLeafServiceImpl
+	}
+
+	TestCatalogServiceImpl struct {
+		TestCatalogService
+	}
+
+	TestStreamingServiceImpl struct {
+		TestStreamingService
+		Counts   chan int64  // Receives the result of each call to Count, or -1 if the stream was aborted
+		Received chan string // If not nil, receives the key of each value received by Count
+	}
 )
 
 /*
@@ -75,6 +160,22 @@ func NewLeafServiceImpl(ctx ctxx.Context) (*TestLeafServiceImpl, error) {
 	return &TestLeafServiceImpl{}, nil
 }
 
+func NewRoutedServiceImpl(ctx context.Context) (*TestRoutedServiceImpl, error) {
+	return &TestRoutedServiceImpl{}, nil
+}
+
+func NewEmbeddingServiceImpl(ctx context.Context) (TestEmbeddingService, error) {
+	return &TestEmbeddingServiceImpl{}, nil
+}
+
+func NewCatalogServiceImpl(ctx context.Context) (*TestCatalogServiceImpl, error) {
+	return &TestCatalogServiceImpl{}, nil
+}
+
+func NewStreamingServiceImpl(ctx context.Context) (*TestStreamingServiceImpl, error) {
+	return &TestStreamingServiceImpl{Counts: make(chan int64, 16)}, nil
+}
+
 /*
 Interface method bodies
 */
@@ -122,6 +223,102 @@ func (nl *TestNonLeafServiceImpl) Hello(ctx context.Context, a TestMyInt) (int64
 	return int64(nl.count), nil
 }
 
+func (r *TestRoutedServiceImpl) Greet(ctx context.Context, name TestName, greeting string, times int) (string, error) {
+	return strings.Repeat(greeting+" "+string(name)+"!", times), nil
+}
+
+func (r *TestRoutedServiceImpl) PutObject(ctx context.Context, name TestName, id int64, obj TestLeafObject) (*TestLeafObject, error) {
+	obj.ID = id
+	obj.Name = string(name)
+	return &obj, nil
+}
+
+func (e *TestEmbeddingServiceImpl) Goodbye(ctx context.Context) error {
+	return nil
+}
+
+func (c *TestCatalogServiceImpl) GetItem(ctx context.Context, id int64) (*TestCatalogItem, error) {
+	return &TestCatalogItem{ID: id, Status: "available", internal: 1}, nil
+}
+
+func (c *TestCatalogServiceImpl) PutItem(ctx context.Context, id int64, item TestCatalogItem) (TestItemStatus, error) {
+	return item.Status, nil
+}
+
+// Sends objects with keys 1 to n, or returns a nil channel if n is 0
+func (s *TestStreamingServiceImpl) Watch(ctx context.Context, n int64) (<-chan TestNestedLeafObject, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	objs := make(chan TestNestedLeafObject)
+	go func() {
+		defer close(objs)
+		for i := int64(1); i <= n; i++ {
+			select {
+			case objs <- TestNestedLeafObject{Key: strconv.FormatInt(i, 10)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return objs, nil
+}
+
+func (s *TestStreamingServiceImpl) List(ctx context.Context, n int64, yield func(TestNestedLeafObject) error) error {
+	for i := int64(1); i <= n; i++ {
+		if err := yield(TestNestedLeafObject{Key: strconv.FormatInt(i, 10)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TestStreamingServiceImpl) Count(ctx context.Context, objs <-chan TestNestedLeafObject) (int64, error) {
+	count := int64(0)
+	for {
+		select {
+		case obj, ok := <-objs:
+			if !ok {
+				s.Counts <- count
+				return count, nil
+			}
+			count++
+			if s.Received != nil {
+				s.Received <- obj.Key
+			}
+		case <-ctx.Done():
+			s.Counts <- -1
+			return 0, ctx.Err()
+		}
+	}
+}
+
+func (s *TestStreamingServiceImpl) Echo(ctx context.Context, prefix string, objs <-chan TestNestedLeafObject) (<-chan TestNestedLeafObject, error) {
+	echoes := make(chan TestNestedLeafObject)
+	go func() {
+		defer close(echoes)
+		for {
+			var obj TestNestedLeafObject
+			var ok bool
+			select {
+			case obj, ok = <-objs:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			obj.Key = prefix + obj.Key
+			select {
+			case echoes <- obj:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return echoes, nil
+}
+
 /*
 Non-interface functions
 */